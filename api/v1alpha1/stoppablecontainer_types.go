@@ -18,9 +18,15 @@ package v1alpha1
 
 import (
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// AnnotationAllowPrivileged on a StoppableContainer, set to "true", allows its template to
+// request privileged containers. Without it the validating webhook rejects them, and the
+// controller does not make the consumer container privileged.
+const AnnotationAllowPrivileged = "stoppablecontainer.xtlsoft.top/allow-privileged"
+
 // PodTemplateSpec defines the pod template for the consumer pod.
 // This embeds the standard Kubernetes PodSpec, providing full compatibility
 // with all PodSpec fields and admission controllers.
@@ -45,6 +51,12 @@ type ProviderSpec struct {
 	// +optional
 	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
 
+	// InfraResources defines the resource requirements for the operator's infra containers
+	// (the rootfs holder sidecar and the init containers). When unset, small built-in
+	// defaults are used. Set it to {} to omit them, so namespace LimitRange defaults apply.
+	// +optional
+	InfraResources *corev1.ResourceRequirements `json:"infraResources,omitempty"`
+
 	// NodeSelector defines the node selector for the provider pod
 	// The consumer pod will be scheduled on the same node
 	// +optional
@@ -53,6 +65,87 @@ type ProviderSpec struct {
 	// Tolerations for the provider pod
 	// +optional
 	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+
+	// Affinity defines the scheduling constraints for the provider pod.
+	// The consumer pod will be scheduled on the same node
+	// +optional
+	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+
+	// TopologySpreadConstraints spread provider pods across topology domains such as zones.
+	// The consumer pod will be scheduled on the same node
+	// +optional
+	TopologySpreadConstraints []corev1.TopologySpreadConstraint `json:"topologySpreadConstraints,omitempty"`
+
+	// SpreadAcrossNodes adds a required pod anti-affinity that keeps the provider pod off
+	// nodes that already run the provider pod of a StoppableContainer with the same labels.
+	// Without labels on the StoppableContainer, it allows one provider pod per node in
+	// the namespace
+	// +optional
+	SpreadAcrossNodes bool `json:"spreadAcrossNodes,omitempty"`
+
+	// PriorityClassName is the priority class of the provider pod. Preempting the provider
+	// loses the rootfs, so it can be given a higher priority than the consumer, whose
+	// priority class is set in the template.
+	// +optional
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+
+	// PrePullImage adds an init container to the provider pod that pulls and starts the
+	// user image before the rootfs container, so image pull errors surface early and clearly
+	// +optional
+	PrePullImage bool `json:"prePullImage,omitempty"`
+
+	// OverlayDir is a node directory, e.g. on local NVMe, that holds the rootfs overlay's
+	// upper and work directories instead of the container runtime's disk. The mount-helper
+	// DaemonSet must allow it with --overlay-dir-allowlist.
+	// +kubebuilder:validation:Pattern=`^/`
+	// +optional
+	OverlayDir string `json:"overlayDir,omitempty"`
+
+	// TerminationGracePeriodSeconds is the provider pod's termination grace period.
+	// On deletion the provider uses it to wait for mount-helper to unmount the rootfs
+	// and remove the work directory. Defaults to 30 seconds.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	TerminationGracePeriodSeconds *int64 `json:"terminationGracePeriodSeconds,omitempty"`
+
+	// LivenessProbe configures the liveness probe of the provider container, which
+	// checks that the rootfs directory is present. Restarting the provider can lose
+	// the rootfs, so the default only restarts it after 5 minutes of failed checks.
+	// +optional
+	LivenessProbe *ProviderLivenessProbe `json:"livenessProbe,omitempty"`
+
+	// Image is the image of the provider pod's infra containers, e.g. a mirror in an
+	// internal registry. It must provide the same binaries as the exec-wrapper image,
+	// which is used when unset.
+	// +optional
+	Image string `json:"image,omitempty"`
+}
+
+// ProviderLivenessProbe configures the provider container's liveness probe
+type ProviderLivenessProbe struct {
+	// Disabled removes the liveness probe, so the provider is never restarted by it
+	// +optional
+	Disabled bool `json:"disabled,omitempty"`
+
+	// PeriodSeconds is how often the probe runs. Defaults to 10.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	PeriodSeconds int32 `json:"periodSeconds,omitempty"`
+
+	// FailureThreshold is how many consecutive failed checks restart the provider.
+	// Defaults to 30.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	FailureThreshold int32 `json:"failureThreshold,omitempty"`
+}
+
+// PersistenceSpec places the rootfs overlay's upper and work directories on a volume
+type PersistenceSpec struct {
+	// ClaimName is a PersistentVolumeClaim in the StoppableContainer's namespace. It is
+	// mounted into the provider pod, so it must be usable from the provider's node, and
+	// its filesystem must support overlayfs upper directories (e.g. ext4 or xfs, not NFS).
+	// +kubebuilder:validation:MinLength=1
+	ClaimName string `json:"claimName"`
 }
 
 // StoppableContainerSpec defines the desired state of StoppableContainer
@@ -66,6 +159,14 @@ type StoppableContainerSpec struct {
 	// +kubebuilder:validation:Required
 	Template PodTemplateSpec `json:"template"`
 
+	// Replicas is the number of consumer pods, all chrooted into the same rootfs.
+	// Defaults to 1. The rootfs is shared, not copied: writes from one replica are
+	// visible to the others and concurrent writes to the same files may conflict.
+	// With more than one replica the pods are named <name>-consumer-<ordinal>.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	Replicas *int32 `json:"replicas,omitempty"`
+
 	// Provider defines the provider pod specification
 	// +optional
 	Provider ProviderSpec `json:"provider,omitempty"`
@@ -74,10 +175,71 @@ type StoppableContainerSpec struct {
 	// +kubebuilder:default="/var/lib/stoppablecontainer"
 	// +optional
 	HostPathPrefix string `json:"hostPathPrefix,omitempty"`
+
+	// CreateService makes the controller create and manage a Service that selects
+	// the consumer pod and exposes the ports declared in the template's containers
+	// +optional
+	CreateService bool `json:"createService,omitempty"`
+
+	// DeleteServiceOnStop deletes the managed Service while the container is stopped.
+	// By default the Service is kept (with no endpoints) across stop/start.
+	// +optional
+	DeleteServiceOnStop bool `json:"deleteServiceOnStop,omitempty"`
+
+	// ServiceType is the type of the managed Service. Defaults to ClusterIP.
+	// +kubebuilder:validation:Enum=ClusterIP;NodePort;LoadBalancer
+	// +optional
+	ServiceType corev1.ServiceType `json:"serviceType,omitempty"`
+
+	// ServiceAnnotations are added to the managed Service, e.g. to configure a cloud
+	// load balancer. Annotations removed from this map are removed from the Service.
+	// +optional
+	ServiceAnnotations map[string]string `json:"serviceAnnotations,omitempty"`
+
+	// SkipNetworkConfigCopy skips copying /etc/resolv.conf and /etc/hosts into the
+	// rootfs on start, relying on the files already present or mounted there
+	// +optional
+	SkipNetworkConfigCopy bool `json:"skipNetworkConfigCopy,omitempty"`
+
+	// ExecWrapperImage is the image of the consumer pod's exec-wrapper containers, e.g.
+	// a mirror in an internal registry. Defaults to the controller's exec-wrapper image.
+	// +optional
+	ExecWrapperImage string `json:"execWrapperImage,omitempty"`
+
+	// Persistence keeps the rootfs's writable layer on a PersistentVolumeClaim, so it
+	// survives the provider pod being recreated, e.g. on another node
+	// +optional
+	Persistence *PersistenceSpec `json:"persistence,omitempty"`
+
+	// StopGracePeriodSeconds is the grace period the consumer pods are deleted with on
+	// stop, giving preStop hooks and SIGTERM handlers time to finish. The container is
+	// Stopping until the pods are gone. Defaults to the template's
+	// terminationGracePeriodSeconds.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	StopGracePeriodSeconds *int64 `json:"stopGracePeriodSeconds,omitempty"`
+
+	// IdleTimeoutSeconds stops the container automatically, by setting running to false,
+	// once its consumer pods have used less than IdleCPUThreshold for this long.
+	// Requires the metrics.k8s.io API, e.g. from metrics-server.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	IdleTimeoutSeconds *int64 `json:"idleTimeoutSeconds,omitempty"`
+
+	// IdleCPUThreshold is the CPU usage of a consumer pod below which it counts as idle.
+	// Defaults to 10m.
+	// +optional
+	IdleCPUThreshold *resource.Quantity `json:"idleCPUThreshold,omitempty"`
+
+	// IdleInitialDelaySeconds is how long after a start the container is never stopped
+	// for being idle. Defaults to 0.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	IdleInitialDelaySeconds *int64 `json:"idleInitialDelaySeconds,omitempty"`
 }
 
 // Phase represents the current phase of the StoppableContainer
-// +kubebuilder:validation:Enum=Pending;ProviderReady;Running;Stopped;Failed
+// +kubebuilder:validation:Enum=Pending;ProviderReady;Running;CrashLooping;Stopping;Stopped;Completed;Failed
 type Phase string
 
 const (
@@ -90,9 +252,18 @@ const (
 	// PhaseRunning indicates the container is running
 	PhaseRunning Phase = "Running"
 
+	// PhaseCrashLooping indicates the consumer keeps crashing and being restarted
+	PhaseCrashLooping Phase = "CrashLooping"
+
+	// PhaseStopping indicates the consumer is shutting down after a stop
+	PhaseStopping Phase = "Stopping"
+
 	// PhaseStopped indicates the container is stopped but rootfs is preserved
 	PhaseStopped Phase = "Stopped"
 
+	// PhaseCompleted indicates the consumer has run to completion successfully
+	PhaseCompleted Phase = "Completed"
+
 	// PhaseFailed indicates the container has failed
 	PhaseFailed Phase = "Failed"
 )
@@ -111,10 +282,18 @@ type StoppableContainerStatus struct {
 	// +optional
 	ProviderPodName string `json:"providerPodName,omitempty"`
 
-	// ConsumerPodName is the name of the consumer pod
+	// ConsumerPodName is the name of the consumer pod, the first one with several replicas
 	// +optional
 	ConsumerPodName string `json:"consumerPodName,omitempty"`
 
+	// Replicas is the desired number of consumer pods
+	// +optional
+	Replicas int32 `json:"replicas,omitempty"`
+
+	// ReadyReplicas is the number of ready consumer pods
+	// +optional
+	ReadyReplicas int32 `json:"readyReplicas,omitempty"`
+
 	// HostPath is the path on the host where the rootfs is exposed
 	// +optional
 	HostPath string `json:"hostPath,omitempty"`
@@ -123,6 +302,28 @@ type StoppableContainerStatus struct {
 	// +optional
 	NodeName string `json:"nodeName,omitempty"`
 
+	// ExitCode is the exit code of the consumer container once it has terminated
+	// +optional
+	ExitCode *int32 `json:"exitCode,omitempty"`
+
+	// RestartCount is how often the controller recreated the crash looping consumer pod.
+	// Unlike the container restart count, it counts whole pod recreations.
+	// +optional
+	RestartCount int32 `json:"restartCount,omitempty"`
+
+	// IdleSince is when the consumer pods were first seen below IdleCPUThreshold,
+	// unset while they are busy or idle stop is disabled
+	// +optional
+	IdleSince *metav1.Time `json:"idleSince,omitempty"`
+
+	// LastStartedTime is when the container last entered the Running phase
+	// +optional
+	LastStartedTime *metav1.Time `json:"lastStartedTime,omitempty"`
+
+	// LastStoppedTime is when the container last entered the Stopped phase
+	// +optional
+	LastStoppedTime *metav1.Time `json:"lastStoppedTime,omitempty"`
+
 	// Conditions represent the current state of the StoppableContainer resource
 	// +listType=map
 	// +listMapKey=type
@@ -139,6 +340,9 @@ type StoppableContainerStatus struct {
 // +kubebuilder:printcolumn:name="Running",type="boolean",JSONPath=".spec.running",description="Whether the container should be running"
 // +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase",description="Current phase"
 // +kubebuilder:printcolumn:name="Node",type="string",JSONPath=".status.nodeName",description="Node where provider is running"
+// +kubebuilder:printcolumn:name="Restarts",type="integer",JSONPath=".status.restartCount",description="Consumer pod recreations by the controller"
+// +kubebuilder:printcolumn:name="Last Started",type="date",JSONPath=".status.lastStartedTime",description="When the container last started running"
+// +kubebuilder:printcolumn:name="Last Stopped",type="date",JSONPath=".status.lastStoppedTime",description="When the container last stopped"
 // +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
 // StoppableContainer is the Schema for the stoppablecontainers API
@@ -21,7 +21,7 @@ import (
 )
 
 // InstancePhase represents the current phase of the StoppableContainerInstance
-// +kubebuilder:validation:Enum=Pending;ProviderStarting;ProviderReady;ConsumerStarting;Running;Stopping;Stopped;Failed
+// +kubebuilder:validation:Enum=Pending;ProviderStarting;ProviderReady;ConsumerStarting;Running;ConsumerCrashLooping;Stopping;Stopped;Completed;Failed
 type InstancePhase string
 
 const (
@@ -40,12 +40,19 @@ const (
 	// InstancePhaseRunning indicates both pods are running
 	InstancePhaseRunning InstancePhase = "Running"
 
+	// InstancePhaseConsumerCrashLooping indicates the consumer container keeps crashing
+	// and being restarted by kubelet
+	InstancePhaseConsumerCrashLooping InstancePhase = "ConsumerCrashLooping"
+
 	// InstancePhaseStopping indicates the consumer is being stopped
 	InstancePhaseStopping InstancePhase = "Stopping"
 
 	// InstancePhaseStopped indicates the consumer is stopped but provider is running
 	InstancePhaseStopped InstancePhase = "Stopped"
 
+	// InstancePhaseCompleted indicates the consumer has run to completion successfully
+	InstancePhaseCompleted InstancePhase = "Completed"
+
 	// InstancePhaseFailed indicates a failure occurred
 	InstancePhaseFailed InstancePhase = "Failed"
 )
@@ -64,14 +71,36 @@ type StoppableContainerInstanceSpec struct {
 	// +kubebuilder:validation:Required
 	Template PodTemplateSpec `json:"template"`
 
-	// Provider is copied from the parent StoppableContainer
+	// Replicas is copied from the parent StoppableContainer
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	Replicas *int32 `json:"replicas,omitempty"`
+
+	// Provider is synced from the parent StoppableContainer. A change recreates the provider pod.
 	// +optional
 	Provider ProviderSpec `json:"provider,omitempty"`
 
-	// HostPathPrefix is the prefix for the hostPath
+	// HostPathPrefix is the prefix for the hostPath, synced from the parent StoppableContainer
 	// +kubebuilder:default="/var/lib/stoppablecontainer"
 	// +optional
 	HostPathPrefix string `json:"hostPathPrefix,omitempty"`
+
+	// SkipNetworkConfigCopy is synced from the parent StoppableContainer
+	// +optional
+	SkipNetworkConfigCopy bool `json:"skipNetworkConfigCopy,omitempty"`
+
+	// ExecWrapperImage is synced from the parent StoppableContainer
+	// +optional
+	ExecWrapperImage string `json:"execWrapperImage,omitempty"`
+
+	// Persistence is synced from the parent StoppableContainer. A change recreates the provider pod.
+	// +optional
+	Persistence *PersistenceSpec `json:"persistence,omitempty"`
+
+	// StopGracePeriodSeconds is synced from the parent StoppableContainer
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	StopGracePeriodSeconds *int64 `json:"stopGracePeriodSeconds,omitempty"`
 }
 
 // StoppableContainerInstanceStatus defines the observed state of StoppableContainerInstance.
@@ -88,14 +117,22 @@ type StoppableContainerInstanceStatus struct {
 	// +optional
 	ProviderPodUID string `json:"providerPodUID,omitempty"`
 
-	// ConsumerPodName is the name of the consumer pod
+	// ConsumerPodName is the name of the consumer pod, the first one with several replicas
 	// +optional
 	ConsumerPodName string `json:"consumerPodName,omitempty"`
 
-	// ConsumerPodUID is the UID of the consumer pod
+	// ConsumerPodUID is the UID of the consumer pod named by ConsumerPodName
 	// +optional
 	ConsumerPodUID string `json:"consumerPodUID,omitempty"`
 
+	// Replicas is the desired number of consumer pods
+	// +optional
+	Replicas int32 `json:"replicas,omitempty"`
+
+	// ReadyReplicas is the number of ready consumer pods
+	// +optional
+	ReadyReplicas int32 `json:"readyReplicas,omitempty"`
+
 	// HostPath is the full path on the host where rootfs is exposed
 	// +optional
 	HostPath string `json:"hostPath,omitempty"`
@@ -108,6 +145,19 @@ type StoppableContainerInstanceStatus struct {
 	// +optional
 	RootfsPID int32 `json:"rootfsPID,omitempty"`
 
+	// ExitCode is the exit code of the consumer container once it has terminated
+	// +optional
+	ExitCode *int32 `json:"exitCode,omitempty"`
+
+	// RestartCount is how often the controller recreated the consumer pod
+	// because it was crash looping. It is reset when the instance is stopped.
+	// +optional
+	RestartCount int32 `json:"restartCount,omitempty"`
+
+	// LastConsumerRestartTime is when the controller last recreated the consumer pod
+	// +optional
+	LastConsumerRestartTime *metav1.Time `json:"lastConsumerRestartTime,omitempty"`
+
 	// Message provides additional information about the current state
 	// +optional
 	Message string `json:"message,omitempty"`
@@ -129,6 +179,7 @@ type StoppableContainerInstanceStatus struct {
 // +kubebuilder:printcolumn:name="Running",type="boolean",JSONPath=".spec.running",description="Whether consumer should be running"
 // +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase",description="Current phase"
 // +kubebuilder:printcolumn:name="Node",type="string",JSONPath=".status.nodeName",description="Node name"
+// +kubebuilder:printcolumn:name="Restarts",type="integer",JSONPath=".status.restartCount",description="Consumer pod recreations by the controller"
 // +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
 // StoppableContainerInstance is the Schema for the stoppablecontainerinstances API
@@ -26,6 +26,21 @@ import (
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PersistenceSpec) DeepCopyInto(out *PersistenceSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PersistenceSpec.
+func (in *PersistenceSpec) DeepCopy() *PersistenceSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PersistenceSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PodTemplateSpec) DeepCopyInto(out *PodTemplateSpec) {
 	*out = *in
@@ -43,10 +58,30 @@ func (in *PodTemplateSpec) DeepCopy() *PodTemplateSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProviderLivenessProbe) DeepCopyInto(out *ProviderLivenessProbe) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProviderLivenessProbe.
+func (in *ProviderLivenessProbe) DeepCopy() *ProviderLivenessProbe {
+	if in == nil {
+		return nil
+	}
+	out := new(ProviderLivenessProbe)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ProviderSpec) DeepCopyInto(out *ProviderSpec) {
 	*out = *in
 	in.Resources.DeepCopyInto(&out.Resources)
+	if in.InfraResources != nil {
+		in, out := &in.InfraResources, &out.InfraResources
+		*out = new(v1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.NodeSelector != nil {
 		in, out := &in.NodeSelector, &out.NodeSelector
 		*out = make(map[string]string, len(*in))
@@ -61,6 +96,28 @@ func (in *ProviderSpec) DeepCopyInto(out *ProviderSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Affinity != nil {
+		in, out := &in.Affinity, &out.Affinity
+		*out = new(v1.Affinity)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TopologySpreadConstraints != nil {
+		in, out := &in.TopologySpreadConstraints, &out.TopologySpreadConstraints
+		*out = make([]v1.TopologySpreadConstraint, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.TerminationGracePeriodSeconds != nil {
+		in, out := &in.TerminationGracePeriodSeconds, &out.TerminationGracePeriodSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	if in.LivenessProbe != nil {
+		in, out := &in.LivenessProbe, &out.LivenessProbe
+		*out = new(ProviderLivenessProbe)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProviderSpec.
@@ -163,7 +220,22 @@ func (in *StoppableContainerInstanceList) DeepCopyObject() runtime.Object {
 func (in *StoppableContainerInstanceSpec) DeepCopyInto(out *StoppableContainerInstanceSpec) {
 	*out = *in
 	in.Template.DeepCopyInto(&out.Template)
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = new(int32)
+		**out = **in
+	}
 	in.Provider.DeepCopyInto(&out.Provider)
+	if in.Persistence != nil {
+		in, out := &in.Persistence, &out.Persistence
+		*out = new(PersistenceSpec)
+		**out = **in
+	}
+	if in.StopGracePeriodSeconds != nil {
+		in, out := &in.StopGracePeriodSeconds, &out.StopGracePeriodSeconds
+		*out = new(int64)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StoppableContainerInstanceSpec.
@@ -179,6 +251,15 @@ func (in *StoppableContainerInstanceSpec) DeepCopy() *StoppableContainerInstance
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *StoppableContainerInstanceStatus) DeepCopyInto(out *StoppableContainerInstanceStatus) {
 	*out = *in
+	if in.ExitCode != nil {
+		in, out := &in.ExitCode, &out.ExitCode
+		*out = new(int32)
+		**out = **in
+	}
+	if in.LastConsumerRestartTime != nil {
+		in, out := &in.LastConsumerRestartTime, &out.LastConsumerRestartTime
+		*out = (*in).DeepCopy()
+	}
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
 		*out = make([]metav1.Condition, len(*in))
@@ -234,7 +315,44 @@ func (in *StoppableContainerList) DeepCopyObject() runtime.Object {
 func (in *StoppableContainerSpec) DeepCopyInto(out *StoppableContainerSpec) {
 	*out = *in
 	in.Template.DeepCopyInto(&out.Template)
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = new(int32)
+		**out = **in
+	}
 	in.Provider.DeepCopyInto(&out.Provider)
+	if in.ServiceAnnotations != nil {
+		in, out := &in.ServiceAnnotations, &out.ServiceAnnotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Persistence != nil {
+		in, out := &in.Persistence, &out.Persistence
+		*out = new(PersistenceSpec)
+		**out = **in
+	}
+	if in.StopGracePeriodSeconds != nil {
+		in, out := &in.StopGracePeriodSeconds, &out.StopGracePeriodSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	if in.IdleTimeoutSeconds != nil {
+		in, out := &in.IdleTimeoutSeconds, &out.IdleTimeoutSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	if in.IdleCPUThreshold != nil {
+		in, out := &in.IdleCPUThreshold, &out.IdleCPUThreshold
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.IdleInitialDelaySeconds != nil {
+		in, out := &in.IdleInitialDelaySeconds, &out.IdleInitialDelaySeconds
+		*out = new(int64)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StoppableContainerSpec.
@@ -250,6 +368,23 @@ func (in *StoppableContainerSpec) DeepCopy() *StoppableContainerSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *StoppableContainerStatus) DeepCopyInto(out *StoppableContainerStatus) {
 	*out = *in
+	if in.ExitCode != nil {
+		in, out := &in.ExitCode, &out.ExitCode
+		*out = new(int32)
+		**out = **in
+	}
+	if in.IdleSince != nil {
+		in, out := &in.IdleSince, &out.IdleSince
+		*out = (*in).DeepCopy()
+	}
+	if in.LastStartedTime != nil {
+		in, out := &in.LastStartedTime, &out.LastStartedTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastStoppedTime != nil {
+		in, out := &in.LastStoppedTime, &out.LastStoppedTime
+		*out = (*in).DeepCopy()
+	}
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
 		*out = make([]metav1.Condition, len(*in))
@@ -20,9 +20,15 @@ limitations under the License.
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -40,16 +46,175 @@ const (
 
 	// EnvSCDebug enables debug logging
 	EnvSCDebug = "SC_DEBUG"
+
+	// DefaultShell is the command run when the template specifies none
+	DefaultShell = "/bin/sh"
+
+	// EnvSCLogJSON enables JSON lines logging of each phase to stderr.
+	//
+	// Deprecated: set EnvSCLogFormat to "json" instead.
+	EnvSCLogJSON = "SC_LOG_JSON"
+
+	// EnvSCLogFormat selects the log format: "json" for JSON lines only, plain text otherwise
+	EnvSCLogFormat = "SC_LOG_FORMAT"
+
+	// EnvSkipNetworkConfigCopy disables copying resolv.conf/hosts into the rootfs
+	EnvSkipNetworkConfigCopy = "SC_SKIP_NETWORK_CONFIG_COPY"
+
+	// EnvCommandFromImage tells which part of the command comes from the image:
+	// "entrypoint-cmd" when the template sets neither command nor args, "entrypoint"
+	// when it sets only args
+	EnvCommandFromImage = "SC_COMMAND_FROM_IMAGE"
+
+	// ImageConfigPath is where the image's Entrypoint, Cmd and Env, as read by the
+	// DaemonSet, are mounted
+	ImageConfigPath = "/.sc-image.json"
+
+	// EnvTemplateEnv lists the template's env var names, comma-separated
+	EnvTemplateEnv = "SC_TEMPLATE_ENV"
+
+	// EnvExtraCommands lists more commands to symlink into the /bin overlay, comma-separated
+	EnvExtraCommands = "SC_EXEC_EXTRA_COMMANDS"
+
+	// EnvRootfs is the rootfs mount path, as set by the controller
+	EnvRootfs = "SC_ROOTFS"
+
+	// MaxDiscoveredCommands caps how many executables found in the rootfs are symlinked
+	MaxDiscoveredCommands = 2000
 )
 
+// imageConfig is the image config file written by the DaemonSet
+type imageConfig struct {
+	Entrypoint []string `json:"entrypoint,omitempty"`
+	Cmd        []string `json:"cmd,omitempty"`
+	Env        []string `json:"env,omitempty"`
+}
+
+// runtimeDefaultEnv are set by the container runtime for the consumer container itself,
+// so the image's values take precedence unless the template sets them
+var runtimeDefaultEnv = map[string]bool{"PATH": true, "HOME": true}
+
+// defaultSearchPaths are searched for commands after the directories in PATH
+var defaultSearchPaths = []string{"/usr/local/sbin", "/usr/local/bin", "/usr/sbin", "/usr/bin", "/sbin", "/bin"}
+
+// defaultCommands are always symlinked into the /bin overlay, even if the rootfs cannot
+// be searched
+var defaultCommands = []string{
+	// Shells
+	"sh", "bash", "zsh", "ash", "dash", "ksh", "csh", "tcsh", "fish",
+	// Common utilities
+	"cat", "ls", "pwd", "id", "whoami", "uname", "hostname", "env", "printenv",
+	"grep", "awk", "sed", "head", "tail", "echo", "test", "[", "cp", "mkdir",
+	"rm", "mv", "touch", "chmod", "chown", "date", "sleep", "true", "false",
+	// kubectl cp streams files through tar
+	"tar",
+	// Programming languages
+	"python", "python3", "python3.10", "python3.11", "python3.12",
+	"node", "npm", "npx", "ruby", "perl", "php", "java",
+	// Package managers
+	"apt", "apt-get", "yum", "dnf", "apk",
+}
+
+// shellCandidates are the shells --shell looks for, in order
+var shellCandidates = []string{"bash", "ash", "sh"}
+
+// discoverDirs are the rootfs directories whose executables are symlinked into the /bin overlay
+var discoverDirs = []string{"/usr/local/bin", "/usr/bin", "/bin"}
+
+// version is set by ldflags during build
+var version = "dev"
+
+// capSysChroot is the bit of CAP_SYS_CHROOT in the capability sets
+const capSysChroot = 18
+
+// initBinDir is where --init installs sc-exec
+var initBinDir = filepath.Dir(WrapperBinPath)
+
+// ServiceAccountPath is where kubelet mounts the service account token
+const ServiceAccountPath = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// saBindMountAttempts is how often the service account bind mount is tried before copying
+const saBindMountAttempts = 3
+
+// saBindMountRetryDelay is the delay before the first bind mount retry; it grows linearly
+var saBindMountRetryDelay = 100 * time.Millisecond
+
+// bindMount bind mounts source onto target; a variable so tests can replace it
+var bindMount = func(source, target string) error {
+	return syscall.Mount(source, target, "", syscall.MS_BIND, "")
+}
+
+// logOutput is where JSON phase log lines are written
+var logOutput io.Writer = os.Stderr
+
+// jsonLogFormat reports whether SC_LOG_FORMAT=json asks for JSON log lines only
+func jsonLogFormat() bool {
+	return os.Getenv(EnvSCLogFormat) == "json"
+}
+
+// writeLogEntry writes a JSON log line to w
+func writeLogEntry(w io.Writer, entry map[string]interface{}) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	_, _ = fmt.Fprintln(w, string(data))
+}
+
+// logPhase emits a JSON log line for a phase when SC_LOG_JSON=1 or SC_LOG_FORMAT=json.
+// Its level is "info" unless fields set one.
+func logPhase(phase, msg string, fields map[string]interface{}) {
+	if os.Getenv(EnvSCLogJSON) != "1" && !jsonLogFormat() {
+		return
+	}
+	entry := map[string]interface{}{
+		"time":      time.Now().UTC().Format(time.RFC3339Nano),
+		"level":     "info",
+		"component": "sc-exec",
+		"phase":     phase,
+		"msg":       msg,
+	}
+	for k, v := range fields {
+		entry[k] = v
+	}
+	writeLogEntry(logOutput, entry)
+}
+
+// logf writes a plain text log line tagged with the component to w, or with
+// SC_LOG_FORMAT=json a JSON log line with the level
+func logf(w io.Writer, component, level, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	if jsonLogFormat() {
+		writeLogEntry(w, map[string]interface{}{
+			"time":      time.Now().UTC().Format(time.RFC3339Nano),
+			"level":     level,
+			"component": component,
+			"msg":       msg,
+		})
+		return
+	}
+	prefix := "[" + component + "] "
+	switch level {
+	case "error":
+		prefix += "ERROR: "
+	case "warning":
+		prefix += "WARNING: "
+	}
+	_, _ = fmt.Fprintln(w, prefix+msg)
+}
+
 func debug(format string, args ...interface{}) {
 	if os.Getenv(EnvSCDebug) != "" {
-		fmt.Fprintf(os.Stderr, "[sc-exec] "+format+"\n", args...)
+		logf(os.Stderr, "sc-exec", "debug", format, args...)
 	}
 }
 
 func fatal(format string, args ...interface{}) {
-	fmt.Fprintf(os.Stderr, "[sc-exec] ERROR: "+format+"\n", args...)
+	msg := fmt.Sprintf(format, args...)
+	logPhase("error", msg, map[string]interface{}{"level": "error"})
+	if !jsonLogFormat() {
+		fmt.Fprintf(os.Stderr, "[sc-exec] ERROR: %s\n", msg)
+	}
 	os.Exit(1)
 }
 
@@ -60,53 +225,8 @@ func main() {
 
 	// Handle special built-in commands
 	if execName == "sc-exec" || execName == "stoppablecontainer-exec" {
-		if len(os.Args) >= 2 {
-			switch os.Args[1] {
-			case "--ready":
-				// Readiness probe: check if rootfs is ready
-				handleReadinessProbe()
-				return
-			case "--entrypoint":
-				// Entrypoint mode: wait for rootfs and run user command
-				if len(os.Args) < 4 {
-					fatal("Usage: sc-exec --entrypoint <workdir> <command...>")
-				}
-				handleEntrypoint(os.Args[2], os.Args[3:])
-				return
-			case "--init":
-				// Init mode: setup /bin overlay with symlinks
-				if len(os.Args) < 3 {
-					fatal("Usage: sc-exec --init <overlay-path>")
-				}
-				handleInit(os.Args[2])
-				return
-			case "--copy":
-				// Copy mode: copy a file from src to dst
-				if len(os.Args) < 4 {
-					fatal("Usage: sc-exec --copy <src> <dst>")
-				}
-				handleCopy(os.Args[2], os.Args[3])
-				return
-			case "--check-file":
-				// Check if a file exists
-				if len(os.Args) < 3 {
-					fatal("Usage: sc-exec --check-file <path>")
-				}
-				if _, err := os.Stat(os.Args[2]); err != nil {
-					os.Exit(1)
-				}
-				os.Exit(0)
-			case "--check-dir":
-				// Check if a directory exists
-				if len(os.Args) < 3 {
-					fatal("Usage: sc-exec --check-dir <path>")
-				}
-				info, err := os.Stat(os.Args[2])
-				if err != nil || !info.IsDir() {
-					os.Exit(1)
-				}
-				os.Exit(0)
-			}
+		if code, ok := dispatchBuiltin(os.Args[1:], os.Stderr); ok {
+			os.Exit(code)
 		}
 	}
 
@@ -117,13 +237,7 @@ func main() {
 
 	if execName == "sc-exec" || execName == "stoppablecontainer-exec" {
 		if len(os.Args) < 2 {
-			fmt.Fprintf(os.Stderr, "Usage: %s <command> [args...]\n", os.Args[0])
-			fmt.Fprintf(os.Stderr, "\nThis wrapper executes commands inside the chroot at %s\n", RootfsPath)
-			fmt.Fprintf(os.Stderr, "\nBuilt-in commands:\n")
-			fmt.Fprintf(os.Stderr, "  --ready              Check if rootfs is ready (for readiness probe)\n")
-			fmt.Fprintf(os.Stderr, "  --entrypoint <wd> <cmd...>  Run entrypoint in chroot\n")
-			fmt.Fprintf(os.Stderr, "  --init <overlay>     Setup /bin overlay with symlinks\n")
-			fmt.Fprintf(os.Stderr, "  --copy <src> <dst>   Copy a file from src to dst\n")
+			printUsage(os.Stderr)
 			os.Exit(1)
 		}
 		command = os.Args[1]
@@ -141,80 +255,290 @@ func main() {
 		return
 	}
 
+	execInRootfs(command, args)
+}
+
+// execInRootfs runs command with args (args[0] being the command name) in the chroot
+func execInRootfs(command string, args []string) {
 	// Verify rootfs exists
 	if _, err := os.Stat(RootfsPath); os.IsNotExist(err) {
 		fatal("Rootfs not found at %s. Is the provider pod ready?", RootfsPath)
 	}
 
 	// Setup bind mounts for special filesystems
+	logPhase("mount", "setting up mounts", map[string]interface{}{"rootfs": RootfsPath})
 	setupMounts()
 
+	env := rootfsEnv(loadImageConfig(ImageConfigPath))
+
 	// Find the actual binary path in the rootfs
-	binaryPath := findBinary(command)
+	binaryPath := findBinary(command, env)
 	if binaryPath == "" {
 		fatal("Command not found: %s", command)
 	}
 	debug("Found binary at: %s", binaryPath)
+	logPhase("resolve", "resolved binary", map[string]interface{}{"command": command, "path": binaryPath})
 
 	// Perform chroot and exec
-	chrootExec(binaryPath, args)
+	chrootExec(binaryPath, args, env)
 }
 
-// handleReadinessProbe checks if the rootfs is ready
-func handleReadinessProbe() {
-	// Check if rootfs directory exists
-	if _, err := os.Stat(RootfsPath); os.IsNotExist(err) {
-		debug("Rootfs not found at %s", RootfsPath)
-		os.Exit(1)
-	}
+// builtinCommand is an sc-exec built-in, selected by its flag as the first argument
+type builtinCommand struct {
+	// usage is the argument synopsis shown in the usage message
+	usage string
+	// help is a one-line description
+	help string
+	// minArgs is the number of arguments required after the flag
+	minArgs int
+	// run executes the built-in and returns the exit code. Built-ins that exec never return.
+	run func(args []string) int
+}
 
-	// Check if /bin or /usr/bin exists (indicating rootfs is mounted)
-	binExists := false
-	for _, p := range []string{RootfsPath + "/bin", RootfsPath + "/usr/bin"} {
-		if info, err := os.Lstat(p); err == nil {
-			// Accept both directories and symlinks (Ubuntu has /bin -> usr/bin)
-			if info.IsDir() || info.Mode()&os.ModeSymlink != 0 {
-				binExists = true
-				break
+// builtins maps each built-in flag to its command
+var builtins = map[string]builtinCommand{
+	"--ready": {
+		usage: "[command...]",
+		help:  "Check if rootfs is ready (for readiness probe), then run the optional probe command in the chroot",
+		run: func(args []string) int {
+			if code := handleReadinessProbe(); code != 0 || len(args) == 0 {
+				return code
 			}
+			execInRootfs(args[0], args)
+			return 0
+		},
+	},
+	"--entrypoint": {
+		usage:   "<workdir> <command...>",
+		help:    "Wait for rootfs and run the command in the chroot",
+		minArgs: 2,
+		run: func(args []string) int {
+			handleEntrypoint(args[0], args[1:])
+			return 0
+		},
+	},
+	"--init": {
+		usage:   "<overlay-path>",
+		help:    "Setup /bin overlay with symlinks",
+		minArgs: 1,
+		run: func(args []string) int {
+			handleInit(args[0])
+			return 0
+		},
+	},
+	"--copy": {
+		usage:   "<src> <dst>",
+		help:    "Copy a file from src to dst",
+		minArgs: 2,
+		run: func(args []string) int {
+			handleCopy(args[0], args[1])
+			return 0
+		},
+	},
+	"--check-file": {
+		usage:   "<path>",
+		help:    "Exit 0 if the file exists",
+		minArgs: 1,
+		run: func(args []string) int {
+			if !fileExists(args[0]) {
+				return 1
+			}
+			return 0
+		},
+	},
+	"--version": {
+		help: "Print the sc-exec version",
+		run: func(args []string) int {
+			fmt.Println(versionString())
+			return 0
+		},
+	},
+	"--selftest": {
+		help: "Check that the rootfs is mounted, has a shell, and chroot is permitted",
+		run: func(args []string) int {
+			results := runSelftest(RootfsPath, "/proc/self/status")
+			fmt.Println(versionString())
+			failed := false
+			for _, r := range results {
+				status := "ok"
+				if !r.OK {
+					status = "FAIL"
+					failed = true
+				}
+				fmt.Printf("[%s] %s: %s\n", status, r.Name, r.Detail)
+			}
+			if failed {
+				return 1
+			}
+			return 0
+		},
+	},
+	"--shell": {
+		help: "Run the first shell found in the rootfs (" + strings.Join(shellCandidates, ", ") + ")",
+		run: func(args []string) int {
+			env := rootfsEnv(loadImageConfig(ImageConfigPath))
+			shell, err := findShell(RootfsPath, env)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "[sc-exec] %v\n", err)
+				return 1
+			}
+			execInRootfs(shell, []string{shell})
+			return 0
+		},
+	},
+	"--check-dir": {
+		usage:   "<path>",
+		help:    "Exit 0 if the directory exists",
+		minArgs: 1,
+		run: func(args []string) int {
+			if info, err := os.Stat(args[0]); err != nil || !info.IsDir() {
+				return 1
+			}
+			return 0
+		},
+	},
+}
+
+// dispatchBuiltin runs the built-in named by args[0]. It returns false if args do not
+// select a built-in; an unknown flag or missing arguments print usage to w.
+func dispatchBuiltin(args []string, w io.Writer) (int, bool) {
+	if len(args) == 0 || !strings.HasPrefix(args[0], "--") {
+		return 0, false
+	}
+	cmd, ok := builtins[args[0]]
+	if !ok {
+		_, _ = fmt.Fprintf(w, "Unknown built-in command: %s\n\n", args[0])
+		printUsage(w)
+		return 1, true
+	}
+	if len(args)-1 < cmd.minArgs {
+		_, _ = fmt.Fprintf(w, "Usage: sc-exec %s %s\n", args[0], cmd.usage)
+		return 1, true
+	}
+	return cmd.run(args[1:]), true
+}
+
+// printUsage writes the sc-exec usage message, listing the built-in commands
+func printUsage(w io.Writer) {
+	_, _ = fmt.Fprintf(w, "Usage: sc-exec <command> [args...]\n")
+	_, _ = fmt.Fprintf(w, "\nThis wrapper executes commands inside the chroot at %s\n", RootfsPath)
+	_, _ = fmt.Fprintf(w, "\nBuilt-in commands:\n")
+	names := make([]string, 0, len(builtins))
+	for name := range builtins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		cmd := builtins[name]
+		_, _ = fmt.Fprintf(w, "  %-36s %s\n", strings.TrimSpace(name+" "+cmd.usage), cmd.help)
+	}
+}
+
+// versionString identifies the sc-exec binary, to spot a mismatched wrapper image
+func versionString() string {
+	return fmt.Sprintf("sc-exec %s (%s/%s)", version, runtime.GOOS, runtime.GOARCH)
+}
+
+// selftestResult is the outcome of one --selftest check
+type selftestResult struct {
+	Name   string
+	OK     bool
+	Detail string
+}
+
+// runSelftest checks that sc-exec can do its job in this container: the rootfs and its
+// mounts are in place, the rootfs has a shell, and the process may chroot. It does not
+// change any state. statusFile is the /proc/<pid>/status file to read capabilities from.
+func runSelftest(rootfs, statusFile string) []selftestResult {
+	var results []selftestResult
+
+	if info, err := os.Stat(rootfs); err != nil || !info.IsDir() {
+		results = append(results, selftestResult{"rootfs", false, rootfs + " not found"})
+	} else {
+		results = append(results, selftestResult{"rootfs", true, rootfs + " exists"})
+	}
+
+	procPath := filepath.Join(rootfs, "proc")
+	if isMounted(procPath) {
+		results = append(results, selftestResult{"mount", true, procPath + " is mounted"})
+	} else {
+		results = append(results, selftestResult{"mount", false, procPath + " is not mounted; check mount-helper"})
+	}
+
+	if fileExists(filepath.Join(rootfs, DefaultShell)) {
+		results = append(results, selftestResult{"shell", true, DefaultShell + " found in rootfs"})
+	} else {
+		results = append(results, selftestResult{"shell", false, DefaultShell + " not found in rootfs"})
+	}
+
+	if ok, err := hasEffectiveCapability(statusFile, capSysChroot); err != nil {
+		results = append(results, selftestResult{"chroot", false, err.Error()})
+	} else if ok {
+		results = append(results, selftestResult{"chroot", true, "CAP_SYS_CHROOT is effective"})
+	} else {
+		results = append(results, selftestResult{"chroot", false, "CAP_SYS_CHROOT is missing"})
+	}
+
+	return results
+}
+
+// hasEffectiveCapability reports whether the capability bit is set in the CapEff
+// line of a /proc/<pid>/status file
+func hasEffectiveCapability(statusFile string, bit uint) (bool, error) {
+	data, err := os.ReadFile(statusFile)
+	if err != nil {
+		return false, fmt.Errorf("failed to read %s: %w", statusFile, err)
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		value, ok := strings.CutPrefix(line, "CapEff:")
+		if !ok {
+			continue
 		}
+		caps, err := strconv.ParseUint(strings.TrimSpace(value), 16, 64)
+		if err != nil {
+			return false, fmt.Errorf("invalid CapEff in %s: %w", statusFile, err)
+		}
+		return caps&(1<<bit) != 0, nil
 	}
+	return false, fmt.Errorf("no CapEff in %s", statusFile)
+}
+
+// rootfsReady reports whether the DaemonSet has finished setting up rootfs. It mounts
+// proc only after the overlay, so a mounted proc means the rootfs is ready, even for
+// images without /bin (e.g. distroless).
+func rootfsReady(rootfs string) bool {
+	return isMounted(rootfs + "/proc")
+}
 
-	if !binExists {
-		debug("Rootfs bin directory not found")
-		os.Exit(1)
+// handleReadinessProbe checks if the rootfs is ready and returns the probe's exit code
+func handleReadinessProbe() int {
+	// Check if rootfs directory exists
+	if _, err := os.Stat(RootfsPath); os.IsNotExist(err) {
+		debug("Rootfs not found at %s", RootfsPath)
+		return 1
 	}
 
-	// Check if proc is mounted (indicates DaemonSet has completed setup)
-	procPath := RootfsPath + "/proc"
-	if !isMounted(procPath) {
-		debug("Proc not mounted at %s", procPath)
-		os.Exit(1)
+	if !rootfsReady(RootfsPath) {
+		debug("Proc not mounted at %s/proc", RootfsPath)
+		return 1
 	}
 
 	// All checks passed
 	debug("Rootfs is ready")
-	os.Exit(0)
+	return 0
 }
 
 // handleEntrypoint runs the user command in a chroot environment
 func handleEntrypoint(workdir string, command []string) {
-	fmt.Println("[sc-entrypoint] Starting consumer container...")
+	logf(os.Stdout, "sc-entrypoint", "info", "Starting consumer container...")
+	if os.Getenv(EnvSCLogJSON) == "1" && !jsonLogFormat() {
+		logf(os.Stdout, "sc-entrypoint", "warning", "%s is deprecated, set %s=json instead", EnvSCLogJSON, EnvSCLogFormat)
+	}
 
 	// Wait for rootfs to be available
 	maxAttempts := 120
 	for attempt := 0; attempt < maxAttempts; attempt++ {
-		binExists := false
-		for _, p := range []string{RootfsPath + "/bin", RootfsPath + "/usr/bin"} {
-			if info, err := os.Lstat(p); err == nil {
-				if info.IsDir() || info.Mode()&os.ModeSymlink != 0 {
-					binExists = true
-					break
-				}
-			}
-		}
-
-		if binExists && isMounted(RootfsPath+"/proc") {
+		if rootfsReady(RootfsPath) {
 			break
 		}
 
@@ -222,7 +546,7 @@ func handleEntrypoint(workdir string, command []string) {
 			time.Sleep(200 * time.Millisecond)
 		} else {
 			if attempt%10 == 0 {
-				fmt.Printf("[sc-entrypoint] Waiting for DaemonSet to complete rootfs setup... (%d/%d)\n", attempt, maxAttempts)
+				logf(os.Stdout, "sc-entrypoint", "info", "Waiting for DaemonSet to complete rootfs setup... (%d/%d)", attempt, maxAttempts)
 			}
 			time.Sleep(time.Second)
 		}
@@ -232,17 +556,29 @@ func handleEntrypoint(workdir string, command []string) {
 		}
 	}
 
-	fmt.Println("[sc-entrypoint] Rootfs ready with mounts from DaemonSet")
+	logf(os.Stdout, "sc-entrypoint", "info", "Rootfs ready with mounts from DaemonSet")
+
+	image := loadImageConfig(ImageConfigPath)
+	command = commandFromImage(image, os.Getenv(EnvCommandFromImage), command)
+	command, err := selectEntrypointCommand(RootfsPath, command)
+	if err != nil {
+		fatal("%v", err)
+	}
 
 	// Copy network configuration
-	copyNetworkConfig()
+	if shouldCopyNetworkConfig() {
+		copyNetworkConfig()
+	} else {
+		logf(os.Stdout, "sc-entrypoint", "info", "Skipping network config copy")
+	}
 
 	// Mount service account secrets
 	mountServiceAccountSecrets()
 
-	fmt.Println("[sc-entrypoint] Setup complete, chrooting...")
+	logf(os.Stdout, "sc-entrypoint", "info", "Setup complete, chrooting...")
 
 	// Chroot and exec
+	logPhase("chroot", "chrooting", map[string]interface{}{"rootfs": RootfsPath, "workdir": workdir})
 	if err := syscall.Chroot(RootfsPath); err != nil {
 		fatal("Failed to chroot: %v", err)
 	}
@@ -257,6 +593,11 @@ func handleEntrypoint(workdir string, command []string) {
 		}
 	}
 
+	// The container environment is passed through, so env vars that kubelet resolved
+	// from valueFrom (fieldRef, secretKeyRef, ...) survive the chroot. The image's own
+	// environment (PATH, LANG, ...) fills in what the template does not set.
+	env := rootfsEnv(image)
+
 	// Find and execute the command
 	binaryPath := ""
 	cmdName := command[0]
@@ -265,8 +606,7 @@ func handleEntrypoint(workdir string, command []string) {
 			binaryPath = cmdName
 		}
 	} else {
-		searchPaths := []string{"/usr/local/sbin", "/usr/local/bin", "/usr/sbin", "/usr/bin", "/sbin", "/bin"}
-		for _, dir := range searchPaths {
+		for _, dir := range searchPaths(env) {
 			candidate := dir + "/" + cmdName
 			if _, err := os.Stat(candidate); err == nil {
 				binaryPath = candidate
@@ -278,8 +618,9 @@ func handleEntrypoint(workdir string, command []string) {
 	if binaryPath == "" {
 		fatal("Command not found: %s", cmdName)
 	}
+	logPhase("resolve", "resolved binary", map[string]interface{}{"command": cmdName, "path": binaryPath})
 
-	env := os.Environ()
+	logPhase("exec", "executing", map[string]interface{}{"path": binaryPath, "args": command})
 	if err := syscall.Exec(binaryPath, command, env); err != nil {
 		fatal("Failed to exec %s: %v", binaryPath, err)
 	}
@@ -287,10 +628,11 @@ func handleEntrypoint(workdir string, command []string) {
 
 // handleInit sets up the /bin overlay with symlinks to sc-exec
 func handleInit(overlayPath string) {
-	fmt.Println("[sc-init] Setting up /bin overlay for transparent chroot execution")
+	logf(os.Stdout, "sc-init", "info", "Setting up /bin overlay for transparent chroot execution")
+	logPhase("init", "setting up bin overlay", map[string]interface{}{"overlay": overlayPath})
 
 	// Copy sc-exec to /.sc-bin
-	scBinPath := "/.sc-bin"
+	scBinPath := initBinDir
 	if err := os.MkdirAll(scBinPath, 0755); err != nil {
 		fatal("Failed to create %s: %v", scBinPath, err)
 	}
@@ -309,20 +651,8 @@ func handleInit(overlayPath string) {
 		fatal("Failed to chmod sc-exec: %v", err)
 	}
 
-	// Create symlinks for common commands
-	commands := []string{
-		// Shells
-		"sh", "bash", "zsh", "ash", "dash", "ksh", "csh", "tcsh", "fish",
-		// Common utilities
-		"cat", "ls", "pwd", "id", "whoami", "uname", "hostname", "env", "printenv",
-		"grep", "awk", "sed", "head", "tail", "echo", "test", "[", "cp", "mkdir",
-		"rm", "mv", "touch", "chmod", "chown", "date", "sleep", "true", "false",
-		// Programming languages
-		"python", "python3", "python3.10", "python3.11", "python3.12",
-		"node", "npm", "npx", "ruby", "perl", "php", "java",
-		// Package managers
-		"apt", "apt-get", "yum", "dnf", "apk",
-	}
+	// Create symlinks for common commands, the configured extras and the rootfs executables
+	commands := initCommands(RootfsPath, os.Getenv(EnvExtraCommands), MaxDiscoveredCommands)
 
 	for _, cmd := range commands {
 		linkPath := overlayPath + "/" + cmd
@@ -331,7 +661,65 @@ func handleInit(overlayPath string) {
 		}
 	}
 
-	fmt.Println("[sc-init] Setup complete")
+	logf(os.Stdout, "sc-init", "info", "Setup complete")
+	logPhase("init", "setup complete", map[string]interface{}{"symlinks": len(commands)})
+}
+
+// initCommands returns the commands to symlink into the /bin overlay: the defaultCommands,
+// the comma-separated extra commands, and up to limit executables found in the rootfs's
+// discoverDirs, without duplicates
+func initCommands(rootfs, extra string, limit int) []string {
+	seen := make(map[string]bool)
+	var commands []string
+	add := func(name string) {
+		if name != "" && !strings.Contains(name, "/") && !seen[name] {
+			seen[name] = true
+			commands = append(commands, name)
+		}
+	}
+
+	for _, name := range defaultCommands {
+		add(name)
+	}
+	for _, name := range strings.Split(extra, ",") {
+		add(strings.TrimSpace(name))
+	}
+	for _, name := range discoverCommands(rootfs, limit) {
+		add(name)
+	}
+	return commands
+}
+
+// discoverCommands returns the names of up to limit executables in the rootfs's
+// discoverDirs. Symlinks count as executables: absolute ones point into the rootfs
+// and cannot be followed from outside it.
+func discoverCommands(rootfs string, limit int) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, dir := range discoverDirs {
+		entries, err := os.ReadDir(rootfs + dir)
+		if err != nil {
+			debug("Cannot read %s%s: %v", rootfs, dir, err)
+			continue
+		}
+		for _, entry := range entries {
+			if len(names) >= limit {
+				debug("Discovered the maximum of %d commands", limit)
+				return names
+			}
+			if entry.Type()&os.ModeSymlink == 0 {
+				info, err := entry.Info()
+				if err != nil || !info.Mode().IsRegular() || info.Mode().Perm()&0111 == 0 {
+					continue
+				}
+			}
+			if name := entry.Name(); !strings.HasPrefix(name, ".") && !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	return names
 }
 
 // handleCopy copies a file from src to dst
@@ -343,6 +731,7 @@ func handleCopy(src, dst string) {
 		fatal("Failed to chmod %s: %v", dst, err)
 	}
 	debug("Copied %s to %s", src, dst)
+	logPhase("copy", "copied file", map[string]interface{}{"src": src, "dst": dst})
 }
 
 // copyFile copies a file from src to dst
@@ -354,6 +743,11 @@ func copyFile(src, dst string) error {
 	return os.WriteFile(dst, data, 0755)
 }
 
+// shouldCopyNetworkConfig reports whether resolv.conf/hosts should be copied into the rootfs
+func shouldCopyNetworkConfig() bool {
+	return os.Getenv(EnvSkipNetworkConfigCopy) != "true"
+}
+
 // copyNetworkConfig copies network configuration files to rootfs
 func copyNetworkConfig() {
 	configs := []string{"/etc/resolv.conf", "/etc/hosts"}
@@ -371,41 +765,74 @@ func copyNetworkConfig() {
 
 // mountServiceAccountSecrets mounts the service account secrets into rootfs
 func mountServiceAccountSecrets() {
-	saPath := "/var/run/secrets/kubernetes.io/serviceaccount"
-	if _, err := os.Stat(saPath); os.IsNotExist(err) {
+	if _, err := os.Stat(ServiceAccountPath); os.IsNotExist(err) {
 		return
 	}
 
 	// Determine target path (handle /var/run -> /run symlink)
-	targetPath := RootfsPath + saPath
+	targetPath := RootfsPath + ServiceAccountPath
 	if info, err := os.Lstat(RootfsPath + "/var/run"); err == nil {
 		if info.Mode()&os.ModeSymlink != 0 {
 			targetPath = RootfsPath + "/run/secrets/kubernetes.io/serviceaccount"
 		}
 	}
 
-	// Create target directory
-	if err := os.MkdirAll(targetPath, 0755); err != nil {
-		debug("Failed to create SA path: %v", err)
+	// A projected token volume from the template is already mounted into the rootfs
+	// and rotates on its own, so neither a bind mount nor a copy is needed
+	if isMounted(targetPath) {
+		debug("Service account path already mounted: %s", targetPath)
 		return
 	}
 
-	// Try bind mount first, fall back to copy
-	if err := syscall.Mount(saPath, targetPath, "", syscall.MS_BIND, ""); err != nil {
-		debug("Bind mount failed, copying SA secrets: %v", err)
-		// Copy files
-		entries, err := os.ReadDir(saPath)
-		if err != nil {
-			return
+	if err := mountServiceAccountDir(ServiceAccountPath, targetPath); err != nil {
+		debug("Failed to provide SA secrets: %v", err)
+	}
+}
+
+// mountServiceAccountDir bind mounts the service account directory onto target,
+// retrying a few times before falling back to copying the files. A copy is not
+// updated when kubelet rotates the token, so the fallback is always reported.
+func mountServiceAccountDir(saPath, targetPath string) error {
+	if err := os.MkdirAll(targetPath, 0755); err != nil {
+		return fmt.Errorf("failed to create SA path: %w", err)
+	}
+
+	var err error
+	for attempt := 1; attempt <= saBindMountAttempts; attempt++ {
+		if err = bindMount(saPath, targetPath); err == nil {
+			return nil
 		}
-		for _, entry := range entries {
-			srcFile := saPath + "/" + entry.Name()
-			dstFile := targetPath + "/" + entry.Name()
-			if data, err := os.ReadFile(srcFile); err == nil {
-				_ = os.WriteFile(dstFile, data, 0644)
-			}
+		debug("Bind mount of SA secrets failed (attempt %d/%d): %v", attempt, saBindMountAttempts, err)
+		if attempt < saBindMountAttempts {
+			time.Sleep(time.Duration(attempt) * saBindMountRetryDelay)
 		}
 	}
+
+	if !jsonLogFormat() {
+		fmt.Fprintf(os.Stderr, "[sc-exec] WARNING: bind mount of %s failed (%v), copying it instead; "+
+			"token rotation will not be visible in the container\n", saPath, err)
+	}
+	logPhase("mount", "service account bind mount failed, copied secrets instead", map[string]interface{}{
+		"level":    "warning",
+		"source":   saPath,
+		"target":   targetPath,
+		"attempts": saBindMountAttempts,
+		"error":    err.Error(),
+		"fallback": "copy",
+	})
+
+	entries, err := os.ReadDir(saPath)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		srcFile := saPath + "/" + entry.Name()
+		dstFile := targetPath + "/" + entry.Name()
+		if data, err := os.ReadFile(srcFile); err == nil {
+			_ = os.WriteFile(dstFile, data, 0644)
+		}
+	}
+	return nil
 }
 
 // setupMounts creates necessary bind mounts inside rootfs.
@@ -426,7 +853,7 @@ func setupMounts() {
 	}
 
 	// Also bind mount any kubernetes service account tokens
-	saPath := "/var/run/secrets/kubernetes.io/serviceaccount"
+	saPath := ServiceAccountPath
 	if _, err := os.Stat(saPath); err == nil {
 		targetPath := RootfsPath + saPath
 		_ = os.MkdirAll(filepath.Dir(targetPath), 0755)
@@ -488,6 +915,145 @@ func ensureTarget(source, target string) error {
 	return f.Close()
 }
 
+// fileExists reports whether a path exists (the --check-file built-in)
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// selectEntrypointCommand checks the command to exec in the rootfs. Commands are always
+// exec'd directly, without an intermediate shell, so images without a shell work as long
+// as the template sets a command. Only the default shell command needs a shell: when the
+// rootfs has none (e.g. distroless), fail with a clear error instead of "command not found".
+func selectEntrypointCommand(rootfs string, command []string) ([]string, error) {
+	if len(command) == 1 && command[0] == DefaultShell && !fileExists(rootfs+DefaultShell) {
+		return nil, fmt.Errorf("no command specified by the template or the image, and the image has no %s: "+
+			"set command in the StoppableContainer template", DefaultShell)
+	}
+	return command, nil
+}
+
+// commandFromImage replaces the parts of command the template left unset with the
+// image's Entrypoint and Cmd, following the Kubernetes rules. The controller cannot see
+// the image, so it passes the default shell (or only the args) and says in mode what to
+// fill in. If the image config is empty, command is returned unchanged.
+func commandFromImage(config imageConfig, mode string, command []string) []string {
+	var resolved []string
+	switch mode {
+	case "entrypoint-cmd":
+		resolved = append(append(resolved, config.Entrypoint...), config.Cmd...)
+	case "entrypoint":
+		if len(config.Entrypoint) > 0 {
+			resolved = append(append(resolved, config.Entrypoint...), command...)
+		}
+	}
+	if len(resolved) == 0 {
+		return command
+	}
+	logPhase("command", "using the image's command", map[string]interface{}{"mode": mode, "command": resolved})
+	return resolved
+}
+
+// loadImageConfig reads the image config written by the DaemonSet. It is empty if the
+// file is missing, not written yet or invalid.
+func loadImageConfig(path string) imageConfig {
+	var config imageConfig
+	data, err := os.ReadFile(path)
+	if err != nil {
+		debug("No image config at %s: %v", path, err)
+		return config
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &config); err != nil {
+			debug("Invalid image config at %s: %v", path, err)
+			return imageConfig{}
+		}
+	}
+	return config
+}
+
+// internalEnv are the variables the controller and sc-exec set for sc-exec itself. They
+// are not passed on to the chrooted process. Variables the user sets in the template,
+// such as SC_DEBUG, are kept.
+var internalEnv = []string{
+	EnvSCExecOriginal,
+	EnvRootfs,
+	EnvTemplateEnv,
+	EnvCommandFromImage,
+	EnvSkipNetworkConfigCopy,
+}
+
+// rootfsEnv returns the environment for the chrooted process: the container's, merged
+// over the image's, without internalEnv
+func rootfsEnv(image imageConfig) []string {
+	return stripInternalEnv(mergeImageEnv(os.Environ(), image.Env, os.Getenv(EnvTemplateEnv)))
+}
+
+// stripInternalEnv returns env without the internalEnv variables
+func stripInternalEnv(env []string) []string {
+	stripped := make([]string, 0, len(env))
+	for _, e := range env {
+		name, _, _ := strings.Cut(e, "=")
+		if !slices.Contains(internalEnv, name) {
+			stripped = append(stripped, e)
+		}
+	}
+	return stripped
+}
+
+// mergeImageEnv adds the image's env vars to environ. Vars in environ take precedence,
+// except runtimeDefaultEnv vars that are not among the comma-separated templateNames.
+func mergeImageEnv(environ, imageEnv []string, templateNames string) []string {
+	if len(imageEnv) == 0 {
+		return environ
+	}
+	fromTemplate := make(map[string]bool)
+	for _, name := range strings.Split(templateNames, ",") {
+		fromTemplate[name] = true
+	}
+
+	merged := make([]string, len(environ), len(environ)+len(imageEnv))
+	copy(merged, environ)
+	index := make(map[string]int, len(merged))
+	for i, e := range merged {
+		name, _, _ := strings.Cut(e, "=")
+		index[name] = i
+	}
+	for _, e := range imageEnv {
+		name, _, _ := strings.Cut(e, "=")
+		if i, ok := index[name]; ok {
+			if runtimeDefaultEnv[name] && !fromTemplate[name] {
+				merged[i] = e
+			}
+			continue
+		}
+		index[name] = len(merged)
+		merged = append(merged, e)
+	}
+	return merged
+}
+
+// searchPaths returns the directories to look up commands in: those in env's PATH,
+// then the defaultSearchPaths not already listed
+func searchPaths(env []string) []string {
+	var paths []string
+	for _, e := range env {
+		if value, ok := strings.CutPrefix(e, "PATH="); ok {
+			for _, dir := range strings.Split(value, ":") {
+				if strings.HasPrefix(dir, "/") && !slices.Contains(paths, dir) {
+					paths = append(paths, dir)
+				}
+			}
+		}
+	}
+	for _, dir := range defaultSearchPaths {
+		if !slices.Contains(paths, dir) {
+			paths = append(paths, dir)
+		}
+	}
+	return paths
+}
+
 // isMounted checks if a path is already a mount point
 func isMounted(path string) bool {
 	// Simple check: see if we can stat it and it's not under rootfs's parent mount
@@ -511,8 +1077,22 @@ func isMounted(path string) bool {
 	return false
 }
 
-// findBinary locates a binary in the rootfs
-func findBinary(name string) string {
+// findShell returns the path in rootfs of the first of shellCandidates found in the
+// PATH in env, or an error listing what was tried
+func findShell(rootfs string, env []string) (string, error) {
+	for _, name := range shellCandidates {
+		for _, dir := range searchPaths(env) {
+			if fileExists(rootfs + dir + "/" + name) {
+				return dir + "/" + name, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no shell found in the rootfs: tried %s in %s",
+		strings.Join(shellCandidates, ", "), strings.Join(searchPaths(env), ":"))
+}
+
+// findBinary locates a binary in the rootfs, searching the PATH in env
+func findBinary(name string, env []string) string {
 	// If it's an absolute path, use it directly
 	if strings.HasPrefix(name, "/") {
 		fullPath := RootfsPath + name
@@ -523,16 +1103,7 @@ func findBinary(name string) string {
 	}
 
 	// Search in PATH-like locations within rootfs
-	searchPaths := []string{
-		"/usr/local/sbin",
-		"/usr/local/bin",
-		"/usr/sbin",
-		"/usr/bin",
-		"/sbin",
-		"/bin",
-	}
-
-	for _, dir := range searchPaths {
+	for _, dir := range searchPaths(env) {
 		fullPath := RootfsPath + dir + "/" + name
 		if _, err := os.Stat(fullPath); err == nil {
 			return dir + "/" + name
@@ -542,8 +1113,8 @@ func findBinary(name string) string {
 	return ""
 }
 
-// chrootExec performs chroot and exec
-func chrootExec(binaryPath string, args []string) {
+// chrootExec performs chroot and exec with env
+func chrootExec(binaryPath string, args []string, env []string) {
 	debug("Chrooting to %s and executing %s", RootfsPath, binaryPath)
 
 	// Set environment variable to prevent recursion
@@ -556,6 +1127,7 @@ func chrootExec(binaryPath string, args []string) {
 	}
 
 	// Chroot
+	logPhase("chroot", "chrooting", map[string]interface{}{"rootfs": RootfsPath, "cwd": cwd})
 	if err := syscall.Chroot(RootfsPath); err != nil {
 		fatal("Failed to chroot: %v", err)
 	}
@@ -574,17 +1146,12 @@ func chrootExec(binaryPath string, args []string) {
 	}
 
 	// Prepare environment
-	env := os.Environ()
-	// Filter out our special env var from the child's environment
-	filteredEnv := make([]string, 0, len(env))
-	for _, e := range env {
-		if !strings.HasPrefix(e, EnvSCExecOriginal+"=") {
-			filteredEnv = append(filteredEnv, e)
-		}
-	}
+	// Filter out our special env vars from the child's environment
+	filteredEnv := stripInternalEnv(env)
 
 	// Exec the command
 	debug("Execing: %s with args %v", binaryPath, args)
+	logPhase("exec", "executing", map[string]interface{}{"path": binaryPath, "args": args})
 	if err := syscall.Exec(binaryPath, args, filteredEnv); err != nil {
 		fatal("Failed to exec %s: %v", binaryPath, err)
 	}
@@ -17,8 +17,16 @@ limitations under the License.
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/json"
 	"os"
 	"path/filepath"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+	"syscall"
 	"testing"
 )
 
@@ -179,16 +187,7 @@ func TestPathConstruction(t *testing.T) {
 
 func TestSearchPaths(t *testing.T) {
 	// Verify the search paths are reasonable
-	searchPaths := []string{
-		"/usr/local/sbin",
-		"/usr/local/bin",
-		"/usr/sbin",
-		"/usr/bin",
-		"/sbin",
-		"/bin",
-	}
-
-	for _, p := range searchPaths {
+	for _, p := range defaultSearchPaths {
 		if p == "" {
 			t.Error("Search path should not be empty")
 		}
@@ -197,3 +196,615 @@ func TestSearchPaths(t *testing.T) {
 		}
 	}
 }
+
+func TestSelectEntrypointCommand(t *testing.T) {
+	withShell := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(withShell, "bin"), 0755); err != nil {
+		t.Fatalf("Failed to create bin dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(withShell, "bin", "sh"), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatalf("Failed to create shell: %v", err)
+	}
+	distroless := t.TempDir()
+
+	tests := []struct {
+		name      string
+		rootfs    string
+		command   []string
+		expectErr bool
+	}{
+		{"default shell present", withShell, []string{DefaultShell}, false},
+		{"default shell absent", distroless, []string{DefaultShell}, true},
+		{"direct command with shell", withShell, []string{"/app/server", "--port", "8080"}, false},
+		{"direct command without shell", distroless, []string{"/app/server", "--port", "8080"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := selectEntrypointCommand(tt.rootfs, tt.command)
+			if tt.expectErr {
+				if err == nil {
+					t.Errorf("selectEntrypointCommand() = %v, expected error", result)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("selectEntrypointCommand() unexpected error: %v", err)
+			}
+			// Commands are exec'd directly, never wrapped in a shell
+			if !reflect.DeepEqual(result, tt.command) {
+				t.Errorf("selectEntrypointCommand() = %v, want %v", result, tt.command)
+			}
+		})
+	}
+}
+
+func TestCommandFromImage(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write image config: %v", err)
+		}
+		return path
+	}
+	nginx := writeConfig("nginx.json", `{"entrypoint":["/docker-entrypoint.sh"],"cmd":["nginx","-g","daemon off;"]}`)
+	cmdOnly := writeConfig("cmd.json", `{"cmd":["/bin/bash"]}`)
+	empty := writeConfig("empty.json", `{}`)
+	created := writeConfig("created.json", "")
+	invalid := writeConfig("invalid.json", "{")
+
+	tests := []struct {
+		name    string
+		config  string
+		mode    string
+		command []string
+		want    []string
+	}{
+		{"template sets command", nginx, "", []string{"/app/server"}, []string{"/app/server"}},
+		{"entrypoint and cmd", nginx, "entrypoint-cmd", []string{DefaultShell},
+			[]string{"/docker-entrypoint.sh", "nginx", "-g", "daemon off;"}},
+		{"cmd only", cmdOnly, "entrypoint-cmd", []string{DefaultShell}, []string{"/bin/bash"}},
+		{"args with entrypoint", nginx, "entrypoint", []string{"nginx", "-v"},
+			[]string{"/docker-entrypoint.sh", "nginx", "-v"}},
+		{"args without entrypoint", cmdOnly, "entrypoint", []string{"/app/server", "-v"}, []string{"/app/server", "-v"}},
+		{"nothing discoverable", empty, "entrypoint-cmd", []string{DefaultShell}, []string{DefaultShell}},
+		{"config not written yet", created, "entrypoint-cmd", []string{DefaultShell}, []string{DefaultShell}},
+		{"invalid config", invalid, "entrypoint-cmd", []string{DefaultShell}, []string{DefaultShell}},
+		{"missing config", filepath.Join(dir, "missing.json"), "entrypoint-cmd", []string{DefaultShell}, []string{DefaultShell}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := commandFromImage(loadImageConfig(tt.config), tt.mode, tt.command); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("commandFromImage() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchPaths_FromPATH(t *testing.T) {
+	env := []string{"HOME=/root", "PATH=/opt/venv/bin:relative:/usr/bin:/opt/venv/bin"}
+	expected := []string{"/opt/venv/bin", "/usr/bin", "/usr/local/sbin", "/usr/local/bin", "/usr/sbin", "/sbin", "/bin"}
+	if got := searchPaths(env); !reflect.DeepEqual(got, expected) {
+		t.Errorf("searchPaths() = %v, want %v", got, expected)
+	}
+
+	if got := searchPaths(nil); !reflect.DeepEqual(got, defaultSearchPaths) {
+		t.Errorf("searchPaths(nil) = %v, want %v", got, defaultSearchPaths)
+	}
+}
+
+func TestMergeImageEnv(t *testing.T) {
+	image := []string{"PATH=/opt/venv/bin:/usr/bin", "HOME=/app", "LANG=C.UTF-8", "APP_MODE=production"}
+
+	tests := []struct {
+		name          string
+		environ       []string
+		imageEnv      []string
+		templateNames string
+		expected      []string
+	}{
+		{
+			name:     "no image env",
+			environ:  []string{"PATH=/usr/bin", "HOSTNAME=app-0"},
+			expected: []string{"PATH=/usr/bin", "HOSTNAME=app-0"},
+		},
+		{
+			name:     "image fills in and replaces runtime defaults",
+			environ:  []string{"PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin", "HOSTNAME=app-0", "HOME=/"},
+			imageEnv: image,
+			expected: []string{"PATH=/opt/venv/bin:/usr/bin", "HOSTNAME=app-0", "HOME=/app", "LANG=C.UTF-8", "APP_MODE=production"},
+		},
+		{
+			name:          "template env takes precedence",
+			environ:       []string{"PATH=/custom/bin", "APP_MODE=debug", "HOME=/"},
+			imageEnv:      image,
+			templateNames: "PATH,APP_MODE",
+			expected:      []string{"PATH=/custom/bin", "APP_MODE=debug", "HOME=/app", "LANG=C.UTF-8"},
+		},
+		{
+			name:     "envFrom and other container vars take precedence",
+			environ:  []string{"LANG=en_US.UTF-8"},
+			imageEnv: image,
+			expected: []string{"LANG=en_US.UTF-8", "PATH=/opt/venv/bin:/usr/bin", "HOME=/app", "APP_MODE=production"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mergeImageEnv(tt.environ, tt.imageEnv, tt.templateNames); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("mergeImageEnv() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestStripInternalEnv(t *testing.T) {
+	env := []string{
+		"PATH=/usr/bin:/bin",
+		"SC_ROOTFS=/rootfs",
+		"SC_TEMPLATE_ENV=APP_MODE,SC_DEBUG",
+		"APP_MODE=production",
+		"SC_COMMAND_FROM_IMAGE=entrypoint-cmd",
+		"SC_SKIP_NETWORK_CONFIG_COPY=true",
+		"SC_EXEC_ORIGINAL=1",
+		"SC_DEBUG=1",
+	}
+	expected := []string{"PATH=/usr/bin:/bin", "APP_MODE=production", "SC_DEBUG=1"}
+	if got := stripInternalEnv(env); !reflect.DeepEqual(got, expected) {
+		t.Errorf("stripInternalEnv() = %v, want %v", got, expected)
+	}
+}
+
+func TestDiscoverCommands(t *testing.T) {
+	rootfs := t.TempDir()
+	for _, dir := range []string{"usr/local/bin", "usr/bin", "bin"} {
+		if err := os.MkdirAll(filepath.Join(rootfs, dir), 0755); err != nil {
+			t.Fatalf("Failed to create %s: %v", dir, err)
+		}
+	}
+	write := func(path string, mode os.FileMode) {
+		if err := os.WriteFile(filepath.Join(rootfs, path), []byte("#!/bin/sh\n"), mode); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+	write("usr/local/bin/kubectl", 0755)
+	write("usr/bin/go", 0755)
+	write("usr/bin/git", 0755)
+	write("usr/bin/README", 0644)
+	write("usr/bin/.hidden", 0755)
+	write("bin/git", 0755)
+	write("bin/busybox", 0755)
+	// Absolute symlinks point into the rootfs, so they are kept without following them
+	if err := os.Symlink("/bin/busybox", filepath.Join(rootfs, "bin", "ls")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+	if err := os.Mkdir(filepath.Join(rootfs, "usr", "bin", "subdir"), 0755); err != nil {
+		t.Fatalf("Failed to create subdir: %v", err)
+	}
+
+	expected := []string{"kubectl", "git", "go", "busybox", "ls"}
+	if got := discoverCommands(rootfs, MaxDiscoveredCommands); !reflect.DeepEqual(got, expected) {
+		t.Errorf("discoverCommands() = %v, want %v", got, expected)
+	}
+
+	if got := discoverCommands(rootfs, 2); !reflect.DeepEqual(got, expected[:2]) {
+		t.Errorf("discoverCommands() with limit 2 = %v, want %v", got, expected[:2])
+	}
+
+	if got := discoverCommands(filepath.Join(rootfs, "missing"), MaxDiscoveredCommands); len(got) != 0 {
+		t.Errorf("discoverCommands() of a missing rootfs = %v, want none", got)
+	}
+}
+
+func TestInitCommands(t *testing.T) {
+	rootfs := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(rootfs, "usr", "bin"), 0755); err != nil {
+		t.Fatalf("Failed to create usr/bin: %v", err)
+	}
+	for _, name := range []string{"bash", "psql"} {
+		if err := os.WriteFile(filepath.Join(rootfs, "usr", "bin", name), nil, 0755); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	commands := initCommands(rootfs, " go, git,,bash,../escape,go", MaxDiscoveredCommands)
+	expected := append(slices.Clone(defaultCommands), "go", "git", "psql")
+	if !reflect.DeepEqual(commands, expected) {
+		t.Errorf("initCommands() = %v, want %v", commands, expected)
+	}
+}
+
+func TestHandleInit_SkipsExisting(t *testing.T) {
+	origBinDir := initBinDir
+	defer func() { initBinDir = origBinDir }()
+	initBinDir = t.TempDir()
+
+	overlay := t.TempDir()
+	existing := filepath.Join(overlay, "sh")
+	if err := os.WriteFile(existing, []byte("keep"), 0755); err != nil {
+		t.Fatalf("Failed to write existing entry: %v", err)
+	}
+	t.Setenv(EnvExtraCommands, "psql")
+
+	handleInit(overlay)
+
+	if data, err := os.ReadFile(existing); err != nil || string(data) != "keep" {
+		t.Errorf("Existing entry was clobbered: %q, %v", data, err)
+	}
+	target, err := os.Readlink(filepath.Join(overlay, "psql"))
+	if err != nil || target != filepath.Join(initBinDir, "sc-exec") {
+		t.Errorf("psql symlink = %q, %v, want sc-exec", target, err)
+	}
+}
+
+func TestFindShell(t *testing.T) {
+	rootfs := t.TempDir()
+	for _, dir := range []string{"bin", "opt/tools"} {
+		if err := os.MkdirAll(filepath.Join(rootfs, dir), 0755); err != nil {
+			t.Fatalf("Failed to create %s: %v", dir, err)
+		}
+	}
+	write := func(path string) {
+		if err := os.WriteFile(filepath.Join(rootfs, path), nil, 0755); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+
+	// Nothing found: the error lists the shells and directories tried
+	_, err := findShell(rootfs, nil)
+	if err == nil || !strings.Contains(err.Error(), "bash, ash, sh") || !strings.Contains(err.Error(), "/usr/bin") {
+		t.Errorf("findShell() error = %v, want the shells and paths tried", err)
+	}
+
+	write("bin/sh")
+	if shell, err := findShell(rootfs, nil); err != nil || shell != "/bin/sh" {
+		t.Errorf("findShell() = %q, %v, want /bin/sh", shell, err)
+	}
+
+	// ash is preferred over sh (e.g. Alpine)
+	write("bin/ash")
+	if shell, err := findShell(rootfs, nil); err != nil || shell != "/bin/ash" {
+		t.Errorf("findShell() = %q, %v, want /bin/ash", shell, err)
+	}
+
+	// bash is preferred over both, and is looked up in the image's PATH
+	write("opt/tools/bash")
+	env := []string{"PATH=/opt/tools:/usr/bin"}
+	if shell, err := findShell(rootfs, env); err != nil || shell != "/opt/tools/bash" {
+		t.Errorf("findShell() = %q, %v, want /opt/tools/bash", shell, err)
+	}
+}
+
+func TestShouldCopyNetworkConfig(t *testing.T) {
+	t.Setenv(EnvSkipNetworkConfigCopy, "")
+	if !shouldCopyNetworkConfig() {
+		t.Error("shouldCopyNetworkConfig() = false, want true by default")
+	}
+
+	t.Setenv(EnvSkipNetworkConfigCopy, "true")
+	if shouldCopyNetworkConfig() {
+		t.Error("shouldCopyNetworkConfig() = true, want false when skipped")
+	}
+}
+
+func TestLogPhaseJSON(t *testing.T) {
+	var buf bytes.Buffer
+	origOutput, origBinDir := logOutput, initBinDir
+	defer func() { logOutput, initBinDir = origOutput, origBinDir }()
+	logOutput = &buf
+	initBinDir = t.TempDir()
+
+	// Disabled by default
+	t.Setenv(EnvSCLogJSON, "")
+	logPhase("init", "ignored", nil)
+	if buf.Len() != 0 {
+		t.Fatalf("Expected no output without %s, got %q", EnvSCLogJSON, buf.String())
+	}
+
+	t.Setenv(EnvSCLogJSON, "1")
+
+	overlay := t.TempDir()
+	handleInit(overlay)
+
+	src := filepath.Join(t.TempDir(), "src")
+	if err := os.WriteFile(src, []byte("data"), 0644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+	handleCopy(src, filepath.Join(t.TempDir(), "dst"))
+
+	var phases []string
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var entry map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("Log line is not valid JSON: %q: %v", scanner.Text(), err)
+		}
+		if entry["component"] != "sc-exec" || entry["msg"] == "" || entry["time"] == "" {
+			t.Errorf("Log line missing fields: %v", entry)
+		}
+		phases = append(phases, entry["phase"].(string))
+	}
+
+	expected := []string{"init", "init", "copy"}
+	if !reflect.DeepEqual(phases, expected) {
+		t.Errorf("Logged phases = %v, want %v", phases, expected)
+	}
+}
+
+func TestRootfsReady(t *testing.T) {
+	// A distroless rootfs has no /bin, but is ready once proc is mounted in it.
+	// The host root stands in for such a rootfs.
+	if _, err := os.Stat("/proc/self"); err != nil {
+		t.Skip("/proc is not mounted")
+	}
+	if !rootfsReady("") {
+		t.Error("rootfsReady() = false with proc mounted, want true")
+	}
+
+	// /bin alone is not enough: the DaemonSet may not have mounted the overlay yet
+	rootfs := t.TempDir()
+	for _, dir := range []string{"bin", "proc"} {
+		if err := os.Mkdir(filepath.Join(rootfs, dir), 0755); err != nil {
+			t.Fatalf("Failed to create %s: %v", dir, err)
+		}
+	}
+	if rootfsReady(rootfs) {
+		t.Error("rootfsReady() = true without proc mounted, want false")
+	}
+}
+
+func TestLogFormatJSON(t *testing.T) {
+	var buf bytes.Buffer
+	origOutput := logOutput
+	defer func() { logOutput = origOutput }()
+	logOutput = &buf
+
+	t.Run("plain text by default", func(t *testing.T) {
+		buf.Reset()
+		t.Setenv(EnvSCLogFormat, "")
+		t.Setenv(EnvSCLogJSON, "")
+		var out bytes.Buffer
+		logf(&out, "sc-init", "warning", "missing %s", "ls")
+		logPhase("init", "ignored", nil)
+		if out.String() != "[sc-init] WARNING: missing ls\n" || buf.Len() != 0 {
+			t.Errorf("output = %q, JSON output = %q", out.String(), buf.String())
+		}
+	})
+
+	t.Run("JSON lines with SC_LOG_FORMAT=json", func(t *testing.T) {
+		buf.Reset()
+		t.Setenv(EnvSCLogFormat, "json")
+		t.Setenv(EnvSCLogJSON, "")
+		var out bytes.Buffer
+		logf(&out, "sc-entrypoint", "info", "Setup complete")
+		logPhase("mount", "copied", map[string]interface{}{"level": "warning"})
+
+		// logf keeps writing to its stream, phase lines go to logOutput
+		var entries []map[string]interface{}
+		for _, r := range []*bytes.Buffer{&out, &buf} {
+			scanner := bufio.NewScanner(r)
+			for scanner.Scan() {
+				var entry map[string]interface{}
+				if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+					t.Fatalf("Log line is not valid JSON: %q: %v", scanner.Text(), err)
+				}
+				entries = append(entries, entry)
+			}
+		}
+		if len(entries) != 2 {
+			t.Fatalf("Expected 2 log lines, got %d", len(entries))
+		}
+		if entries[0]["component"] != "sc-entrypoint" || entries[0]["level"] != "info" || entries[0]["msg"] != "Setup complete" {
+			t.Errorf("logf line = %v", entries[0])
+		}
+		if entries[1]["phase"] != "mount" || entries[1]["level"] != "warning" {
+			t.Errorf("logPhase line = %v", entries[1])
+		}
+	})
+}
+
+func TestDispatchBuiltin(t *testing.T) {
+	t.Run("each built-in is dispatched with its arguments", func(t *testing.T) {
+		original := builtins
+		defer func() { builtins = original }()
+
+		for name, cmd := range original {
+			var got []string
+			stub := cmd
+			stub.run = func(args []string) int {
+				got = args
+				return 7
+			}
+			builtins = map[string]builtinCommand{name: stub}
+
+			args := make([]string, cmd.minArgs)
+			for i := range args {
+				args[i] = "arg"
+			}
+			code, ok := dispatchBuiltin(append([]string{name}, args...), &bytes.Buffer{})
+			if !ok || code != 7 {
+				t.Errorf("%s: dispatchBuiltin() = %d, %v, want 7, true", name, code, ok)
+			}
+			if len(got) != cmd.minArgs {
+				t.Errorf("%s: handler got args %v, want %d args", name, got, cmd.minArgs)
+			}
+		}
+	})
+
+	t.Run("check-file and check-dir", func(t *testing.T) {
+		dir := t.TempDir()
+		file := filepath.Join(dir, "file")
+		if err := os.WriteFile(file, []byte("x"), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+		tests := []struct {
+			args []string
+			code int
+		}{
+			{[]string{"--check-file", file}, 0},
+			{[]string{"--check-file", filepath.Join(dir, "missing")}, 1},
+			{[]string{"--check-dir", dir}, 0},
+			{[]string{"--check-dir", file}, 1},
+		}
+		for _, tt := range tests {
+			if code, ok := dispatchBuiltin(tt.args, &bytes.Buffer{}); !ok || code != tt.code {
+				t.Errorf("dispatchBuiltin(%v) = %d, %v, want %d, true", tt.args, code, ok, tt.code)
+			}
+		}
+	})
+
+	t.Run("missing arguments print the built-in usage", func(t *testing.T) {
+		var out bytes.Buffer
+		code, ok := dispatchBuiltin([]string{"--copy", "src"}, &out)
+		if !ok || code != 1 {
+			t.Errorf("dispatchBuiltin() = %d, %v, want 1, true", code, ok)
+		}
+		if !bytes.Contains(out.Bytes(), []byte("Usage: sc-exec --copy <src> <dst>")) {
+			t.Errorf("unexpected usage message: %q", out.String())
+		}
+	})
+
+	t.Run("unknown flags print the usage", func(t *testing.T) {
+		var out bytes.Buffer
+		code, ok := dispatchBuiltin([]string{"--bogus"}, &out)
+		if !ok || code != 1 {
+			t.Errorf("dispatchBuiltin() = %d, %v, want 1, true", code, ok)
+		}
+		for _, want := range []string{"Unknown built-in command: --bogus", "Built-in commands:", "--entrypoint <workdir> <command...>"} {
+			if !bytes.Contains(out.Bytes(), []byte(want)) {
+				t.Errorf("usage %q does not contain %q", out.String(), want)
+			}
+		}
+	})
+
+	t.Run("plain commands are not built-ins", func(t *testing.T) {
+		for _, args := range [][]string{nil, {"ls", "-la"}} {
+			if _, ok := dispatchBuiltin(args, &bytes.Buffer{}); ok {
+				t.Errorf("dispatchBuiltin(%v) handled a plain command", args)
+			}
+		}
+	})
+}
+
+func TestVersionString(t *testing.T) {
+	original := version
+	defer func() { version = original }()
+
+	version = "v1.2.3"
+	got := versionString()
+	if !strings.HasPrefix(got, "sc-exec v1.2.3 (") || !strings.Contains(got, runtime.GOARCH) {
+		t.Errorf("versionString() = %q, want sc-exec v1.2.3 with platform", got)
+	}
+}
+
+func TestRunSelftest(t *testing.T) {
+	rootfs := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(rootfs, "bin"), 0755); err != nil {
+		t.Fatalf("Failed to create bin: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(rootfs, DefaultShell), []byte{}, 0755); err != nil {
+		t.Fatalf("Failed to create shell: %v", err)
+	}
+	statusFile := filepath.Join(t.TempDir(), "status")
+	// CAP_SYS_CHROOT only (bit 18)
+	if err := os.WriteFile(statusFile, []byte("Name:\tsc-exec\nCapEff:\t0000000000040000\n"), 0644); err != nil {
+		t.Fatalf("Failed to write status: %v", err)
+	}
+
+	results := runSelftest(rootfs, statusFile)
+	got := map[string]bool{}
+	var names []string
+	for _, r := range results {
+		if r.Detail == "" {
+			t.Errorf("check %s has no detail", r.Name)
+		}
+		got[r.Name] = r.OK
+		names = append(names, r.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"rootfs", "mount", "shell", "chroot"}) {
+		t.Errorf("selftest checks = %v", names)
+	}
+	// A temp dir is not a mount point
+	want := map[string]bool{"rootfs": true, "mount": false, "shell": true, "chroot": true}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("selftest results = %v, want %v", got, want)
+	}
+
+	// Without the shell or the capability those checks fail
+	if err := os.WriteFile(statusFile, []byte("CapEff:\t0000000000000000\n"), 0644); err != nil {
+		t.Fatalf("Failed to write status: %v", err)
+	}
+	for _, r := range runSelftest(filepath.Join(rootfs, "missing"), statusFile) {
+		if r.OK {
+			t.Errorf("check %s passed, want failure", r.Name)
+		}
+	}
+}
+
+func TestMountServiceAccountDir(t *testing.T) {
+	origBindMount, origDelay, origOutput := bindMount, saBindMountRetryDelay, logOutput
+	defer func() { bindMount, saBindMountRetryDelay, logOutput = origBindMount, origDelay, origOutput }()
+	saBindMountRetryDelay = 0
+	t.Setenv(EnvSCLogJSON, "1")
+
+	saPath := t.TempDir()
+	if err := os.WriteFile(filepath.Join(saPath, "token"), []byte("secret-token"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("retries the bind mount", func(t *testing.T) {
+		var buf bytes.Buffer
+		logOutput = &buf
+		attempts := 0
+		bindMount = func(source, target string) error {
+			attempts++
+			if attempts < saBindMountAttempts {
+				return syscall.EBUSY
+			}
+			return nil
+		}
+		targetPath := filepath.Join(t.TempDir(), "serviceaccount")
+		if err := mountServiceAccountDir(saPath, targetPath); err != nil {
+			t.Fatalf("mountServiceAccountDir() error = %v", err)
+		}
+		if attempts != saBindMountAttempts {
+			t.Errorf("attempts = %d, want %d", attempts, saBindMountAttempts)
+		}
+		if fileExists(filepath.Join(targetPath, "token")) {
+			t.Error("token was copied although the bind mount succeeded")
+		}
+		if buf.Len() != 0 {
+			t.Errorf("unexpected fallback log: %s", buf.String())
+		}
+	})
+
+	t.Run("falls back to copy", func(t *testing.T) {
+		var buf bytes.Buffer
+		logOutput = &buf
+		attempts := 0
+		bindMount = func(source, target string) error {
+			attempts++
+			return syscall.EPERM
+		}
+		targetPath := filepath.Join(t.TempDir(), "serviceaccount")
+		if err := mountServiceAccountDir(saPath, targetPath); err != nil {
+			t.Fatalf("mountServiceAccountDir() error = %v", err)
+		}
+		if attempts != saBindMountAttempts {
+			t.Errorf("attempts = %d, want %d", attempts, saBindMountAttempts)
+		}
+		data, err := os.ReadFile(filepath.Join(targetPath, "token"))
+		if err != nil || string(data) != "secret-token" {
+			t.Errorf("copied token = %q, %v; want %q", data, err, "secret-token")
+		}
+		var entry map[string]interface{}
+		if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+			t.Fatalf("fallback log is not JSON: %q", buf.String())
+		}
+		if entry["fallback"] != "copy" || entry["phase"] != "mount" {
+			t.Errorf("fallback log = %v", entry)
+		}
+	})
+}
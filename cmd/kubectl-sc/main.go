@@ -25,29 +25,51 @@ limitations under the License.
 // Usage:
 //
 //	kubectl sc list                     # List all StoppableContainers
-//	kubectl sc status <name>            # Show status of a StoppableContainer
+//	kubectl sc status <name> [-w]       # Show (or watch) status of a StoppableContainer
 //	kubectl sc start <name>             # Start a StoppableContainer
 //	kubectl sc stop <name>              # Stop a StoppableContainer
+//	kubectl sc wait <name> --for=...    # Wait for a phase or condition
 //	kubectl sc exec <name> -- <cmd>     # Execute command in container
 //	kubectl sc logs <name>              # Show logs from container
+//	kubectl sc events <name>            # Show events of a StoppableContainer and its pods
+//	kubectl sc get-instances <name>     # List the StoppableContainerInstances of a StoppableContainer
+//	kubectl sc export-rootfs <name> -o <file> # Save the full rootfs as a tarball
+//	kubectl sc top [name]               # Show CPU and memory usage of the pods
+//	kubectl sc edit <name>              # Edit a StoppableContainer and summarize the changes
 //	kubectl sc create <name> --image=<image> -- <cmd>  # Create a new StoppableContainer
+//	kubectl sc apply -f <file>          # Server-side apply a StoppableContainer manifest
+//	kubectl sc clone <src> <dst>        # Copy a StoppableContainer
 //	kubectl sc delete <name>            # Delete a StoppableContainer
 package main
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
 	"strings"
 	"text/tabwriter"
 	"time"
 
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/tools/clientcmd"
 )
@@ -55,15 +77,25 @@ import (
 const (
 	// GroupVersion for StoppableContainer API
 	GroupVersion = "stoppablecontainer.xtlsoft.top/v1alpha1"
+
+	// FieldManager is the server-side apply field manager used by kubectl sc apply
+	FieldManager = "kubectl-sc"
+
+	// LabelInstance is the pod label naming the StoppableContainerInstance, as set by the operator
+	LabelInstance = "stoppablecontainer.xtlsoft.top/instance"
+
+	// LabelRole is the pod label telling provider and consumer pods apart
+	LabelRole = "stoppablecontainer.xtlsoft.top/role"
 )
 
 // version is set by ldflags during build
 var version = "dev"
 
 var (
-	namespace  string
-	kubeconfig string
-	allNs      bool
+	namespace   string
+	kubeconfig  string
+	kubeContext string
+	allNs       bool
 )
 
 // StoppableContainer GVR
@@ -80,6 +112,25 @@ var sciGVR = schema.GroupVersionResource{
 	Resource: "stoppablecontainerinstances",
 }
 
+// Pod GVR
+var podGVR = schema.GroupVersionResource{
+	Version:  "v1",
+	Resource: "pods",
+}
+
+// Event GVR
+var eventGVR = schema.GroupVersionResource{
+	Version:  "v1",
+	Resource: "events",
+}
+
+// PodMetrics GVR, served by metrics-server
+var podMetricsGVR = schema.GroupVersionResource{
+	Group:    "metrics.k8s.io",
+	Version:  "v1beta1",
+	Resource: "pods",
+}
+
 func main() {
 	rootCmd := &cobra.Command{
 		Use:   "kubectl-sc",
@@ -106,10 +157,15 @@ Examples:
   # View logs
   kubectl sc logs my-app
 
+  # Show events of the container, its instance and pods
+  kubectl sc events my-app
+
   # Delete a StoppableContainer
   kubectl sc delete my-app`,
 		Version: version,
 	}
+	// Replaced by completionCmd, which documents the kubectl plugin setup
+	rootCmd.CompletionOptions.DisableDefaultCmd = true
 
 	// Global flags
 	rootCmd.PersistentFlags().StringVarP(
@@ -117,18 +173,33 @@ Examples:
 		"Kubernetes namespace (default: current context)",
 	)
 	rootCmd.PersistentFlags().StringVar(&kubeconfig, "kubeconfig", "", "Path to kubeconfig file")
+	rootCmd.PersistentFlags().StringVar(&kubeContext, "context", "", "Kubeconfig context to use (default: current context)")
 	rootCmd.PersistentFlags().BoolVarP(&allNs, "all-namespaces", "A", false, "List across all namespaces")
 
 	// Add commands
 	rootCmd.AddCommand(listCmd())
 	rootCmd.AddCommand(statusCmd())
+	rootCmd.AddCommand(describeCmd())
+	rootCmd.AddCommand(getInstancesCmd())
 	rootCmd.AddCommand(startCmd())
 	rootCmd.AddCommand(stopCmd())
+	rootCmd.AddCommand(restartCmd())
+	rootCmd.AddCommand(waitCmd())
 	rootCmd.AddCommand(execCmd())
 	rootCmd.AddCommand(logsCmd())
+	rootCmd.AddCommand(eventsCmd())
+	rootCmd.AddCommand(topCmd())
+	rootCmd.AddCommand(portForwardCmd())
+	rootCmd.AddCommand(cpCmd())
+	rootCmd.AddCommand(exportRootfsCmd())
+	rootCmd.AddCommand(imageCmd())
+	rootCmd.AddCommand(editCmd())
 	rootCmd.AddCommand(createCmd())
+	rootCmd.AddCommand(applyCmd())
+	rootCmd.AddCommand(cloneCmd())
 	rootCmd.AddCommand(deleteCmd())
 	rootCmd.AddCommand(versionCmd())
+	rootCmd.AddCommand(completionCmd())
 
 	if err := rootCmd.Execute(); err != nil {
 		os.Exit(1)
@@ -141,7 +212,7 @@ func getClient() (dynamic.Interface, string, error) {
 		loadingRules.ExplicitPath = kubeconfig
 	}
 
-	configOverrides := &clientcmd.ConfigOverrides{}
+	configOverrides := &clientcmd.ConfigOverrides{CurrentContext: kubeContext}
 	if namespace != "" {
 		configOverrides.Context.Namespace = namespace
 	}
@@ -228,12 +299,25 @@ func listCmd() *cobra.Command {
 
 func statusCmd() *cobra.Command {
 	var output string
+	var watchStatus bool
 	cmd := &cobra.Command{
 		Use:   "status <name>",
 		Short: "Show status of a StoppableContainer",
-		Args:  cobra.ExactArgs(1),
+		Long: `Show the status of a StoppableContainer.
+
+Examples:
+  # Show the status once
+  kubectl sc status my-app
+
+  # Keep showing the status while it changes, e.g. during a start
+  kubectl sc status my-app -w`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeStoppableContainerNames,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			name := args[0]
+			if watchStatus && output != "" {
+				return fmt.Errorf("--watch cannot be combined with --output")
+			}
 			client, ns, err := getClient()
 			if err != nil {
 				return err
@@ -255,118 +339,143 @@ func statusCmd() *cobra.Command {
 				return runKubectl("get", "stoppablecontainer", name, "-n", ns, "-o", "yaml")
 			}
 
-			// Pretty print status
-			fmt.Printf("Name:        %s\n", name)
-			fmt.Printf("Namespace:   %s\n", ns)
-
-			running, _, _ := unstructured.NestedBool(sc.Object, "spec", "running")
-			fmt.Printf("Running:     %v\n", running)
-
-			phase, _, _ := unstructured.NestedString(sc.Object, "status", "phase")
-			if phase == "" {
-				phase = "Pending"
-			}
-			fmt.Printf("Phase:       %s\n", phase)
-
-			message, _, _ := unstructured.NestedString(sc.Object, "status", "message")
-			if message != "" {
-				fmt.Printf("Message:     %s\n", message)
-			}
-
-			instanceName, _, _ := unstructured.NestedString(sc.Object, "status", "instanceName")
-			if instanceName != "" {
-				fmt.Printf("Instance:    %s\n", instanceName)
-			}
-
-			nodeName, _, _ := unstructured.NestedString(sc.Object, "status", "nodeName")
-			if nodeName != "" {
-				fmt.Printf("Node:        %s\n", nodeName)
-			}
-
-			// Show conditions
-			conditions, found, _ := unstructured.NestedSlice(sc.Object, "status", "conditions")
-			if found && len(conditions) > 0 {
-				fmt.Println("\nConditions:")
-				for _, c := range conditions {
-					cond, ok := c.(map[string]interface{})
-					if !ok {
-						continue
-					}
-					condType, _, _ := unstructured.NestedString(cond, "type")
-					status, _, _ := unstructured.NestedString(cond, "status")
-					reason, _, _ := unstructured.NestedString(cond, "reason")
-					fmt.Printf("  %s: %s (%s)\n", condType, status, reason)
-				}
+			if !watchStatus {
+				fmt.Print(formatStatus(sc))
+				return nil
 			}
 
-			return nil
+			// Stop cleanly on Ctrl-C
+			ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+			defer stop()
+			return watchStatusChanges(ctx, client, sc, term.IsTerminal(int(os.Stdout.Fd())), os.Stdout)
 		},
 	}
 	cmd.Flags().StringVarP(&output, "output", "o", "", "Output format (json, yaml)")
+	cmd.Flags().BoolVarP(&watchStatus, "watch", "w", false, "Keep showing the status as it changes")
 	return cmd
 }
 
-func startCmd() *cobra.Command {
-	var wait bool
-	var timeout time.Duration
+// formatStatus renders the status block of a StoppableContainer
+func formatStatus(sc *unstructured.Unstructured) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Name:        %s\n", sc.GetName())
+	fmt.Fprintf(&sb, "Namespace:   %s\n", sc.GetNamespace())
 
-	cmd := &cobra.Command{
-		Use:   "start <name>",
-		Short: "Start a StoppableContainer",
-		Args:  cobra.ExactArgs(1),
-		RunE: func(cmd *cobra.Command, args []string) error {
-			name := args[0]
-			client, ns, err := getClient()
-			if err != nil {
-				return err
-			}
+	running, _, _ := unstructured.NestedBool(sc.Object, "spec", "running")
+	fmt.Fprintf(&sb, "Running:     %v\n", running)
 
-			ctx := context.Background()
+	phase, _, _ := unstructured.NestedString(sc.Object, "status", "phase")
+	if phase == "" {
+		phase = "Pending"
+	}
+	fmt.Fprintf(&sb, "Phase:       %s\n", phase)
 
-			// Get current resource
-			sc, err := client.Resource(scGVR).Namespace(ns).Get(ctx, name, metav1.GetOptions{})
-			if err != nil {
-				return fmt.Errorf("failed to get StoppableContainer %s: %w", name, err)
-			}
+	message, _, _ := unstructured.NestedString(sc.Object, "status", "message")
+	if message != "" {
+		fmt.Fprintf(&sb, "Message:     %s\n", message)
+	}
 
-			// Check if already running
-			running, _, _ := unstructured.NestedBool(sc.Object, "spec", "running")
-			if running {
-				fmt.Printf("StoppableContainer %s is already running\n", name)
-				return nil
-			}
+	instanceName, _, _ := unstructured.NestedString(sc.Object, "status", "instanceName")
+	if instanceName != "" {
+		fmt.Fprintf(&sb, "Instance:    %s\n", instanceName)
+	}
 
-			// Update to running
-			if err := unstructured.SetNestedField(sc.Object, true, "spec", "running"); err != nil {
-				return err
-			}
+	nodeName, _, _ := unstructured.NestedString(sc.Object, "status", "nodeName")
+	if nodeName != "" {
+		fmt.Fprintf(&sb, "Node:        %s\n", nodeName)
+	}
 
-			_, err = client.Resource(scGVR).Namespace(ns).Update(ctx, sc, metav1.UpdateOptions{})
-			if err != nil {
-				return fmt.Errorf("failed to start StoppableContainer %s: %w", name, err)
+	exitCode, found, _ := unstructured.NestedInt64(sc.Object, "status", "exitCode")
+	if found {
+		fmt.Fprintf(&sb, "Exit Code:   %d\n", exitCode)
+	}
+
+	// Show conditions
+	conditions, found, _ := unstructured.NestedSlice(sc.Object, "status", "conditions")
+	if found && len(conditions) > 0 {
+		sb.WriteString("\nConditions:\n")
+		for _, c := range conditions {
+			cond, ok := c.(map[string]interface{})
+			if !ok {
+				continue
 			}
+			condType, _, _ := unstructured.NestedString(cond, "type")
+			status, _, _ := unstructured.NestedString(cond, "status")
+			reason, _, _ := unstructured.NestedString(cond, "reason")
+			fmt.Fprintf(&sb, "  %s: %s (%s)\n", condType, status, reason)
+		}
+	}
+	return sb.String()
+}
 
-			fmt.Printf("StoppableContainer %s starting...\n", name)
+// clearScreen moves the cursor home and clears a terminal
+const clearScreen = "\033[H\033[2J"
+
+// watchStatusChanges prints the status of sc and prints it again whenever it changes,
+// until the StoppableContainer is deleted or ctx is done. On a terminal the screen is
+// cleared first, otherwise the new status is appended after a blank line.
+func watchStatusChanges(ctx context.Context, client dynamic.Interface, sc *unstructured.Unstructured, clear bool, w io.Writer) error {
+	name, ns := sc.GetName(), sc.GetNamespace()
+	last := formatStatus(sc)
+	if clear {
+		fmt.Fprint(w, clearScreen)
+	}
+	fmt.Fprint(w, last)
 
-			if wait {
-				return waitForPhase(client, ns, name, "Running", timeout)
+	resourceVersion := sc.GetResourceVersion()
+	for {
+		watcher, err := client.Resource(scGVR).Namespace(ns).Watch(ctx, metav1.ListOptions{
+			FieldSelector:   "metadata.name=" + name,
+			ResourceVersion: resourceVersion,
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to watch StoppableContainer %s: %w", name, err)
+		}
+		for e := range watcher.ResultChan() {
+			if e.Type == watch.Error {
+				watcher.Stop()
+				return fmt.Errorf("watching StoppableContainer %s: %v", name, errors.FromObject(e.Object))
+			}
+			obj, ok := e.Object.(*unstructured.Unstructured)
+			if !ok || obj.GetName() != name {
+				continue
+			}
+			resourceVersion = obj.GetResourceVersion()
+			if e.Type == watch.Deleted {
+				watcher.Stop()
+				fmt.Fprintf(w, "\nStoppableContainer %s was deleted\n", name)
+				return nil
+			}
+			status := formatStatus(obj)
+			if status == last {
+				continue
+			}
+			last = status
+			if clear {
+				fmt.Fprint(w, clearScreen)
+			} else {
+				fmt.Fprintln(w)
 			}
+			fmt.Fprint(w, status)
+		}
+		// The server ends watches after a while, resume from the last change seen
+		if ctx.Err() != nil {
 			return nil
-		},
+		}
 	}
-	cmd.Flags().BoolVarP(&wait, "wait", "w", false, "Wait for container to be running")
-	cmd.Flags().DurationVar(&timeout, "timeout", 2*time.Minute, "Timeout for wait")
-	return cmd
 }
 
-func stopCmd() *cobra.Command {
-	var wait bool
-	var timeout time.Duration
-
-	cmd := &cobra.Command{
-		Use:   "stop <name>",
-		Short: "Stop a StoppableContainer",
-		Args:  cobra.ExactArgs(1),
+func describeCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "describe <name>",
+		Short: "Show a StoppableContainer with its instance, pods and events",
+		Long: `Show a StoppableContainer together with its StoppableContainerInstance,
+the provider and consumer pods, and recent events for all of them.`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeStoppableContainerNames,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			name := args[0]
 			client, ns, err := getClient()
@@ -374,179 +483,1613 @@ func stopCmd() *cobra.Command {
 				return err
 			}
 
-			ctx := context.Background()
-
-			// Get current resource
-			sc, err := client.Resource(scGVR).Namespace(ns).Get(ctx, name, metav1.GetOptions{})
+			pods, err := describeStoppableContainer(client, ns, name, os.Stdout)
 			if err != nil {
-				return fmt.Errorf("failed to get StoppableContainer %s: %w", name, err)
-			}
-
-			// Check if already stopped
-			running, _, _ := unstructured.NestedBool(sc.Object, "spec", "running")
-			if !running {
-				fmt.Printf("StoppableContainer %s is already stopped\n", name)
-				return nil
-			}
-
-			// Update to stopped
-			if err := unstructured.SetNestedField(sc.Object, false, "spec", "running"); err != nil {
 				return err
 			}
 
-			_, err = client.Resource(scGVR).Namespace(ns).Update(ctx, sc, metav1.UpdateOptions{})
-			if err != nil {
-				return fmt.Errorf("failed to stop StoppableContainer %s: %w", name, err)
-			}
-
-			fmt.Printf("StoppableContainer %s stopping...\n", name)
-
-			if wait {
-				return waitForPhase(client, ns, name, "Stopped", timeout)
+			// The StoppableContainer, its instance and the consumer pod share the name
+			fmt.Println("\nEvents:")
+			for _, objectName := range append([]string{name}, pods...) {
+				if err := runKubectl("get", "events", "-n", ns,
+					"--field-selector", "involvedObject.name="+objectName,
+					"--sort-by", ".lastTimestamp"); err != nil {
+					return err
+				}
 			}
 			return nil
 		},
 	}
-	cmd.Flags().BoolVarP(&wait, "wait", "w", false, "Wait for container to be stopped")
-	cmd.Flags().DurationVar(&timeout, "timeout", 30*time.Second, "Timeout for wait")
-	return cmd
 }
 
-func execCmd() *cobra.Command {
-	var stdin bool
-	var tty bool
-	var container string
+// describeStoppableContainer prints the StoppableContainer, its instance and its pods.
+// Objects that don't exist yet are shown as "not created". Returns the names of the
+// pods whose events are not already covered by the StoppableContainer's name.
+func describeStoppableContainer(client dynamic.Interface, ns, name string, w io.Writer) ([]string, error) {
+	ctx := context.Background()
+	sc, err := client.Resource(scGVR).Namespace(ns).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get StoppableContainer %s: %w", name, err)
+	}
 
-	cmd := &cobra.Command{
-		Use:   "exec <name> -- <command> [args...]",
-		Short: "Execute a command in a StoppableContainer",
-		Long: `Execute a command inside the consumer container of a StoppableContainer.
+	running, _, _ := unstructured.NestedBool(sc.Object, "spec", "running")
+	phase, _, _ := unstructured.NestedString(sc.Object, "status", "phase")
+	if phase == "" {
+		phase = "Pending"
+	}
+	message, _, _ := unstructured.NestedString(sc.Object, "status", "message")
+	image, _ := getImage(sc)
+	fmt.Fprintf(w, "Name:        %s\n", name)
+	fmt.Fprintf(w, "Namespace:   %s\n", ns)
+	fmt.Fprintf(w, "Image:       %s\n", image)
+	fmt.Fprintf(w, "Running:     %v\n", running)
+	fmt.Fprintf(w, "Phase:       %s\n", phase)
+	if message != "" {
+		fmt.Fprintf(w, "Message:     %s\n", message)
+	}
+	printConditions(w, sc)
 
-The command runs inside the chroot environment with the container's rootfs.
+	instanceName, _, _ := unstructured.NestedString(sc.Object, "status", "instanceName")
+	if instanceName == "" {
+		instanceName = name
+	}
+	providerPodName, consumerPodName := name+"-provider", name
+
+	fmt.Fprintf(w, "\nInstance %s:\n", instanceName)
+	sci, err := client.Resource(sciGVR).Namespace(ns).Get(ctx, instanceName, metav1.GetOptions{})
+	switch {
+	case errors.IsNotFound(err):
+		fmt.Fprintln(w, "  not created")
+	case err != nil:
+		return nil, fmt.Errorf("failed to get StoppableContainerInstance %s: %w", instanceName, err)
+	default:
+		sciPhase, _, _ := unstructured.NestedString(sci.Object, "status", "phase")
+		sciMessage, _, _ := unstructured.NestedString(sci.Object, "status", "message")
+		nodeName, _, _ := unstructured.NestedString(sci.Object, "status", "nodeName")
+		fmt.Fprintf(w, "  Phase:     %s\n", sciPhase)
+		if sciMessage != "" {
+			fmt.Fprintf(w, "  Message:   %s\n", sciMessage)
+		}
+		if nodeName != "" {
+			fmt.Fprintf(w, "  Node:      %s\n", nodeName)
+		}
+		if replicas, _, _ := unstructured.NestedInt64(sci.Object, "status", "replicas"); replicas > 1 {
+			ready, _, _ := unstructured.NestedInt64(sci.Object, "status", "readyReplicas")
+			fmt.Fprintf(w, "  Replicas:  %d/%d ready\n", ready, replicas)
+		}
+		if podName, _, _ := unstructured.NestedString(sci.Object, "status", "providerPodName"); podName != "" {
+			providerPodName = podName
+		}
+		if podName, _, _ := unstructured.NestedString(sci.Object, "status", "consumerPodName"); podName != "" {
+			consumerPodName = podName
+		}
+	}
 
-Examples:
-  # Run a shell
-  kubectl sc exec my-app -it -- /bin/bash
+	var pods []string
+	for _, p := range []struct{ role, name string }{
+		{"Provider", providerPodName},
+		{"Consumer", consumerPodName},
+	} {
+		fmt.Fprintf(w, "\n%s pod %s:\n", p.role, p.name)
+		pod, err := client.Resource(podGVR).Namespace(ns).Get(ctx, p.name, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			fmt.Fprintln(w, "  not created")
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get pod %s: %w", p.name, err)
+		}
+		printPodDetail(w, pod)
+		if p.name != name {
+			pods = append(pods, p.name)
+		}
+	}
+	return pods, nil
+}
 
-  # Run a command
-  kubectl sc exec my-app -- ls -la /
+// printConditions prints the status conditions of an object
+func printConditions(w io.Writer, obj *unstructured.Unstructured) {
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	if len(conditions) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "Conditions:")
+	for _, c := range conditions {
+		cond, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		condType, _, _ := unstructured.NestedString(cond, "type")
+		status, _, _ := unstructured.NestedString(cond, "status")
+		reason, _, _ := unstructured.NestedString(cond, "reason")
+		message, _, _ := unstructured.NestedString(cond, "message")
+		fmt.Fprintf(w, "  %s: %s (%s) %s\n", condType, status, reason, message)
+	}
+}
 
-  # Run with environment variables
-  kubectl sc exec my-app -- env`,
-		Args:               cobra.MinimumNArgs(1),
-		DisableFlagParsing: false,
-		RunE: func(cmd *cobra.Command, args []string) error {
-			if len(args) == 0 {
-				return fmt.Errorf("name is required")
-			}
-			name := args[0]
+// printPodDetail prints a pod's phase, node and container statuses
+func printPodDetail(w io.Writer, pod *unstructured.Unstructured) {
+	phase, _, _ := unstructured.NestedString(pod.Object, "status", "phase")
+	nodeName, _, _ := unstructured.NestedString(pod.Object, "spec", "nodeName")
+	podIP, _, _ := unstructured.NestedString(pod.Object, "status", "podIP")
+	fmt.Fprintf(w, "  Phase:     %s\n", phase)
+	if nodeName != "" {
+		fmt.Fprintf(w, "  Node:      %s\n", nodeName)
+	}
+	if podIP != "" {
+		fmt.Fprintf(w, "  IP:        %s\n", podIP)
+	}
 
-			// ArgsLenAtDash returns the index of "--" if present, or -1
-			// Everything after "--" is in args[dash+1:]
-			dash := cmd.ArgsLenAtDash()
-			var cmdArgs []string
-			if dash >= 0 && dash < len(args) {
-				cmdArgs = args[dash:]
-			} else if len(args) > 1 {
-				// No "--" found, but there are extra args - treat them as command
-				cmdArgs = args[1:]
+	for _, field := range []string{"initContainerStatuses", "containerStatuses"} {
+		statuses, _, _ := unstructured.NestedSlice(pod.Object, "status", field)
+		for _, s := range statuses {
+			cs, ok := s.(map[string]interface{})
+			if !ok {
+				continue
 			}
-
-			if len(cmdArgs) == 0 {
-				return fmt.Errorf("no command specified after --")
+			csName, _, _ := unstructured.NestedString(cs, "name")
+			ready, _, _ := unstructured.NestedBool(cs, "ready")
+			restarts, _, _ := unstructured.NestedInt64(cs, "restartCount")
+			kind := "Container"
+			if field == "initContainerStatuses" {
+				kind = "Init"
 			}
+			fmt.Fprintf(w, "  %s %s: %s, ready=%v, restarts=%d\n", kind, csName, containerState(cs), ready, restarts)
+		}
+	}
+}
 
-			_, ns, err := getClient()
+// containerState summarizes the state of a container status
+func containerState(cs map[string]interface{}) string {
+	if _, found, _ := unstructured.NestedMap(cs, "state", "running"); found {
+		return "Running"
+	}
+	if reason, found, _ := unstructured.NestedString(cs, "state", "waiting", "reason"); found {
+		return "Waiting (" + reason + ")"
+	}
+	if _, found, _ := unstructured.NestedMap(cs, "state", "terminated"); found {
+		reason, _, _ := unstructured.NestedString(cs, "state", "terminated", "reason")
+		exitCode, _, _ := unstructured.NestedInt64(cs, "state", "terminated", "exitCode")
+		return fmt.Sprintf("Terminated (%s, exit code %d)", reason, exitCode)
+	}
+	return "Unknown"
+}
+
+func getInstancesCmd() *cobra.Command {
+	var output string
+	cmd := &cobra.Command{
+		Use:   "get-instances <name>",
+		Short: "List the StoppableContainerInstances of a StoppableContainer",
+		Long: `List the StoppableContainerInstances owned by a StoppableContainer.
+
+The operator runs a StoppableContainer through an instance, which owns the
+provider and consumer pods. The instance is created on the first start and
+kept while the container is stopped.`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeStoppableContainerNames,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+			client, ns, err := getClient()
 			if err != nil {
 				return err
 			}
 
-			// Build kubectl exec command
-			kubectlArgs := []string{"exec"}
-			if stdin {
-				kubectlArgs = append(kubectlArgs, "-i")
+			instances, err := listInstances(client, ns, name)
+			if err != nil {
+				return err
 			}
-			if tty {
-				kubectlArgs = append(kubectlArgs, "-t")
+			if len(instances) == 0 {
+				fmt.Printf("StoppableContainer %s has no instances\n", name)
+				return nil
 			}
-			kubectlArgs = append(kubectlArgs, "-n", ns)
 
-			// Consumer pod uses the same name as the SCI
-			podName := name
-			if container != "" {
-				kubectlArgs = append(kubectlArgs, "-c", container)
+			switch output {
+			case "json":
+				items := make([]interface{}, 0, len(instances))
+				for _, sci := range instances {
+					items = append(items, sci.Object)
+				}
+				data, _ := json.MarshalIndent(items, "", "  ")
+				fmt.Println(string(data))
+				return nil
+			case "yaml":
+				kubectlArgs := []string{"get", "stoppablecontainerinstance"}
+				for _, sci := range instances {
+					kubectlArgs = append(kubectlArgs, sci.GetName())
+				}
+				return runKubectl(append(kubectlArgs, "-n", ns, "-o", "yaml")...)
+			case "":
+				return printInstances(os.Stdout, instances)
+			default:
+				return fmt.Errorf("unknown output format %q, must be json or yaml", output)
 			}
-			kubectlArgs = append(kubectlArgs, podName, "--")
-
-			// Use sc-exec wrapper to run commands in the chroot environment
-			kubectlArgs = append(kubectlArgs, "/.sc-bin/sc-exec")
-			kubectlArgs = append(kubectlArgs, cmdArgs...)
-
-			return runKubectl(kubectlArgs...)
 		},
 	}
-	cmd.Flags().BoolVarP(&stdin, "stdin", "i", false, "Pass stdin to the container")
-	cmd.Flags().BoolVarP(&tty, "tty", "t", false, "Stdin is a TTY")
-	cmd.Flags().StringVarP(&container, "container", "c", "", "Container name")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "Output format (json, yaml)")
 	return cmd
 }
 
-func logsCmd() *cobra.Command {
-	var follow bool
-	var tail int64
-	var previous bool
-	var timestamps bool
-	var container string
+// listInstances returns the StoppableContainerInstances controlled by a StoppableContainer
+func listInstances(client dynamic.Interface, ns, name string) ([]*unstructured.Unstructured, error) {
+	ctx := context.Background()
+	sc, err := client.Resource(scGVR).Namespace(ns).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get StoppableContainer %s: %w", name, err)
+	}
+	list, err := client.Resource(sciGVR).Namespace(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list StoppableContainerInstances: %w", err)
+	}
+
+	var instances []*unstructured.Unstructured
+	for i := range list.Items {
+		for _, ref := range list.Items[i].GetOwnerReferences() {
+			if ref.Kind == "StoppableContainer" && ref.UID == sc.GetUID() {
+				instances = append(instances, &list.Items[i])
+				break
+			}
+		}
+	}
+	return instances, nil
+}
+
+// printInstances prints a table of StoppableContainerInstances
+func printInstances(out io.Writer, instances []*unstructured.Unstructured) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "NAME\tPHASE\tNODE\tPROVIDER\tCONSUMER\tHOST PATH\tAGE")
+	for _, sci := range instances {
+		field := func(name string) string {
+			value, _, _ := unstructured.NestedString(sci.Object, "status", name)
+			if value == "" {
+				return "<none>"
+			}
+			return value
+		}
+		age := formatAge(time.Since(sci.GetCreationTimestamp().Time))
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", sci.GetName(), field("phase"), field("nodeName"),
+			field("providerPodName"), field("consumerPodName"), field("hostPath"), age)
+	}
+	return w.Flush()
+}
+
+func eventsCmd() *cobra.Command {
+	var watchEvents bool
 
 	cmd := &cobra.Command{
-		Use:   "logs <name>",
-		Short: "Show logs from a StoppableContainer",
-		Args:  cobra.ExactArgs(1),
+		Use:   "events <name>",
+		Short: "Show events of a StoppableContainer, its instance and pods",
+		Long: `Show the events of a StoppableContainer, its StoppableContainerInstance and
+the provider and consumer pods as one timeline, oldest first. Each line starts
+with the object the event is about.
+
+Examples:
+  # Show the timeline
+  kubectl sc events my-app
+
+  # Keep printing new events as they arrive
+  kubectl sc events my-app -w`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeStoppableContainerNames,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			name := args[0]
-			_, ns, err := getClient()
+			client, ns, err := getClient()
 			if err != nil {
 				return err
 			}
 
-			// Consumer pod uses the same name as the SCI
-			podName := name
-			kubectlArgs := []string{"logs", "-n", ns}
-
-			if follow {
-				kubectlArgs = append(kubectlArgs, "-f")
-			}
-			if tail > 0 {
-				kubectlArgs = append(kubectlArgs, "--tail", fmt.Sprintf("%d", tail))
+			ctx := context.Background()
+			sources, err := findEventSources(ctx, client, ns, name)
+			if err != nil {
+				return err
 			}
-			if previous {
-				kubectlArgs = append(kubectlArgs, "-p")
+			events, resourceVersions, err := listEvents(ctx, client, ns, sources)
+			if err != nil {
+				return err
 			}
-			if timestamps {
-				kubectlArgs = append(kubectlArgs, "--timestamps")
+			for _, event := range events {
+				fmt.Println(formatEvent(event))
 			}
-			if container != "" {
-				kubectlArgs = append(kubectlArgs, "-c", container)
+			if !watchEvents {
+				if len(events) == 0 {
+					fmt.Printf("No events found for StoppableContainer %s\n", name)
+				}
+				return nil
 			}
-			kubectlArgs = append(kubectlArgs, podName)
-
-			return runKubectl(kubectlArgs...)
+			return watchEventStream(ctx, client, ns, sources, resourceVersions, os.Stdout)
 		},
 	}
-	cmd.Flags().BoolVarP(&follow, "follow", "f", false, "Follow log output")
-	cmd.Flags().Int64Var(&tail, "tail", 0, "Lines of recent log file to display")
-	cmd.Flags().BoolVarP(&previous, "previous", "p", false, "Print logs from previous container")
-	cmd.Flags().BoolVar(&timestamps, "timestamps", false, "Include timestamps")
-	cmd.Flags().StringVarP(&container, "container", "c", "", "Container name")
+	cmd.Flags().BoolVarP(&watchEvents, "watch", "w", false, "Keep printing new events as they arrive")
 	return cmd
 }
 
-func createCmd() *cobra.Command {
-	var image string
+// eventSources are the objects of a StoppableContainer whose events are shown,
+// keyed by kind and then name
+type eventSources struct {
+	objects map[string]map[string]bool
+}
+
+// fieldSelectors returns an involvedObject field selector for each source, so only
+// their events are listed and watched. Field selectors cannot match several names,
+// hence one per object.
+func (s eventSources) fieldSelectors() []fields.Selector {
+	var selectors []fields.Selector
+	for kind, names := range s.objects {
+		for name := range names {
+			selectors = append(selectors, fields.Set{
+				"involvedObject.kind": kind,
+				"involvedObject.name": name,
+			}.AsSelector())
+		}
+	}
+	sort.Slice(selectors, func(i, j int) bool {
+		return selectors[i].String() < selectors[j].String()
+	})
+	return selectors
+}
+
+// eventMatches reports whether an event's involved object matches a field selector. The
+// API server applies the selector already, checking again keeps a server that ignores it
+// from printing every event once per selector.
+func eventMatches(selector fields.Selector, event *unstructured.Unstructured) bool {
+	kind, _, _ := unstructured.NestedString(event.Object, "involvedObject", "kind")
+	name, _, _ := unstructured.NestedString(event.Object, "involvedObject", "name")
+	return selector.Matches(fields.Set{"involvedObject.kind": kind, "involvedObject.name": name})
+}
+
+// findEventSources collects the StoppableContainer, its instance and the instance's pods.
+// The default pod names, and the consumer pod names of all replicas, are included too, so
+// events of pods that are deleted or not created yet show up.
+func findEventSources(ctx context.Context, client dynamic.Interface, ns, name string) (eventSources, error) {
+	sc, err := client.Resource(scGVR).Namespace(ns).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return eventSources{}, fmt.Errorf("failed to get StoppableContainer %s: %w", name, err)
+	}
+	instanceName, _, _ := unstructured.NestedString(sc.Object, "status", "instanceName")
+	if instanceName == "" {
+		instanceName = name
+	}
+
+	sources := eventSources{
+		objects: map[string]map[string]bool{
+			"StoppableContainer":         {name: true},
+			"StoppableContainerInstance": {instanceName: true},
+			"Pod":                        {instanceName: true, instanceName + "-provider": true},
+		},
+	}
+	if replicas, _, _ := unstructured.NestedInt64(sc.Object, "spec", "replicas"); replicas > 1 {
+		for i := range replicas {
+			sources.objects["Pod"][fmt.Sprintf("%s-consumer-%d", instanceName, i)] = true
+		}
+	}
+	pods, err := client.Resource(podGVR).Namespace(ns).List(ctx, metav1.ListOptions{
+		LabelSelector: LabelInstance + "=" + instanceName,
+	})
+	if err != nil {
+		return eventSources{}, fmt.Errorf("failed to list pods of %s: %w", instanceName, err)
+	}
+	for _, pod := range pods.Items {
+		sources.objects["Pod"][pod.GetName()] = true
+	}
+	return sources, nil
+}
+
+// listEvents returns the events of the sources sorted oldest first, and the resource
+// version to watch each field selector from
+func listEvents(ctx context.Context, client dynamic.Interface, ns string, sources eventSources) ([]*unstructured.Unstructured, map[string]string, error) {
+	var events []*unstructured.Unstructured
+	resourceVersions := make(map[string]string)
+	for _, selector := range sources.fieldSelectors() {
+		matching, resourceVersion, err := listMatchingEvents(ctx, client, ns, selector)
+		if err != nil {
+			return nil, nil, err
+		}
+		events = append(events, matching...)
+		resourceVersions[selector.String()] = resourceVersion
+	}
+	sortEvents(events)
+	return events, resourceVersions, nil
+}
+
+// listMatchingEvents returns the events matching a field selector sorted oldest first,
+// and the resource version of the list
+func listMatchingEvents(ctx context.Context, client dynamic.Interface, ns string, selector fields.Selector) ([]*unstructured.Unstructured, string, error) {
+	list, err := client.Resource(eventGVR).Namespace(ns).List(ctx, metav1.ListOptions{
+		FieldSelector: selector.String(),
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list events: %w", err)
+	}
+	var events []*unstructured.Unstructured
+	for i := range list.Items {
+		if eventMatches(selector, &list.Items[i]) {
+			events = append(events, &list.Items[i])
+		}
+	}
+	sortEvents(events)
+	return events, list.GetResourceVersion(), nil
+}
+
+// sortEvents sorts events oldest first
+func sortEvents(events []*unstructured.Unstructured) {
+	sort.SliceStable(events, func(i, j int) bool {
+		return eventTime(events[i]).Before(eventTime(events[j]))
+	})
+}
+
+// watchEventStream prints the sources' events as they are added or updated, watching each
+// field selector from its resource version, until a watch fails or ctx ends
+func watchEventStream(ctx context.Context, client dynamic.Interface, ns string, sources eventSources, resourceVersions map[string]string, w io.Writer) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	selectors := sources.fieldSelectors()
+	lines := make(chan string)
+	errs := make(chan error, len(selectors))
+	for _, selector := range selectors {
+		go func() {
+			errs <- watchMatchingEvents(ctx, client, ns, selector, resourceVersions[selector.String()], lines)
+		}()
+	}
+	for remaining := len(selectors); remaining > 0; {
+		select {
+		case line := <-lines:
+			fmt.Fprintln(w, line)
+		case err := <-errs:
+			if err != nil {
+				return err
+			}
+			remaining--
+		case <-ctx.Done():
+			return nil
+		}
+	}
+	return nil
+}
+
+// watchMatchingEvents sends the lines of events matching a field selector to out until the
+// watch fails or ctx ends. Watches the server ends are resumed from the last event seen.
+// If that resource version has expired (410 Gone), the events are listed again and those
+// changed since the watch started, and not sent yet, are sent.
+func watchMatchingEvents(ctx context.Context, client dynamic.Interface, ns string, selector fields.Selector, resourceVersion string, out chan<- string) error {
+	started := time.Now()
+	sent := make(map[string]string)
+	send := func(event *unstructured.Unstructured) bool {
+		if version, ok := sent[event.GetName()]; ok && version == event.GetResourceVersion() {
+			return true
+		}
+		sent[event.GetName()] = event.GetResourceVersion()
+		select {
+		case out <- formatEvent(event):
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	for {
+		expired, err := func() (bool, error) {
+			watcher, err := client.Resource(eventGVR).Namespace(ns).Watch(ctx, metav1.ListOptions{
+				FieldSelector:   selector.String(),
+				ResourceVersion: resourceVersion,
+			})
+			if err != nil {
+				return errors.IsResourceExpired(err) || errors.IsGone(err), err
+			}
+			defer watcher.Stop()
+			for e := range watcher.ResultChan() {
+				if e.Type == watch.Error {
+					err := errors.FromObject(e.Object)
+					return errors.IsResourceExpired(err) || errors.IsGone(err), err
+				}
+				event, ok := e.Object.(*unstructured.Unstructured)
+				if !ok {
+					continue
+				}
+				resourceVersion = event.GetResourceVersion()
+				if (e.Type == watch.Added || e.Type == watch.Modified) && eventMatches(selector, event) && !send(event) {
+					return false, nil
+				}
+			}
+			return false, nil
+		}()
+		if ctx.Err() != nil {
+			return nil
+		}
+		switch {
+		case expired:
+			// Events may have been missed, list them again and resume from the list
+			events, listVersion, err := listMatchingEvents(ctx, client, ns, selector)
+			if err != nil {
+				return err
+			}
+			for _, event := range events {
+				if !eventTime(event).Before(started) && !send(event) {
+					return nil
+				}
+			}
+			resourceVersion = listVersion
+		case err != nil:
+			return fmt.Errorf("failed to watch events: %w", err)
+		}
+		// Otherwise the server ended the watch after a while, resume from the last event seen
+	}
+}
+
+// eventTime returns when an event last happened, falling back to the newer
+// eventTime and to when it was recorded
+func eventTime(event *unstructured.Unstructured) time.Time {
+	for _, field := range []string{"lastTimestamp", "eventTime", "firstTimestamp"} {
+		if s, _, _ := unstructured.NestedString(event.Object, field); s != "" {
+			if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+				return t
+			}
+		}
+	}
+	return event.GetCreationTimestamp().Time
+}
+
+// formatEvent renders an event as one timeline line, prefixed with its object
+func formatEvent(event *unstructured.Unstructured) string {
+	kind, _, _ := unstructured.NestedString(event.Object, "involvedObject", "kind")
+	name, _, _ := unstructured.NestedString(event.Object, "involvedObject", "name")
+	eventType, _, _ := unstructured.NestedString(event.Object, "type")
+	reason, _, _ := unstructured.NestedString(event.Object, "reason")
+	message, _, _ := unstructured.NestedString(event.Object, "message")
+
+	line := fmt.Sprintf("%s  %s/%s  %s  %s  %s", eventTime(event).Local().Format("2006-01-02 15:04:05"),
+		strings.ToLower(kind), name, eventType, reason, strings.TrimSpace(message))
+	if count, _, _ := unstructured.NestedInt64(event.Object, "count"); count > 1 {
+		line += fmt.Sprintf(" (x%d)", count)
+	}
+	return line
+}
+
+func topCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "top [name]",
+		Short: "Show CPU and memory usage of StoppableContainer pods",
+		Long: `Show the CPU and memory usage of the consumer and provider pods, as reported
+by metrics-server. The workload runs in the consumer pod, the provider pod only
+holds the rootfs.
+
+Examples:
+  # Usage of one StoppableContainer
+  kubectl sc top my-app
+
+  # Usage of all StoppableContainers in all namespaces
+  kubectl sc top -A`,
+		Args:              cobra.MaximumNArgs(1),
+		ValidArgsFunction: completeStoppableContainerNames,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, ns, err := getClient()
+			if err != nil {
+				return err
+			}
+
+			ctx := context.Background()
+			selector := LabelInstance
+			if len(args) == 1 {
+				sc, err := client.Resource(scGVR).Namespace(ns).Get(ctx, args[0], metav1.GetOptions{})
+				if err != nil {
+					return fmt.Errorf("failed to get StoppableContainer %s: %w", args[0], err)
+				}
+				instanceName, _, _ := unstructured.NestedString(sc.Object, "status", "instanceName")
+				if instanceName == "" {
+					instanceName = args[0]
+				}
+				selector = LabelInstance + "=" + instanceName
+			}
+
+			usages, err := listPodUsage(ctx, client, ns, selector, allNs && len(args) == 0)
+			if err != nil {
+				return err
+			}
+			if len(usages) == 0 {
+				fmt.Println("No metrics found, the pods may not be running or were just started")
+				return nil
+			}
+			return printPodUsage(os.Stdout, usages, allNs && len(args) == 0)
+		},
+	}
+}
+
+// podUsage is the CPU and memory usage of one StoppableContainer pod
+type podUsage struct {
+	namespace string
+	instance  string
+	role      string
+	pod       string
+	cpu       resource.Quantity
+	memory    resource.Quantity
+}
+
+// listPodUsage reads the metrics of the pods matching the selector, sorted by
+// namespace, instance, role and pod
+func listPodUsage(ctx context.Context, client dynamic.Interface, ns, selector string, allNamespaces bool) ([]podUsage, error) {
+	opts := metav1.ListOptions{LabelSelector: selector}
+	var list *unstructured.UnstructuredList
+	var err error
+	if allNamespaces {
+		list, err = client.Resource(podMetricsGVR).List(ctx, opts)
+	} else {
+		list, err = client.Resource(podMetricsGVR).Namespace(ns).List(ctx, opts)
+	}
+	if errors.IsNotFound(err) || errors.IsServiceUnavailable(err) {
+		return nil, fmt.Errorf("the metrics.k8s.io API is not available, is metrics-server installed? (%w)", err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pod metrics: %w", err)
+	}
+
+	usages := make([]podUsage, 0, len(list.Items))
+	for _, item := range list.Items {
+		labels := item.GetLabels()
+		u := podUsage{
+			namespace: item.GetNamespace(),
+			instance:  labels[LabelInstance],
+			role:      labels[LabelRole],
+			pod:       item.GetName(),
+		}
+		containers, _, _ := unstructured.NestedSlice(item.Object, "containers")
+		for _, c := range containers {
+			container, ok := c.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			for _, r := range []struct {
+				name  string
+				total *resource.Quantity
+			}{{"cpu", &u.cpu}, {"memory", &u.memory}} {
+				value, _, _ := unstructured.NestedString(container, "usage", r.name)
+				if value == "" {
+					continue
+				}
+				q, err := resource.ParseQuantity(value)
+				if err != nil {
+					return nil, fmt.Errorf("invalid %s usage %q of pod %s: %w", r.name, value, item.GetName(), err)
+				}
+				r.total.Add(q)
+			}
+		}
+		usages = append(usages, u)
+	}
+	sort.Slice(usages, func(i, j int) bool {
+		a, b := usages[i], usages[j]
+		if a.namespace != b.namespace {
+			return a.namespace < b.namespace
+		}
+		if a.instance != b.instance {
+			return a.instance < b.instance
+		}
+		if a.role != b.role {
+			return a.role < b.role
+		}
+		return a.pod < b.pod
+	})
+	return usages, nil
+}
+
+// printPodUsage prints the usage table, CPU in millicores and memory in MiB like kubectl top
+func printPodUsage(out io.Writer, usages []podUsage, allNamespaces bool) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	if allNamespaces {
+		_, _ = fmt.Fprint(w, "NAMESPACE\t")
+	}
+	_, _ = fmt.Fprintln(w, "NAME\tROLE\tPOD\tCPU(cores)\tMEMORY(bytes)")
+	for _, u := range usages {
+		if allNamespaces {
+			_, _ = fmt.Fprintf(w, "%s\t", u.namespace)
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%dm\t%dMi\n",
+			u.instance, u.role, u.pod, u.cpu.MilliValue(), u.memory.Value()/(1024*1024))
+	}
+	return w.Flush()
+}
+
+func waitCmd() *cobra.Command {
+	var forValue string
+	var timeout time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "wait <name> --for=phase=<phase>|condition=<type>[=<status>]",
+		Short: "Wait for a StoppableContainer to reach a phase or condition",
+		Long: `Wait until a StoppableContainer reaches a phase or one of its status
+conditions has a status. A container that fails ends the wait with an error.
+
+Examples:
+  # Wait for the container to run
+  kubectl sc wait my-app --for=phase=Running --timeout=2m
+
+  # Wait for the Ready condition to become True
+  kubectl sc wait my-app --for=condition=Ready`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeStoppableContainerNames,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cond, err := parseWaitFor(forValue)
+			if err != nil {
+				return err
+			}
+			client, ns, err := getClient()
+			if err != nil {
+				return err
+			}
+			return waitFor(client, ns, args[0], cond, timeout)
+		},
+	}
+	cmd.Flags().StringVar(&forValue, "for", "", "phase=<phase> or condition=<type>[=<status>]")
+	cmd.Flags().DurationVar(&timeout, "timeout", 2*time.Minute, "Timeout for wait")
+	_ = cmd.MarkFlagRequired("for")
+	return cmd
+}
+
+func startCmd() *cobra.Command {
+	var wait bool
+	var timeout time.Duration
+
+	cmd := &cobra.Command{
+		Use:               "start <name>",
+		Short:             "Start a StoppableContainer",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeStoppableContainerNames,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+			client, ns, err := getClient()
+			if err != nil {
+				return err
+			}
+
+			ctx := context.Background()
+
+			// Get current resource
+			sc, err := client.Resource(scGVR).Namespace(ns).Get(ctx, name, metav1.GetOptions{})
+			if err != nil {
+				return fmt.Errorf("failed to get StoppableContainer %s: %w", name, err)
+			}
+
+			// Check if already running
+			running, _, _ := unstructured.NestedBool(sc.Object, "spec", "running")
+			if running {
+				fmt.Printf("StoppableContainer %s is already running\n", name)
+				return nil
+			}
+
+			// Update to running
+			if err := unstructured.SetNestedField(sc.Object, true, "spec", "running"); err != nil {
+				return err
+			}
+
+			_, err = client.Resource(scGVR).Namespace(ns).Update(ctx, sc, metav1.UpdateOptions{})
+			if err != nil {
+				return fmt.Errorf("failed to start StoppableContainer %s: %w", name, err)
+			}
+
+			fmt.Printf("StoppableContainer %s starting...\n", name)
+
+			if wait {
+				return waitForPhase(client, ns, name, "Running", timeout)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVarP(&wait, "wait", "w", false, "Wait for container to be running")
+	cmd.Flags().DurationVar(&timeout, "timeout", 2*time.Minute, "Timeout for wait")
+	return cmd
+}
+
+func stopCmd() *cobra.Command {
+	var wait bool
+	var timeout time.Duration
+
+	cmd := &cobra.Command{
+		Use:               "stop <name>",
+		Short:             "Stop a StoppableContainer",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeStoppableContainerNames,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+			client, ns, err := getClient()
+			if err != nil {
+				return err
+			}
+
+			ctx := context.Background()
+
+			// Get current resource
+			sc, err := client.Resource(scGVR).Namespace(ns).Get(ctx, name, metav1.GetOptions{})
+			if err != nil {
+				return fmt.Errorf("failed to get StoppableContainer %s: %w", name, err)
+			}
+
+			// Check if already stopped
+			running, _, _ := unstructured.NestedBool(sc.Object, "spec", "running")
+			if !running {
+				fmt.Printf("StoppableContainer %s is already stopped\n", name)
+				return nil
+			}
+
+			// Update to stopped
+			if err := unstructured.SetNestedField(sc.Object, false, "spec", "running"); err != nil {
+				return err
+			}
+
+			_, err = client.Resource(scGVR).Namespace(ns).Update(ctx, sc, metav1.UpdateOptions{})
+			if err != nil {
+				return fmt.Errorf("failed to stop StoppableContainer %s: %w", name, err)
+			}
+
+			fmt.Printf("StoppableContainer %s stopping...\n", name)
+
+			if wait {
+				return waitForPhase(client, ns, name, "Stopped", timeout)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVarP(&wait, "wait", "w", false, "Wait for container to be stopped")
+	cmd.Flags().DurationVar(&timeout, "timeout", 30*time.Second, "Timeout for wait")
+	return cmd
+}
+
+func restartCmd() *cobra.Command {
+	var wait bool
+	var timeout time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "restart <name>",
+		Short: "Restart a StoppableContainer",
+		Long: `Stop a StoppableContainer, wait for it to be stopped, then start it again.
+
+A container that is already stopped is simply started.`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeStoppableContainerNames,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, ns, err := getClient()
+			if err != nil {
+				return err
+			}
+			return restartStoppableContainer(client, ns, args[0], wait, timeout)
+		},
+	}
+	cmd.Flags().BoolVarP(&wait, "wait", "w", false, "Wait for container to be running")
+	cmd.Flags().DurationVar(&timeout, "timeout", 2*time.Minute, "Timeout for each wait")
+	return cmd
+}
+
+// restartStoppableContainer stops a StoppableContainer, waits for phase Stopped and
+// starts it again. An already stopped container is started without waiting.
+func restartStoppableContainer(client dynamic.Interface, ns, name string, wait bool, timeout time.Duration) error {
+	ctx := context.Background()
+
+	sc, err := client.Resource(scGVR).Namespace(ns).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get StoppableContainer %s: %w", name, err)
+	}
+
+	running, _, _ := unstructured.NestedBool(sc.Object, "spec", "running")
+	if running {
+		if err := setRunning(client, ns, name, false); err != nil {
+			return fmt.Errorf("failed to stop StoppableContainer %s: %w", name, err)
+		}
+		fmt.Printf("StoppableContainer %s stopping...\n", name)
+		if err := waitForPhase(client, ns, name, "Stopped", timeout); err != nil {
+			return err
+		}
+	} else {
+		fmt.Printf("StoppableContainer %s is already stopped, starting it\n", name)
+	}
+
+	if err := setRunning(client, ns, name, true); err != nil {
+		return fmt.Errorf("failed to start StoppableContainer %s: %w", name, err)
+	}
+	fmt.Printf("StoppableContainer %s starting...\n", name)
+
+	if wait {
+		return waitForPhase(client, ns, name, "Running", timeout)
+	}
+	return nil
+}
+
+// setRunning patches spec.running of a StoppableContainer
+func setRunning(client dynamic.Interface, ns, name string, running bool) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{"running": running},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = client.Resource(scGVR).Namespace(ns).Patch(context.Background(), name,
+		types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
+func execCmd() *cobra.Command {
+	var stdin bool
+	var tty bool
+	var container string
+
+	cmd := &cobra.Command{
+		Use:   "exec <name> [-- <command> [args...]]",
+		Short: "Execute a command in a StoppableContainer",
+		Long: `Execute a command inside the consumer container of a StoppableContainer.
+
+The command runs inside the chroot environment with the container's rootfs.
+Without a command, the first of bash, ash and sh found in the rootfs is run.
+
+Examples:
+  # Run a shell (-it is implied for shells when run from a terminal)
+  kubectl sc exec my-app -it -- /bin/bash
+
+  # Run whichever shell the image has
+  kubectl sc exec my-app
+
+  # Run a command
+  kubectl sc exec my-app -- ls -la /
+
+  # Run with environment variables
+  kubectl sc exec my-app -- env`,
+		Args:               cobra.MinimumNArgs(1),
+		ValidArgsFunction:  completeStoppableContainerNames,
+		DisableFlagParsing: false,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return fmt.Errorf("name is required")
+			}
+			name := args[0]
+
+			// ArgsLenAtDash returns the index of "--" if present, or -1
+			// Everything after "--" is in args[dash+1:]
+			dash := cmd.ArgsLenAtDash()
+			var cmdArgs []string
+			if dash >= 0 && dash < len(args) {
+				cmdArgs = args[dash:]
+			} else if len(args) > 1 {
+				// No "--" found, but there are extra args - treat them as command
+				cmdArgs = args[1:]
+			}
+
+			client, ns, err := getClient()
+			if err != nil {
+				return err
+			}
+
+			podName, err := resolveConsumerPod(client, ns, name)
+			if err != nil {
+				return err
+			}
+
+			// Run interactive shells with -it unless -i/-t were given explicitly
+			if !cmd.Flags().Changed("stdin") && !cmd.Flags().Changed("tty") &&
+				shouldAutoTTY(cmdArgs, term.IsTerminal(int(os.Stdin.Fd())), term.IsTerminal(int(os.Stdout.Fd()))) {
+				stdin, tty = true, true
+			}
+
+			return runKubectl(buildExecArgs(ns, podName, container, stdin, tty, cmdArgs)...)
+		},
+	}
+	cmd.Flags().BoolVarP(&stdin, "stdin", "i", false, "Pass stdin to the container")
+	cmd.Flags().BoolVarP(&tty, "tty", "t", false, "Stdin is a TTY")
+	cmd.Flags().StringVarP(&container, "container", "c", "", "Container name")
+	return cmd
+}
+
+// buildExecArgs assembles the kubectl exec arguments. The command runs through the
+// sc-exec wrapper in the chroot; without one, sc-exec --shell picks the rootfs's shell.
+func buildExecArgs(ns, podName, container string, stdin, tty bool, command []string) []string {
+	kubectlArgs := []string{"exec"}
+	if stdin {
+		kubectlArgs = append(kubectlArgs, "-i")
+	}
+	if tty {
+		kubectlArgs = append(kubectlArgs, "-t")
+	}
+	kubectlArgs = append(kubectlArgs, "-n", ns)
+	if container != "" {
+		kubectlArgs = append(kubectlArgs, "-c", container)
+	}
+	kubectlArgs = append(kubectlArgs, podName, "--", "/.sc-bin/sc-exec")
+	if len(command) == 0 {
+		return append(kubectlArgs, "--shell")
+	}
+	return append(kubectlArgs, command...)
+}
+
+// interactiveShells are the commands for which exec enables -it on a terminal
+var interactiveShells = map[string]bool{
+	"sh": true, "bash": true, "ash": true, "dash": true, "zsh": true,
+	"ksh": true, "mksh": true, "fish": true, "csh": true, "tcsh": true,
+}
+
+// shouldAutoTTY reports whether exec should enable -i and -t on its own: the command
+// is a known shell started without -c (or empty, which runs a shell), and both stdin
+// and stdout are terminals
+func shouldAutoTTY(command []string, stdinIsTerminal, stdoutIsTerminal bool) bool {
+	if !stdinIsTerminal || !stdoutIsTerminal {
+		return false
+	}
+	if len(command) == 0 {
+		return true
+	}
+	if !interactiveShells[filepath.Base(command[0])] {
+		return false
+	}
+	for _, arg := range command[1:] {
+		if arg == "-c" {
+			return false
+		}
+	}
+	return true
+}
+
+func logsCmd() *cobra.Command {
+	var follow bool
+	var tail int64
+	var previous bool
+	var timestamps bool
+	var container string
+
+	cmd := &cobra.Command{
+		Use:               "logs <name>",
+		Short:             "Show logs from a StoppableContainer",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeStoppableContainerNames,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+			client, ns, err := getClient()
+			if err != nil {
+				return err
+			}
+
+			podName, err := resolveConsumerPod(client, ns, name)
+			if err != nil {
+				return err
+			}
+
+			return runKubectl(buildLogsArgs(ns, podName, container, follow, tail, previous, timestamps)...)
+		},
+	}
+	cmd.Flags().BoolVarP(&follow, "follow", "f", false, "Follow log output")
+	cmd.Flags().Int64Var(&tail, "tail", 0, "Lines of recent log file to display")
+	cmd.Flags().BoolVarP(&previous, "previous", "p", false, "Print logs from previous container")
+	cmd.Flags().BoolVar(&timestamps, "timestamps", false, "Include timestamps")
+	cmd.Flags().StringVarP(&container, "container", "c", "", "Container name")
+	return cmd
+}
+
+// buildLogsArgs assembles the kubectl logs arguments
+func buildLogsArgs(ns, podName, container string, follow bool, tail int64, previous, timestamps bool) []string {
+	kubectlArgs := []string{"logs", "-n", ns}
+	if follow {
+		kubectlArgs = append(kubectlArgs, "-f")
+	}
+	if tail > 0 {
+		kubectlArgs = append(kubectlArgs, "--tail", fmt.Sprintf("%d", tail))
+	}
+	if previous {
+		kubectlArgs = append(kubectlArgs, "-p")
+	}
+	if timestamps {
+		kubectlArgs = append(kubectlArgs, "--timestamps")
+	}
+	if container != "" {
+		kubectlArgs = append(kubectlArgs, "-c", container)
+	}
+	return append(kubectlArgs, podName)
+}
+
+func portForwardCmd() *cobra.Command {
+	var addresses []string
+
+	cmd := &cobra.Command{
+		Use:   "port-forward <name> <localPort>:<remotePort> [...]",
+		Short: "Forward local ports to a StoppableContainer",
+		Long: `Forward one or more local ports to the consumer pod of a running StoppableContainer.
+
+Examples:
+  # Forward local port 8080 to port 80
+  kubectl sc port-forward my-app 8080:80
+
+  # Forward multiple ports, listening on all addresses
+  kubectl sc port-forward my-app 8080:80 8443:443 --address 0.0.0.0`,
+		Args: cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+			client, ns, err := getClient()
+			if err != nil {
+				return err
+			}
+
+			podName, err := resolveConsumerPod(client, ns, name)
+			if err != nil {
+				return err
+			}
+
+			return runKubectl(buildPortForwardArgs(ns, podName, args[1:], addresses)...)
+		},
+	}
+	cmd.Flags().StringSliceVar(&addresses, "address", nil, "Addresses to listen on (comma separated)")
+	return cmd
+}
+
+// resolveConsumerPod returns the name of the consumer pod of a StoppableContainer
+func resolveConsumerPod(client dynamic.Interface, ns, name string) (string, error) {
+	sc, err := client.Resource(scGVR).Namespace(ns).Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get StoppableContainer %s: %w", name, err)
+	}
+
+	podName, _, _ := unstructured.NestedString(sc.Object, "status", "consumerPodName")
+	if podName == "" {
+		return "", fmt.Errorf("StoppableContainer %s has no consumer pod, is it running?", name)
+	}
+	return podName, nil
+}
+
+// buildPortForwardArgs assembles the kubectl port-forward arguments
+func buildPortForwardArgs(ns, podName string, ports, addresses []string) []string {
+	kubectlArgs := []string{"port-forward", "-n", ns}
+	if len(addresses) > 0 {
+		kubectlArgs = append(kubectlArgs, "--address", strings.Join(addresses, ","))
+	}
+	kubectlArgs = append(kubectlArgs, "pod/"+podName)
+	return append(kubectlArgs, ports...)
+}
+
+func cpCmd() *cobra.Command {
+	var container string
+
+	cmd := &cobra.Command{
+		Use:   "cp <src> <dest>",
+		Short: "Copy files to and from a StoppableContainer",
+		Long: `Copy files and directories between the local machine and the rootfs of a
+running StoppableContainer. Paths inside the container are rootfs paths, as seen
+by processes in the container, and relative paths start at the rootfs root.
+Directories are copied recursively, following kubectl cp semantics.
+
+The consumer image does not ship tar; kubectl cp runs the tar from the rootfs, so
+the container image must provide one.
+
+Examples:
+  # Copy a local file into the container
+  kubectl sc cp ./app.conf my-app:/etc/app.conf
+
+  # Copy a directory out of the container
+  kubectl sc cp my-app:/var/log ./logs`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			src, dst, err := parseCpArgs(args[0], args[1])
+			if err != nil {
+				return err
+			}
+
+			client, ns, err := getClient()
+			if err != nil {
+				return err
+			}
+
+			name := src.name
+			if name == "" {
+				name = dst.name
+			}
+			podName, err := resolveConsumerPod(client, ns, name)
+			if err != nil {
+				return err
+			}
+
+			return runKubectl(buildCpArgs(ns, podName, container, src, dst)...)
+		},
+	}
+	cmd.Flags().StringVarP(&container, "container", "c", "consumer", "Container name")
+	return cmd
+}
+
+// cpFileSpec is one side of a kubectl sc cp, name is empty for local paths
+type cpFileSpec struct {
+	name string
+	path string
+}
+
+// parseCpFileSpec splits a <name>:<path> argument. Like kubectl cp, arguments
+// without a colon, or that start with "/" or ".", are local paths.
+func parseCpFileSpec(arg string) (cpFileSpec, error) {
+	i := strings.Index(arg, ":")
+	if i < 0 || strings.HasPrefix(arg, "/") || strings.HasPrefix(arg, ".") {
+		return cpFileSpec{path: arg}, nil
+	}
+
+	name, file := arg[:i], arg[i+1:]
+	if name == "" || strings.Contains(name, "/") {
+		return cpFileSpec{}, fmt.Errorf("invalid file spec %q, expected <name>:<path>", arg)
+	}
+	if file == "" {
+		return cpFileSpec{}, fmt.Errorf("remote path cannot be empty in %q", arg)
+	}
+	return cpFileSpec{name: name, path: file}, nil
+}
+
+// parseCpArgs parses both kubectl sc cp arguments, exactly one of which must
+// refer to a StoppableContainer
+func parseCpArgs(srcArg, dstArg string) (cpFileSpec, cpFileSpec, error) {
+	src, err := parseCpFileSpec(srcArg)
+	if err != nil {
+		return cpFileSpec{}, cpFileSpec{}, err
+	}
+	dst, err := parseCpFileSpec(dstArg)
+	if err != nil {
+		return cpFileSpec{}, cpFileSpec{}, err
+	}
+
+	if (src.name == "") == (dst.name == "") {
+		return cpFileSpec{}, cpFileSpec{}, fmt.Errorf("exactly one of source and destination must be <name>:<path>")
+	}
+	return src, dst, nil
+}
+
+// rootfsPath makes a container path absolute within the rootfs. A trailing
+// slash is kept so kubectl cp treats the path the same way.
+func rootfsPath(p string) string {
+	cleaned := path.Clean("/" + p)
+	if strings.HasSuffix(p, "/") && cleaned != "/" {
+		cleaned += "/"
+	}
+	return cleaned
+}
+
+// buildCpArgs assembles the kubectl cp arguments. kubectl cp runs tar in the
+// consumer container, which sc-exec chroots into the rootfs, so container
+// paths are passed as rootfs paths.
+func buildCpArgs(ns, podName, container string, src, dst cpFileSpec) []string {
+	arg := func(spec cpFileSpec) string {
+		if spec.name == "" {
+			return spec.path
+		}
+		return podName + ":" + rootfsPath(spec.path)
+	}
+	return []string{"cp", "-n", ns, "-c", container, arg(src), arg(dst)}
+}
+
+func exportRootfsCmd() *cobra.Command {
+	var output string
+	var force bool
+
+	cmd := &cobra.Command{
+		Use:   "export-rootfs <name> -o <file>",
+		Short: "Save the full rootfs of a StoppableContainer as a tarball",
+		Long: `Save the full root filesystem of a StoppableContainer, the image together with
+everything written to it, as a tar archive. This is not a diff of the writable
+layer. The provider pod streams the archive, so the container image does not
+need tar. /proc, /dev and /sys are kept as empty directories.
+
+The archive is written by the default provider image, so a StoppableContainer
+with spec.provider.image set cannot be exported.
+
+Stop the container first so nothing writes to the rootfs while it is archived.
+A running container is only archived with --force, and the archive may then be
+inconsistent.
+
+The archive can be imported as an image, e.g. with docker import or podman import.
+
+Examples:
+  # Save the rootfs of a stopped container
+  kubectl sc stop my-app --wait
+  kubectl sc export-rootfs my-app -o my-app.tar
+
+  # Import it as an image
+  kubectl sc export-rootfs my-app -o - | docker import - registry.example.com/my-app:exported`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeStoppableContainerNames,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+			client, ns, err := getClient()
+			if err != nil {
+				return err
+			}
+
+			sc, err := client.Resource(scGVR).Namespace(ns).Get(context.Background(), name, metav1.GetOptions{})
+			if err != nil {
+				return fmt.Errorf("failed to get StoppableContainer %s: %w", name, err)
+			}
+			podName, err := exportProviderPod(sc, force)
+			if err != nil {
+				return err
+			}
+
+			return runExportRootfs(buildExportRootfsArgs(ns, podName), output)
+		},
+	}
+	cmd.Flags().StringVarP(&output, "output", "o", "", "File to write the tar archive to, or - for stdout")
+	cmd.Flags().BoolVar(&force, "force", false, "Archive the rootfs even if the container is running")
+	_ = cmd.MarkFlagRequired("output")
+	return cmd
+}
+
+// exportProviderPod returns the provider pod holding the rootfs of sc. Unless force
+// is set, the consumer must be gone, so nothing writes to the rootfs while it is archived.
+// The archive is written by the provider binary, so the provider image must not be overridden.
+func exportProviderPod(sc *unstructured.Unstructured, force bool) (string, error) {
+	if image, _, _ := unstructured.NestedString(sc.Object, "spec", "provider", "image"); image != "" {
+		return "", fmt.Errorf("StoppableContainer %s uses the provider image %s; the rootfs can only be "+
+			"exported with the default provider image, which has /sc-provider --export-full-rootfs",
+			sc.GetName(), image)
+	}
+
+	phase, _, _ := unstructured.NestedString(sc.Object, "status", "phase")
+	if !force && phase != "Stopped" && phase != "Completed" {
+		if phase == "" {
+			phase = "not set up yet"
+		}
+		return "", fmt.Errorf("StoppableContainer %s is %s; stop it first (kubectl sc stop %s --wait) "+
+			"so nothing writes to the rootfs, or pass --force", sc.GetName(), phase, sc.GetName())
+	}
+
+	instanceName, _, _ := unstructured.NestedString(sc.Object, "status", "instanceName")
+	if instanceName == "" {
+		instanceName = sc.GetName()
+	}
+	return instanceName + "-provider", nil
+}
+
+// buildExportRootfsArgs assembles the kubectl exec arguments that make the provider
+// stream a tar archive of the full rootfs it holds
+func buildExportRootfsArgs(ns, podName string) []string {
+	return []string{"exec", "-n", ns, "-c", "provider", podName, "--",
+		"/sc-provider", "--export-full-rootfs", "/propagated/rootfs"}
+}
+
+// runExportRootfs runs kubectl with args and writes its output to the file output, or to
+// stdout for "-". A partial file is removed if the export fails.
+func runExportRootfs(args []string, output string) error {
+	kubectlCmd := exec.Command("kubectl", append(kubectlGlobalArgs(), args...)...)
+	kubectlCmd.Stderr = os.Stderr
+	if output == "-" {
+		kubectlCmd.Stdout = os.Stdout
+		return kubectlCmd.Run()
+	}
+
+	f, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	kubectlCmd.Stdout = f
+	err = kubectlCmd.Run()
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(output)
+		return fmt.Errorf("failed to export the rootfs: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "Saved the rootfs to %s\n", output)
+	return nil
+}
+
+func imageCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "image",
+		Short: "Show or update the image of a StoppableContainer",
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "get <name>",
+		Short: "Show the image of a StoppableContainer",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+			client, ns, err := getClient()
+			if err != nil {
+				return err
+			}
+
+			sc, err := client.Resource(scGVR).Namespace(ns).Get(context.Background(), name, metav1.GetOptions{})
+			if err != nil {
+				return fmt.Errorf("failed to get StoppableContainer %s: %w", name, err)
+			}
+
+			image, err := getImage(sc)
+			if err != nil {
+				return err
+			}
+			fmt.Println(image)
+			if resolved := resolvedImage(client, ns, sc); resolved != "" {
+				fmt.Printf("Resolved: %s\n", resolved)
+			}
+			return nil
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "set <name> <image>",
+		Short: "Update the image of a StoppableContainer",
+		Long: `Update the image of the main container of a StoppableContainer.
+
+A running instance recreates its provider and consumer pods with the new
+image. The existing rootfs is not carried over.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name, image := args[0], args[1]
+			client, ns, err := getClient()
+			if err != nil {
+				return err
+			}
+
+			patch, err := buildImagePatch(image)
+			if err != nil {
+				return err
+			}
+
+			_, err = client.Resource(scGVR).Namespace(ns).Patch(context.Background(), name,
+				types.JSONPatchType, patch, metav1.PatchOptions{})
+			if err != nil {
+				return fmt.Errorf("failed to set image of StoppableContainer %s: %w", name, err)
+			}
+
+			fmt.Printf("StoppableContainer %s image set to %s\n", name, image)
+			fmt.Println("Warning: a running instance is recreated with the new image; " +
+				"changes to its current rootfs are lost")
+			return nil
+		},
+	})
+
+	return cmd
+}
+
+// resolvedImage returns the image ID (digest) kubelet pulled for the provider's rootfs
+// container, or "" if the provider pod is not running
+func resolvedImage(client dynamic.Interface, ns string, sc *unstructured.Unstructured) string {
+	podName, _, _ := unstructured.NestedString(sc.Object, "status", "providerPodName")
+	if podName == "" {
+		return ""
+	}
+	pod, err := client.Resource(podGVR).Namespace(ns).Get(context.Background(), podName, metav1.GetOptions{})
+	if err != nil {
+		return ""
+	}
+	statuses, _, _ := unstructured.NestedSlice(pod.Object, "status", "containerStatuses")
+	for _, s := range statuses {
+		status, ok := s.(map[string]interface{})
+		if !ok || status["name"] != "rootfs" {
+			continue
+		}
+		imageID, _, _ := unstructured.NestedString(status, "imageID")
+		return imageID
+	}
+	return ""
+}
+
+// getImage returns the image of the main (first) container of a StoppableContainer
+func getImage(sc *unstructured.Unstructured) (string, error) {
+	containers, _, _ := unstructured.NestedSlice(sc.Object, "spec", "template", "spec", "containers")
+	if len(containers) == 0 {
+		return "", fmt.Errorf("StoppableContainer %s has no containers", sc.GetName())
+	}
+	container, ok := containers[0].(map[string]interface{})
+	if !ok {
+		return "", fmt.Errorf("StoppableContainer %s has an invalid container", sc.GetName())
+	}
+	image, _, _ := unstructured.NestedString(container, "image")
+	return image, nil
+}
+
+// buildImagePatch builds a JSON patch replacing the image of the main (first) container
+func buildImagePatch(image string) ([]byte, error) {
+	if image == "" {
+		return nil, fmt.Errorf("image must not be empty")
+	}
+	return json.Marshal([]map[string]interface{}{
+		{
+			"op":    "replace",
+			"path":  "/spec/template/spec/containers/0/image",
+			"value": image,
+		},
+	})
+}
+
+func editCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "edit <name>",
+		Short: "Edit a StoppableContainer in $EDITOR",
+		Long: `Edit a StoppableContainer with kubectl edit, then summarize what changed.
+
+The summary covers whether it is running and the image and command of its
+main container.`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeStoppableContainerNames,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+			client, ns, err := getClient()
+			if err != nil {
+				return err
+			}
+
+			ctx := context.Background()
+			before, err := client.Resource(scGVR).Namespace(ns).Get(ctx, name, metav1.GetOptions{})
+			if err != nil {
+				return fmt.Errorf("failed to get StoppableContainer %s: %w", name, err)
+			}
+
+			if err := runKubectl("edit", "stoppablecontainer", name, "-n", ns); err != nil {
+				return err
+			}
+
+			after, err := client.Resource(scGVR).Namespace(ns).Get(ctx, name, metav1.GetOptions{})
+			if err != nil {
+				return fmt.Errorf("failed to get StoppableContainer %s: %w", name, err)
+			}
+			for _, line := range summarizeEdit(before, after) {
+				fmt.Println(line)
+			}
+			return nil
+		},
+	}
+}
+
+// summarizeEdit describes the changes between two versions of a StoppableContainer to
+// the fields users edit most: running, and the main container's image and command.
+// Only the spec, labels and annotations are compared, so a status update by the
+// controller in between is not reported as a change.
+func summarizeEdit(before, after *unstructured.Unstructured) []string {
+	if reflect.DeepEqual(before.Object["spec"], after.Object["spec"]) &&
+		reflect.DeepEqual(before.GetLabels(), after.GetLabels()) &&
+		reflect.DeepEqual(before.GetAnnotations(), after.GetAnnotations()) {
+		return []string{"No changes"}
+	}
+
+	var lines []string
+	wasRunning, _, _ := unstructured.NestedBool(before.Object, "spec", "running")
+	running, _, _ := unstructured.NestedBool(after.Object, "spec", "running")
+	if wasRunning != running {
+		lines = append(lines, fmt.Sprintf("running: %t -> %t", wasRunning, running))
+	}
+	oldImage, _ := getImage(before)
+	image, _ := getImage(after)
+	if oldImage != image {
+		lines = append(lines, fmt.Sprintf("image: %s -> %s", oldImage, image))
+	}
+	oldCommand, command := mainCommand(before), mainCommand(after)
+	if oldCommand != command {
+		lines = append(lines, fmt.Sprintf("command: %s -> %s", oldCommand, command))
+	}
+
+	if len(lines) == 0 {
+		return []string{"Changed fields other than running, image and command"}
+	}
+	return lines
+}
+
+// mainCommand returns the command and args of the main (first) container of a
+// StoppableContainer for display, or "<image default>" if neither is set
+func mainCommand(sc *unstructured.Unstructured) string {
+	containers, _, _ := unstructured.NestedSlice(sc.Object, "spec", "template", "spec", "containers")
+	if len(containers) == 0 {
+		return "<image default>"
+	}
+	container, ok := containers[0].(map[string]interface{})
+	if !ok {
+		return "<image default>"
+	}
+	command, _, _ := unstructured.NestedStringSlice(container, "command")
+	args, _, _ := unstructured.NestedStringSlice(container, "args")
+	if len(command)+len(args) == 0 {
+		return "<image default>"
+	}
+	quoted := make([]string, 0, len(command)+len(args))
+	for _, arg := range append(command, args...) {
+		quoted = append(quoted, fmt.Sprintf("%q", arg))
+	}
+	return "[" + strings.Join(quoted, " ") + "]"
+}
+
+func createCmd() *cobra.Command {
+	var image string
 	var running bool
 	var workingDir string
 	var env []string
+	var envFromConfigMaps []string
+	var envFromSecrets []string
 	var ports []string
+	var resources containerResources
+	var pvcs []string
+	var hostPaths []string
+	var dryRun string
 
 	cmd := &cobra.Command{
 		Use:   "create <name> [--image=<image>] [-- <command> [args...]]",
@@ -560,8 +2103,20 @@ Examples:
   # Create with environment variables
   kubectl sc create my-app --image=nginx:latest -e PORT=8080
 
+  # Create with all keys of a ConfigMap and a Secret as environment variables
+  kubectl sc create my-app --image=nginx:latest --env-from-configmap=app-config --env-from-secret=app-secrets
+
   # Create with port mapping
-  kubectl sc create my-app --image=nginx:latest -p 80:http`,
+  kubectl sc create my-app --image=nginx:latest -p 80:http
+
+  # Create with resource requests and limits
+  kubectl sc create my-app --image=nginx:latest --cpu-request=250m --memory-request=256Mi --memory-limit=1Gi
+
+  # Create with a PersistentVolumeClaim mounted at /data
+  kubectl sc create my-app --image=ubuntu:22.04 --pvc=my-data:/data
+
+  # Print the manifest instead of creating it
+  kubectl sc create my-app --image=ubuntu:22.04 --dry-run=client > my-app.yaml`,
 		Args: cobra.MinimumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			name := args[0]
@@ -569,6 +2124,17 @@ Examples:
 			if image == "" {
 				return fmt.Errorf("--image is required")
 			}
+			applyArgs, err := createApplyArgs(dryRun)
+			if err != nil {
+				return err
+			}
+			if err := resources.validate(); err != nil {
+				return err
+			}
+			volumes, err := parseVolumeFlags(pvcs, hostPaths)
+			if err != nil {
+				return err
+			}
 
 			// Find the -- separator for command
 			var command []string
@@ -589,10 +2155,16 @@ Examples:
 			}
 
 			// Build the YAML
-			yaml := buildStoppableContainerYAML(name, ns, image, command, running, workingDir, env, ports)
+			yaml := buildStoppableContainerYAML(name, ns, image, command, running, workingDir,
+				env, envFromConfigMaps, envFromSecrets, ports, resources, volumes)
+
+			if dryRun == "client" {
+				fmt.Print(yaml)
+				return nil
+			}
 
 			// Apply using kubectl
-			kubectlCmd := exec.Command("kubectl", "apply", "-f", "-")
+			kubectlCmd := exec.Command("kubectl", append(kubectlGlobalArgs(), applyArgs...)...)
 			kubectlCmd.Stdin = strings.NewReader(yaml)
 			kubectlCmd.Stdout = os.Stdout
 			kubectlCmd.Stderr = os.Stderr
@@ -601,6 +2173,10 @@ Examples:
 				return fmt.Errorf("failed to create StoppableContainer: %w", err)
 			}
 
+			// kubectl already reports the server dry run
+			if dryRun == "server" {
+				return nil
+			}
 			fmt.Printf("StoppableContainer %s created\n", name)
 			return nil
 		},
@@ -609,19 +2185,436 @@ Examples:
 	cmd.Flags().BoolVar(&running, "running", true, "Start container immediately")
 	cmd.Flags().StringVarP(&workingDir, "workdir", "w", "", "Working directory")
 	cmd.Flags().StringArrayVarP(&env, "env", "e", nil, "Environment variables (KEY=VALUE)")
+	cmd.Flags().StringArrayVar(&envFromConfigMaps, "env-from-configmap", nil, "ConfigMap whose keys become environment variables")
+	cmd.Flags().StringArrayVar(&envFromSecrets, "env-from-secret", nil, "Secret whose keys become environment variables")
 	cmd.Flags().StringArrayVarP(&ports, "port", "p", nil, "Port mappings (port:name)")
+	cmd.Flags().StringVar(&resources.cpuRequest, "cpu-request", "", "CPU request, e.g. 250m")
+	cmd.Flags().StringVar(&resources.cpuLimit, "cpu-limit", "", "CPU limit, e.g. 2")
+	cmd.Flags().StringVar(&resources.memoryRequest, "memory-request", "", "Memory request, e.g. 256Mi")
+	cmd.Flags().StringVar(&resources.memoryLimit, "memory-limit", "", "Memory limit, e.g. 1Gi")
+	cmd.Flags().StringArrayVar(&pvcs, "pvc", nil, "PersistentVolumeClaim to mount (claim:/mountpath)")
+	cmd.Flags().StringArrayVar(&hostPaths, "hostpath", nil, "Node directory to mount (/host/path:/mountpath)")
+	cmd.Flags().StringVar(&dryRun, "dry-run", "none",
+		"none creates the container, client prints the manifest, server validates it against the cluster")
+	return cmd
+}
+
+// createApplyArgs returns the kubectl apply arguments for a kubectl sc create --dry-run value
+func createApplyArgs(dryRun string) ([]string, error) {
+	switch dryRun {
+	case "none", "client":
+		return []string{"apply", "-f", "-"}, nil
+	case "server":
+		return []string{"apply", "--dry-run=server", "-f", "-"}, nil
+	default:
+		return nil, fmt.Errorf("invalid --dry-run %q, must be none, client or server", dryRun)
+	}
+}
+
+// createVolume is a volume from the kubectl sc create flags, with either pvc or hostPath set
+type createVolume struct {
+	name      string
+	pvc       string
+	hostPath  string
+	mountPath string
+}
+
+// parseVolumeFlags parses the --pvc and --hostpath flags into volumes named volume-0, volume-1, ...
+func parseVolumeFlags(pvcs, hostPaths []string) ([]createVolume, error) {
+	var volumes []createVolume
+	for _, arg := range pvcs {
+		source, mountPath, err := splitVolumeFlag("--pvc", arg)
+		if err != nil {
+			return nil, err
+		}
+		volumes = append(volumes, createVolume{pvc: source, mountPath: mountPath})
+	}
+	for _, arg := range hostPaths {
+		source, mountPath, err := splitVolumeFlag("--hostpath", arg)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(source, "/") {
+			return nil, fmt.Errorf("invalid --hostpath %q, the host path must be absolute", arg)
+		}
+		volumes = append(volumes, createVolume{hostPath: source, mountPath: mountPath})
+	}
+	for i := range volumes {
+		volumes[i].name = fmt.Sprintf("volume-%d", i)
+	}
+	return volumes, nil
+}
+
+// splitVolumeFlag splits a <source>:<mountpath> volume flag
+func splitVolumeFlag(flag, arg string) (string, string, error) {
+	source, mountPath, ok := strings.Cut(arg, ":")
+	if !ok || source == "" || mountPath == "" {
+		return "", "", fmt.Errorf("invalid %s %q, expected <source>:<mountpath>", flag, arg)
+	}
+	if !strings.HasPrefix(mountPath, "/") {
+		return "", "", fmt.Errorf("invalid %s %q, the mount path must be absolute", flag, arg)
+	}
+	return source, mountPath, nil
+}
+
+// containerResources holds the kubectl sc create resource flags, empty fields are left out
+type containerResources struct {
+	cpuRequest    string
+	cpuLimit      string
+	memoryRequest string
+	memoryLimit   string
+}
+
+// validate checks that every set flag is a valid resource quantity
+func (r containerResources) validate() error {
+	for _, q := range []struct{ flag, value string }{
+		{"--cpu-request", r.cpuRequest},
+		{"--cpu-limit", r.cpuLimit},
+		{"--memory-request", r.memoryRequest},
+		{"--memory-limit", r.memoryLimit},
+	} {
+		if q.value == "" {
+			continue
+		}
+		if _, err := resource.ParseQuantity(q.value); err != nil {
+			return fmt.Errorf("invalid %s %q: %w", q.flag, q.value, err)
+		}
+	}
+	return nil
+}
+
+// writeYAML writes the resources block of the container, if any flag is set
+func (r containerResources) writeYAML(sb *strings.Builder) {
+	requests := resourceListYAML(r.cpuRequest, r.memoryRequest)
+	limits := resourceListYAML(r.cpuLimit, r.memoryLimit)
+	if requests == "" && limits == "" {
+		return
+	}
+	sb.WriteString("          resources:\n")
+	if requests != "" {
+		sb.WriteString("            requests:\n" + requests)
+	}
+	if limits != "" {
+		sb.WriteString("            limits:\n" + limits)
+	}
+}
+
+// resourceListYAML renders the cpu and memory entries of a requests or limits map
+func resourceListYAML(cpu, memory string) string {
+	var out string
+	if cpu != "" {
+		out += fmt.Sprintf("              cpu: %q\n", cpu)
+	}
+	if memory != "" {
+		out += fmt.Sprintf("              memory: %q\n", memory)
+	}
+	return out
+}
+
+func applyCmd() *cobra.Command {
+	var filename string
+	var fieldManager string
+	var forceConflicts bool
+
+	cmd := &cobra.Command{
+		Use:   "apply -f <file>",
+		Short: "Apply a StoppableContainer manifest with server-side apply",
+		Long: `Apply one or more StoppableContainer manifests using server-side apply.
+
+Fields are owned by the kubectl-sc field manager, so kubectl sc apply can be used
+next to GitOps tools such as Argo CD or Flux, which use their own field managers.
+Applying a field owned by another manager fails with a conflict unless
+--force-conflicts is set. The fields changed by each apply are reported.
+
+Examples:
+  # Apply a manifest
+  kubectl sc apply -f my-app.yaml
+
+  # Apply from stdin, taking over fields owned by another manager
+  cat my-app.yaml | kubectl sc apply -f - --force-conflicts`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var data []byte
+			var err error
+			if filename == "-" {
+				data, err = io.ReadAll(os.Stdin)
+			} else {
+				data, err = os.ReadFile(filename)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %w", filename, err)
+			}
+
+			objs, err := decodeStoppableContainers(data)
+			if err != nil {
+				return err
+			}
+
+			client, ns, err := getClient()
+			if err != nil {
+				return err
+			}
+
+			for _, obj := range objs {
+				if obj.GetNamespace() == "" {
+					obj.SetNamespace(ns)
+				} else if namespace != "" && obj.GetNamespace() != namespace {
+					return fmt.Errorf("the namespace of StoppableContainer %s (%s) does not match --namespace %s",
+						obj.GetName(), obj.GetNamespace(), namespace)
+				}
+				if err := applyStoppableContainer(client, obj, fieldManager, forceConflicts, os.Stdout); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&filename, "filename", "f", "", "Manifest to apply, - for stdin")
+	cmd.Flags().StringVar(&fieldManager, "field-manager", FieldManager, "Name of the field manager")
+	cmd.Flags().BoolVar(&forceConflicts, "force-conflicts", false, "Take ownership of fields owned by other managers")
+	_ = cmd.MarkFlagRequired("filename")
+	return cmd
+}
+
+// decodeStoppableContainers decodes a YAML or JSON stream of StoppableContainer manifests
+func decodeStoppableContainers(data []byte) ([]*unstructured.Unstructured, error) {
+	decoder := utilyaml.NewYAMLOrJSONDecoder(bytes.NewReader(data), 4096)
+
+	var objs []*unstructured.Unstructured
+	for {
+		obj := &unstructured.Unstructured{}
+		if err := decoder.Decode(&obj.Object); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, fmt.Errorf("failed to decode manifest: %w", err)
+		}
+		if len(obj.Object) == 0 {
+			// Empty document, e.g. a leading ---
+			continue
+		}
+		if obj.GetAPIVersion() != GroupVersion || obj.GetKind() != "StoppableContainer" {
+			return nil, fmt.Errorf("unsupported object %s %s, only %s StoppableContainer can be applied",
+				obj.GetAPIVersion(), obj.GetKind(), GroupVersion)
+		}
+		if obj.GetName() == "" {
+			return nil, fmt.Errorf("StoppableContainer manifest has no metadata.name")
+		}
+		objs = append(objs, obj)
+	}
+
+	if len(objs) == 0 {
+		return nil, fmt.Errorf("no StoppableContainer found in manifest")
+	}
+	return objs, nil
+}
+
+// applyStoppableContainer server-side applies obj and reports the changed fields to w
+func applyStoppableContainer(client dynamic.Interface, obj *unstructured.Unstructured, fieldManager string, force bool, w io.Writer) error {
+	ctx := context.Background()
+	ns, name := obj.GetNamespace(), obj.GetName()
+
+	before, err := client.Resource(scGVR).Namespace(ns).Get(ctx, name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		before = nil
+	} else if err != nil {
+		return fmt.Errorf("failed to get StoppableContainer %s: %w", name, err)
+	}
+
+	body, err := buildApplyBody(obj)
+	if err != nil {
+		return err
+	}
+
+	after, err := client.Resource(scGVR).Namespace(ns).Patch(ctx, name, types.ApplyPatchType, body,
+		applyPatchOptions(fieldManager, force))
+	if err != nil {
+		return fmt.Errorf("failed to apply StoppableContainer %s: %w", name, err)
+	}
+
+	if before == nil {
+		_, _ = fmt.Fprintf(w, "stoppablecontainer/%s created\n", name)
+		return nil
+	}
+
+	changed := changedFields(before, after)
+	if len(changed) == 0 {
+		_, _ = fmt.Fprintf(w, "stoppablecontainer/%s unchanged\n", name)
+		return nil
+	}
+	_, _ = fmt.Fprintf(w, "stoppablecontainer/%s configured\n", name)
+	for _, field := range changed {
+		_, _ = fmt.Fprintf(w, "  changed: %s\n", field)
+	}
+	return nil
+}
+
+// buildApplyBody returns the apply patch for obj. Server-owned fields are
+// dropped so that the output of kubectl get -o yaml can be applied as is.
+func buildApplyBody(obj *unstructured.Unstructured) ([]byte, error) {
+	body := obj.DeepCopy()
+	unstructured.RemoveNestedField(body.Object, "status")
+	for _, field := range []string{"managedFields", "resourceVersion", "uid", "generation", "creationTimestamp"} {
+		unstructured.RemoveNestedField(body.Object, "metadata", field)
+	}
+	return json.Marshal(body.Object)
+}
+
+// applyPatchOptions returns the patch options for a server-side apply
+func applyPatchOptions(fieldManager string, force bool) metav1.PatchOptions {
+	return metav1.PatchOptions{
+		FieldManager: fieldManager,
+		Force:        &force,
+	}
+}
+
+// changedFields lists the labels, annotations and spec fields that differ between two objects
+func changedFields(before, after *unstructured.Unstructured) []string {
+	var changed []string
+	for _, fields := range [][]string{{"metadata", "labels"}, {"metadata", "annotations"}, {"spec"}} {
+		b, _, _ := unstructured.NestedFieldNoCopy(before.Object, fields...)
+		a, _, _ := unstructured.NestedFieldNoCopy(after.Object, fields...)
+		changed = append(changed, diffFields(strings.Join(fields, "."), b, a)...)
+	}
+	return changed
+}
+
+// diffFields returns the dotted paths below prefix whose values differ
+func diffFields(prefix string, before, after interface{}) []string {
+	beforeMap, beforeIsMap := before.(map[string]interface{})
+	afterMap, afterIsMap := after.(map[string]interface{})
+	if !beforeIsMap || !afterIsMap {
+		if reflect.DeepEqual(before, after) {
+			return nil
+		}
+		return []string{prefix}
+	}
+
+	keys := make(map[string]bool, len(beforeMap)+len(afterMap))
+	for k := range beforeMap {
+		keys[k] = true
+	}
+	for k := range afterMap {
+		keys[k] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	var changed []string
+	for _, k := range sorted {
+		changed = append(changed, diffFields(prefix+"."+k, beforeMap[k], afterMap[k])...)
+	}
+	return changed
+}
+
+func cloneCmd() *cobra.Command {
+	var targetNamespace string
+	var image string
+
+	cmd := &cobra.Command{
+		Use:   "clone <src> <dst>",
+		Short: "Copy a StoppableContainer under a new name",
+		Long: `Create a new StoppableContainer with the spec, labels and annotations of an existing one.
+
+The copy starts with a fresh rootfs: the source's filesystem is not carried over.
+
+Examples:
+  # Clone within the current namespace
+  kubectl sc clone my-app my-app-2
+
+  # Clone into another namespace with a different image
+  kubectl sc clone my-app my-app --target-namespace=staging --image=ubuntu:24.04`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			srcName, dstName := args[0], args[1]
+			client, ns, err := getClient()
+			if err != nil {
+				return err
+			}
+
+			dstNs := ns
+			if targetNamespace != "" {
+				dstNs = targetNamespace
+			}
+
+			src, err := client.Resource(scGVR).Namespace(ns).Get(context.Background(), srcName, metav1.GetOptions{})
+			if err != nil {
+				return fmt.Errorf("failed to get StoppableContainer %s: %w", srcName, err)
+			}
+
+			dst, err := cloneStoppableContainer(src, dstName, dstNs, image)
+			if err != nil {
+				return err
+			}
+
+			if _, err := client.Resource(scGVR).Namespace(dstNs).Create(context.Background(), dst, metav1.CreateOptions{}); err != nil {
+				return fmt.Errorf("failed to create StoppableContainer %s: %w", dstName, err)
+			}
+
+			fmt.Printf("StoppableContainer %s/%s cloned to %s/%s\n", ns, srcName, dstNs, dstName)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&targetNamespace, "target-namespace", "",
+		"Namespace of the copy (default: the source namespace)")
+	cmd.Flags().StringVar(&image, "image", "", "Override the image of the main container")
 	return cmd
 }
 
+// cloneStoppableContainer returns a copy of src named name in namespace ns, without status
+// or server-set metadata. If image is not empty, it replaces the main container's image.
+func cloneStoppableContainer(src *unstructured.Unstructured, name, ns, image string) (*unstructured.Unstructured, error) {
+	spec, ok, err := unstructured.NestedMap(src.Object, "spec")
+	if err != nil || !ok {
+		return nil, fmt.Errorf("StoppableContainer %s has no spec", src.GetName())
+	}
+
+	dst := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": src.GetAPIVersion(),
+		"kind":       src.GetKind(),
+		"spec":       spec,
+	}}
+	dst.SetName(name)
+	dst.SetNamespace(ns)
+	dst.SetLabels(src.GetLabels())
+
+	annotations := src.GetAnnotations()
+	delete(annotations, "kubectl.kubernetes.io/last-applied-configuration")
+	if len(annotations) > 0 {
+		dst.SetAnnotations(annotations)
+	}
+
+	if image != "" {
+		containers, _, _ := unstructured.NestedSlice(dst.Object, "spec", "template", "spec", "containers")
+		if len(containers) == 0 {
+			return nil, fmt.Errorf("StoppableContainer %s has no containers", src.GetName())
+		}
+		container, ok := containers[0].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("StoppableContainer %s has an invalid container", src.GetName())
+		}
+		container["image"] = image
+		if err := unstructured.SetNestedSlice(dst.Object, containers, "spec", "template", "spec", "containers"); err != nil {
+			return nil, err
+		}
+	}
+
+	return dst, nil
+}
+
 func deleteCmd() *cobra.Command {
 	var force bool
 	var wait bool
 
 	cmd := &cobra.Command{
-		Use:     "delete <name>",
-		Aliases: []string{"rm", "remove"},
-		Short:   "Delete a StoppableContainer",
-		Args:    cobra.ExactArgs(1),
+		Use:               "delete <name>",
+		Aliases:           []string{"rm", "remove"},
+		Short:             "Delete a StoppableContainer",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeStoppableContainerNames,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			name := args[0]
 			_, ns, err := getClient()
@@ -645,6 +2638,88 @@ func deleteCmd() *cobra.Command {
 	return cmd
 }
 
+func completionCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "completion <bash|zsh|fish|powershell>",
+		Short: "Generate a shell completion script",
+		Long: `Generate a shell completion script for kubectl-sc.
+
+Completion suggests the names of existing StoppableContainers, honoring -n and -A.
+
+Examples:
+  # Bash, for the current shell
+  source <(kubectl-sc completion bash)
+
+  # Zsh
+  kubectl-sc completion zsh > "${fpath[1]}/_kubectl-sc"
+
+To complete "kubectl sc ..." as well, kubectl (v1.26+) needs an executable named
+kubectl_complete-sc on the PATH that forwards to kubectl-sc __complete "$@".`,
+		ValidArgs: []string{"bash", "zsh", "fish", "powershell"},
+		Args:      cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			root := cmd.Root()
+			switch args[0] {
+			case "bash":
+				return root.GenBashCompletionV2(os.Stdout, true)
+			case "zsh":
+				return root.GenZshCompletion(os.Stdout)
+			case "fish":
+				return root.GenFishCompletion(os.Stdout, true)
+			default:
+				return root.GenPowerShellCompletionWithDesc(os.Stdout)
+			}
+		},
+	}
+}
+
+// completeStoppableContainerNames completes the <name> argument with existing
+// StoppableContainers in the selected namespace, or in all namespaces with -A
+func completeStoppableContainerNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	client, ns, err := getClient()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	names, err := listStoppableContainerNames(client, ns, allNs, toComplete)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// listStoppableContainerNames returns the sorted names of the StoppableContainers
+// starting with prefix. With allNamespaces, each name carries its namespace as description.
+func listStoppableContainerNames(client dynamic.Interface, ns string, allNamespaces bool, prefix string) ([]string, error) {
+	ctx := context.Background()
+	var list *unstructured.UnstructuredList
+	var err error
+	if allNamespaces {
+		list, err = client.Resource(scGVR).List(ctx, metav1.ListOptions{})
+	} else {
+		list, err = client.Resource(scGVR).Namespace(ns).List(ctx, metav1.ListOptions{})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, item := range list.Items {
+		if !strings.HasPrefix(item.GetName(), prefix) {
+			continue
+		}
+		if allNamespaces {
+			names = append(names, item.GetName()+"\t"+item.GetNamespace())
+		} else {
+			names = append(names, item.GetName())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
 func versionCmd() *cobra.Command {
 	return &cobra.Command{
 		Use:   "version",
@@ -657,8 +2732,21 @@ func versionCmd() *cobra.Command {
 
 // Helper functions
 
+// kubectlGlobalArgs passes --kubeconfig and --context on to kubectl, so it
+// targets the same cluster as the API calls of kubectl sc
+func kubectlGlobalArgs() []string {
+	var args []string
+	if kubeconfig != "" {
+		args = append(args, "--kubeconfig", kubeconfig)
+	}
+	if kubeContext != "" {
+		args = append(args, "--context", kubeContext)
+	}
+	return args
+}
+
 func runKubectl(args ...string) error {
-	kubectlCmd := exec.Command("kubectl", args...)
+	kubectlCmd := exec.Command("kubectl", append(kubectlGlobalArgs(), args...)...)
 	kubectlCmd.Stdin = os.Stdin
 	kubectlCmd.Stdout = os.Stdout
 	kubectlCmd.Stderr = os.Stderr
@@ -666,10 +2754,70 @@ func runKubectl(args ...string) error {
 }
 
 func waitForPhase(client dynamic.Interface, ns, name, targetPhase string, timeout time.Duration) error {
+	return waitFor(client, ns, name, waitCondition{phase: targetPhase}, timeout)
+}
+
+// waitCondition is what kubectl sc wait waits for, either a phase or a condition status
+type waitCondition struct {
+	phase           string
+	conditionType   string
+	conditionStatus string
+}
+
+// parseWaitFor parses a --for value: phase=<phase>, condition=<type> or condition=<type>=<status>
+func parseWaitFor(value string) (waitCondition, error) {
+	kind, target, _ := strings.Cut(value, "=")
+	switch {
+	case kind == "phase" && target != "":
+		return waitCondition{phase: target}, nil
+	case kind == "condition" && target != "":
+		conditionType, status, found := strings.Cut(target, "=")
+		if !found {
+			status = "True"
+		}
+		if conditionType == "" || status == "" {
+			break
+		}
+		// Condition statuses are True, False or Unknown, accept them in any case like kubectl wait
+		return waitCondition{conditionType: conditionType, conditionStatus: strings.ToUpper(status[:1]) + strings.ToLower(status[1:])}, nil
+	}
+	return waitCondition{}, fmt.Errorf("invalid --for %q, expected phase=<phase> or condition=<type>[=<status>]", value)
+}
+
+// String describes the condition for progress and error messages
+func (c waitCondition) String() string {
+	if c.phase != "" {
+		return "phase " + c.phase
+	}
+	return fmt.Sprintf("condition %s=%s", c.conditionType, c.conditionStatus)
+}
+
+// met reports whether the StoppableContainer reached the condition
+func (c waitCondition) met(sc *unstructured.Unstructured) bool {
+	if c.phase != "" {
+		phase, _, _ := unstructured.NestedString(sc.Object, "status", "phase")
+		return phase == c.phase
+	}
+	conditions, _, _ := unstructured.NestedSlice(sc.Object, "status", "conditions")
+	for _, cond := range conditions {
+		condition, ok := cond.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if condition["type"] == c.conditionType {
+			return condition["status"] == c.conditionStatus
+		}
+	}
+	return false
+}
+
+// waitFor polls the StoppableContainer until it meets the condition. A Failed
+// container ends the wait with an error, unless the Failed phase is awaited.
+func waitFor(client dynamic.Interface, ns, name string, cond waitCondition, timeout time.Duration) error {
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
-	fmt.Printf("Waiting for phase %s...\n", targetPhase)
+	fmt.Printf("Waiting for %s...\n", cond)
 
 	ticker := time.NewTicker(time.Second)
 	defer ticker.Stop()
@@ -677,19 +2825,22 @@ func waitForPhase(client dynamic.Interface, ns, name, targetPhase string, timeou
 	for {
 		select {
 		case <-ctx.Done():
-			return fmt.Errorf("timeout waiting for phase %s", targetPhase)
+			return fmt.Errorf("timeout waiting for %s", cond)
 		case <-ticker.C:
 			sc, err := client.Resource(scGVR).Namespace(ns).Get(ctx, name, metav1.GetOptions{})
 			if err != nil {
 				continue
 			}
 
-			phase, _, _ := unstructured.NestedString(sc.Object, "status", "phase")
-			if phase == targetPhase {
-				fmt.Printf("StoppableContainer %s is now %s\n", name, targetPhase)
+			if cond.met(sc) {
+				if cond.phase != "" {
+					fmt.Printf("StoppableContainer %s is now %s\n", name, cond.phase)
+				} else {
+					fmt.Printf("StoppableContainer %s met %s\n", name, cond)
+				}
 				return nil
 			}
-			if phase == "Failed" {
+			if phase, _, _ := unstructured.NestedString(sc.Object, "status", "phase"); phase == "Failed" {
 				message, _, _ := unstructured.NestedString(sc.Object, "status", "message")
 				return fmt.Errorf("StoppableContainer failed: %s", message)
 			}
@@ -718,7 +2869,9 @@ func buildStoppableContainerYAML(
 	command []string,
 	running bool,
 	workingDir string,
-	env, ports []string,
+	env, envFromConfigMaps, envFromSecrets, ports []string,
+	resources containerResources,
+	volumes []createVolume,
 ) string {
 	var sb strings.Builder
 
@@ -730,39 +2883,68 @@ metadata:
 spec:
   running: %v
   template:
-    container:
-      image: %s
+    spec:
+      containers:
+        - name: main
+          image: %s
 `, GroupVersion, name, ns, running, image))
 
 	if len(command) > 0 {
-		sb.WriteString("      command:\n")
+		sb.WriteString("          command:\n")
 		for _, c := range command {
-			sb.WriteString(fmt.Sprintf("        - %q\n", c))
+			sb.WriteString(fmt.Sprintf("            - %q\n", c))
 		}
 	}
 
 	if workingDir != "" {
-		sb.WriteString(fmt.Sprintf("      workingDir: %q\n", workingDir))
+		sb.WriteString(fmt.Sprintf("          workingDir: %q\n", workingDir))
 	}
 
 	if len(env) > 0 {
-		sb.WriteString("      env:\n")
+		sb.WriteString("          env:\n")
 		for _, e := range env {
 			parts := strings.SplitN(e, "=", 2)
 			if len(parts) == 2 {
-				sb.WriteString(fmt.Sprintf("        - name: %s\n          value: %q\n", parts[0], parts[1]))
+				sb.WriteString(fmt.Sprintf("            - name: %s\n              value: %q\n", parts[0], parts[1]))
 			}
 		}
 	}
 
+	if len(envFromConfigMaps) > 0 || len(envFromSecrets) > 0 {
+		sb.WriteString("          envFrom:\n")
+		for _, cm := range envFromConfigMaps {
+			sb.WriteString(fmt.Sprintf("            - configMapRef:\n                name: %q\n", cm))
+		}
+		for _, secret := range envFromSecrets {
+			sb.WriteString(fmt.Sprintf("            - secretRef:\n                name: %q\n", secret))
+		}
+	}
+
 	if len(ports) > 0 {
-		sb.WriteString("      ports:\n")
+		sb.WriteString("          ports:\n")
 		for _, p := range ports {
 			parts := strings.SplitN(p, ":", 2)
 			if len(parts) == 2 {
-				sb.WriteString(fmt.Sprintf("        - containerPort: %s\n          name: %s\n", parts[0], parts[1]))
+				sb.WriteString(fmt.Sprintf("            - containerPort: %s\n              name: %s\n", parts[0], parts[1]))
+			} else {
+				sb.WriteString(fmt.Sprintf("            - containerPort: %s\n", parts[0]))
+			}
+		}
+	}
+
+	resources.writeYAML(&sb)
+
+	if len(volumes) > 0 {
+		sb.WriteString("          volumeMounts:\n")
+		for _, v := range volumes {
+			sb.WriteString(fmt.Sprintf("            - name: %s\n              mountPath: %q\n", v.name, v.mountPath))
+		}
+		sb.WriteString("      volumes:\n")
+		for _, v := range volumes {
+			if v.pvc != "" {
+				sb.WriteString(fmt.Sprintf("        - name: %s\n          persistentVolumeClaim:\n            claimName: %q\n", v.name, v.pvc))
 			} else {
-				sb.WriteString(fmt.Sprintf("        - containerPort: %s\n", parts[0]))
+				sb.WriteString(fmt.Sprintf("        - name: %s\n          hostPath:\n            path: %q\n", v.name, v.hostPath))
 			}
 		}
 	}
@@ -17,9 +17,26 @@ limitations under the License.
 package main
 
 import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
+	"sync"
 	"testing"
 	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/apimachinery/pkg/watch"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	clienttesting "k8s.io/client-go/testing"
 )
 
 func TestFormatAge(t *testing.T) {
@@ -60,7 +77,11 @@ func TestBuildStoppableContainerYAML(t *testing.T) {
 		running    bool
 		workingDir string
 		env        []string
+		configMaps []string
+		secrets    []string
 		ports      []string
+		resources  containerResources
+		volumes    []createVolume
 		contains   []string
 	}{
 		{
@@ -115,13 +136,65 @@ func TestBuildStoppableContainerYAML(t *testing.T) {
 				"name: https",
 			},
 		},
+		{
+			name:       "with env from configmaps and secrets",
+			scName:     "cfg-app",
+			ns:         "default",
+			image:      "nginx:latest",
+			running:    true,
+			env:        []string{"PORT=8080"},
+			configMaps: []string{"app-config", "shared-config"},
+			secrets:    []string{"app-secrets"},
+			contains: []string{
+				"          envFrom:\n" +
+					"            - configMapRef:\n                name: \"app-config\"\n" +
+					"            - configMapRef:\n                name: \"shared-config\"\n" +
+					"            - secretRef:\n                name: \"app-secrets\"\n",
+			},
+		},
+		{
+			name:    "with resources",
+			scName:  "sized-app",
+			ns:      "default",
+			image:   "nginx:latest",
+			running: true,
+			resources: containerResources{
+				cpuRequest:    "250m",
+				memoryRequest: "256Mi",
+				memoryLimit:   "1Gi",
+			},
+			contains: []string{
+				"          resources:\n" +
+					"            requests:\n              cpu: \"250m\"\n              memory: \"256Mi\"\n" +
+					"            limits:\n              memory: \"1Gi\"\n",
+			},
+		},
+		{
+			name:    "with volumes",
+			scName:  "data-app",
+			ns:      "default",
+			image:   "ubuntu:22.04",
+			running: true,
+			volumes: []createVolume{
+				{name: "volume-0", pvc: "my-data", mountPath: "/data"},
+				{name: "volume-1", hostPath: "/mnt/cache", mountPath: "/cache"},
+			},
+			contains: []string{
+				"          volumeMounts:\n" +
+					"            - name: volume-0\n              mountPath: \"/data\"\n" +
+					"            - name: volume-1\n              mountPath: \"/cache\"\n",
+				"      volumes:\n" +
+					"        - name: volume-0\n          persistentVolumeClaim:\n            claimName: \"my-data\"\n" +
+					"        - name: volume-1\n          hostPath:\n            path: \"/mnt/cache\"\n",
+			},
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			result := buildStoppableContainerYAML(
 				tt.scName, tt.ns, tt.image, tt.command,
-				tt.running, tt.workingDir, tt.env, tt.ports,
+				tt.running, tt.workingDir, tt.env, tt.configMaps, tt.secrets, tt.ports, tt.resources, tt.volumes,
 			)
 
 			for _, expected := range tt.contains {
@@ -133,6 +206,160 @@ func TestBuildStoppableContainerYAML(t *testing.T) {
 	}
 }
 
+func TestBuildStoppableContainerYAML_Structure(t *testing.T) {
+	result := buildStoppableContainerYAML("my-app", "default", "nginx:latest", []string{"nginx"},
+		true, "/app", []string{"PORT=8080"}, []string{"app-config"}, []string{"app-secrets"}, []string{"80:http"},
+		containerResources{cpuLimit: "2", memoryRequest: "128Mi"},
+		[]createVolume{{name: "volume-0", pvc: "my-data", mountPath: "/data"}})
+
+	obj := &unstructured.Unstructured{}
+	if err := utilyaml.NewYAMLOrJSONDecoder(strings.NewReader(result), 4096).Decode(&obj.Object); err != nil {
+		t.Fatalf("generated YAML does not parse: %v\n%s", err, result)
+	}
+	containers, found, err := unstructured.NestedSlice(obj.Object, "spec", "template", "spec", "containers")
+	if err != nil || !found || len(containers) != 1 {
+		t.Fatalf("spec.template.spec.containers = %v (found %v, err %v)", containers, found, err)
+	}
+	container := containers[0].(map[string]interface{})
+	for _, field := range []string{"image", "command", "workingDir", "env", "ports", "resources", "volumeMounts"} {
+		if _, ok := container[field]; !ok {
+			t.Errorf("container is missing %s: %v", field, container)
+		}
+	}
+
+	want := []interface{}{
+		map[string]interface{}{"configMapRef": map[string]interface{}{"name": "app-config"}},
+		map[string]interface{}{"secretRef": map[string]interface{}{"name": "app-secrets"}},
+	}
+	if !reflect.DeepEqual(container["envFrom"], want) {
+		t.Errorf("envFrom = %v, want %v", container["envFrom"], want)
+	}
+
+	volumes, _, _ := unstructured.NestedSlice(obj.Object, "spec", "template", "spec", "volumes")
+	wantVolumes := []interface{}{
+		map[string]interface{}{"name": "volume-0", "persistentVolumeClaim": map[string]interface{}{"claimName": "my-data"}},
+	}
+	if !reflect.DeepEqual(volumes, wantVolumes) {
+		t.Errorf("volumes = %v, want %v", volumes, wantVolumes)
+	}
+}
+
+func TestCreateApplyArgs(t *testing.T) {
+	tests := []struct {
+		dryRun  string
+		want    []string
+		wantErr bool
+	}{
+		{dryRun: "none", want: []string{"apply", "-f", "-"}},
+		{dryRun: "client", want: []string{"apply", "-f", "-"}},
+		{dryRun: "server", want: []string{"apply", "--dry-run=server", "-f", "-"}},
+		{dryRun: "true", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.dryRun, func(t *testing.T) {
+			got, err := createApplyArgs(tt.dryRun)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("createApplyArgs(%q) error = %v, wantErr %v", tt.dryRun, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("createApplyArgs(%q) = %v, want %v", tt.dryRun, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseVolumeFlags(t *testing.T) {
+	volumes, err := parseVolumeFlags([]string{"my-data:/data"}, []string{"/mnt/cache:/cache"})
+	if err != nil {
+		t.Fatalf("parseVolumeFlags() error = %v", err)
+	}
+	want := []createVolume{
+		{name: "volume-0", pvc: "my-data", mountPath: "/data"},
+		{name: "volume-1", hostPath: "/mnt/cache", mountPath: "/cache"},
+	}
+	if !reflect.DeepEqual(volumes, want) {
+		t.Errorf("parseVolumeFlags() = %+v, want %+v", volumes, want)
+	}
+
+	for _, tt := range []struct {
+		pvcs, hostPaths []string
+		wantErr         string
+	}{
+		{pvcs: []string{"my-data"}, wantErr: "expected <source>:<mountpath>"},
+		{pvcs: []string{":/data"}, wantErr: "expected <source>:<mountpath>"},
+		{pvcs: []string{"my-data:data"}, wantErr: "mount path must be absolute"},
+		{hostPaths: []string{"mnt/cache:/cache"}, wantErr: "host path must be absolute"},
+	} {
+		if _, err := parseVolumeFlags(tt.pvcs, tt.hostPaths); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("parseVolumeFlags(%v, %v) error = %v, want %q", tt.pvcs, tt.hostPaths, err, tt.wantErr)
+		}
+	}
+}
+
+func TestContainerResources_Validate(t *testing.T) {
+	tests := []struct {
+		name      string
+		resources containerResources
+		wantErr   string
+	}{
+		{name: "empty", resources: containerResources{}},
+		{name: "valid", resources: containerResources{cpuRequest: "100m", cpuLimit: "1.5", memoryRequest: "64Mi", memoryLimit: "1G"}},
+		{name: "invalid cpu limit", resources: containerResources{cpuLimit: "two"}, wantErr: "--cpu-limit"},
+		{name: "invalid memory request", resources: containerResources{memoryRequest: "256MB"}, wantErr: "--memory-request"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.resources.validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validate() error = %v, want it to mention %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestContextFlag(t *testing.T) {
+	kubeconfigPath := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(kubeconfigPath, []byte(`apiVersion: v1
+kind: Config
+current-context: dev
+clusters:
+- name: dev
+  cluster: {server: "https://dev.example.com"}
+- name: prod
+  cluster: {server: "https://prod.example.com"}
+contexts:
+- name: dev
+  context: {cluster: dev, namespace: dev-ns}
+- name: prod
+  context: {cluster: prod, namespace: prod-ns}
+`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	defer func(path, ctx string) { kubeconfig, kubeContext = path, ctx }(kubeconfig, kubeContext)
+	kubeconfig, kubeContext = kubeconfigPath, "prod"
+
+	if _, ns, err := getClient(); err != nil || ns != "prod-ns" {
+		t.Errorf("getClient() namespace = %q, err = %v, want the prod context's prod-ns", ns, err)
+	}
+	want := []string{"--kubeconfig", kubeconfigPath, "--context", "prod"}
+	if got := kubectlGlobalArgs(); !reflect.DeepEqual(got, want) {
+		t.Errorf("kubectlGlobalArgs() = %v, want %v", got, want)
+	}
+
+	kubeconfig, kubeContext = "", ""
+	if got := kubectlGlobalArgs(); len(got) != 0 {
+		t.Errorf("kubectlGlobalArgs() = %v, want none without flags", got)
+	}
+}
+
 func TestGVRDefinitions(t *testing.T) {
 	// Test StoppableContainer GVR
 	if scGVR.Group != "stoppablecontainer.xtlsoft.top" {
@@ -168,3 +395,1370 @@ func TestGroupVersion(t *testing.T) {
 		t.Errorf("GroupVersion = %q, want %q", GroupVersion, "stoppablecontainer.xtlsoft.top/v1alpha1")
 	}
 }
+
+func TestBuildPortForwardArgs(t *testing.T) {
+	sc := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": GroupVersion,
+		"kind":       "StoppableContainer",
+		"metadata":   map[string]interface{}{"name": "my-app", "namespace": "default"},
+		"status":     map[string]interface{}{"consumerPodName": "my-app-pod"},
+	}}
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{scGVR: "StoppableContainerList"}, sc)
+
+	podName, err := resolveConsumerPod(client, "default", "my-app")
+	if err != nil {
+		t.Fatalf("resolveConsumerPod() error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		ports     []string
+		addresses []string
+		expected  []string
+	}{
+		{
+			name:     "single port",
+			ports:    []string{"8080:80"},
+			expected: []string{"port-forward", "-n", "default", "pod/my-app-pod", "8080:80"},
+		},
+		{
+			name:      "multiple ports with address",
+			ports:     []string{"8080:80", "8443:443"},
+			addresses: []string{"127.0.0.1", "0.0.0.0"},
+			expected: []string{"port-forward", "-n", "default", "--address", "127.0.0.1,0.0.0.0",
+				"pod/my-app-pod", "8080:80", "8443:443"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := buildPortForwardArgs("default", podName, tt.ports, tt.addresses)
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("buildPortForwardArgs() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+
+	// A StoppableContainer without a consumer pod can't be port-forwarded to
+	unstructured.RemoveNestedField(sc.Object, "status")
+	client = dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{scGVR: "StoppableContainerList"}, sc)
+	if _, err := resolveConsumerPod(client, "default", "my-app"); err == nil {
+		t.Error("resolveConsumerPod() expected error for stopped container")
+	}
+}
+
+func TestImageGetSet(t *testing.T) {
+	sc := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": GroupVersion,
+		"kind":       "StoppableContainer",
+		"metadata":   map[string]interface{}{"name": "my-app", "namespace": "default"},
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"containers": []interface{}{
+						map[string]interface{}{"name": "main", "image": "nginx:1.25"},
+						map[string]interface{}{"name": "sidecar", "image": "busybox:stable"},
+					},
+				},
+			},
+		},
+	}}
+
+	image, err := getImage(sc)
+	if err != nil {
+		t.Fatalf("getImage() error = %v", err)
+	}
+	if image != "nginx:1.25" {
+		t.Errorf("getImage() = %q, want %q", image, "nginx:1.25")
+	}
+
+	patch, err := buildImagePatch("nginx:1.27")
+	if err != nil {
+		t.Fatalf("buildImagePatch() error = %v", err)
+	}
+	expected := `[{"op":"replace","path":"/spec/template/spec/containers/0/image","value":"nginx:1.27"}]`
+	if string(patch) != expected {
+		t.Errorf("buildImagePatch() = %s, want %s", patch, expected)
+	}
+
+	// Apply the patch and check only the main container's image changed
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{scGVR: "StoppableContainerList"}, sc)
+	patched, err := client.Resource(scGVR).Namespace("default").Patch(context.Background(), "my-app",
+		types.JSONPatchType, patch, metav1.PatchOptions{})
+	if err != nil {
+		t.Fatalf("Patch() error = %v", err)
+	}
+	if image, _ := getImage(patched); image != "nginx:1.27" {
+		t.Errorf("patched image = %q, want %q", image, "nginx:1.27")
+	}
+	containers, _, _ := unstructured.NestedSlice(patched.Object, "spec", "template", "spec", "containers")
+	if sidecar, _, _ := unstructured.NestedString(containers[1].(map[string]interface{}), "image"); sidecar != "busybox:stable" {
+		t.Errorf("sidecar image = %q, want %q", sidecar, "busybox:stable")
+	}
+
+	if _, err := buildImagePatch(""); err == nil {
+		t.Error("buildImagePatch(\"\") expected error")
+	}
+
+	if _, err := getImage(&unstructured.Unstructured{Object: map[string]interface{}{}}); err == nil {
+		t.Error("getImage() expected error without containers")
+	}
+}
+
+func TestResolvedImage(t *testing.T) {
+	sc := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": GroupVersion,
+		"kind":       "StoppableContainer",
+		"metadata":   map[string]interface{}{"name": "my-app", "namespace": "default"},
+	}}
+	pod := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Pod",
+		"metadata":   map[string]interface{}{"name": "my-app-provider", "namespace": "default"},
+		"status": map[string]interface{}{
+			"containerStatuses": []interface{}{
+				map[string]interface{}{"name": "provider", "imageID": "docker.io/library/busybox@sha256:aaa"},
+				map[string]interface{}{"name": "rootfs", "imageID": "docker.io/library/nginx@sha256:bbb"},
+			},
+		},
+	}}
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{podGVR: "PodList"}, pod)
+
+	// Not started yet: no provider pod in the status
+	if got := resolvedImage(client, "default", sc); got != "" {
+		t.Errorf("resolvedImage() without provider pod = %q, want empty", got)
+	}
+
+	sc.Object["status"] = map[string]interface{}{"providerPodName": "my-app-provider"}
+	if got := resolvedImage(client, "default", sc); got != "docker.io/library/nginx@sha256:bbb" {
+		t.Errorf("resolvedImage() = %q, want the rootfs container's image ID", got)
+	}
+}
+
+func TestSummarizeEdit(t *testing.T) {
+	newSC := func(resourceVersion string, running bool, image string, command ...interface{}) *unstructured.Unstructured {
+		container := map[string]interface{}{"name": "main", "image": image}
+		if len(command) > 0 {
+			container["command"] = command
+		}
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"metadata": map[string]interface{}{"name": "my-app", "resourceVersion": resourceVersion},
+			"spec": map[string]interface{}{
+				"running": running,
+				"template": map[string]interface{}{
+					"spec": map[string]interface{}{
+						"containers": []interface{}{container},
+					},
+				},
+			},
+		}}
+	}
+
+	before := newSC("1", false, "nginx:1.25")
+	statusOnly := newSC("2", false, "nginx:1.25")
+	statusOnly.Object["status"] = map[string]interface{}{"phase": "Stopped"}
+	otherFields := newSC("2", false, "nginx:1.25")
+	otherFields.Object["spec"].(map[string]interface{})["replicas"] = int64(2)
+	labeled := newSC("2", false, "nginx:1.25")
+	labeled.SetLabels(map[string]string{"team": "web"})
+	tests := []struct {
+		name  string
+		after *unstructured.Unstructured
+		want  []string
+	}{
+		{"unchanged", newSC("1", false, "nginx:1.25"), []string{"No changes"}},
+		{"status update by the controller", statusOnly, []string{"No changes"}},
+		{"other fields", otherFields, []string{"Changed fields other than running, image and command"}},
+		{"labels", labeled, []string{"Changed fields other than running, image and command"}},
+		{"running", newSC("2", true, "nginx:1.25"), []string{"running: false -> true"}},
+		{
+			"image and command",
+			newSC("2", false, "nginx:1.27", "nginx", "-g", "daemon off;"),
+			[]string{"image: nginx:1.25 -> nginx:1.27", `command: <image default> -> ["nginx" "-g" "daemon off;"]`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := summarizeEdit(before, tt.after); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("summarizeEdit() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCloneStoppableContainer(t *testing.T) {
+	src := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": GroupVersion,
+		"kind":       "StoppableContainer",
+		"metadata": map[string]interface{}{
+			"name":              "my-app",
+			"namespace":         "default",
+			"uid":               "1234",
+			"resourceVersion":   "42",
+			"generation":        int64(3),
+			"creationTimestamp": "2026-01-01T00:00:00Z",
+			"finalizers":        []interface{}{"stoppablecontainer.xtlsoft.top/finalizer"},
+			"labels":            map[string]interface{}{"team": "a"},
+			"annotations": map[string]interface{}{
+				"note": "keep",
+				"kubectl.kubernetes.io/last-applied-configuration": "{}",
+			},
+		},
+		"spec": map[string]interface{}{
+			"running": true,
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"containers": []interface{}{
+						map[string]interface{}{"name": "main", "image": "nginx:1.25"},
+					},
+				},
+			},
+		},
+		"status": map[string]interface{}{"phase": "Running"},
+	}}
+
+	dst, err := cloneStoppableContainer(src, "my-app-2", "staging", "")
+	if err != nil {
+		t.Fatalf("cloneStoppableContainer() error = %v", err)
+	}
+
+	if dst.GetName() != "my-app-2" || dst.GetNamespace() != "staging" {
+		t.Errorf("clone = %s/%s, want staging/my-app-2", dst.GetNamespace(), dst.GetName())
+	}
+	creationTimestamp := dst.GetCreationTimestamp()
+	if dst.GetUID() != "" || dst.GetResourceVersion() != "" || dst.GetGeneration() != 0 ||
+		!creationTimestamp.IsZero() || len(dst.GetFinalizers()) != 0 {
+		t.Errorf("server-set metadata not stripped: %v", dst.Object["metadata"])
+	}
+	if _, ok := dst.Object["status"]; ok {
+		t.Error("status not stripped")
+	}
+	if !reflect.DeepEqual(dst.GetLabels(), map[string]string{"team": "a"}) {
+		t.Errorf("labels = %v", dst.GetLabels())
+	}
+	if !reflect.DeepEqual(dst.GetAnnotations(), map[string]string{"note": "keep"}) {
+		t.Errorf("annotations = %v", dst.GetAnnotations())
+	}
+	if !reflect.DeepEqual(dst.Object["spec"], src.Object["spec"]) {
+		t.Errorf("spec = %v, want %v", dst.Object["spec"], src.Object["spec"])
+	}
+
+	// Overriding the image must not touch the source
+	dst, err = cloneStoppableContainer(src, "my-app-2", "default", "nginx:1.27")
+	if err != nil {
+		t.Fatalf("cloneStoppableContainer() error = %v", err)
+	}
+	if image, _ := getImage(dst); image != "nginx:1.27" {
+		t.Errorf("clone image = %q, want %q", image, "nginx:1.27")
+	}
+	if image, _ := getImage(src); image != "nginx:1.25" {
+		t.Errorf("source image changed to %q", image)
+	}
+
+	if _, err := cloneStoppableContainer(&unstructured.Unstructured{Object: map[string]interface{}{}}, "x", "default", ""); err == nil {
+		t.Error("cloneStoppableContainer() expected error without spec")
+	}
+}
+
+func TestRestartStoppableContainer(t *testing.T) {
+	newSC := func(running bool, phase string) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": GroupVersion,
+			"kind":       "StoppableContainer",
+			"metadata":   map[string]interface{}{"name": "my-app", "namespace": "default"},
+			"spec":       map[string]interface{}{"running": running},
+			"status":     map[string]interface{}{"phase": phase},
+		}}
+	}
+	newClient := func(sc *unstructured.Unstructured) *dynamicfake.FakeDynamicClient {
+		return dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+			map[schema.GroupVersionResource]string{scGVR: "StoppableContainerList"}, sc)
+	}
+	// runningPatches returns the spec.running values patched, in order
+	runningPatches := func(client *dynamicfake.FakeDynamicClient) []string {
+		var patches []string
+		for _, action := range client.Actions() {
+			if action.GetVerb() == "patch" {
+				patches = append(patches, string(action.(clienttesting.PatchAction).GetPatch()))
+			}
+		}
+		return patches
+	}
+
+	t.Run("already stopped is started", func(t *testing.T) {
+		client := newClient(newSC(false, "Stopped"))
+		if err := restartStoppableContainer(client, "default", "my-app", false, time.Second); err != nil {
+			t.Fatalf("restartStoppableContainer() error = %v", err)
+		}
+		expected := []string{`{"spec":{"running":true}}`}
+		if got := runningPatches(client); !reflect.DeepEqual(got, expected) {
+			t.Errorf("patches = %v, want %v", got, expected)
+		}
+	})
+
+	t.Run("running is stopped then started", func(t *testing.T) {
+		// The phase already reads Stopped so the wait returns on the first poll
+		client := newClient(newSC(true, "Stopped"))
+		if err := restartStoppableContainer(client, "default", "my-app", false, 5*time.Second); err != nil {
+			t.Fatalf("restartStoppableContainer() error = %v", err)
+		}
+		expected := []string{`{"spec":{"running":false}}`, `{"spec":{"running":true}}`}
+		if got := runningPatches(client); !reflect.DeepEqual(got, expected) {
+			t.Errorf("patches = %v, want %v", got, expected)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		client := newClient(newSC(true, "Running"))
+		if err := restartStoppableContainer(client, "default", "missing", false, time.Second); err == nil {
+			t.Error("expected error for missing StoppableContainer")
+		}
+	})
+}
+
+func TestShouldAutoTTY(t *testing.T) {
+	tests := []struct {
+		name     string
+		command  []string
+		stdin    bool
+		stdout   bool
+		expected bool
+	}{
+		{"shell on terminal", []string{"/bin/bash"}, true, true, true},
+		{"bare shell name", []string{"sh"}, true, true, true},
+		{"login shell", []string{"/bin/zsh", "-l"}, true, true, true},
+		{"shell with -c", []string{"/bin/sh", "-c", "ls"}, true, true, false},
+		{"not a shell", []string{"ls", "-la"}, true, true, false},
+		{"stdin piped", []string{"/bin/bash"}, false, true, false},
+		{"stdout redirected", []string{"/bin/bash"}, true, false, false},
+		{"empty command runs a shell", nil, true, true, true},
+		{"empty command piped", nil, false, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := shouldAutoTTY(tt.command, tt.stdin, tt.stdout); got != tt.expected {
+				t.Errorf("shouldAutoTTY(%v, %v, %v) = %v, want %v", tt.command, tt.stdin, tt.stdout, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestBuildExecArgs(t *testing.T) {
+	tests := []struct {
+		name      string
+		container string
+		stdin     bool
+		tty       bool
+		command   []string
+		expected  []string
+	}{
+		{
+			name:     "command",
+			command:  []string{"ls", "-la", "/"},
+			expected: []string{"exec", "-n", "default", "my-app", "--", "/.sc-bin/sc-exec", "ls", "-la", "/"},
+		},
+		{
+			name:      "interactive with container",
+			container: "main",
+			stdin:     true,
+			tty:       true,
+			command:   []string{"/bin/bash"},
+			expected:  []string{"exec", "-i", "-t", "-n", "default", "-c", "main", "my-app", "--", "/.sc-bin/sc-exec", "/bin/bash"},
+		},
+		{
+			name:     "no command detects the shell",
+			stdin:    true,
+			tty:      true,
+			expected: []string{"exec", "-i", "-t", "-n", "default", "my-app", "--", "/.sc-bin/sc-exec", "--shell"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := buildExecArgs("default", "my-app", tt.container, tt.stdin, tt.tty, tt.command)
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("buildExecArgs() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestResolveConsumerPod_Replicas(t *testing.T) {
+	// With more than one replica the consumer pods are named <name>-consumer-<ordinal>
+	sc := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": GroupVersion,
+		"kind":       "StoppableContainer",
+		"metadata":   map[string]interface{}{"name": "my-app", "namespace": "default"},
+		"spec":       map[string]interface{}{"replicas": int64(3)},
+		"status":     map[string]interface{}{"consumerPodName": "my-app-consumer-0"},
+	}}
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{scGVR: "StoppableContainerList"}, sc)
+
+	podName, err := resolveConsumerPod(client, "default", "my-app")
+	if err != nil {
+		t.Fatalf("resolveConsumerPod() error = %v", err)
+	}
+
+	execArgs := buildExecArgs("default", podName, "", false, false, []string{"ls"})
+	wantExec := []string{"exec", "-n", "default", "my-app-consumer-0", "--", "/.sc-bin/sc-exec", "ls"}
+	if !reflect.DeepEqual(execArgs, wantExec) {
+		t.Errorf("buildExecArgs() = %v, want %v", execArgs, wantExec)
+	}
+
+	logsArgs := buildLogsArgs("default", podName, "", true, 10, false, false)
+	wantLogs := []string{"logs", "-n", "default", "-f", "--tail", "10", "my-app-consumer-0"}
+	if !reflect.DeepEqual(logsArgs, wantLogs) {
+		t.Errorf("buildLogsArgs() = %v, want %v", logsArgs, wantLogs)
+	}
+}
+
+func TestExecCmd_CombinedShortFlags(t *testing.T) {
+	for _, flags := range [][]string{{"-it"}, {"-ti"}, {"-i", "-t"}} {
+		cmd := execCmd()
+		args := append(append([]string{"my-app"}, flags...), "--", "sh")
+		if err := cmd.ParseFlags(args); err != nil {
+			t.Fatalf("ParseFlags(%v) error = %v", args, err)
+		}
+		stdin, _ := cmd.Flags().GetBool("stdin")
+		tty, _ := cmd.Flags().GetBool("tty")
+		if !stdin || !tty {
+			t.Errorf("ParseFlags(%v): stdin = %v, tty = %v, want both set", args, stdin, tty)
+		}
+		if got := cmd.Flags().Args(); !reflect.DeepEqual(got, []string{"my-app", "sh"}) {
+			t.Errorf("ParseFlags(%v): args = %v, want [my-app sh]", args, got)
+		}
+	}
+}
+
+func TestDescribeStoppableContainer(t *testing.T) {
+	sc := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": GroupVersion,
+		"kind":       "StoppableContainer",
+		"metadata":   map[string]interface{}{"name": "my-app", "namespace": "default"},
+		"spec": map[string]interface{}{
+			"running": true,
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"containers": []interface{}{map[string]interface{}{"name": "main", "image": "nginx:1.25"}},
+				},
+			},
+		},
+		"status": map[string]interface{}{
+			"phase": "Running",
+			"conditions": []interface{}{
+				map[string]interface{}{"type": "Ready", "status": "True", "reason": "Running", "message": "All pods running"},
+			},
+		},
+	}}
+	listKinds := map[schema.GroupVersionResource]string{
+		scGVR:  "StoppableContainerList",
+		sciGVR: "StoppableContainerInstanceList",
+		podGVR: "PodList",
+	}
+
+	t.Run("instance and pods not created", func(t *testing.T) {
+		client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds, sc.DeepCopy())
+		var buf strings.Builder
+		pods, err := describeStoppableContainer(client, "default", "my-app", &buf)
+		if err != nil {
+			t.Fatalf("describeStoppableContainer() error = %v", err)
+		}
+		if len(pods) != 0 {
+			t.Errorf("pods = %v, want none", pods)
+		}
+		out := buf.String()
+		for _, want := range []string{"Image:       nginx:1.25", "Ready: True (Running) All pods running",
+			"Instance my-app:\n  not created", "Provider pod my-app-provider:\n  not created",
+			"Consumer pod my-app:\n  not created"} {
+			if !strings.Contains(out, want) {
+				t.Errorf("output missing %q:\n%s", want, out)
+			}
+		}
+	})
+
+	t.Run("with instance and pods", func(t *testing.T) {
+		sci := &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": GroupVersion,
+			"kind":       "StoppableContainerInstance",
+			"metadata":   map[string]interface{}{"name": "my-app", "namespace": "default"},
+			"status": map[string]interface{}{
+				"phase":           "Running",
+				"nodeName":        "node-1",
+				"providerPodName": "my-app-provider",
+				"consumerPodName": "my-app",
+			},
+		}}
+		newPod := func(name string, status map[string]interface{}) *unstructured.Unstructured {
+			return &unstructured.Unstructured{Object: map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "Pod",
+				"metadata":   map[string]interface{}{"name": name, "namespace": "default"},
+				"spec":       map[string]interface{}{"nodeName": "node-1"},
+				"status":     status,
+			}}
+		}
+		providerPod := newPod("my-app-provider", map[string]interface{}{
+			"phase": "Running",
+			"containerStatuses": []interface{}{map[string]interface{}{
+				"name": "provider", "ready": true, "restartCount": int64(0),
+				"state": map[string]interface{}{"running": map[string]interface{}{}},
+			}},
+		})
+		consumerPod := newPod("my-app", map[string]interface{}{
+			"phase": "Pending",
+			"containerStatuses": []interface{}{map[string]interface{}{
+				"name": "main", "ready": false, "restartCount": int64(3),
+				"state": map[string]interface{}{"waiting": map[string]interface{}{"reason": "CrashLoopBackOff"}},
+			}},
+		})
+		client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds,
+			sc.DeepCopy(), sci, providerPod, consumerPod)
+		var buf strings.Builder
+		pods, err := describeStoppableContainer(client, "default", "my-app", &buf)
+		if err != nil {
+			t.Fatalf("describeStoppableContainer() error = %v", err)
+		}
+		if !reflect.DeepEqual(pods, []string{"my-app-provider"}) {
+			t.Errorf("pods = %v, want [my-app-provider]", pods)
+		}
+		out := buf.String()
+		for _, want := range []string{"  Node:      node-1", "Container provider: Running, ready=true, restarts=0",
+			"Container main: Waiting (CrashLoopBackOff), ready=false, restarts=3"} {
+			if !strings.Contains(out, want) {
+				t.Errorf("output missing %q:\n%s", want, out)
+			}
+		}
+		if strings.Contains(out, "not created") {
+			t.Errorf("unexpected not created section:\n%s", out)
+		}
+	})
+
+	t.Run("missing StoppableContainer", func(t *testing.T) {
+		client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds)
+		if _, err := describeStoppableContainer(client, "default", "my-app", &strings.Builder{}); err == nil {
+			t.Error("expected error for missing StoppableContainer")
+		}
+	})
+}
+
+func TestListInstances(t *testing.T) {
+	sc := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": GroupVersion,
+		"kind":       "StoppableContainer",
+		"metadata":   map[string]interface{}{"name": "my-app", "namespace": "default", "uid": "sc-uid"},
+	}}
+	newSCI := func(name, ownerUID string, status map[string]interface{}) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": GroupVersion,
+			"kind":       "StoppableContainerInstance",
+			"metadata": map[string]interface{}{
+				"name":      name,
+				"namespace": "default",
+				"ownerReferences": []interface{}{map[string]interface{}{
+					"apiVersion": GroupVersion, "kind": "StoppableContainer", "name": "my-app", "uid": ownerUID,
+				}},
+			},
+			"status": status,
+		}}
+	}
+	owned := newSCI("my-app", "sc-uid", map[string]interface{}{
+		"phase":           "Running",
+		"nodeName":        "node-1",
+		"providerPodName": "my-app-provider",
+		"consumerPodName": "my-app",
+		"hostPath":        "/var/lib/stoppablecontainer/default/my-app",
+	})
+	pending := newSCI("my-app-2", "sc-uid", map[string]interface{}{"phase": "Pending"})
+	// A leftover of an earlier StoppableContainer with the same name
+	other := newSCI("my-app-old", "old-uid", map[string]interface{}{"phase": "Stopped"})
+
+	listKinds := map[schema.GroupVersionResource]string{
+		scGVR:  "StoppableContainerList",
+		sciGVR: "StoppableContainerInstanceList",
+	}
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds,
+		sc, owned, pending, other)
+
+	instances, err := listInstances(client, "default", "my-app")
+	if err != nil {
+		t.Fatalf("listInstances() error = %v", err)
+	}
+	var names []string
+	for _, sci := range instances {
+		names = append(names, sci.GetName())
+	}
+	if !reflect.DeepEqual(names, []string{"my-app", "my-app-2"}) {
+		t.Fatalf("listInstances() = %v, want [my-app my-app-2]", names)
+	}
+
+	var buf strings.Builder
+	if err := printInstances(&buf, instances); err != nil {
+		t.Fatalf("printInstances() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("printInstances() printed %d lines, want 3:\n%s", len(lines), buf.String())
+	}
+	for i, want := range [][]string{
+		{"NAME", "PHASE", "NODE", "PROVIDER", "CONSUMER", "HOST PATH", "AGE"},
+		{"my-app", "Running", "node-1", "my-app-provider", "my-app", "/var/lib/stoppablecontainer/default/my-app"},
+		{"my-app-2", "Pending", "<none>", "<none>", "<none>", "<none>"},
+	} {
+		for _, field := range want {
+			if !strings.Contains(lines[i], field) {
+				t.Errorf("line %d = %q, missing %q", i, lines[i], field)
+			}
+		}
+	}
+
+	if _, err := listInstances(client, "default", "missing"); err == nil {
+		t.Error("listInstances() expected error for missing StoppableContainer")
+	}
+}
+
+func TestEvents(t *testing.T) {
+	sc := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": GroupVersion,
+		"kind":       "StoppableContainer",
+		"metadata":   map[string]interface{}{"name": "my-app", "namespace": "default"},
+		"spec":       map[string]interface{}{"replicas": int64(3)},
+		"status":     map[string]interface{}{"instanceName": "my-app"},
+	}}
+	replicaPod := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Pod",
+		"metadata": map[string]interface{}{
+			"name": "my-app-consumer-1", "namespace": "default",
+			"labels": map[string]interface{}{LabelInstance: "my-app"},
+		},
+	}}
+	newEvent := func(name, kind, object, reason, lastTimestamp string) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion":     "v1",
+			"kind":           "Event",
+			"metadata":       map[string]interface{}{"name": name, "namespace": "default"},
+			"involvedObject": map[string]interface{}{"kind": kind, "name": object},
+			"type":           "Normal",
+			"reason":         reason,
+			"message":        reason + " happened",
+			"lastTimestamp":  lastTimestamp,
+		}}
+	}
+	listKinds := map[schema.GroupVersionResource]string{
+		scGVR:    "StoppableContainerList",
+		podGVR:   "PodList",
+		eventGVR: "EventList",
+	}
+	newClient := func() *dynamicfake.FakeDynamicClient {
+		return dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds,
+			sc.DeepCopy(), replicaPod,
+			newEvent("e1", "Pod", "my-app-provider", "Scheduled", "2026-01-01T10:00:02Z"),
+			newEvent("e2", "StoppableContainer", "my-app", "Created", "2026-01-01T10:00:00Z"),
+			newEvent("e3", "StoppableContainerInstance", "my-app", "RootfsLost", "2026-01-01T10:00:03Z"),
+			newEvent("e4", "Pod", "my-app-consumer-1", "Started", "2026-01-01T10:00:04Z"),
+			newEvent("e5", "Pod", "other-app", "Scheduled", "2026-01-01T10:00:01Z"),
+			newEvent("e6", "Deployment", "my-app", "ScalingReplicaSet", "2026-01-01T10:00:01Z"),
+		)
+	}
+
+	t.Run("list sorted by time", func(t *testing.T) {
+		client := newClient()
+		sources, err := findEventSources(context.Background(), client, "default", "my-app")
+		if err != nil {
+			t.Fatalf("findEventSources() error = %v", err)
+		}
+		events, resourceVersions, err := listEvents(context.Background(), client, "default", sources)
+		if err != nil {
+			t.Fatalf("listEvents() error = %v", err)
+		}
+		var reasons []string
+		for _, e := range events {
+			reasons = append(reasons, e.Object["reason"].(string))
+		}
+		if want := []string{"Created", "Scheduled", "RootfsLost", "Started"}; !reflect.DeepEqual(reasons, want) {
+			t.Errorf("reasons = %v, want %v", reasons, want)
+		}
+		if len(resourceVersions) != len(sources.fieldSelectors()) {
+			t.Errorf("got %d resource versions for %d selectors", len(resourceVersions), len(sources.fieldSelectors()))
+		}
+
+		// Only the events of the sources are requested
+		for _, action := range client.Actions() {
+			list, ok := action.(clienttesting.ListAction)
+			if !ok || action.GetResource() != eventGVR {
+				continue
+			}
+			if selector := list.GetListRestrictions().Fields.String(); !strings.Contains(selector, "involvedObject.name=") {
+				t.Errorf("events listed with field selector %q, want an involvedObject selector", selector)
+			}
+		}
+	})
+
+	t.Run("watch", func(t *testing.T) {
+		client := newClient()
+		sources, err := findEventSources(context.Background(), client, "default", "my-app")
+		if err != nil {
+			t.Fatalf("findEventSources() error = %v", err)
+		}
+		selectorOf := func(kind, name string) string {
+			return "involvedObject.kind=" + kind + ",involvedObject.name=" + name
+		}
+		// The consumer pod of replica 2 does not exist yet, but its events are watched
+		consumerWatch, instanceWatch := watch.NewFake(), watch.NewFake()
+		watchers := map[string]*watch.FakeWatcher{
+			selectorOf("Pod", "my-app-consumer-2"):             consumerWatch,
+			selectorOf("StoppableContainerInstance", "my-app"): instanceWatch,
+		}
+		var mu sync.Mutex
+		watched := make(map[string]bool)
+		client.PrependWatchReactor("events", func(action clienttesting.Action) (bool, watch.Interface, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			selector := action.(clienttesting.WatchAction).GetWatchRestrictions().Fields.String()
+			if w, ok := watchers[selector]; ok && !watched[selector] {
+				watched[selector] = true
+				return true, w, nil
+			}
+			watched[selector] = true
+			return true, watch.NewFake(), nil
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		var buf lockedBuffer
+		done := make(chan error)
+		go func() {
+			done <- watchEventStream(ctx, client, "default", sources, nil, &buf)
+		}()
+
+		// An event of another object that a server ignoring the selector would send
+		consumerWatch.Add(newEvent("e7", "Pod", "other-app", "Killing", "2026-01-01T10:00:05Z"))
+		consumerWatch.Add(newEvent("e8", "Pod", "my-app-consumer-2", "Pulled", "2026-01-01T10:00:06Z"))
+		instanceWatch.Modify(newEvent("e3", "StoppableContainerInstance", "my-app", "RootfsLost", "2026-01-01T10:00:07Z"))
+
+		// An event recorded while the watch is down is found by the relist after a 410 Gone
+		missed := newEvent("e9", "StoppableContainerInstance", "my-app", "Recovered",
+			time.Now().Add(time.Minute).UTC().Format(time.RFC3339))
+		if _, err := client.Resource(eventGVR).Namespace("default").Create(context.Background(), missed, metav1.CreateOptions{}); err != nil {
+			t.Fatalf("creating event: %v", err)
+		}
+		instanceWatch.Error(&metav1.Status{
+			Status:  metav1.StatusFailure,
+			Code:    410,
+			Reason:  metav1.StatusReasonExpired,
+			Message: "too old resource version",
+		})
+
+		deadline := time.Now().Add(5 * time.Second)
+		for !strings.Contains(buf.String(), "Recovered") && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		cancel()
+		if err := <-done; err != nil {
+			t.Fatalf("watchEventStream() error = %v", err)
+		}
+
+		out := buf.String()
+		for _, want := range []string{"pod/my-app-consumer-2  Normal  Pulled  Pulled happened",
+			"stoppablecontainerinstance/my-app  Normal  RootfsLost",
+			"stoppablecontainerinstance/my-app  Normal  Recovered"} {
+			if !strings.Contains(out, want) {
+				t.Errorf("output missing %q:\n%s", want, out)
+			}
+		}
+		if strings.Contains(out, "other-app") {
+			t.Errorf("output has events of another object:\n%s", out)
+		}
+		if got := strings.Count(out, "Normal  RootfsLost"); got != 1 {
+			t.Errorf("RootfsLost printed %d times, want once:\n%s", got, out)
+		}
+	})
+
+	t.Run("watch error", func(t *testing.T) {
+		client := newClient()
+		client.PrependWatchReactor("events", func(clienttesting.Action) (bool, watch.Interface, error) {
+			return true, nil, errors.New("watch refused")
+		})
+		sources, err := findEventSources(context.Background(), client, "default", "my-app")
+		if err != nil {
+			t.Fatalf("findEventSources() error = %v", err)
+		}
+		if err := watchEventStream(context.Background(), client, "default", sources, nil, &strings.Builder{}); err == nil {
+			t.Error("expected the failed watch to be returned")
+		}
+	})
+}
+
+// lockedBuffer is a strings.Builder safe for one writer and concurrent readers
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf strings.Builder
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestWatchStatusChanges(t *testing.T) {
+	newSC := func(phase string, conditions ...interface{}) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": GroupVersion,
+			"kind":       "StoppableContainer",
+			"metadata":   map[string]interface{}{"name": "my-app", "namespace": "default", "resourceVersion": "1"},
+			"spec":       map[string]interface{}{"running": true},
+			"status":     map[string]interface{}{"phase": phase, "conditions": conditions},
+		}}
+	}
+	ready := func(status, reason string) interface{} {
+		return map[string]interface{}{"type": "Ready", "status": status, "reason": reason}
+	}
+
+	for _, tt := range []struct {
+		name  string
+		clear bool
+	}{
+		{name: "append when not a terminal"},
+		{name: "clear on a terminal", clear: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+				map[schema.GroupVersionResource]string{scGVR: "StoppableContainerList"})
+			fakeWatch := watch.NewFake()
+			client.PrependWatchReactor("stoppablecontainers", func(clienttesting.Action) (bool, watch.Interface, error) {
+				return true, fakeWatch, nil
+			})
+			go func() {
+				fakeWatch.Modify(newSC("Pending", ready("Unknown", "Unknown")))
+				fakeWatch.Modify(newSC("Running", ready("True", "Running")))
+				// A change that does not show in the status is not printed again
+				fakeWatch.Modify(newSC("Running", ready("True", "Running")))
+				fakeWatch.Delete(newSC("Running", ready("True", "Running")))
+			}()
+
+			var buf strings.Builder
+			if err := watchStatusChanges(context.Background(), client, newSC(""), tt.clear, &buf); err != nil {
+				t.Fatalf("watchStatusChanges() error = %v", err)
+			}
+			out := buf.String()
+			if got := strings.Count(out, "Name:        my-app"); got != 3 {
+				t.Errorf("status printed %d times, want 3:\n%s", got, out)
+			}
+			if got, want := strings.Count(out, clearScreen), map[bool]int{false: 0, true: 3}[tt.clear]; got != want {
+				t.Errorf("screen cleared %d times, want %d", got, want)
+			}
+			for _, want := range []string{"Phase:       Pending", "Ready: True (Running)", "StoppableContainer my-app was deleted"} {
+				if !strings.Contains(out, want) {
+					t.Errorf("output missing %q:\n%s", want, out)
+				}
+			}
+		})
+	}
+}
+
+func TestFormatEvent(t *testing.T) {
+	event := &unstructured.Unstructured{Object: map[string]interface{}{
+		"involvedObject": map[string]interface{}{"kind": "Pod", "name": "my-app"},
+		"type":           "Warning",
+		"reason":         "BackOff",
+		"message":        "Back-off restarting failed container\n",
+		"count":          int64(3),
+		"eventTime":      "2026-01-01T10:00:00.000000Z",
+	}}
+	want := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC).Local().Format("2006-01-02 15:04:05") +
+		"  pod/my-app  Warning  BackOff  Back-off restarting failed container (x3)"
+	if got := formatEvent(event); got != want {
+		t.Errorf("formatEvent() = %q, want %q", got, want)
+	}
+}
+
+func TestPodUsage(t *testing.T) {
+	newMetrics := func(ns, pod, instance, role string, usage ...map[string]interface{}) *unstructured.Unstructured {
+		var containers []interface{}
+		for _, u := range usage {
+			containers = append(containers, map[string]interface{}{"name": "c", "usage": u})
+		}
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "metrics.k8s.io/v1beta1",
+			"kind":       "PodMetrics",
+			"metadata": map[string]interface{}{
+				"name": pod, "namespace": ns,
+				"labels": map[string]interface{}{LabelInstance: instance, LabelRole: role},
+			},
+			"containers": containers,
+		}}
+	}
+	listKinds := map[schema.GroupVersionResource]string{podMetricsGVR: "PodMetricsList"}
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds)
+	// Created through the GVR, the fake client would guess "podmetricses" from the kind
+	for _, m := range []*unstructured.Unstructured{
+		newMetrics("default", "my-app-provider", "my-app", "provider",
+			map[string]interface{}{"cpu": "1m", "memory": "8Mi"},
+			map[string]interface{}{"cpu": "500u", "memory": "4Mi"}),
+		newMetrics("default", "my-app", "my-app", "consumer",
+			map[string]interface{}{"cpu": "250m", "memory": "256Mi"}),
+		newMetrics("default", "other", "other", "consumer",
+			map[string]interface{}{"cpu": "1", "memory": "1Gi"}),
+		newMetrics("team-b", "web", "web", "consumer",
+			map[string]interface{}{"cpu": "2m", "memory": "16Mi"}),
+	} {
+		if _, err := client.Resource(podMetricsGVR).Namespace(m.GetNamespace()).Create(
+			context.Background(), m, metav1.CreateOptions{}); err != nil {
+			t.Fatalf("creating pod metrics: %v", err)
+		}
+	}
+
+	t.Run("one container", func(t *testing.T) {
+		usages, err := listPodUsage(context.Background(), client, "default", LabelInstance+"=my-app", false)
+		if err != nil {
+			t.Fatalf("listPodUsage() error = %v", err)
+		}
+		var buf strings.Builder
+		if err := printPodUsage(&buf, usages, false); err != nil {
+			t.Fatalf("printPodUsage() error = %v", err)
+		}
+		want := "NAME    ROLE      POD              CPU(cores)  MEMORY(bytes)\n" +
+			"my-app  consumer  my-app           250m        256Mi\n" +
+			"my-app  provider  my-app-provider  2m          12Mi\n"
+		if buf.String() != want {
+			t.Errorf("output =\n%s\nwant\n%s", buf.String(), want)
+		}
+	})
+
+	t.Run("all namespaces", func(t *testing.T) {
+		usages, err := listPodUsage(context.Background(), client, "", LabelInstance, true)
+		if err != nil {
+			t.Fatalf("listPodUsage() error = %v", err)
+		}
+		var pods []string
+		for _, u := range usages {
+			pods = append(pods, u.namespace+"/"+u.pod)
+		}
+		want := []string{"default/my-app", "default/my-app-provider", "default/other", "team-b/web"}
+		if !reflect.DeepEqual(pods, want) {
+			t.Errorf("pods = %v, want %v", pods, want)
+		}
+		var buf strings.Builder
+		if err := printPodUsage(&buf, usages, true); err != nil {
+			t.Fatalf("printPodUsage() error = %v", err)
+		}
+		if !strings.HasPrefix(buf.String(), "NAMESPACE  NAME") {
+			t.Errorf("output missing NAMESPACE column:\n%s", buf.String())
+		}
+	})
+
+	t.Run("metrics API missing", func(t *testing.T) {
+		client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds)
+		client.PrependReactor("list", "pods", func(clienttesting.Action) (bool, runtime.Object, error) {
+			return true, nil, apierrors.NewNotFound(schema.GroupResource{Group: "metrics.k8s.io", Resource: "pods"}, "")
+		})
+		_, err := listPodUsage(context.Background(), client, "default", LabelInstance, false)
+		if err == nil || !strings.Contains(err.Error(), "is metrics-server installed?") {
+			t.Errorf("listPodUsage() error = %v, want a hint about metrics-server", err)
+		}
+	})
+}
+
+func TestParseWaitFor(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    waitCondition
+		wantErr bool
+	}{
+		{value: "phase=Running", want: waitCondition{phase: "Running"}},
+		{value: "condition=Ready", want: waitCondition{conditionType: "Ready", conditionStatus: "True"}},
+		{value: "condition=Ready=false", want: waitCondition{conditionType: "Ready", conditionStatus: "False"}},
+		{value: "phase=", wantErr: true},
+		{value: "condition==True", wantErr: true},
+		{value: "Running", wantErr: true},
+		{value: "delete", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseWaitFor(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseWaitFor(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseWaitFor(%q) = %+v, want %+v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWaitFor(t *testing.T) {
+	newSC := func(phase, ready string) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": GroupVersion,
+			"kind":       "StoppableContainer",
+			"metadata":   map[string]interface{}{"name": "my-app", "namespace": "default"},
+			"status": map[string]interface{}{
+				"phase":      phase,
+				"message":    "provider image pull failed",
+				"conditions": []interface{}{map[string]interface{}{"type": "Ready", "status": ready}},
+			},
+		}}
+	}
+	listKinds := map[schema.GroupVersionResource]string{scGVR: "StoppableContainerList"}
+
+	if err := waitFor(dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds,
+		newSC("Running", "True")), "default", "my-app", waitCondition{conditionType: "Ready", conditionStatus: "True"},
+		5*time.Second); err != nil {
+		t.Errorf("waitFor(condition Ready) error = %v", err)
+	}
+
+	err := waitFor(dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds,
+		newSC("Failed", "False")), "default", "my-app", waitCondition{phase: "Running"}, 5*time.Second)
+	if err == nil || !strings.Contains(err.Error(), "provider image pull failed") {
+		t.Errorf("waitFor() on a failed container error = %v, want the failure message", err)
+	}
+
+	if !(waitCondition{phase: "Failed"}).met(newSC("Failed", "False")) {
+		t.Error("waiting for the Failed phase should be met by a failed container")
+	}
+}
+
+func TestListStoppableContainerNames(t *testing.T) {
+	newSC := func(name, ns string) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": GroupVersion,
+			"kind":       "StoppableContainer",
+			"metadata":   map[string]interface{}{"name": name, "namespace": ns},
+		}}
+	}
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{scGVR: "StoppableContainerList"},
+		newSC("web", "default"), newSC("worker", "default"), newSC("db", "default"), newSC("web-2", "team-a"))
+
+	tests := []struct {
+		name          string
+		ns            string
+		allNamespaces bool
+		prefix        string
+		expected      []string
+	}{
+		{"namespace", "default", false, "", []string{"db", "web", "worker"}},
+		{"prefix", "default", false, "w", []string{"web", "worker"}},
+		{"other namespace", "team-a", false, "", []string{"web-2"}},
+		{"all namespaces", "default", true, "web", []string{"web\tdefault", "web-2\tteam-a"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			names, err := listStoppableContainerNames(client, tt.ns, tt.allNamespaces, tt.prefix)
+			if err != nil {
+				t.Fatalf("listStoppableContainerNames() error = %v", err)
+			}
+			if !reflect.DeepEqual(names, tt.expected) {
+				t.Errorf("listStoppableContainerNames() = %v, want %v", names, tt.expected)
+			}
+		})
+	}
+}
+
+func TestBuildCpArgs(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		dst      string
+		expected []string
+		wantErr  bool
+	}{
+		{
+			name:     "local to container",
+			src:      "./app.conf",
+			dst:      "my-app:/etc/app.conf",
+			expected: []string{"cp", "-n", "default", "-c", "consumer", "./app.conf", "my-app-pod:/etc/app.conf"},
+		},
+		{
+			name:     "container to local",
+			src:      "my-app:/var/log",
+			dst:      "logs",
+			expected: []string{"cp", "-n", "default", "-c", "consumer", "my-app-pod:/var/log", "logs"},
+		},
+		{
+			name:     "trailing slash is kept",
+			src:      "my-app:/var/log/",
+			dst:      "/tmp/logs/",
+			expected: []string{"cp", "-n", "default", "-c", "consumer", "my-app-pod:/var/log/", "/tmp/logs/"},
+		},
+		{
+			name:     "relative path starts at the rootfs root",
+			src:      "data",
+			dst:      "my-app:srv/../data/",
+			expected: []string{"cp", "-n", "default", "-c", "consumer", "data", "my-app-pod:/data/"},
+		},
+		{
+			name:    "both sides remote",
+			src:     "my-app:/a",
+			dst:     "other:/b",
+			wantErr: true,
+		},
+		{
+			name:    "both sides local",
+			src:     "a",
+			dst:     "/b",
+			wantErr: true,
+		},
+		{
+			name:    "empty remote path",
+			src:     "a",
+			dst:     "my-app:",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src, dst, err := parseCpArgs(tt.src, tt.dst)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseCpArgs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			result := buildCpArgs("default", "my-app-pod", "consumer", src, dst)
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("buildCpArgs() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestExportProviderPod(t *testing.T) {
+	newSC := func(phase, instanceName string) *unstructured.Unstructured {
+		sc := &unstructured.Unstructured{Object: map[string]interface{}{
+			"metadata": map[string]interface{}{"name": "my-app"},
+			"status":   map[string]interface{}{"phase": phase},
+		}}
+		if instanceName != "" {
+			_ = unstructured.SetNestedField(sc.Object, instanceName, "status", "instanceName")
+		}
+		return sc
+	}
+	withProviderImage := func(sc *unstructured.Unstructured) *unstructured.Unstructured {
+		_ = unstructured.SetNestedField(sc.Object, "registry.example.com/provider:v1", "spec", "provider", "image")
+		return sc
+	}
+
+	tests := []struct {
+		name    string
+		sc      *unstructured.Unstructured
+		force   bool
+		want    string
+		wantErr bool
+	}{
+		{name: "stopped", sc: newSC("Stopped", "my-app"), want: "my-app-provider"},
+		{name: "completed", sc: newSC("Completed", ""), want: "my-app-provider"},
+		{name: "running", sc: newSC("Running", "my-app"), wantErr: true},
+		{name: "stopping", sc: newSC("Stopping", "my-app"), wantErr: true},
+		{name: "running with force", sc: newSC("Running", "my-app"), force: true, want: "my-app-provider"},
+		{name: "custom provider image", sc: withProviderImage(newSC("Stopped", "my-app")), force: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := exportProviderPod(tt.sc, tt.force)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("exportProviderPod() = %q, expected error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("exportProviderPod() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("exportProviderPod() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildExportRootfsArgs(t *testing.T) {
+	want := []string{"exec", "-n", "default", "-c", "provider", "my-app-provider", "--",
+		"/sc-provider", "--export-full-rootfs", "/propagated/rootfs"}
+	if got := buildExportRootfsArgs("default", "my-app-provider"); !reflect.DeepEqual(got, want) {
+		t.Errorf("buildExportRootfsArgs() = %v, want %v", got, want)
+	}
+}
+
+func TestBuildApplyBody(t *testing.T) {
+	objs, err := decodeStoppableContainers([]byte(`---
+apiVersion: stoppablecontainer.xtlsoft.top/v1alpha1
+kind: StoppableContainer
+metadata:
+  name: my-app
+  resourceVersion: "42"
+  uid: 1234
+  managedFields:
+  - manager: kubectl
+spec:
+  running: true
+status:
+  phase: Running
+`))
+	if err != nil {
+		t.Fatalf("decodeStoppableContainers() error = %v", err)
+	}
+	if len(objs) != 1 {
+		t.Fatalf("decoded %d objects, want 1", len(objs))
+	}
+
+	body, err := buildApplyBody(objs[0])
+	if err != nil {
+		t.Fatalf("buildApplyBody() error = %v", err)
+	}
+	expected := `{"apiVersion":"stoppablecontainer.xtlsoft.top/v1alpha1","kind":"StoppableContainer",` +
+		`"metadata":{"name":"my-app"},"spec":{"running":true}}`
+	if string(body) != expected {
+		t.Errorf("buildApplyBody() = %s, want %s", body, expected)
+	}
+
+	opts := applyPatchOptions(FieldManager, false)
+	if opts.FieldManager != "kubectl-sc" {
+		t.Errorf("FieldManager = %q, want kubectl-sc", opts.FieldManager)
+	}
+	if opts.Force == nil || *opts.Force {
+		t.Errorf("Force = %v, want false", opts.Force)
+	}
+	if opts := applyPatchOptions("argocd", true); opts.Force == nil || !*opts.Force || opts.FieldManager != "argocd" {
+		t.Errorf("applyPatchOptions(argocd, true) = %+v", opts)
+	}
+}
+
+func TestDecodeStoppableContainers(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		count    int
+		wantErr  bool
+	}{
+		{
+			name: "multiple documents",
+			manifest: `apiVersion: stoppablecontainer.xtlsoft.top/v1alpha1
+kind: StoppableContainer
+metadata:
+  name: a
+---
+apiVersion: stoppablecontainer.xtlsoft.top/v1alpha1
+kind: StoppableContainer
+metadata:
+  name: b
+`,
+			count: 2,
+		},
+		{
+			name:     "json",
+			manifest: `{"apiVersion":"stoppablecontainer.xtlsoft.top/v1alpha1","kind":"StoppableContainer","metadata":{"name":"a"}}`,
+			count:    1,
+		},
+		{
+			name: "other kind",
+			manifest: `apiVersion: v1
+kind: Pod
+metadata:
+  name: a
+`,
+			wantErr: true,
+		},
+		{
+			name: "missing name",
+			manifest: `apiVersion: stoppablecontainer.xtlsoft.top/v1alpha1
+kind: StoppableContainer
+`,
+			wantErr: true,
+		},
+		{
+			name:     "empty",
+			manifest: "---\n",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objs, err := decodeStoppableContainers([]byte(tt.manifest))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeStoppableContainers() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(objs) != tt.count {
+				t.Errorf("decoded %d objects, want %d", len(objs), tt.count)
+			}
+		})
+	}
+}
+
+func TestApplyStoppableContainer(t *testing.T) {
+	newSC := func(running bool, labels map[string]interface{}) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": GroupVersion,
+			"kind":       "StoppableContainer",
+			"metadata":   map[string]interface{}{"name": "my-app", "namespace": "default", "labels": labels},
+			"spec":       map[string]interface{}{"running": running},
+		}}
+	}
+	// newClient returns a client whose apply patches return applied
+	newClient := func(applied *unstructured.Unstructured, objs ...runtime.Object) *dynamicfake.FakeDynamicClient {
+		client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+			map[schema.GroupVersionResource]string{scGVR: "StoppableContainerList"}, objs...)
+		client.PrependReactor("patch", "stoppablecontainers", func(action clienttesting.Action) (bool, runtime.Object, error) {
+			if pt := action.(clienttesting.PatchAction).GetPatchType(); pt != types.ApplyPatchType {
+				t.Errorf("patch type = %s, want %s", pt, types.ApplyPatchType)
+			}
+			return true, applied, nil
+		})
+		return client
+	}
+
+	t.Run("created", func(t *testing.T) {
+		applied := newSC(true, nil)
+		var out strings.Builder
+		if err := applyStoppableContainer(newClient(applied), applied, FieldManager, false, &out); err != nil {
+			t.Fatalf("applyStoppableContainer() error = %v", err)
+		}
+		if out.String() != "stoppablecontainer/my-app created\n" {
+			t.Errorf("output = %q", out.String())
+		}
+	})
+
+	t.Run("unchanged", func(t *testing.T) {
+		applied := newSC(true, map[string]interface{}{"app": "web"})
+		var out strings.Builder
+		err := applyStoppableContainer(newClient(applied, applied.DeepCopy()), applied, FieldManager, false, &out)
+		if err != nil {
+			t.Fatalf("applyStoppableContainer() error = %v", err)
+		}
+		if out.String() != "stoppablecontainer/my-app unchanged\n" {
+			t.Errorf("output = %q", out.String())
+		}
+	})
+
+	t.Run("configured", func(t *testing.T) {
+		applied := newSC(false, map[string]interface{}{"app": "web", "tier": "front"})
+		existing := newSC(true, map[string]interface{}{"app": "web"})
+		var out strings.Builder
+		if err := applyStoppableContainer(newClient(applied, existing), applied, FieldManager, false, &out); err != nil {
+			t.Fatalf("applyStoppableContainer() error = %v", err)
+		}
+		expected := "stoppablecontainer/my-app configured\n" +
+			"  changed: metadata.labels.tier\n" +
+			"  changed: spec.running\n"
+		if out.String() != expected {
+			t.Errorf("output = %q, want %q", out.String(), expected)
+		}
+	})
+}
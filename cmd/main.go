@@ -20,6 +20,7 @@ import (
 	"crypto/tls"
 	"flag"
 	"os"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -37,6 +38,8 @@ import (
 
 	stoppablecontainerv1alpha1 "github.com/xtlsoft/stoppablecontainer/api/v1alpha1"
 	"github.com/xtlsoft/stoppablecontainer/internal/controller"
+	"github.com/xtlsoft/stoppablecontainer/internal/provider"
+	webhookv1alpha1 "github.com/xtlsoft/stoppablecontainer/internal/webhook/v1alpha1"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -61,6 +64,16 @@ func main() {
 	var probeAddr string
 	var secureMetrics bool
 	var enableHTTP2 bool
+	var infraImagePullSecrets string
+	var execWrapperImage, providerImage, execWrapperPullPolicy string
+	var allowedHostPathPrefixes string
+	var allowedInfraImages string
+	var minTransitionInterval time.Duration
+	var reconcileTimeout time.Duration
+	var requeueInterval time.Duration
+	var mountHelperTimeout time.Duration
+	var imagePullTimeout time.Duration
+	var maxConsumerRestarts int
 	var tlsOpts []func(*tls.Config)
 	flag.StringVar(&metricsAddr, "metrics-bind-address", "0", "The address the metrics endpoint binds to. "+
 		"Use :8443 for HTTPS or :8080 for HTTP, or leave as 0 to disable the metrics service.")
@@ -79,6 +92,38 @@ func main() {
 	flag.StringVar(&metricsCertKey, "metrics-cert-key", "tls.key", "The name of the metrics server key file.")
 	flag.BoolVar(&enableHTTP2, "enable-http2", false,
 		"If set, HTTP/2 will be enabled for the metrics and webhook servers")
+	flag.StringVar(&infraImagePullSecrets, "infra-image-pull-secrets", "",
+		"Comma-separated image pull secrets added to provider and consumer pods for pulling the infra images.")
+	flag.StringVar(&execWrapperImage, "exec-wrapper-image", provider.ExecWrapperImage,
+		"Image of the exec-wrapper containers in consumer pods. Defaults to $STOPPABLECONTAINER_EXEC_WRAPPER_IMAGE if set.")
+	flag.StringVar(&execWrapperPullPolicy, "exec-wrapper-pull-policy", string(provider.ExecWrapperPullPolicy),
+		"Pull policy of the exec-wrapper and provider infra containers, including init containers: "+
+			"Always, IfNotPresent or Never. Defaults to $STOPPABLECONTAINER_EXEC_WRAPPER_PULL_POLICY if set.")
+	flag.StringVar(&providerImage, "provider-image", provider.ProviderImage,
+		"Image of the infra containers in provider pods. Defaults to $STOPPABLECONTAINER_PROVIDER_IMAGE if set, "+
+			"otherwise the exec-wrapper image.")
+	flag.StringVar(&allowedHostPathPrefixes, "allowed-host-path-prefixes", provider.DefaultHostPathPrefix,
+		"Comma-separated host directories that spec.hostPathPrefix must be in. Other prefixes are rejected "+
+			"by the validating webhook, and their instances fail.")
+	flag.StringVar(&allowedInfraImages, "allowed-infra-images", "",
+		"Comma-separated images that spec.provider.image and spec.execWrapperImage may name besides the "+
+			"--provider-image and --exec-wrapper-image images. An image without a tag or digest allows any of its tags. "+
+			"Other images are rejected by the validating webhook, and their instances fail.")
+	flag.DurationVar(&minTransitionInterval, "min-transition-interval", controller.DefaultMinTransitionInterval,
+		"Minimum interval between start/stop actions on a StoppableContainer, to avoid flapping.")
+	flag.DurationVar(&mountHelperTimeout, "mount-helper-timeout", controller.DefaultMountHelperTimeout,
+		"How long a running provider may wait for mount-helper before the instance reports MountHelperUnavailable.")
+	flag.DurationVar(&imagePullTimeout, "image-pull-timeout", controller.DefaultImagePullTimeout,
+		"How long kubelet may retry a failing pull of the user image (ErrImagePull, ImagePullBackOff) "+
+			"before the instance fails.")
+	flag.DurationVar(&reconcileTimeout, "reconcile-timeout", controller.DefaultReconcileTimeout,
+		"Deadline for a single reconcile; a reconcile that exceeds it is requeued.")
+	flag.DurationVar(&requeueInterval, "reconcile-requeue-interval", controller.DefaultRequeueInterval,
+		"Delay before re-checking objects that are being created or deleted. "+
+			"Instances in an intermediate phase are polled at twice this interval.")
+	flag.IntVar(&maxConsumerRestarts, "max-consumer-restarts", controller.DefaultMaxConsumerRestarts,
+		"How often a crash looping consumer pod is recreated, with exponential backoff, before the instance is "+
+			"marked Failed. A negative value disables recreation.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -87,6 +132,26 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	provider.InfraImagePullSecrets = provider.ParseImagePullSecrets(infraImagePullSecrets)
+	provider.ExecWrapperImage = execWrapperImage
+	provider.ProviderImage = providerImage
+	pullPolicy, err := provider.ParsePullPolicy(execWrapperPullPolicy)
+	if err != nil {
+		setupLog.Error(err, "invalid --exec-wrapper-pull-policy")
+		os.Exit(1)
+	}
+	provider.ExecWrapperPullPolicy = pullPolicy
+	provider.AllowedHostPathPrefixes, err = provider.ParseHostPathPrefixes(allowedHostPathPrefixes)
+	if err != nil {
+		setupLog.Error(err, "invalid --allowed-host-path-prefixes")
+		os.Exit(1)
+	}
+	provider.AllowedInfraImages, err = provider.ParseInfraImages(allowedInfraImages)
+	if err != nil {
+		setupLog.Error(err, "invalid --allowed-infra-images")
+		os.Exit(1)
+	}
+
 	// if the enable-http2 flag is false (the default), http/2 should be disabled
 	// due to its vulnerabilities. More specifically, disabling http/2 will
 	// prevent from being vulnerable to the HTTP/2 Stream Cancellation and
@@ -179,19 +244,37 @@ func main() {
 	}
 
 	if err := (&controller.StoppableContainerReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:                mgr.GetClient(),
+		Scheme:                mgr.GetScheme(),
+		Recorder:              mgr.GetEventRecorderFor("stoppablecontainer-controller"),
+		MinTransitionInterval: minTransitionInterval,
+		ReconcileTimeout:      reconcileTimeout,
+		RequeueInterval:       requeueInterval,
+		Metrics:               controller.NewMetricsAPIReader(mgr.GetAPIReader()),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "StoppableContainer")
 		os.Exit(1)
 	}
 	if err := (&controller.StoppableContainerInstanceReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:              mgr.GetClient(),
+		Scheme:              mgr.GetScheme(),
+		Recorder:            mgr.GetEventRecorderFor("stoppablecontainerinstance-controller"),
+		ReconcileTimeout:    reconcileTimeout,
+		MountHelperTimeout:  mountHelperTimeout,
+		ImagePullTimeout:    imagePullTimeout,
+		RequeueInterval:     requeueInterval,
+		MaxConsumerRestarts: maxConsumerRestarts,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "StoppableContainerInstance")
 		os.Exit(1)
 	}
+	// nolint:goconst
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		if err := webhookv1alpha1.SetupStoppableContainerWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "StoppableContainer")
+			os.Exit(1)
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -0,0 +1,231 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+)
+
+const (
+	// CRIEndpointEnv overrides the CRI socket used to look up rootfs containers, as a
+	// path inside the mount-helper container or a unix:// URL
+	CRIEndpointEnv = "CONTAINER_RUNTIME_ENDPOINT"
+	// CRITimeout bounds each call to the container runtime
+	CRITimeout = 5 * time.Second
+	// podUIDLabel is the label kubelet sets on every container and pod sandbox with its pod's UID
+	podUIDLabel = "io.kubernetes.pod.uid"
+)
+
+// defaultCRISockets are tried in order when CRIEndpointEnv is not set
+var defaultCRISockets = []string{
+	HostRootPath + "/run/containerd/containerd.sock",
+	HostRootPath + "/run/crio/crio.sock",
+}
+
+// errRootfsContainerNotFound means the lookup worked but the pod has no rootfs container (yet)
+var errRootfsContainerNotFound = errors.New("rootfs container not found")
+
+// criClient looks up rootfs containers through the container runtime; nil scans /proc instead
+var criClient runtimeapi.RuntimeServiceClient
+
+// criImageClient reads the rootfs image's config; nil if no runtime is connected
+var criImageClient runtimeapi.ImageServiceClient
+
+// connectCRI connects to the container runtime at endpoint, or the first default socket
+// that exists. It returns nil clients if no runtime answers.
+func connectCRI(endpoint string) (runtimeapi.RuntimeServiceClient, runtimeapi.ImageServiceClient, string) {
+	candidates := defaultCRISockets
+	if endpoint != "" {
+		candidates = []string{strings.TrimPrefix(endpoint, "unix://")}
+	}
+	for _, socket := range candidates {
+		if _, err := os.Stat(socket); err != nil {
+			continue
+		}
+		conn, err := grpc.NewClient("unix://"+socket, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			log.Error(err, "cannot connect to CRI socket", "socket", socket)
+			continue
+		}
+		client := runtimeapi.NewRuntimeServiceClient(conn)
+		ctx, cancel := context.WithTimeout(context.Background(), CRITimeout)
+		_, err = client.Version(ctx, &runtimeapi.VersionRequest{})
+		cancel()
+		if err != nil {
+			log.Error(err, "CRI socket does not answer", "socket", socket)
+			_ = conn.Close()
+			continue
+		}
+		return client, runtimeapi.NewImageServiceClient(conn), socket
+	}
+	return nil, nil, ""
+}
+
+// findRootfsContainerCRI asks the container runtime for the running containers of the pod
+// and returns the host PID of the one with the rootfs marker in its environment
+func findRootfsContainerCRI(ctx context.Context, client runtimeapi.RuntimeServiceClient, podUID string) (int, error) {
+	pid, _, err := findRootfsContainerStatusCRI(ctx, client, podUID)
+	return pid, err
+}
+
+// findRootfsContainerStatusCRI is findRootfsContainerCRI that also returns the container's status
+func findRootfsContainerStatusCRI(ctx context.Context, client runtimeapi.RuntimeServiceClient, podUID string) (int, *runtimeapi.ContainerStatus, error) {
+	ctx, cancel := context.WithTimeout(ctx, CRITimeout)
+	defer cancel()
+
+	resp, err := client.ListContainers(ctx, &runtimeapi.ListContainersRequest{
+		Filter: &runtimeapi.ContainerFilter{
+			State:         &runtimeapi.ContainerStateValue{State: runtimeapi.ContainerState_CONTAINER_RUNNING},
+			LabelSelector: map[string]string{podUIDLabel: podUID},
+		},
+	})
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+
+	for _, container := range resp.Containers {
+		status, err := client.ContainerStatus(ctx, &runtimeapi.ContainerStatusRequest{
+			ContainerId: container.Id,
+			Verbose:     true,
+		})
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed to get status of container %s: %w", container.Id, err)
+		}
+		pid, marked, err := parseContainerInfo(status.Info["info"])
+		if err != nil {
+			return 0, nil, fmt.Errorf("container %s: %w", container.Id, err)
+		}
+		if marked && pid > 0 {
+			return pid, status.Status, nil
+		}
+	}
+
+	return 0, nil, fmt.Errorf("%w for pod %s", errRootfsContainerNotFound, podUID)
+}
+
+// verifyPodSandboxCRI checks that the container runtime runs a pod sandbox with podUID, and
+// that kubelet created it for the pod namespace/name
+func verifyPodSandboxCRI(ctx context.Context, client runtimeapi.RuntimeServiceClient, podUID, namespace, name string) error {
+	ctx, cancel := context.WithTimeout(ctx, CRITimeout)
+	defer cancel()
+
+	resp, err := client.ListPodSandbox(ctx, &runtimeapi.ListPodSandboxRequest{
+		Filter: &runtimeapi.PodSandboxFilter{
+			LabelSelector: map[string]string{podUIDLabel: podUID},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list pod sandboxes: %w", err)
+	}
+	for _, sandbox := range resp.Items {
+		metadata := sandbox.GetMetadata()
+		if metadata.GetUid() != podUID {
+			continue
+		}
+		if metadata.GetNamespace() != namespace || metadata.GetName() != name {
+			return fmt.Errorf("pod %s is %s/%s, not %s/%s", podUID,
+				metadata.GetNamespace(), metadata.GetName(), namespace, name)
+		}
+		return nil
+	}
+	return fmt.Errorf("no pod sandbox for pod %s", podUID)
+}
+
+// findRootfsImageConfigCRI returns the Entrypoint, Cmd and Env of the image the pod's rootfs
+// container runs, as the container runtime reports them
+func findRootfsImageConfigCRI(ctx context.Context, client runtimeapi.RuntimeServiceClient,
+	images runtimeapi.ImageServiceClient, podUID string) (*ImageConfig, error) {
+	_, status, err := findRootfsContainerStatusCRI(ctx, client, podUID)
+	if err != nil {
+		return nil, err
+	}
+	image := status.GetImageRef()
+	if image == "" {
+		image = status.GetImage().GetImage()
+	}
+	if image == "" {
+		return nil, fmt.Errorf("runtime reported no image for container %s", status.GetId())
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, CRITimeout)
+	defer cancel()
+	resp, err := images.ImageStatus(ctx, &runtimeapi.ImageStatusRequest{
+		Image:   &runtimeapi.ImageSpec{Image: image},
+		Verbose: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get status of image %s: %w", image, err)
+	}
+	config, err := parseImageInfo(resp.Info["info"])
+	if err != nil {
+		return nil, fmt.Errorf("image %s: %w", image, err)
+	}
+	return config, nil
+}
+
+// containerInfo is the part of the verbose container status both containerd and CRI-O return
+type containerInfo struct {
+	PID         int `json:"pid"`
+	RuntimeSpec struct {
+		Process struct {
+			Env []string `json:"env"`
+		} `json:"process"`
+	} `json:"runtimeSpec"`
+}
+
+// parseContainerInfo returns the host PID from a verbose container status, and whether
+// the container's environment has the rootfs marker
+func parseContainerInfo(info string) (int, bool, error) {
+	if info == "" {
+		return 0, false, fmt.Errorf("runtime returned no verbose info")
+	}
+	var parsed containerInfo
+	if err := json.Unmarshal([]byte(info), &parsed); err != nil {
+		return 0, false, fmt.Errorf("invalid verbose info: %w", err)
+	}
+	return parsed.PID, slices.Contains(parsed.RuntimeSpec.Process.Env, RootfsMarkerEnv), nil
+}
+
+// imageInfo is the part of the verbose image status both containerd and CRI-O return
+type imageInfo struct {
+	ImageSpec struct {
+		Config ImageConfig `json:"config"`
+	} `json:"imageSpec"`
+}
+
+// parseImageInfo returns the Entrypoint, Cmd and Env from a verbose image status
+func parseImageInfo(info string) (*ImageConfig, error) {
+	if info == "" {
+		return nil, fmt.Errorf("runtime returned no verbose info")
+	}
+	var parsed imageInfo
+	if err := json.Unmarshal([]byte(info), &parsed); err != nil {
+		return nil, fmt.Errorf("invalid verbose info: %w", err)
+	}
+	return &parsed.ImageSpec.Config, nil
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"google.golang.org/grpc"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+)
+
+// fakeRuntimeService serves ListContainers and ContainerStatus from a fixed set of
+// containers, keyed by ID with their verbose info, and ListPodSandbox from a fixed set
+// of sandboxes
+type fakeRuntimeService struct {
+	runtimeapi.RuntimeServiceClient
+	sandboxes []*runtimeapi.PodSandboxMetadata
+	pods      map[string][]string
+	info      map[string]string
+	images    map[string]string
+	listErr   error
+}
+
+func (f *fakeRuntimeService) ListContainers(_ context.Context, req *runtimeapi.ListContainersRequest, _ ...grpc.CallOption) (*runtimeapi.ListContainersResponse, error) {
+	if f.listErr != nil {
+		return nil, f.listErr
+	}
+	resp := &runtimeapi.ListContainersResponse{}
+	for _, id := range f.pods[req.Filter.LabelSelector[podUIDLabel]] {
+		resp.Containers = append(resp.Containers, &runtimeapi.Container{Id: id})
+	}
+	return resp, nil
+}
+
+func (f *fakeRuntimeService) ListPodSandbox(_ context.Context, req *runtimeapi.ListPodSandboxRequest, _ ...grpc.CallOption) (*runtimeapi.ListPodSandboxResponse, error) {
+	if f.listErr != nil {
+		return nil, f.listErr
+	}
+	resp := &runtimeapi.ListPodSandboxResponse{}
+	for _, metadata := range f.sandboxes {
+		if metadata.Uid == req.Filter.LabelSelector[podUIDLabel] {
+			resp.Items = append(resp.Items, &runtimeapi.PodSandbox{Id: metadata.Uid, Metadata: metadata})
+		}
+	}
+	return resp, nil
+}
+
+func (f *fakeRuntimeService) ContainerStatus(_ context.Context, req *runtimeapi.ContainerStatusRequest, _ ...grpc.CallOption) (*runtimeapi.ContainerStatusResponse, error) {
+	return &runtimeapi.ContainerStatusResponse{
+		Status: &runtimeapi.ContainerStatus{Id: req.ContainerId, ImageRef: f.images[req.ContainerId]},
+		Info:   map[string]string{"info": f.info[req.ContainerId]},
+	}, nil
+}
+
+// fakeImageService serves ImageStatus from a fixed set of images, keyed by reference
+// with their verbose info
+type fakeImageService struct {
+	runtimeapi.ImageServiceClient
+	info map[string]string
+}
+
+func (f *fakeImageService) ImageStatus(_ context.Context, req *runtimeapi.ImageStatusRequest, _ ...grpc.CallOption) (*runtimeapi.ImageStatusResponse, error) {
+	info, ok := f.info[req.Image.Image]
+	if !ok {
+		return nil, errors.New("image not found")
+	}
+	return &runtimeapi.ImageStatusResponse{
+		Image: &runtimeapi.Image{Id: req.Image.Image},
+		Info:  map[string]string{"info": info},
+	}, nil
+}
+
+func TestParseContainerInfo(t *testing.T) {
+	tests := []struct {
+		name       string
+		info       string
+		wantPID    int
+		wantMarker bool
+		wantErr    bool
+	}{
+		{
+			name:       "rootfs container",
+			info:       `{"pid": 4242, "runtimeSpec": {"process": {"env": ["PATH=/bin", "ROOTFS_MARKER=true"]}}}`,
+			wantPID:    4242,
+			wantMarker: true,
+		},
+		{
+			name:    "other container",
+			info:    `{"pid": 17, "runtimeSpec": {"process": {"env": ["PATH=/bin"]}}}`,
+			wantPID: 17,
+		},
+		{name: "no verbose info", info: "", wantErr: true},
+		{name: "invalid JSON", info: "{", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pid, marker, err := parseContainerInfo(tt.info)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseContainerInfo() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if pid != tt.wantPID || marker != tt.wantMarker {
+				t.Errorf("parseContainerInfo() = %d, %v, want %d, %v", pid, marker, tt.wantPID, tt.wantMarker)
+			}
+		})
+	}
+}
+
+func TestFindRootfsContainerCRI(t *testing.T) {
+	client := &fakeRuntimeService{
+		pods: map[string][]string{
+			"pod-a": {"pause", "rootfs"},
+			"pod-b": {"app"},
+		},
+		info: map[string]string{
+			"pause":  `{"pid": 100, "runtimeSpec": {"process": {"env": []}}}`,
+			"rootfs": `{"pid": 200, "runtimeSpec": {"process": {"env": ["ROOTFS_MARKER=true"]}}}`,
+			"app":    `{"pid": 300, "runtimeSpec": {"process": {"env": ["HOME=/root"]}}}`,
+		},
+	}
+
+	pid, err := findRootfsContainerCRI(context.Background(), client, "pod-a")
+	if err != nil || pid != 200 {
+		t.Errorf("findRootfsContainerCRI(pod-a) = %d, %v, want 200", pid, err)
+	}
+
+	if _, err := findRootfsContainerCRI(context.Background(), client, "pod-b"); !errors.Is(err, errRootfsContainerNotFound) {
+		t.Errorf("findRootfsContainerCRI(pod-b) error = %v, want not found", err)
+	}
+
+	client.listErr = errors.New("connection refused")
+	_, err = findRootfsContainerCRI(context.Background(), client, "pod-a")
+	if err == nil || errors.Is(err, errRootfsContainerNotFound) {
+		t.Errorf("findRootfsContainerCRI() error = %v, want a runtime error", err)
+	}
+}
+
+func TestConnectCRI_NoSocket(t *testing.T) {
+	client, images, socket := connectCRI(t.TempDir() + "/missing.sock")
+	if client != nil || images != nil || socket != "" {
+		t.Errorf("connectCRI() = %v, %v, %q, want no client", client, images, socket)
+	}
+}
+
+func TestParseImageInfo(t *testing.T) {
+	tests := []struct {
+		name    string
+		info    string
+		want    ImageConfig
+		wantErr bool
+	}{
+		{
+			name: "entrypoint and cmd",
+			info: `{"chainID": "sha256:1", "imageSpec": {"config": {"Entrypoint": ["/docker-entrypoint.sh"], "Cmd": ["nginx", "-g", "daemon off;"]}}}`,
+			want: ImageConfig{Entrypoint: []string{"/docker-entrypoint.sh"}, Cmd: []string{"nginx", "-g", "daemon off;"}},
+		},
+		{
+			name: "env",
+			info: `{"imageSpec": {"config": {"Env": ["PATH=/opt/venv/bin:/usr/bin", "LANG=C.UTF-8"], "Cmd": ["python3"]}}}`,
+			want: ImageConfig{Cmd: []string{"python3"}, Env: []string{"PATH=/opt/venv/bin:/usr/bin", "LANG=C.UTF-8"}},
+		},
+		{
+			name: "cmd only",
+			info: `{"imageSpec": {"config": {"Cmd": ["/bin/bash"]}}}`,
+			want: ImageConfig{Cmd: []string{"/bin/bash"}},
+		},
+		{name: "no config", info: `{"imageSpec": {}}`},
+		{name: "no verbose info", info: "", wantErr: true},
+		{name: "invalid JSON", info: "{", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := parseImageInfo(tt.info)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseImageInfo() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(*config, tt.want) {
+				t.Errorf("parseImageInfo() = %+v, want %+v", *config, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindRootfsImageConfigCRI(t *testing.T) {
+	client := &fakeRuntimeService{
+		pods: map[string][]string{
+			"pod-a": {"pause", "rootfs"},
+			"pod-b": {"rootfs-unknown"},
+		},
+		info: map[string]string{
+			"pause":          `{"pid": 100, "runtimeSpec": {"process": {"env": []}}}`,
+			"rootfs":         `{"pid": 200, "runtimeSpec": {"process": {"env": ["ROOTFS_MARKER=true"]}}}`,
+			"rootfs-unknown": `{"pid": 300, "runtimeSpec": {"process": {"env": ["ROOTFS_MARKER=true"]}}}`,
+		},
+		images: map[string]string{
+			"pause":          "sha256:pause",
+			"rootfs":         "sha256:nginx",
+			"rootfs-unknown": "sha256:missing",
+		},
+	}
+	images := &fakeImageService{
+		info: map[string]string{
+			"sha256:nginx": `{"imageSpec": {"config": {"Entrypoint": ["/docker-entrypoint.sh"], "Cmd": ["nginx"]}}}`,
+		},
+	}
+
+	config, err := findRootfsImageConfigCRI(context.Background(), client, images, "pod-a")
+	want := ImageConfig{Entrypoint: []string{"/docker-entrypoint.sh"}, Cmd: []string{"nginx"}}
+	if err != nil || !reflect.DeepEqual(*config, want) {
+		t.Errorf("findRootfsImageConfigCRI(pod-a) = %+v, %v, want %+v", config, err, want)
+	}
+
+	if _, err := findRootfsImageConfigCRI(context.Background(), client, images, "pod-b"); err == nil {
+		t.Error("findRootfsImageConfigCRI(pod-b) error = nil, want an image error")
+	}
+
+	if _, err := findRootfsImageConfigCRI(context.Background(), client, images, "pod-c"); !errors.Is(err, errRootfsContainerNotFound) {
+		t.Errorf("findRootfsImageConfigCRI(pod-c) error = %v, want not found", err)
+	}
+}
+
+func TestVerifyPodSandboxCRI(t *testing.T) {
+	client := &fakeRuntimeService{
+		sandboxes: []*runtimeapi.PodSandboxMetadata{
+			{Uid: "uid-a", Namespace: "team-a", Name: "web-provider"},
+			{Uid: "uid-b", Namespace: "team-b", Name: "db-provider"},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		podUID    string
+		namespace string
+		podName   string
+		wantErr   bool
+	}{
+		{name: "own pod", podUID: "uid-a", namespace: "team-a", podName: "web-provider"},
+		{name: "pod of another namespace", podUID: "uid-b", namespace: "team-a", podName: "db-provider", wantErr: true},
+		{name: "pod of another instance", podUID: "uid-b", namespace: "team-b", podName: "web-provider", wantErr: true},
+		{name: "unknown pod", podUID: "uid-c", namespace: "team-a", podName: "web-provider", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifyPodSandboxCRI(context.Background(), client, tt.podUID, tt.namespace, tt.podName)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("verifyPodSandboxCRI() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	client.listErr = errors.New("connection refused")
+	if err := verifyPodSandboxCRI(context.Background(), client, "uid-a", "team-a", "web-provider"); err == nil {
+		t.Error("verifyPodSandboxCRI() expected error when the runtime fails")
+	}
+}
+
+func TestVerifyProviderPod(t *testing.T) {
+	origClient := criClient
+	defer func() { criClient = origClient }()
+
+	workDir := "/host/var/lib/stoppablecontainer/team-a/web"
+	criClient = nil
+	if err := verifyProviderPod(workDir, "uid-a"); err == nil {
+		t.Error("verifyProviderPod() without a runtime expected error")
+	}
+
+	criClient = &fakeRuntimeService{
+		sandboxes: []*runtimeapi.PodSandboxMetadata{
+			{Uid: "uid-a", Namespace: "team-a", Name: "web-provider"},
+			{Uid: "uid-b", Namespace: "team-b", Name: "web-provider"},
+		},
+	}
+	if err := verifyProviderPod(workDir, "uid-a"); err != nil {
+		t.Errorf("verifyProviderPod() error = %v", err)
+	}
+	if err := verifyProviderPod(workDir, "uid-b"); err == nil {
+		t.Error("verifyProviderPod() with another namespace's pod expected error")
+	}
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// OrphanSweepInterval is how often work directories are checked for pods that are gone
+	OrphanSweepInterval = time.Minute
+	// OrphanGracePeriod is how long a pod's rootfs container must be gone before its work
+	// directory is removed, so a provider container restarting in place keeps its mount
+	OrphanGracePeriod = 2 * time.Minute
+)
+
+// orphanSweeper removes the work directories of pods that went away without writing
+// the delete signal, e.g. a provider killed with SIGKILL or lost with its node
+type orphanSweeper struct {
+	lastSweep time.Time
+	// orphanedSince records when each work directory was first seen without its pod
+	orphanedSince map[string]time.Time
+}
+
+var sweeper = &orphanSweeper{orphanedSince: map[string]time.Time{}}
+
+// sweep checks the given work directories at most once per OrphanSweepInterval and
+// removes those whose pod has been gone for OrphanGracePeriod
+func (s *orphanSweeper) sweep(workDirs []string, now time.Time) {
+	if now.Sub(s.lastSweep) < OrphanSweepInterval {
+		return
+	}
+	s.lastSweep = now
+
+	orphaned := make(map[string]bool)
+	for _, workDir := range workDirs {
+		podUID, ok := mountedPodUID(workDir)
+		if !ok || podAlive(podUID) {
+			continue
+		}
+		since, known := s.orphanedSince[workDir]
+		if !known {
+			log.Info("pod of work directory is gone", "workDir", workDir, "podUID", podUID)
+			since = now
+		}
+		orphaned[workDir] = true
+		s.orphanedSince[workDir] = since
+		if now.Sub(since) < OrphanGracePeriod {
+			continue
+		}
+
+		log.Info("removing orphaned work directory", "workDir", workDir, "podUID", podUID)
+		if err := cleanupWorkDir(workDir); err != nil {
+			log.Error(err, "failed to clean up orphaned work directory", "workDir", workDir)
+			continue
+		}
+		if err := removeOverlayDirs(podUID, overlayDirAllowlist); err != nil {
+			log.Error(err, "failed to remove overlay directories", "podUID", podUID)
+		}
+		// Remove the namespace directory too once it is empty
+		_ = os.Remove(filepath.Dir(workDir))
+		delete(orphaned, workDir)
+	}
+
+	for workDir := range s.orphanedSince {
+		if !orphaned[workDir] {
+			delete(s.orphanedSince, workDir)
+		}
+	}
+}
+
+// mountedPodUID returns the pod UID recorded in the ready response of a mounted work
+// directory. Pending requests are left to processRequest.
+func mountedPodUID(workDir string) (string, bool) {
+	readyFile := filepath.Join(workDir, ReadyFileName)
+	readyStat, err := os.Stat(readyFile)
+	if err != nil {
+		return "", false
+	}
+	if requestStat, err := os.Stat(filepath.Join(workDir, RequestFileName)); err == nil &&
+		readyStat.ModTime().Before(requestStat.ModTime()) {
+		return "", false
+	}
+	data, err := os.ReadFile(readyFile)
+	if err != nil {
+		return "", false
+	}
+	var response MountResponse
+	if err := json.Unmarshal(data, &response); err != nil || response.Status != "ready" || response.PodUID == "" {
+		return "", false
+	}
+	return response.PodUID, true
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestOrphanSweeper(t *testing.T) {
+	origUnmount, origPodAlive := unmount, podAlive
+	t.Cleanup(func() { unmount, podAlive = origUnmount, origPodAlive })
+	unmount = func(string, int) error { return syscall.EINVAL }
+	alive := map[string]bool{}
+	podAlive = func(uid string) bool { return alive[uid] }
+
+	base := t.TempDir()
+	newWorkDir := func(name, uid string, ready bool) string {
+		workDir := filepath.Join(base, "default", name)
+		if err := os.MkdirAll(filepath.Join(workDir, "rootfs"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		// processRequest removes a handled request and records the pod UID in the response
+		if ready {
+			response := `{"status":"ready","pod_uid":"` + uid + `"}`
+			if err := os.WriteFile(filepath.Join(workDir, ReadyFileName), []byte(response), 0644); err != nil {
+				t.Fatal(err)
+			}
+		} else {
+			request := `{"pod_uid":"` + uid + `"}`
+			if err := os.WriteFile(filepath.Join(workDir, RequestFileName), []byte(request), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return workDir
+	}
+	exists := func(dir string) bool {
+		_, err := os.Stat(dir)
+		return err == nil
+	}
+
+	orphan := newWorkDir("orphan", "uid-gone", true)
+	running := newWorkDir("running", "uid-alive", true)
+	pending := newWorkDir("pending", "uid-pending", false)
+	restarted := newWorkDir("restarted", "uid-restarted", true)
+	alive["uid-alive"] = true
+	workDirs := []string{orphan, running, pending, restarted}
+
+	s := &orphanSweeper{orphanedSince: map[string]time.Time{}}
+	start := time.Now()
+
+	s.sweep(workDirs, start)
+	if !exists(orphan) {
+		t.Fatal("orphaned work directory removed before the grace period")
+	}
+	if len(s.orphanedSince) != 2 {
+		t.Errorf("orphanedSince = %v, want the orphaned and restarted work directories", s.orphanedSince)
+	}
+
+	// A provider container that comes back keeps its mount
+	alive["uid-restarted"] = true
+	s.sweep(workDirs, start.Add(OrphanSweepInterval))
+	if _, ok := s.orphanedSince[restarted]; ok {
+		t.Error("restarted pod should no longer count as orphaned")
+	}
+
+	// Sweeps are rate limited, so this one does not notice the pod going away again
+	alive["uid-restarted"] = false
+	s.sweep(workDirs, start.Add(OrphanSweepInterval+OrphanSweepInterval/2))
+	if len(s.orphanedSince) != 1 {
+		t.Fatalf("orphanedSince = %v, want the orphaned work directory", s.orphanedSince)
+	}
+	alive["uid-restarted"] = true
+
+	s.sweep(workDirs, start.Add(OrphanGracePeriod))
+	if exists(orphan) {
+		t.Error("orphaned work directory not removed after the grace period")
+	}
+	if len(s.orphanedSince) != 0 {
+		t.Errorf("orphanedSince = %v, want empty", s.orphanedSince)
+	}
+	for _, dir := range []string{running, pending, restarted} {
+		if !exists(dir) {
+			t.Errorf("%s removed, want kept", dir)
+		}
+	}
+}
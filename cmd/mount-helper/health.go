@@ -0,0 +1,97 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// ScanStaleAfter is how long after the last successful scan the mount-helper counts as
+// wedged. Scans run at least every FallbackScanInterval, and a single mount request may
+// take a while (container lookup retries, the post-mount hook).
+const ScanStaleAfter = 2 * time.Minute
+
+// RequestStuckAfter is how long a single request may run before the mount-helper counts
+// as wedged: the rootfs container lookup retries and the post-mount hook are bounded, the
+// rest of a request should not take longer than ScanStaleAfter.
+const RequestStuckAfter = MaxRetries*RetryInterval + PostMountHookTimeout + ScanStaleAfter
+
+// scanHealth tracks when the main loop last finished a scan and how long the requests
+// take, for the health endpoints
+type scanHealth struct {
+	started time.Time
+	// lastScan is the time of the last successful scan in Unix nanoseconds, 0 before the first
+	lastScan atomic.Int64
+	// requests is the pool processing the requests, if any
+	requests *requestPool
+}
+
+func newScanHealth(now time.Time, requests *requestPool) *scanHealth {
+	return &scanHealth{started: now, requests: requests}
+}
+
+// scanned records a successful scan
+func (h *scanHealth) scanned(now time.Time) {
+	h.lastScan.Store(now.UnixNano())
+}
+
+// check returns an error if no scan succeeded within ScanStaleAfter, or a request has
+// been running for RequestStuckAfter. Scans do not wait for the requests, so a worker
+// stuck in a mount does not hold them up. Before the first scan, the time since start
+// counts instead if allowStartup is set.
+func (h *scanHealth) check(now time.Time, allowStartup bool) error {
+	if h.requests != nil {
+		if workDir, started, ok := h.requests.oldest(); ok {
+			if age := now.Sub(started); age >= RequestStuckAfter {
+				return fmt.Errorf("request in %s running for %s", workDir, age.Round(time.Second))
+			}
+		}
+	}
+	last := h.lastScan.Load()
+	if last == 0 {
+		if allowStartup && now.Sub(h.started) < ScanStaleAfter {
+			return nil
+		}
+		return fmt.Errorf("no successful scan since start %s ago", now.Sub(h.started).Round(time.Second))
+	}
+	if age := now.Sub(time.Unix(0, last)); age >= ScanStaleAfter {
+		return fmt.Errorf("last successful scan %s ago", age.Round(time.Second))
+	}
+	return nil
+}
+
+// healthzHandler reports whether the main loop is alive; a mount-helper that is still
+// starting counts as alive
+func (h *scanHealth) healthzHandler(w http.ResponseWriter, _ *http.Request) {
+	writeHealth(w, h.check(time.Now(), true))
+}
+
+// readyzHandler reports whether the main loop has scanned recently
+func (h *scanHealth) readyzHandler(w http.ResponseWriter, _ *http.Request) {
+	writeHealth(w, h.check(time.Now(), false))
+}
+
+func writeHealth(w http.ResponseWriter, err error) {
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	_, _ = fmt.Fprintln(w, "ok")
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScanHealth(t *testing.T) {
+	start := time.Now()
+	h := newScanHealth(start, nil)
+
+	// Starting up: alive, but not ready before the first scan
+	if err := h.check(start.Add(time.Second), true); err != nil {
+		t.Errorf("check(startup) = %v, want alive", err)
+	}
+	if err := h.check(start.Add(time.Second), false); err == nil {
+		t.Error("check() before the first scan = nil, want not ready")
+	}
+	if err := h.check(start.Add(ScanStaleAfter), true); err == nil {
+		t.Error("check(startup) = nil, want wedged if the first scan never finishes")
+	}
+
+	h.scanned(start.Add(time.Minute))
+	if err := h.check(start.Add(time.Minute+ScanStaleAfter/2), false); err != nil {
+		t.Errorf("check() after a scan = %v, want ready", err)
+	}
+	if err := h.check(start.Add(time.Minute+ScanStaleAfter), true); err == nil {
+		t.Error("check() after ScanStaleAfter = nil, want wedged")
+	}
+}
+
+func TestScanHealthStuckRequest(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	pool := newRequestPool(2, func(workDir, requestFile string) {
+		close(started)
+		<-release
+	})
+	h := newScanHealth(time.Now(), pool)
+	h.scanned(time.Now())
+
+	const workDir = "/work/ns/sc"
+	pool.submit(workDir, workDir+"/"+RequestFileName)
+	<-started
+	now := time.Now()
+	h.scanned(now.Add(RequestStuckAfter / 2))
+	if err := h.check(now.Add(RequestStuckAfter/2), false); err != nil {
+		t.Errorf("check() with a running request = %v, want ready", err)
+	}
+
+	// Scans keep succeeding while a worker is stuck in a mount
+	h.scanned(now.Add(RequestStuckAfter + time.Second))
+	err := h.check(now.Add(RequestStuckAfter+time.Second), true)
+	if err == nil {
+		t.Fatal("check() with a stuck request = nil, want wedged")
+	}
+	if !strings.Contains(err.Error(), workDir) {
+		t.Errorf("check() = %v, want the stuck work directory", err)
+	}
+
+	close(release)
+	pool.wait()
+	if err := h.check(now.Add(RequestStuckAfter+time.Second), true); err != nil {
+		t.Errorf("check() after the request finished = %v, want alive", err)
+	}
+}
+
+func TestScanHealthHandlers(t *testing.T) {
+	h := newScanHealth(time.Now(), nil)
+
+	rec := httptest.NewRecorder()
+	h.healthzHandler(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("/healthz = %d, want %d", rec.Code, http.StatusOK)
+	}
+	rec = httptest.NewRecorder()
+	h.readyzHandler(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("/readyz before the first scan = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+
+	h.scanned(time.Now())
+	rec = httptest.NewRecorder()
+	h.readyzHandler(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("/readyz = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
@@ -21,16 +21,25 @@ package main
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"net/http"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"golang.org/x/sys/unix"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 )
 
@@ -43,40 +52,257 @@ const (
 	RequestFileName = "request.json"
 	// ReadyFileName is the name of the ready signal file
 	ReadyFileName = "ready.json"
+	// DeleteFileName is the signal file written by the provider on termination
+	DeleteFileName = "delete"
+	// ReadyMarkerName is the provider's readiness marker file
+	ReadyMarkerName = "ready"
+	// ProviderPodSuffix is appended to the instance name to form its provider pod's name
+	ProviderPodSuffix = "-provider"
+	// ImageConfigFileName holds the rootfs image's Entrypoint, Cmd and Env for the consumer
+	ImageConfigFileName = "image.json"
 	// RootfsMarkerEnv is the environment variable that identifies rootfs containers
 	RootfsMarkerEnv = "ROOTFS_MARKER=true"
-	// PollInterval is how often to scan for new requests
+	// PollInterval is how often to scan for new requests if the work directory cannot be watched
 	PollInterval = 500 * time.Millisecond
 	// MaxRetries is the maximum number of retries for finding rootfs container
 	MaxRetries = 30
 	// RetryInterval is the interval between retries
 	RetryInterval = 200 * time.Millisecond
+	// DefaultOverlayMountFlags is the default set of flags applied to the overlay mount
+	DefaultOverlayMountFlags = "nodev,nosuid"
+	// MountsFile lists the mounts visible to the mount-helper
+	MountsFile = "/proc/self/mounts"
+	// PostMountHookTimeout bounds how long the post-mount hook may run
+	PostMountHookTimeout = 30 * time.Second
+	// StorageRootsEnv overrides the node directories holding the container runtime's
+	// snapshots, as a comma-separated list
+	StorageRootsEnv = "CONTAINER_STORAGE_ROOTS"
+	// DefaultStorageRoots are the containerd and CRI-O storage roots
+	DefaultStorageRoots = "/var/lib/containerd,/var/lib/containers/storage"
+	// PersistenceMountPath is where the provider pod mounts its persistence volume in the
+	// rootfs container
+	PersistenceMountPath = "/.sc-persistence"
 )
 
+// overlayMountFlagBits maps the supported overlay mount flag names to their syscall bits.
+// noexec is deliberately absent: it would prevent the workload from executing anything.
+var overlayMountFlagBits = map[string]uintptr{
+	"nodev":      syscall.MS_NODEV,
+	"nosuid":     syscall.MS_NOSUID,
+	"noatime":    syscall.MS_NOATIME,
+	"nodiratime": syscall.MS_NODIRATIME,
+	"relatime":   syscall.MS_RELATIME,
+}
+
+// requiredOverlayMountFlags are always set on the overlay mount, whatever the configured
+// or requested flags say, so device nodes and setuid binaries in the image stay inert
+const requiredOverlayMountFlags = syscall.MS_NODEV | syscall.MS_NOSUID
+
 // MountRequest represents a request from a provider pod to set up mounts
 type MountRequest struct {
 	PodUID    string `json:"pod_uid"`
 	Namespace string `json:"namespace,omitempty"`
 	Name      string `json:"name,omitempty"`
+	// MountFlags overrides the overlay mount flags for this request (e.g. ["noatime"]).
+	// nodev and nosuid are set regardless.
+	MountFlags []string `json:"mount_flags,omitempty"`
+	// OverlayDir is a node directory (e.g. on local NVMe) that holds the overlay upper and
+	// work directories instead of the container runtime's snapshot. It must be listed in
+	// --overlay-dir-allowlist.
+	OverlayDir string `json:"overlay_dir,omitempty"`
+	// Persistent keeps the overlay upper and work directories on the persistence volume
+	// mounted in the rootfs container, so the writable layer outlives the pod
+	Persistent bool `json:"persistent,omitempty"`
 }
 
 // MountResponse represents the response after processing a mount request
 type MountResponse struct {
 	Status  string `json:"status"`
 	Message string `json:"message,omitempty"`
+	// PodUID is the provider pod the rootfs was mounted for. The request file is removed
+	// once handled, so this is what the orphan sweep checks.
+	PodUID string `json:"pod_uid,omitempty"`
+}
+
+// ImageConfig is the part of the rootfs image's config the consumer needs to pick its
+// default command and environment. All fields are empty if the image could not be inspected.
+type ImageConfig struct {
+	Entrypoint []string `json:"entrypoint,omitempty"`
+	Cmd        []string `json:"cmd,omitempty"`
+	Env        []string `json:"env,omitempty"`
 }
 
 var log logr.Logger
 
+// overlayMountFlags is the default overlay mount flags, configurable via --overlay-mount-flags
+var overlayMountFlags []string
+
+// maxMounts is the maximum number of active rootfs mounts on the node (0 = unlimited)
+var maxMounts int
+
+// concurrency is how many mount requests are processed at once, configurable via --concurrency
+var concurrency = DefaultConcurrency
+
+// requests processes the mount requests found by scanAndProcessRequests
+var requests = newRequestPool(DefaultConcurrency, handleRequest)
+
+// mountMu serializes the capacity check with the overlay mount, so concurrent requests
+// cannot exceed maxMounts
+var mountMu sync.Mutex
+
+// storageRoots are the node directories whose paths in the overlay options are looked up
+// below HostRootPath, configurable via CONTAINER_STORAGE_ROOTS
+var storageRoots = splitMountFlags(DefaultStorageRoots)
+
+// postMountHook is the command run after each mount, configurable via --post-mount-hook
+var postMountHook string
+
+// postMountHookAllowlist lists the commands allowed as post-mount hook
+var postMountHookAllowlist []string
+
+// overlayDirAllowlist lists the node directories requests may use as OverlayDir
+var overlayDirAllowlist []string
+
+// unmount detaches a mount; a variable so tests can replace it
+var unmount = syscall.Unmount
+
+// podAlive reports whether the pod with the given UID still has its rootfs container;
+// a variable so tests can replace it
+var podAlive = func(podUID string) bool {
+	_, err := findRootfsContainer(podUID)
+	return err == nil
+}
+
+// activeMountsGauge exposes the number of active rootfs mounts on the node
+var activeMountsGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+	Name: "stoppablecontainer_mount_helper_active_mounts",
+	Help: "Number of active StoppableContainer rootfs mounts on the node",
+})
+
+// mountRequestsCounter counts the handled mount requests by result (success or error)
+var mountRequestsCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "stoppablecontainer_mount_helper_requests_total",
+	Help: "Number of mount requests handled by the mount-helper, by result",
+}, []string{"result"})
+
 func main() {
+	var overlayMountFlagsStr string
+	flag.StringVar(&overlayMountFlagsStr, "overlay-mount-flags", DefaultOverlayMountFlags,
+		"Comma-separated flags for the overlay mount (nodev, nosuid, noatime, nodiratime, relatime). "+
+			"Can be overridden per request. nodev and nosuid are always set.")
+	flag.IntVar(&maxMounts, "max-mounts", 0,
+		"Maximum number of active rootfs mounts on the node. New mount requests beyond it are refused. 0 means unlimited.")
+	flag.IntVar(&concurrency, "concurrency", DefaultConcurrency,
+		"Number of mount requests processed at once.")
+	flag.StringVar(&postMountHook, "post-mount-hook", "",
+		"Absolute path of a command run after each successful mount, with the rootfs path as its only argument. "+
+			"It must also be listed in --post-mount-hook-allowlist.")
+	var postMountHookAllowlistStr string
+	flag.StringVar(&postMountHookAllowlistStr, "post-mount-hook-allowlist", "",
+		"Comma-separated absolute paths of the commands allowed as --post-mount-hook.")
+	var overlayDirAllowlistStr string
+	flag.StringVar(&overlayDirAllowlistStr, "overlay-dir-allowlist", "",
+		"Comma-separated absolute node paths that mount requests may use to hold the overlay upper and work "+
+			"directories, e.g. a directory on local NVMe. Empty disallows relocating them.")
+	var metricsAddr string
+	flag.StringVar(&metricsAddr, "metrics-bind-address", "0",
+		"The address the metrics endpoint binds to. Leave as 0 to disable the metrics endpoint.")
+	var probeAddr string
+	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081",
+		"The address the /healthz and /readyz endpoints bind to. Leave as 0 to disable them.")
+	flag.Parse()
+
 	log = zap.New(zap.UseDevMode(true))
-	log.Info("mount-helper starting", "hostRoot", HostRootPath, "workBase", WorkBasePath)
 
-	// Main loop: scan for mount requests and process them
-	for {
+	if concurrency < 1 {
+		log.Error(fmt.Errorf("must be at least 1, got %d", concurrency), "invalid --concurrency")
+		os.Exit(1)
+	}
+	requests = newRequestPool(concurrency, handleRequest)
+
+	overlayMountFlags = splitMountFlags(overlayMountFlagsStr)
+	if _, err := parseMountFlags(overlayMountFlags); err != nil {
+		log.Error(err, "invalid --overlay-mount-flags")
+		os.Exit(1)
+	}
+
+	overlayDirAllowlist = splitMountFlags(overlayDirAllowlistStr)
+	for _, dir := range overlayDirAllowlist {
+		if !filepath.IsAbs(dir) || filepath.Clean(dir) == "/" {
+			log.Error(fmt.Errorf("%q must be an absolute path below /", dir), "invalid --overlay-dir-allowlist")
+			os.Exit(1)
+		}
+	}
+
+	if roots := os.Getenv(StorageRootsEnv); roots != "" {
+		storageRoots = splitMountFlags(roots)
+	}
+	for _, root := range storageRoots {
+		if !filepath.IsAbs(root) || filepath.Clean(root) == "/" {
+			log.Error(fmt.Errorf("%q must be an absolute path below /", root), "invalid "+StorageRootsEnv)
+			os.Exit(1)
+		}
+	}
+
+	postMountHookAllowlist = splitMountFlags(postMountHookAllowlistStr)
+	if postMountHook != "" {
+		if err := checkPostMountHook(postMountHook, postMountHookAllowlist); err != nil {
+			log.Error(err, "invalid --post-mount-hook")
+			os.Exit(1)
+		}
+	}
+
+	var criSocket string
+	criClient, criImageClient, criSocket = connectCRI(os.Getenv(CRIEndpointEnv))
+	if criClient == nil {
+		log.Info("no CRI socket available, refusing mount requests since their pods cannot be verified")
+	}
+
+	log.Info("mount-helper starting", "hostRoot", HostRootPath, "workBase", WorkBasePath,
+		"overlayMountFlags", overlayMountFlags, "maxMounts", maxMounts, "concurrency", concurrency, "postMountHook", postMountHook,
+		"overlayDirAllowlist", overlayDirAllowlist, "storageRoots", storageRoots, "criSocket", criSocket)
+
+	if metricsAddr != "0" && metricsAddr != "" {
+		registry := prometheus.NewRegistry()
+		registry.MustRegister(activeMountsGauge, mountRequestsCounter)
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+		go func() {
+			if err := http.ListenAndServe(metricsAddr, mux); err != nil {
+				log.Error(err, "metrics server failed")
+			}
+		}()
+	}
+
+	health := newScanHealth(time.Now(), requests)
+	if probeAddr != "0" && probeAddr != "" {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/healthz", health.healthzHandler)
+		mux.HandleFunc("/readyz", health.readyzHandler)
+		go func() {
+			if err := http.ListenAndServe(probeAddr, mux); err != nil {
+				log.Error(err, "health probe server failed")
+			}
+		}()
+	}
+
+	scan := func() {
 		if err := scanAndProcessRequests(); err != nil {
 			log.Error(err, "error processing requests")
+			return
 		}
+		health.scanned(time.Now())
+	}
+
+	// Main loop: scan for mount requests and process them as soon as they are written
+	watcher, err := newRequestWatcher(filepath.Join(HostRootPath, WorkBasePath))
+	if err == nil {
+		watcher.run(scan)
+		return
+	}
+	log.Error(err, "cannot watch the work directory, polling for requests instead")
+	for {
+		scan()
 		time.Sleep(PollInterval)
 	}
 }
@@ -86,6 +312,10 @@ func main() {
 func scanAndProcessRequests() error {
 	hostWorkBase := filepath.Join(HostRootPath, WorkBasePath)
 
+	if mounts, err := listActiveMounts(MountsFile, hostWorkBase); err == nil {
+		activeMountsGauge.Set(float64(len(mounts)))
+	}
+
 	// Scan namespace directories
 	namespaceEntries, err := os.ReadDir(hostWorkBase)
 	if err != nil {
@@ -95,6 +325,7 @@ func scanAndProcessRequests() error {
 		return fmt.Errorf("failed to read work directory: %w", err)
 	}
 
+	var workDirs []string
 	for _, nsEntry := range namespaceEntries {
 		if !nsEntry.IsDir() {
 			continue
@@ -114,6 +345,26 @@ func scanAndProcessRequests() error {
 			}
 
 			workDir := filepath.Join(nsDir, instEntry.Name())
+
+			// A worker owns the directory until its request is done
+			if requests.busy(workDir) {
+				continue
+			}
+
+			// The provider signals on termination that the work directory can go
+			handled, err := processDeleteSignal(workDir)
+			if err != nil {
+				log.Error(err, "failed to clean up work directory", "workDir", workDir)
+				continue
+			}
+			if handled {
+				log.Info("cleaned up work directory", "workDir", workDir)
+				// Remove the namespace directory too once it is empty
+				_ = os.Remove(nsDir)
+				continue
+			}
+			workDirs = append(workDirs, workDir)
+
 			requestFile := filepath.Join(workDir, RequestFileName)
 			readyFile := filepath.Join(workDir, ReadyFileName)
 
@@ -133,21 +384,31 @@ func scanAndProcessRequests() error {
 			}
 
 			log.Info("found mount request", "workDir", workDir)
-
-			if err := processRequest(workDir, requestFile); err != nil {
-				log.Error(err, "failed to process request", "workDir", workDir)
-				// Write error response
-				_ = writeResponse(workDir, MountResponse{
-					Status:  "error",
-					Message: err.Error(),
-				})
-			}
+			requests.submit(workDir, requestFile)
 		}
 	}
 
+	// Pods that went away without the delete signal leave their work directory behind
+	sweeper.sweep(workDirs, time.Now())
+
 	return nil
 }
 
+// handleRequest processes a mount request and records its result
+func handleRequest(workDir, requestFile string) {
+	if err := processRequest(workDir, requestFile); err != nil {
+		mountRequestsCounter.WithLabelValues("error").Inc()
+		log.Error(err, "failed to process request", "workDir", workDir)
+		// Write error response
+		_ = writeResponse(workDir, MountResponse{
+			Status:  "error",
+			Message: err.Error(),
+		})
+		return
+	}
+	mountRequestsCounter.WithLabelValues("success").Inc()
+}
+
 // processRequest handles a single mount request
 func processRequest(workDir, requestFile string) error {
 	// Read request
@@ -163,6 +424,18 @@ func processRequest(workDir, requestFile string) error {
 
 	log.Info("processing request", "podUID", request.PodUID)
 
+	// Anyone who controls the provider container can write the request, so make sure
+	// it names the pod the work directory belongs to, not another instance's
+	if err := verifyProviderPod(workDir, request.PodUID); err != nil {
+		return fmt.Errorf("pod %s does not own %s: %w", request.PodUID, workDir, err)
+	}
+
+	// Refuse new mounts once the node is at capacity
+	rootfsDir := filepath.Join(workDir, "rootfs")
+	if err := checkMountCapacity(MountsFile, filepath.Join(HostRootPath, WorkBasePath), rootfsDir, maxMounts); err != nil {
+		return err
+	}
+
 	// Find the rootfs container PID with retries
 	// This handles the race condition where the request is written before
 	// the rootfs container is fully registered in /proc
@@ -196,26 +469,75 @@ func processRequest(workDir, requestFile string) error {
 	log.Info("got overlayfs options", "opts", overlayOpts)
 
 	// Create rootfs directory
-	rootfsDir := filepath.Join(workDir, "rootfs")
 	if err := os.MkdirAll(rootfsDir, 0755); err != nil {
 		return fmt.Errorf("failed to create rootfs dir: %w", err)
 	}
 
 	// Adjust paths to use /host prefix
-	overlayOptsHost := adjustPathsForHost(overlayOpts)
+	overlayOptsHost := adjustPathsForHost(overlayOpts, storageRoots)
 
-	// Mount overlayfs
-	if err := mountOverlay(rootfsDir, overlayOptsHost); err != nil {
-		return fmt.Errorf("failed to mount overlay: %w", err)
+	// Requests may move the upper and work directories to a faster disk or a volume
+	if request.Persistent {
+		if request.OverlayDir != "" {
+			return fmt.Errorf("overlay dir and persistence cannot be combined")
+		}
+		dirs, err := preparePersistentDir(fmt.Sprintf("/proc/%d/root", rootfsPID))
+		if err != nil {
+			return fmt.Errorf("invalid persistence volume: %w", err)
+		}
+		// The directories stay open until the overlay is mounted from their fd paths
+		defer dirs.Close()
+		overlayOptsHost = relocateOverlayDirs(overlayOptsHost, dirs.UpperPath(), dirs.WorkPath())
+		log.Info("placed overlay upper and work dirs on the persistence volume")
+	} else if request.OverlayDir != "" {
+		upperDir, overlayWorkDir, err := prepareOverlayDir(request.OverlayDir, request.PodUID, overlayDirAllowlist)
+		if err != nil {
+			return fmt.Errorf("invalid overlay dir: %w", err)
+		}
+		overlayOptsHost = relocateOverlayDirs(overlayOptsHost, upperDir, overlayWorkDir)
+		log.Info("relocated overlay upper and work dirs", "overlayDir", request.OverlayDir)
 	}
 
-	log.Info("mounted overlay")
+	// Requests may override the default overlay mount flags
+	flagNames := overlayMountFlags
+	if len(request.MountFlags) > 0 {
+		flagNames = request.MountFlags
+	}
+	mountFlags, err := parseMountFlags(flagNames)
+	if err != nil {
+		return fmt.Errorf("invalid mount flags: %w", err)
+	}
+
+	// Mount overlayfs, checking the capacity again since other requests may have
+	// mounted in the meantime
+	if err := mountOverlayWithinCapacity(rootfsDir, overlayOptsHost, mountFlags); err != nil {
+		return err
+	}
+
+	log.Info("mounted overlay", "flags", flagNames)
 
 	// Mount proc, dev, sys
-	if err := mountProcDevSys(rootfsDir); err != nil {
-		log.Error(err, "warning: failed to mount some special filesystems")
+	specialErr := mountProcDevSys(rootfsDir)
+	if specialErr != nil {
+		log.Error(specialErr, "warning: failed to mount some special filesystems")
 		// Continue anyway, these might already be mounted or not strictly required
 	}
+	if postMountHook != "" {
+		// The hook may rely on the special filesystems, so don't run it without them,
+		// but don't skip it silently either
+		if specialErr != nil {
+			return fmt.Errorf("post-mount hook not run, special filesystems failed to mount: %w", specialErr)
+		}
+		if err := runPostMountHook(postMountHook, postMountHookAllowlist, rootfsDir); err != nil {
+			return fmt.Errorf("post-mount hook failed: %w", err)
+		}
+		log.Info("ran post-mount hook", "hook", postMountHook)
+	}
+
+	// The consumer falls back to the image's command if the template sets none
+	if err := writeImageConfig(workDir, findRootfsImageConfig(request.PodUID)); err != nil {
+		log.Error(err, "warning: failed to write image config")
+	}
 
 	// Remove request file
 	if err := os.Remove(requestFile); err != nil {
@@ -223,15 +545,160 @@ func processRequest(workDir, requestFile string) error {
 	}
 
 	// Write ready response
-	_ = writeResponse(workDir, MountResponse{Status: "ready"})
+	_ = writeResponse(workDir, MountResponse{Status: "ready", PodUID: request.PodUID})
 
 	log.Info("mount complete", "workDir", workDir)
 	return nil
 }
 
-// findRootfsContainer searches /proc for a container with ROOTFS_MARKER env var
-// belonging to the specified pod UID
+// processDeleteSignal cleans up workDir if its provider has written the delete signal,
+// which holds the provider pod UID. A signal older than a pending request is stale
+// (a new provider took over the directory) and is dropped. Cleanup waits until the
+// pod's rootfs container is gone, since a provider container restarted in place also
+// writes the signal. The pod's relocated overlay directories are removed with it.
+// Returns true if the directory was removed.
+func processDeleteSignal(workDir string) (bool, error) {
+	deleteFile := filepath.Join(workDir, DeleteFileName)
+	deleteStat, err := os.Stat(deleteFile)
+	if err != nil {
+		return false, nil
+	}
+	if requestStat, err := os.Stat(filepath.Join(workDir, RequestFileName)); err == nil &&
+		requestStat.ModTime().After(deleteStat.ModTime()) {
+		log.Info("ignoring stale delete signal", "workDir", workDir)
+		return false, os.Remove(deleteFile)
+	}
+	podUID, err := os.ReadFile(deleteFile)
+	if err != nil {
+		return false, fmt.Errorf("failed to read delete signal: %w", err)
+	}
+	uid := strings.TrimSpace(string(podUID))
+	if uid != "" && podAlive(uid) {
+		return false, nil
+	}
+	if err := cleanupWorkDir(workDir); err != nil {
+		return true, err
+	}
+	return true, removeOverlayDirs(uid, overlayDirAllowlist)
+}
+
+// cleanupWorkDir detaches the rootfs mount tree and removes the work directory.
+// Only the known files and empty directories are removed, never recursively, so
+// nothing is deleted through a rootfs that is still mounted.
+func cleanupWorkDir(workDir string) error {
+	rootfsDir := filepath.Join(workDir, "rootfs")
+	// MNT_DETACH also detaches proc, dev and sys mounted below the rootfs
+	if err := unmount(rootfsDir, syscall.MNT_DETACH); err != nil &&
+		err != syscall.EINVAL && err != syscall.ENOENT {
+		return fmt.Errorf("failed to unmount %s: %w", rootfsDir, err)
+	}
+
+	for _, name := range []string{RequestFileName, ReadyFileName, ReadyMarkerName, ImageConfigFileName} {
+		if err := os.Remove(filepath.Join(workDir, name)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %w", name, err)
+		}
+	}
+	if err := os.Remove(rootfsDir); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove %s: %w", rootfsDir, err)
+	}
+	// The signal goes last, so a failed cleanup is retried on the next scan
+	if err := os.Remove(filepath.Join(workDir, DeleteFileName)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove delete signal: %w", err)
+	}
+	if err := os.Remove(workDir); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove %s: %w", workDir, err)
+	}
+	return nil
+}
+
+// listActiveMounts returns the rootfs overlay mount points under the work base
+func listActiveMounts(mountsFile, hostWorkBase string) ([]string, error) {
+	file, err := os.Open(mountsFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open mounts: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	var mounts []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// Format: <source> <mountpoint> <fstype> <options> 0 0
+		parts := strings.Fields(scanner.Text())
+		if len(parts) < 3 || parts[2] != "overlay" {
+			continue
+		}
+		mountPoint := parts[1]
+		if strings.HasPrefix(mountPoint, hostWorkBase+"/") && filepath.Base(mountPoint) == "rootfs" {
+			mounts = append(mounts, mountPoint)
+		}
+	}
+	return mounts, scanner.Err()
+}
+
+// checkMountCapacity refuses a new rootfs mount if the node already has maxMounts active ones.
+// Remounting an already-mounted rootfs doesn't count as a new mount.
+func checkMountCapacity(mountsFile, hostWorkBase, rootfsDir string, maxMounts int) error {
+	if maxMounts <= 0 {
+		return nil
+	}
+
+	mounts, err := listActiveMounts(mountsFile, hostWorkBase)
+	if err != nil {
+		return err
+	}
+	for _, m := range mounts {
+		if m == rootfsDir {
+			return nil
+		}
+	}
+	if len(mounts) >= maxMounts {
+		return fmt.Errorf("node has reached the maximum of %d active StoppableContainer mounts", maxMounts)
+	}
+	return nil
+}
+
+// findRootfsContainer returns the host PID of the container with ROOTFS_MARKER env var
+// belonging to the specified pod UID. It asks the container runtime if one is connected,
+// and scans /proc if not or if the runtime cannot be reached.
 func findRootfsContainer(podUID string) (int, error) {
+	if criClient != nil {
+		pid, err := findRootfsContainerCRI(context.Background(), criClient, podUID)
+		if err == nil || errors.Is(err, errRootfsContainerNotFound) {
+			return pid, err
+		}
+		log.Error(err, "CRI lookup failed, scanning /proc", "podUID", podUID)
+	}
+	return findRootfsContainerProc(podUID)
+}
+
+// verifyProviderPod checks with the container runtime that podUID is the provider pod of the
+// instance whose work directory is workDir, <namespace>/<name> below the work base. Only the
+// runtime knows which pod a UID belongs to, so without one no pod is verified.
+func verifyProviderPod(workDir, podUID string) error {
+	if criClient == nil {
+		return fmt.Errorf("no container runtime to verify the pod with")
+	}
+	namespace, name := filepath.Base(filepath.Dir(workDir)), filepath.Base(workDir)
+	return verifyPodSandboxCRI(context.Background(), criClient, podUID, namespace, name+ProviderPodSuffix)
+}
+
+// findRootfsImageConfig returns the Entrypoint, Cmd and Env of the pod's rootfs image. Only the
+// container runtime knows them, so without one (or if it fails) the config is empty.
+func findRootfsImageConfig(podUID string) *ImageConfig {
+	if criClient == nil || criImageClient == nil {
+		return &ImageConfig{}
+	}
+	config, err := findRootfsImageConfigCRI(context.Background(), criClient, criImageClient, podUID)
+	if err != nil {
+		log.Error(err, "cannot read rootfs image config", "podUID", podUID)
+		return &ImageConfig{}
+	}
+	return config
+}
+
+// findRootfsContainerProc searches /proc for a container with ROOTFS_MARKER env var
+// belonging to the specified pod UID
+func findRootfsContainerProc(podUID string) (int, error) {
 	// Build list of possible pod UID formats for cgroup matching:
 	// - cgroup v1: uses underscore format with "pod" prefix (e.g., "pod12345678_1234_1234_1234_123456789012")
 	// - cgroup v2: may use original hyphenated format (e.g., "12345678-1234-1234-1234-123456789012")
@@ -286,64 +753,377 @@ func findRootfsContainer(podUID string) (int, error) {
 		}
 	}
 
-	return 0, fmt.Errorf("rootfs container not found for pod %s", podUID)
+	return 0, fmt.Errorf("%w for pod %s", errRootfsContainerNotFound, podUID)
 }
 
 // getOverlayfsOptions reads the overlayfs mount options from a container's /proc/PID/mounts
 func getOverlayfsOptions(pid int) (string, error) {
-	mountsPath := fmt.Sprintf("/proc/%d/mounts", pid)
-	file, err := os.Open(mountsPath)
+	return rootMountOptions(fmt.Sprintf("/proc/%d/mounts", pid))
+}
+
+// rootMountOptions returns the options of the overlay mounted at / in mountsFile. The
+// mount source is ignored since it differs between runtimes.
+func rootMountOptions(mountsFile string) (string, error) {
+	file, err := os.Open(mountsFile)
 	if err != nil {
 		return "", fmt.Errorf("failed to open mounts: %w", err)
 	}
 	defer func() { _ = file.Close() }()
 
+	// A later mount at / hides the earlier ones
+	var root []string
 	scanner := bufio.NewScanner(file)
 	for scanner.Scan() {
-		line := scanner.Text()
-		// Looking for: overlay / overlay <options> 0 0
-		if strings.HasPrefix(line, "overlay / overlay ") {
-			parts := strings.Fields(line)
-			if len(parts) >= 4 {
-				return parts[3], nil
-			}
+		// Format: <source> <mountpoint> <fstype> <options> 0 0
+		parts := strings.Fields(scanner.Text())
+		if len(parts) >= 4 && parts[1] == "/" {
+			root = parts
 		}
 	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("failed to read mounts: %w", err)
+	}
+	if root == nil {
+		return "", fmt.Errorf("overlayfs mount not found")
+	}
 
-	return "", fmt.Errorf("overlayfs mount not found")
+	fsType, opts := root[2], root[3]
+	switch {
+	case fsType == "overlay":
+		return opts, nil
+	case fsType == "fuse.fuse-overlayfs" && strings.Contains(opts, "lowerdir="):
+		return opts, nil
+	case fsType == "fuse.fuse-overlayfs":
+		return "", fmt.Errorf("root filesystem is fuse-overlayfs, whose layer directories are not listed in the mount table")
+	default:
+		return "", fmt.Errorf("root filesystem is %s, not overlayfs", fsType)
+	}
 }
 
-// adjustPathsForHost adds /host prefix to containerd paths in overlay options
-// and removes unsupported options
-func adjustPathsForHost(opts string) string {
-	// Replace all occurrences of /var/lib/containerd with /host/var/lib/containerd
-	result := strings.ReplaceAll(opts, "/var/lib/containerd", HostRootPath+"/var/lib/containerd")
-
+// adjustPathsForHost adds the /host prefix to the paths below the container runtime's
+// storage roots in overlay options and removes unsupported options
+func adjustPathsForHost(opts string, roots []string) string {
 	// Parse and filter overlay options to only keep essential ones
 	// Some options like uuid=on may not be supported on all kernels
-	parts := strings.Split(result, ",")
+	parts := strings.Split(opts, ",")
 	var filtered []string
 	for _, part := range parts {
-		// Keep only essential overlay options
-		if strings.HasPrefix(part, "lowerdir=") ||
-			strings.HasPrefix(part, "upperdir=") ||
-			strings.HasPrefix(part, "workdir=") {
-			filtered = append(filtered, part)
+		key, value, _ := strings.Cut(part, "=")
+		switch key {
+		case "lowerdir":
+			// Lower directories are separated by colons
+			dirs := strings.Split(value, ":")
+			for i, dir := range dirs {
+				dirs[i] = hostStoragePath(dir, roots)
+			}
+			filtered = append(filtered, key+"="+strings.Join(dirs, ":"))
+		case "upperdir", "workdir":
+			filtered = append(filtered, key+"="+hostStoragePath(value, roots))
 		}
 	}
 
 	return strings.Join(filtered, ",")
 }
 
+// hostStoragePath returns where dir is visible in the mount-helper if it is below one
+// of the storage roots, and dir unchanged otherwise
+func hostStoragePath(dir string, roots []string) string {
+	for _, root := range roots {
+		if dir == root || strings.HasPrefix(dir, strings.TrimSuffix(root, "/")+"/") {
+			return HostRootPath + dir
+		}
+	}
+	return dir
+}
+
+// splitMountFlags splits a comma-separated list of mount flag names
+func splitMountFlags(s string) []string {
+	var names []string
+	for _, name := range strings.Split(s, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// parseMountFlags assembles the syscall mount flags from their names, always including
+// nodev and nosuid. It rejects noexec, since an overlay mounted noexec can't run the workload.
+func parseMountFlags(names []string) (uintptr, error) {
+	flags := uintptr(requiredOverlayMountFlags)
+	for _, name := range names {
+		if name == "noexec" {
+			return 0, fmt.Errorf("noexec is not allowed on the overlay mount: the workload could not execute")
+		}
+		bit, ok := overlayMountFlagBits[name]
+		if !ok {
+			return 0, fmt.Errorf("unsupported mount flag %q", name)
+		}
+		flags |= bit
+	}
+	return flags, nil
+}
+
+// checkPostMountHook verifies that hook is an absolute path listed in the allowlist
+// and is a regular file, not a symlink that could be redirected to another binary.
+func checkPostMountHook(hook string, allowlist []string) error {
+	if !filepath.IsAbs(hook) {
+		return fmt.Errorf("post-mount hook %q must be an absolute path", hook)
+	}
+	allowed := false
+	for _, entry := range allowlist {
+		if filepath.Clean(entry) == filepath.Clean(hook) {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return fmt.Errorf("post-mount hook %q is not in the allowlist", hook)
+	}
+	info, err := os.Lstat(hook)
+	if err != nil {
+		return fmt.Errorf("post-mount hook %q: %w", hook, err)
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("post-mount hook %q is not a regular file", hook)
+	}
+	return nil
+}
+
+// runPostMountHook runs the allowlisted hook with the rootfs path as its argument.
+// The hook is executed directly, without a shell.
+func runPostMountHook(hook string, allowlist []string, rootfsDir string) error {
+	if err := checkPostMountHook(hook, allowlist); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), PostMountHookTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, hook, rootfsDir).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s: %w: %s", hook, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// checkOverlayDir verifies that dir is an absolute node path listed in the allowlist
+// and an existing directory on the node
+func checkOverlayDir(dir string, allowlist []string) error {
+	if !filepath.IsAbs(dir) {
+		return fmt.Errorf("overlay dir %q must be an absolute path", dir)
+	}
+	allowed := false
+	for _, entry := range allowlist {
+		if filepath.Clean(entry) == filepath.Clean(dir) {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return fmt.Errorf("overlay dir %q is not in the allowlist", dir)
+	}
+	info, err := os.Stat(filepath.Join(HostRootPath, dir))
+	if err != nil {
+		return fmt.Errorf("overlay dir %q: %w", dir, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("overlay dir %q is not a directory", dir)
+	}
+	return nil
+}
+
+// validPodUID reports whether uid can safely be used as a directory name
+func validPodUID(uid string) bool {
+	if uid == "" {
+		return false
+	}
+	for _, r := range uid {
+		if !(r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// prepareOverlayDir creates the per-pod upper and work directories under the node's
+// overlay dir and returns their paths inside the mount-helper container. Keying them
+// by pod UID keeps the old semantics: a new provider pod starts from an empty upper layer.
+func prepareOverlayDir(dir, podUID string, allowlist []string) (string, string, error) {
+	if err := checkOverlayDir(dir, allowlist); err != nil {
+		return "", "", err
+	}
+	if !validPodUID(podUID) {
+		return "", "", fmt.Errorf("invalid pod UID %q", podUID)
+	}
+	base := filepath.Join(HostRootPath, dir, podUID)
+	upperDir, workDir := filepath.Join(base, "upper"), filepath.Join(base, "work")
+	for _, d := range []string{upperDir, workDir} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			return "", "", fmt.Errorf("failed to create %s: %w", d, err)
+		}
+	}
+	return upperDir, workDir, nil
+}
+
+// persistentDirs are the upper and work directories on a persistence volume, held open
+// so the overlay can be mounted from their /proc/self/fd paths
+type persistentDirs struct {
+	upper, work *os.File
+}
+
+// UpperPath returns the path of the open upper directory
+func (d *persistentDirs) UpperPath() string {
+	return fmt.Sprintf("/proc/self/fd/%d", d.upper.Fd())
+}
+
+// WorkPath returns the path of the open work directory
+func (d *persistentDirs) WorkPath() string {
+	return fmt.Sprintf("/proc/self/fd/%d", d.work.Fd())
+}
+
+// Close closes the directories; call it once the overlay is mounted
+func (d *persistentDirs) Close() {
+	if d.upper != nil {
+		_ = d.upper.Close()
+	}
+	if d.work != nil {
+		_ = d.work.Close()
+	}
+}
+
+// preparePersistentDir creates the upper and work directories on the persistence volume
+// mounted at PersistenceMountPath in the container root rootDir and opens them. Unlike the
+// overlay dir, they are not keyed by pod UID, so the next provider pod picks up the same
+// writable layer.
+//
+// The volume belongs to the user, who may have planted symlinks in it from another pod.
+// Paths are resolved within rootDir without following any symlink, and upper and work
+// must be directories on the volume's own device, so the overlay never writes elsewhere
+// on the node.
+func preparePersistentDir(rootDir string) (*persistentDirs, error) {
+	root, err := os.Open(rootDir)
+	if err != nil {
+		return nil, fmt.Errorf("container root: %w", err)
+	}
+	defer func() { _ = root.Close() }()
+
+	volume, err := openDirNoFollow(int(root.Fd()), strings.TrimPrefix(PersistenceMountPath, "/"),
+		unix.RESOLVE_IN_ROOT|unix.RESOLVE_NO_SYMLINKS)
+	if err != nil {
+		return nil, fmt.Errorf("persistence volume: %w", err)
+	}
+	defer func() { _ = volume.Close() }()
+	var volumeStat unix.Stat_t
+	if err := unix.Fstat(int(volume.Fd()), &volumeStat); err != nil {
+		return nil, fmt.Errorf("persistence volume: %w", err)
+	}
+
+	dirs := &persistentDirs{}
+	for _, d := range []struct {
+		name string
+		file **os.File
+	}{{"upper", &dirs.upper}, {"work", &dirs.work}} {
+		f, err := openVolumeDir(volume, d.name, volumeStat.Dev)
+		if err != nil {
+			dirs.Close()
+			return nil, err
+		}
+		*d.file = f
+	}
+	return dirs, nil
+}
+
+// openVolumeDir creates the directory name in volume if needed and opens it. It must be
+// a real directory on the device dev.
+func openVolumeDir(volume *os.File, name string, dev uint64) (*os.File, error) {
+	if err := unix.Mkdirat(int(volume.Fd()), name, 0755); err != nil && !errors.Is(err, unix.EEXIST) {
+		return nil, fmt.Errorf("failed to create %s: %w", name, err)
+	}
+	var stat unix.Stat_t
+	if err := unix.Fstatat(int(volume.Fd()), name, &stat, unix.AT_SYMLINK_NOFOLLOW); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	if stat.Mode&unix.S_IFMT != unix.S_IFDIR {
+		return nil, fmt.Errorf("%s on the persistence volume is not a directory", name)
+	}
+	f, err := openDirNoFollow(int(volume.Fd()), name, unix.RESOLVE_BENEATH|unix.RESOLVE_NO_SYMLINKS|unix.RESOLVE_NO_XDEV)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	// Check the opened directory itself, it may have been swapped since the Fstatat
+	if err := unix.Fstat(int(f.Fd()), &stat); err != nil || stat.Dev != dev {
+		_ = f.Close()
+		return nil, fmt.Errorf("%s is not on the persistence volume", name)
+	}
+	return f, nil
+}
+
+// openDirNoFollow opens the directory path below dirfd with openat2 and the resolve flags
+func openDirNoFollow(dirfd int, path string, resolve uint64) (*os.File, error) {
+	fd, err := unix.Openat2(dirfd, path, &unix.OpenHow{
+		Flags:   unix.O_PATH | unix.O_DIRECTORY | unix.O_NOFOLLOW | unix.O_CLOEXEC,
+		Resolve: resolve,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return os.NewFile(uintptr(fd), path), nil
+}
+
+// relocateOverlayDirs replaces the upperdir and workdir of overlay options,
+// keeping the lower layers of the container image
+func relocateOverlayDirs(opts, upperDir, workDir string) string {
+	parts := strings.Split(opts, ",")
+	for i, part := range parts {
+		switch {
+		case strings.HasPrefix(part, "upperdir="):
+			parts[i] = "upperdir=" + upperDir
+		case strings.HasPrefix(part, "workdir="):
+			parts[i] = "workdir=" + workDir
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// removeOverlayDirs removes the per-pod overlay directories of a deleted provider pod
+func removeOverlayDirs(podUID string, allowlist []string) error {
+	if !validPodUID(podUID) {
+		return nil
+	}
+	for _, dir := range allowlist {
+		if err := os.RemoveAll(filepath.Join(HostRootPath, dir, podUID)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mountOverlayWithinCapacity mounts the overlay at rootfsDir unless the node has reached maxMounts
+func mountOverlayWithinCapacity(rootfsDir, options string, flags uintptr) error {
+	mountMu.Lock()
+	defer mountMu.Unlock()
+	if err := checkMountCapacity(MountsFile, filepath.Join(HostRootPath, WorkBasePath), rootfsDir, maxMounts); err != nil {
+		return err
+	}
+	if err := mountOverlay(rootfsDir, options, flags); err != nil {
+		return fmt.Errorf("failed to mount overlay: %w", err)
+	}
+	return nil
+}
+
 // mountOverlay creates an overlay mount
-func mountOverlay(target, options string) error {
+func mountOverlay(target, options string, flags uintptr) error {
 	// Parse options to verify they're valid
 	if !strings.Contains(options, "lowerdir=") || !strings.Contains(options, "upperdir=") {
 		return fmt.Errorf("invalid overlay options: missing lowerdir or upperdir")
 	}
 
+	// The mount syscall only reports ENOENT, so name the missing directory up front
+	if err := checkOverlayPaths(options); err != nil {
+		return err
+	}
+
 	// Use syscall.Mount
-	err := syscall.Mount("overlay", target, "overlay", 0, options)
+	err := syscall.Mount("overlay", target, "overlay", flags, options)
 	if err != nil {
 		return fmt.Errorf("mount syscall failed: %w", err)
 	}
@@ -351,6 +1131,34 @@ func mountOverlay(target, options string) error {
 	return nil
 }
 
+// checkOverlayPaths checks that the lowerdir, upperdir and workdir directories in overlay
+// options exist, so a failed mount tells the provider which one is missing
+func checkOverlayPaths(options string) error {
+	for _, part := range strings.Split(options, ",") {
+		key, value, _ := strings.Cut(part, "=")
+		var dirs []string
+		switch key {
+		case "lowerdir":
+			dirs = strings.Split(value, ":")
+		case "upperdir", "workdir":
+			dirs = []string{value}
+		}
+		for _, dir := range dirs {
+			info, err := os.Stat(dir)
+			switch {
+			case os.IsNotExist(err):
+				return fmt.Errorf("overlay %s %s does not exist in the mount-helper; is its storage root listed in %s?",
+					key, dir, StorageRootsEnv)
+			case err != nil:
+				return fmt.Errorf("overlay %s %s: %w", key, dir, err)
+			case !info.IsDir():
+				return fmt.Errorf("overlay %s %s is not a directory", key, dir)
+			}
+		}
+	}
+	return nil
+}
+
 // mountProcDevSys mounts proc, dev, and sys into the rootfs
 func mountProcDevSys(rootfsDir string) error {
 	var errs []string
@@ -416,6 +1224,16 @@ func mountProcDevSys(rootfsDir string) error {
 	return nil
 }
 
+// writeImageConfig writes the image config file to the work directory. The consumer
+// bind-mounts the file, so it is rewritten in place rather than replaced.
+func writeImageConfig(workDir string, config *ImageConfig) error {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(workDir, ImageConfigFileName), data, 0644)
+}
+
 // writeResponse writes a response file to the work directory
 func writeResponse(workDir string, response MountResponse) error {
 	data, err := json.Marshal(response)
@@ -423,6 +1241,11 @@ func writeResponse(workDir string, response MountResponse) error {
 		return err
 	}
 
+	// Write and rename, so the provider never reads a partial response
 	readyFile := filepath.Join(workDir, ReadyFileName)
-	return os.WriteFile(readyFile, data, 0644)
+	tmpFile := readyFile + ".tmp"
+	if err := os.WriteFile(tmpFile, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile, readyFile)
 }
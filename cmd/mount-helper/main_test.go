@@ -5,7 +5,9 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"syscall"
 	"testing"
+	"time"
 )
 
 func TestAdjustPathsForHost(t *testing.T) {
@@ -35,11 +37,25 @@ func TestAdjustPathsForHost(t *testing.T) {
 			input:    "lowerdir=/var/lib/containerd/a:/other/b,upperdir=/var/lib/containerd/c",
 			expected: "lowerdir=/host/var/lib/containerd/a:/other/b,upperdir=/host/var/lib/containerd/c",
 		},
+		{
+			name: "cri-o paths",
+			input: "rw,relatime,lowerdir=/var/lib/containers/storage/overlay/l/ABC:" +
+				"/var/lib/containers/storage/overlay/l/DEF,upperdir=/var/lib/containers/storage/overlay/1f2e/diff," +
+				"workdir=/var/lib/containers/storage/overlay/1f2e/work,metacopy=on,volatile",
+			expected: "lowerdir=/host/var/lib/containers/storage/overlay/l/ABC:" +
+				"/host/var/lib/containers/storage/overlay/l/DEF,upperdir=/host/var/lib/containers/storage/overlay/1f2e/diff," +
+				"workdir=/host/var/lib/containers/storage/overlay/1f2e/work",
+		},
+		{
+			name:     "root prefix of another directory",
+			input:    "lowerdir=/var/lib/containerd2/a,upperdir=/var/lib/containers/storage-old/b",
+			expected: "lowerdir=/var/lib/containerd2/a,upperdir=/var/lib/containers/storage-old/b",
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := adjustPathsForHost(tt.input)
+			result := adjustPathsForHost(tt.input, splitMountFlags(DefaultStorageRoots))
 			if result != tt.expected {
 				t.Errorf("adjustPathsForHost(%q) = %q, want %q", tt.input, result, tt.expected)
 			}
@@ -47,6 +63,106 @@ func TestAdjustPathsForHost(t *testing.T) {
 	}
 }
 
+func TestAdjustPathsForHost_CustomRoots(t *testing.T) {
+	roots := []string{"/data/containers/storage/"}
+	input := "lowerdir=/data/containers/storage/overlay/l/A,upperdir=/var/lib/containerd/b"
+	want := "lowerdir=/host/data/containers/storage/overlay/l/A,upperdir=/var/lib/containerd/b"
+	if got := adjustPathsForHost(input, roots); got != want {
+		t.Errorf("adjustPathsForHost(%q) = %q, want %q", input, got, want)
+	}
+}
+
+func TestRootMountOptions(t *testing.T) {
+	tests := []struct {
+		name      string
+		mounts    []string
+		want      string
+		expectErr bool
+	}{
+		{
+			name: "containerd",
+			mounts: []string{
+				"overlay / overlay rw,relatime,lowerdir=/var/lib/containerd/1/fs,upperdir=/var/lib/containerd/2/fs,workdir=/var/lib/containerd/2/work 0 0",
+				"proc /proc proc rw,nosuid,nodev,noexec,relatime 0 0",
+			},
+			want: "rw,relatime,lowerdir=/var/lib/containerd/1/fs,upperdir=/var/lib/containerd/2/fs,workdir=/var/lib/containerd/2/work",
+		},
+		{
+			name: "cri-o",
+			mounts: []string{
+				"overlay / overlay rw,relatime,lowerdir=/var/lib/containers/storage/overlay/l/ABC,upperdir=/var/lib/containers/storage/overlay/1f2e/diff,workdir=/var/lib/containers/storage/overlay/1f2e/work,metacopy=on 0 0",
+				"tmpfs /dev tmpfs rw,nosuid,size=65536k,mode=755 0 0",
+			},
+			want: "rw,relatime,lowerdir=/var/lib/containers/storage/overlay/l/ABC,upperdir=/var/lib/containers/storage/overlay/1f2e/diff,workdir=/var/lib/containers/storage/overlay/1f2e/work,metacopy=on",
+		},
+		{
+			name: "other source name",
+			mounts: []string{
+				"none / overlay rw,lowerdir=/a,upperdir=/b,workdir=/c 0 0",
+			},
+			want: "rw,lowerdir=/a,upperdir=/b,workdir=/c",
+		},
+		{
+			name: "root mounted over",
+			mounts: []string{
+				"rootfs / rootfs rw 0 0",
+				"overlay / overlay rw,lowerdir=/a,upperdir=/b,workdir=/c 0 0",
+			},
+			want: "rw,lowerdir=/a,upperdir=/b,workdir=/c",
+		},
+		{
+			name: "fuse-overlayfs with layer directories",
+			mounts: []string{
+				"fuse-overlayfs / fuse.fuse-overlayfs rw,lowerdir=/a,upperdir=/b,workdir=/c 0 0",
+			},
+			want: "rw,lowerdir=/a,upperdir=/b,workdir=/c",
+		},
+		{
+			name: "fuse-overlayfs without layer directories",
+			mounts: []string{
+				"fuse-overlayfs / fuse.fuse-overlayfs rw,nodev,noatime,user_id=0,group_id=0,allow_other 0 0",
+			},
+			expectErr: true,
+		},
+		{
+			name: "not overlayfs",
+			mounts: []string{
+				"/dev/sda1 / ext4 rw,relatime 0 0",
+			},
+			expectErr: true,
+		},
+		{
+			name: "no root mount",
+			mounts: []string{
+				"proc /proc proc rw 0 0",
+			},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mountsFile := filepath.Join(t.TempDir(), "mounts")
+			if err := os.WriteFile(mountsFile, []byte(strings.Join(tt.mounts, "\n")+"\n"), 0644); err != nil {
+				t.Fatalf("Failed to write mounts file: %v", err)
+			}
+			got, err := rootMountOptions(mountsFile)
+			if tt.expectErr {
+				if err == nil {
+					t.Errorf("rootMountOptions() = %q, expected error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("rootMountOptions() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("rootMountOptions() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestMountRequest_JSON(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -111,3 +227,467 @@ func TestWriteResponse(t *testing.T) {
 		t.Errorf("Ready file should contain 'test message', got %s", string(data))
 	}
 }
+
+func TestParseMountFlags(t *testing.T) {
+	tests := []struct {
+		name      string
+		flags     []string
+		expected  uintptr
+		expectErr bool
+	}{
+		{
+			name:     "no flags still sets nodev and nosuid",
+			flags:    nil,
+			expected: syscall.MS_NODEV | syscall.MS_NOSUID,
+		},
+		{
+			name:     "default flags",
+			flags:    splitMountFlags(DefaultOverlayMountFlags),
+			expected: syscall.MS_NODEV | syscall.MS_NOSUID,
+		},
+		{
+			name:     "override without nodev and nosuid keeps them",
+			flags:    []string{"noatime"},
+			expected: syscall.MS_NOATIME | syscall.MS_NODEV | syscall.MS_NOSUID,
+		},
+		{
+			name:      "noexec is rejected",
+			flags:     []string{"nodev", "noexec"},
+			expectErr: true,
+		},
+		{
+			name:      "unknown flag is rejected",
+			flags:     []string{"bogus"},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := parseMountFlags(tt.flags)
+			if tt.expectErr {
+				if err == nil {
+					t.Errorf("parseMountFlags(%v) expected error, got %#x", tt.flags, result)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseMountFlags(%v) unexpected error: %v", tt.flags, err)
+			}
+			if result != tt.expected {
+				t.Errorf("parseMountFlags(%v) = %#x, want %#x", tt.flags, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSplitMountFlags(t *testing.T) {
+	result := splitMountFlags(" nodev, ,nosuid ")
+	if len(result) != 2 || result[0] != "nodev" || result[1] != "nosuid" {
+		t.Errorf("splitMountFlags() = %v, want [nodev nosuid]", result)
+	}
+}
+
+func TestMountRequest_MountFlags(t *testing.T) {
+	var req MountRequest
+	if err := json.Unmarshal([]byte(`{"pod_uid":"abc","mount_flags":["nodev"]}`), &req); err != nil {
+		t.Fatalf("Failed to unmarshal: %v", err)
+	}
+	if len(req.MountFlags) != 1 || req.MountFlags[0] != "nodev" {
+		t.Errorf("MountFlags = %v, want [nodev]", req.MountFlags)
+	}
+}
+
+func TestCheckMountCapacity(t *testing.T) {
+	base := "/host/var/lib/stoppablecontainer"
+	mounts := strings.Join([]string{
+		"overlay / overlay rw,lowerdir=/a,upperdir=/b,workdir=/c 0 0",
+		"overlay " + base + "/default/app1/rootfs overlay rw,nodev,nosuid 0 0",
+		"overlay " + base + "/default/app2/rootfs overlay rw,nodev,nosuid 0 0",
+		"proc " + base + "/default/app1/rootfs/proc proc rw 0 0",
+		"overlay /var/lib/containerd/other/rootfs overlay rw 0 0",
+	}, "\n") + "\n"
+	mountsFile := filepath.Join(t.TempDir(), "mounts")
+	if err := os.WriteFile(mountsFile, []byte(mounts), 0644); err != nil {
+		t.Fatalf("Failed to write mounts file: %v", err)
+	}
+
+	active, err := listActiveMounts(mountsFile, base)
+	if err != nil {
+		t.Fatalf("listActiveMounts() error = %v", err)
+	}
+	if len(active) != 2 {
+		t.Errorf("listActiveMounts() = %v, want 2 mounts", active)
+	}
+
+	tests := []struct {
+		name      string
+		rootfsDir string
+		maxMounts int
+		expectErr bool
+	}{
+		{"unlimited", base + "/default/app3/rootfs", 0, false},
+		{"below max", base + "/default/app3/rootfs", 3, false},
+		{"at max", base + "/default/app3/rootfs", 2, true},
+		{"remount at max", base + "/default/app1/rootfs", 2, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkMountCapacity(mountsFile, base, tt.rootfsDir, tt.maxMounts)
+			if tt.expectErr && err == nil {
+				t.Error("checkMountCapacity() expected error")
+			}
+			if !tt.expectErr && err != nil {
+				t.Errorf("checkMountCapacity() unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestRunPostMountHook(t *testing.T) {
+	dir := t.TempDir()
+	outFile := filepath.Join(dir, "out")
+	hook := filepath.Join(dir, "hook.sh")
+	script := "#!/bin/sh\necho \"$1\" > " + outFile + "\n"
+	if err := os.WriteFile(hook, []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write hook: %v", err)
+	}
+	failing := filepath.Join(dir, "fail.sh")
+	if err := os.WriteFile(failing, []byte("#!/bin/sh\necho boom\nexit 3\n"), 0755); err != nil {
+		t.Fatalf("Failed to write hook: %v", err)
+	}
+	link := filepath.Join(dir, "link.sh")
+	if err := os.Symlink(hook, link); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	rootfsDir := "/host/var/lib/stoppablecontainer/default/app/rootfs"
+
+	t.Run("runs with rootfs argument", func(t *testing.T) {
+		if err := runPostMountHook(hook, []string{hook}, rootfsDir); err != nil {
+			t.Fatalf("runPostMountHook() error = %v", err)
+		}
+		data, err := os.ReadFile(outFile)
+		if err != nil {
+			t.Fatalf("hook did not run: %v", err)
+		}
+		if got := strings.TrimSpace(string(data)); got != rootfsDir {
+			t.Errorf("hook argument = %q, want %q", got, rootfsDir)
+		}
+	})
+
+	t.Run("reports hook failure", func(t *testing.T) {
+		err := runPostMountHook(failing, []string{failing}, rootfsDir)
+		if err == nil || !strings.Contains(err.Error(), "boom") {
+			t.Errorf("runPostMountHook() error = %v, want failure with output", err)
+		}
+	})
+
+	tests := []struct {
+		name      string
+		hook      string
+		allowlist []string
+	}{
+		{"empty allowlist", hook, nil},
+		{"not allowlisted", hook, []string{failing}},
+		{"relative path", "hook.sh", []string{"hook.sh"}},
+		{"symlink", link, []string{link}},
+		{"missing", filepath.Join(dir, "missing"), []string{filepath.Join(dir, "missing")}},
+	}
+	for _, tt := range tests {
+		t.Run("rejects "+tt.name, func(t *testing.T) {
+			_ = os.Remove(outFile)
+			if err := runPostMountHook(tt.hook, tt.allowlist, rootfsDir); err == nil {
+				t.Error("runPostMountHook() expected error")
+			}
+			if _, err := os.Stat(outFile); err == nil {
+				t.Error("hook ran despite being rejected")
+			}
+		})
+	}
+}
+
+func TestProcessDeleteSignal(t *testing.T) {
+	origUnmount, origPodAlive := unmount, podAlive
+	t.Cleanup(func() { unmount, podAlive = origUnmount, origPodAlive })
+	unmount = func(string, int) error { return syscall.EINVAL }
+	podAlive = func(string) bool { return false }
+
+	newWorkDir := func(t *testing.T, files ...string) string {
+		workDir := filepath.Join(t.TempDir(), "default", "app")
+		if err := os.MkdirAll(filepath.Join(workDir, "rootfs"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		for _, f := range files {
+			if err := os.WriteFile(filepath.Join(workDir, f), []byte("uid-1"), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return workDir
+	}
+
+	t.Run("no signal", func(t *testing.T) {
+		workDir := newWorkDir(t, RequestFileName)
+		handled, err := processDeleteSignal(workDir)
+		if handled || err != nil {
+			t.Fatalf("processDeleteSignal() = %v, %v; want false, nil", handled, err)
+		}
+	})
+
+	t.Run("removes work directory", func(t *testing.T) {
+		workDir := newWorkDir(t, RequestFileName, ReadyFileName, ReadyMarkerName, DeleteFileName)
+		handled, err := processDeleteSignal(workDir)
+		if !handled || err != nil {
+			t.Fatalf("processDeleteSignal() = %v, %v; want true, nil", handled, err)
+		}
+		if _, err := os.Stat(workDir); !os.IsNotExist(err) {
+			t.Errorf("work directory still exists: %v", err)
+		}
+	})
+
+	t.Run("drops stale signal", func(t *testing.T) {
+		workDir := newWorkDir(t, DeleteFileName, RequestFileName)
+		old := time.Now().Add(-time.Minute)
+		if err := os.Chtimes(filepath.Join(workDir, DeleteFileName), old, old); err != nil {
+			t.Fatal(err)
+		}
+		handled, err := processDeleteSignal(workDir)
+		if handled || err != nil {
+			t.Fatalf("processDeleteSignal() = %v, %v; want false, nil", handled, err)
+		}
+		if _, err := os.Stat(filepath.Join(workDir, DeleteFileName)); !os.IsNotExist(err) {
+			t.Errorf("stale signal not removed: %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(workDir, RequestFileName)); err != nil {
+			t.Errorf("request removed: %v", err)
+		}
+	})
+
+	t.Run("waits while pod is alive", func(t *testing.T) {
+		podAlive = func(uid string) bool { return uid == "uid-1" }
+		defer func() { podAlive = func(string) bool { return false } }()
+		workDir := newWorkDir(t, DeleteFileName)
+		handled, err := processDeleteSignal(workDir)
+		if handled || err != nil {
+			t.Fatalf("processDeleteSignal() = %v, %v; want false, nil", handled, err)
+		}
+		if _, err := os.Stat(filepath.Join(workDir, DeleteFileName)); err != nil {
+			t.Errorf("signal removed: %v", err)
+		}
+	})
+
+	t.Run("keeps signal when unmount fails", func(t *testing.T) {
+		unmount = func(string, int) error { return syscall.EBUSY }
+		defer func() { unmount = func(string, int) error { return syscall.EINVAL } }()
+		workDir := newWorkDir(t, DeleteFileName)
+		if _, err := processDeleteSignal(workDir); err == nil {
+			t.Fatal("expected an error")
+		}
+		if _, err := os.Stat(filepath.Join(workDir, DeleteFileName)); err != nil {
+			t.Errorf("signal removed: %v", err)
+		}
+	})
+}
+
+func TestRelocateOverlayDirs(t *testing.T) {
+	opts := "lowerdir=/host/var/lib/containerd/a:/host/var/lib/containerd/b," +
+		"upperdir=/host/var/lib/containerd/c,workdir=/host/var/lib/containerd/d"
+	expected := "lowerdir=/host/var/lib/containerd/a:/host/var/lib/containerd/b," +
+		"upperdir=/host/mnt/nvme/sc/uid-1/upper,workdir=/host/mnt/nvme/sc/uid-1/work"
+
+	result := relocateOverlayDirs(opts, "/host/mnt/nvme/sc/uid-1/upper", "/host/mnt/nvme/sc/uid-1/work")
+	if result != expected {
+		t.Errorf("relocateOverlayDirs() = %q, want %q", result, expected)
+	}
+}
+
+func TestCheckOverlayPaths(t *testing.T) {
+	base := t.TempDir()
+	for _, dir := range []string{"lower1", "lower2", "upper", "work"} {
+		if err := os.Mkdir(filepath.Join(base, dir), 0755); err != nil {
+			t.Fatalf("Failed to create %s: %v", dir, err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(base, "file"), nil, 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		options string
+		wantErr string
+	}{
+		{
+			name:    "all present",
+			options: "lowerdir=" + base + "/lower1:" + base + "/lower2,upperdir=" + base + "/upper,workdir=" + base + "/work",
+		},
+		{
+			name:    "missing lowerdir",
+			options: "lowerdir=" + base + "/lower1:" + base + "/gone,upperdir=" + base + "/upper,workdir=" + base + "/work",
+			wantErr: "overlay lowerdir " + base + "/gone does not exist",
+		},
+		{
+			name:    "missing upperdir",
+			options: "lowerdir=" + base + "/lower1,upperdir=/var/lib/containers/storage/overlay/1f2e/diff,workdir=" + base + "/work",
+			wantErr: "overlay upperdir /var/lib/containers/storage/overlay/1f2e/diff does not exist",
+		},
+		{
+			name:    "missing workdir",
+			options: "lowerdir=" + base + "/lower1,upperdir=" + base + "/upper,workdir=" + base + "/gone",
+			wantErr: "overlay workdir " + base + "/gone does not exist",
+		},
+		{
+			name:    "not a directory",
+			options: "lowerdir=" + base + "/file,upperdir=" + base + "/upper,workdir=" + base + "/work",
+			wantErr: "overlay lowerdir " + base + "/file is not a directory",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkOverlayPaths(tt.options)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("checkOverlayPaths() unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("checkOverlayPaths() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPreparePersistentDir(t *testing.T) {
+	rootDir := t.TempDir()
+	volumeDir := filepath.Join(rootDir, PersistenceMountPath)
+	if err := os.Mkdir(volumeDir, 0755); err != nil {
+		t.Fatalf("Failed to create volume: %v", err)
+	}
+	dirs, err := preparePersistentDir(rootDir)
+	if err != nil {
+		t.Fatalf("preparePersistentDir() error = %v", err)
+	}
+	if err := checkOverlayPaths("upperdir=" + dirs.UpperPath() + ",workdir=" + dirs.WorkPath()); err != nil {
+		t.Errorf("directories not opened: %v", err)
+	}
+	// The fd paths lead to the directories on the volume
+	if err := os.WriteFile(filepath.Join(dirs.UpperPath(), "kept"), nil, 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	dirs.Close()
+
+	// The next provider pod reuses the writable layer
+	dirs, err = preparePersistentDir(rootDir)
+	if err != nil {
+		t.Fatalf("preparePersistentDir() again error = %v", err)
+	}
+	dirs.Close()
+	if _, err := os.Stat(filepath.Join(volumeDir, "upper", "kept")); err != nil {
+		t.Errorf("upper dir content lost: %v", err)
+	}
+
+	if _, err := preparePersistentDir(t.TempDir()); err == nil {
+		t.Error("preparePersistentDir() without the volume expected error")
+	}
+}
+
+func TestPreparePersistentDir_Untrusted(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(t *testing.T, volumeDir, target string)
+	}{
+		{
+			name: "symlinked upper",
+			setup: func(t *testing.T, volumeDir, target string) {
+				if err := os.Symlink(target, filepath.Join(volumeDir, "upper")); err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+		{
+			name: "relative symlinked work",
+			setup: func(t *testing.T, volumeDir, target string) {
+				if err := os.Symlink("../../..", filepath.Join(volumeDir, "work")); err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+		{
+			name: "upper is a file",
+			setup: func(t *testing.T, volumeDir, target string) {
+				if err := os.WriteFile(filepath.Join(volumeDir, "upper"), nil, 0644); err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+		{
+			name: "symlinked volume",
+			setup: func(t *testing.T, volumeDir, target string) {
+				if err := os.Remove(volumeDir); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Symlink(target, volumeDir); err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// target stands in for a directory on the node, outside the container root
+			target := t.TempDir()
+			rootDir := t.TempDir()
+			volumeDir := filepath.Join(rootDir, PersistenceMountPath)
+			if err := os.Mkdir(volumeDir, 0755); err != nil {
+				t.Fatal(err)
+			}
+			tt.setup(t, volumeDir, target)
+
+			if dirs, err := preparePersistentDir(rootDir); err == nil {
+				dirs.Close()
+				t.Fatal("preparePersistentDir() expected error")
+			}
+			if entries, _ := os.ReadDir(target); len(entries) != 0 {
+				t.Errorf("created %v outside the volume", entries)
+			}
+		})
+	}
+}
+
+func TestCheckOverlayDir(t *testing.T) {
+	tests := []struct {
+		name      string
+		dir       string
+		allowlist []string
+	}{
+		{"relative path", "mnt/nvme", []string{"mnt/nvme"}},
+		{"not allowlisted", "/mnt/nvme", []string{"/mnt/ssd"}},
+		{"empty allowlist", "/mnt/nvme", nil},
+		{"missing on node", "/nonexistent-overlay-dir", []string{"/nonexistent-overlay-dir"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkOverlayDir(tt.dir, tt.allowlist); err == nil {
+				t.Errorf("checkOverlayDir(%q) expected error", tt.dir)
+			}
+		})
+	}
+}
+
+func TestValidPodUID(t *testing.T) {
+	tests := map[string]bool{
+		"0b6b5e4c-8f3e-4a57-9d61-1c2f3a4b5c6d": true,
+		"":                                     false,
+		"..":                                   false,
+		"../../etc":                            false,
+		"uid/with/slash":                       false,
+	}
+	for uid, expected := range tests {
+		if got := validPodUID(uid); got != expected {
+			t.Errorf("validPodUID(%q) = %v, want %v", uid, got, expected)
+		}
+	}
+}
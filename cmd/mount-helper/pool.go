@@ -0,0 +1,108 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultConcurrency is how many mount requests are processed at once by default
+const DefaultConcurrency = 4
+
+// requestPool processes mount requests on a bounded number of workers, so one request
+// waiting for its rootfs container does not hold up the others. A work directory is
+// handled by at most one worker at a time.
+type requestPool struct {
+	sem     chan struct{}
+	process func(workDir, requestFile string)
+
+	mu sync.Mutex
+	// inFlight holds the work directories with a queued or running request
+	inFlight map[string]bool
+	// running holds when the running requests got a worker, by work directory
+	running map[string]time.Time
+	wg      sync.WaitGroup
+}
+
+func newRequestPool(workers int, process func(workDir, requestFile string)) *requestPool {
+	if workers < 1 {
+		workers = 1
+	}
+	return &requestPool{
+		sem:      make(chan struct{}, workers),
+		process:  process,
+		inFlight: map[string]bool{},
+		running:  map[string]time.Time{},
+	}
+}
+
+// submit queues the request in workDir and returns immediately. It returns false if
+// workDir already has a request queued or running.
+func (p *requestPool) submit(workDir, requestFile string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.inFlight[workDir] {
+		return false
+	}
+	p.inFlight[workDir] = true
+	p.wg.Add(1)
+
+	go func() {
+		defer p.wg.Done()
+		p.sem <- struct{}{}
+		p.mu.Lock()
+		p.running[workDir] = time.Now()
+		p.mu.Unlock()
+		defer func() {
+			<-p.sem
+			p.mu.Lock()
+			delete(p.inFlight, workDir)
+			delete(p.running, workDir)
+			p.mu.Unlock()
+		}()
+		p.process(workDir, requestFile)
+	}()
+	return true
+}
+
+// busy reports whether workDir has a request queued or running
+func (p *requestPool) busy(workDir string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.inFlight[workDir]
+}
+
+// oldest returns the work directory of the request that has been running the longest
+// and when it started, or false if none is running
+func (p *requestPool) oldest() (string, time.Time, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var workDir string
+	var started time.Time
+	for dir, t := range p.running {
+		if workDir == "" || t.Before(started) {
+			workDir, started = dir, t
+		}
+	}
+	return workDir, started, workDir != ""
+}
+
+// wait blocks until all submitted requests are done
+func (p *requestPool) wait() {
+	p.wg.Wait()
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRequestPoolBoundsConcurrency(t *testing.T) {
+	const workers = 3
+	var mu sync.Mutex
+	running, maxRunning, done := 0, 0, 0
+	pool := newRequestPool(workers, func(workDir, requestFile string) {
+		mu.Lock()
+		running++
+		maxRunning = max(maxRunning, running)
+		mu.Unlock()
+
+		// A slow request, e.g. one waiting for its rootfs container
+		time.Sleep(20 * time.Millisecond)
+
+		mu.Lock()
+		running--
+		done++
+		mu.Unlock()
+	})
+
+	for i := 0; i < 10; i++ {
+		workDir := fmt.Sprintf("/work/ns/sc-%d", i)
+		if !pool.submit(workDir, workDir+"/"+RequestFileName) {
+			t.Fatalf("submit(%s) = false, want true", workDir)
+		}
+	}
+	pool.wait()
+
+	if done != 10 {
+		t.Errorf("processed %d requests, want 10", done)
+	}
+	if maxRunning != workers {
+		t.Errorf("at most %d requests ran at once, want %d", maxRunning, workers)
+	}
+}
+
+func TestRequestPoolOneRequestPerWorkDir(t *testing.T) {
+	release := make(chan struct{})
+	calls := 0
+	pool := newRequestPool(2, func(workDir, requestFile string) {
+		calls++
+		<-release
+	})
+
+	const workDir = "/work/ns/sc"
+	if !pool.submit(workDir, workDir+"/"+RequestFileName) {
+		t.Fatal("first submit = false, want true")
+	}
+	if !pool.busy(workDir) {
+		t.Error("busy() while processing = false, want true")
+	}
+	if pool.submit(workDir, workDir+"/"+RequestFileName) {
+		t.Error("second submit while processing = true, want false")
+	}
+
+	close(release)
+	pool.wait()
+	if calls != 1 {
+		t.Errorf("processed %d times, want 1", calls)
+	}
+	if pool.busy(workDir) {
+		t.Error("busy() after processing = true, want false")
+	}
+	if !pool.submit(workDir, workDir+"/"+RequestFileName) {
+		t.Error("submit after processing = false, want true")
+	}
+	pool.wait()
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+const (
+	// FallbackScanInterval is how often the work directory is scanned in case a file event was missed
+	FallbackScanInterval = 30 * time.Second
+	// EventDelay is how long a scan waits after the first file event. Events within it are
+	// handled by the same scan, and a request file written in place is complete by then.
+	EventDelay = 50 * time.Millisecond
+)
+
+// requestWatcher scans the work directory whenever a file changes in it, so mount
+// requests are handled as soon as they are written
+type requestWatcher struct {
+	watcher *fsnotify.Watcher
+	base    string
+}
+
+// newRequestWatcher watches the work directory base and its namespace and instance directories
+func newRequestWatcher(base string) (*requestWatcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	w := &requestWatcher{watcher: watcher, base: base}
+	w.sync()
+	return w, nil
+}
+
+// watchDirs returns the directories to watch for requests below base: base itself, its
+// namespace directories and their instance directories. If base does not exist yet, its
+// parent is watched instead so its creation is noticed.
+func watchDirs(base string) []string {
+	namespaceEntries, err := os.ReadDir(base)
+	if err != nil {
+		return []string{filepath.Dir(base)}
+	}
+	dirs := []string{base}
+	for _, nsEntry := range namespaceEntries {
+		if !nsEntry.IsDir() {
+			continue
+		}
+		nsDir := filepath.Join(base, nsEntry.Name())
+		dirs = append(dirs, nsDir)
+		instanceEntries, err := os.ReadDir(nsDir)
+		if err != nil {
+			continue
+		}
+		for _, instEntry := range instanceEntries {
+			if instEntry.IsDir() {
+				dirs = append(dirs, filepath.Join(nsDir, instEntry.Name()))
+			}
+		}
+	}
+	return dirs
+}
+
+// sync adds watches for new directories. Watches on removed directories are dropped by
+// the kernel.
+func (w *requestWatcher) sync() {
+	dirs := watchDirs(w.base)
+	watched := w.watcher.WatchList()
+	for _, dir := range dirs {
+		if slices.Contains(watched, dir) {
+			continue
+		}
+		if err := w.watcher.Add(dir); err != nil {
+			log.Error(err, "failed to watch directory", "dir", dir)
+		}
+	}
+
+	// The parent is only watched until the work directory exists
+	if parent := filepath.Dir(w.base); slices.Contains(watched, parent) && !slices.Contains(dirs, parent) {
+		_ = w.watcher.Remove(parent)
+	}
+}
+
+// run calls scan on start, shortly after file events and every FallbackScanInterval,
+// until the watcher is closed
+func (w *requestWatcher) run(scan func()) {
+	fallback := time.NewTicker(FallbackScanInterval)
+	defer fallback.Stop()
+
+	// pending fires EventDelay after the first unhandled event; nil while there is none
+	var pending <-chan time.Time
+
+	scan()
+	for {
+		select {
+		case _, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			if pending == nil {
+				pending = time.After(EventDelay)
+			}
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			// Events may have been lost (e.g. queue overflow), so scan anyway
+			log.Error(err, "file watch failed")
+			if pending == nil {
+				pending = time.After(EventDelay)
+			}
+		case <-pending:
+			pending = nil
+			w.sync()
+			scan()
+		case <-fallback.C:
+			w.sync()
+			scan()
+		}
+	}
+}
+
+// Close stops the watcher, which ends run
+func (w *requestWatcher) Close() error {
+	return w.watcher.Close()
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestWatchDirs(t *testing.T) {
+	base := filepath.Join(t.TempDir(), "stoppablecontainer")
+
+	// Until the work directory exists, its parent is watched
+	if dirs := watchDirs(base); !reflect.DeepEqual(dirs, []string{filepath.Dir(base)}) {
+		t.Errorf("watchDirs() = %v, want the parent", dirs)
+	}
+
+	instDir := filepath.Join(base, "default", "my-app")
+	if err := os.MkdirAll(filepath.Join(instDir, "rootfs"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(instDir, RequestFileName), []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Directories below the instance directories (the rootfs mount) are not watched
+	expected := []string{base, filepath.Join(base, "default"), instDir}
+	if dirs := watchDirs(base); !reflect.DeepEqual(dirs, expected) {
+		t.Errorf("watchDirs() = %v, want %v", dirs, expected)
+	}
+}
+
+func TestRequestWatcher(t *testing.T) {
+	base := filepath.Join(t.TempDir(), "stoppablecontainer")
+	instDir := filepath.Join(base, "default", "my-app")
+	requestFile := filepath.Join(instDir, RequestFileName)
+
+	w, err := newRequestWatcher(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := make(chan struct{}, 1)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		w.run(func() {
+			if _, err := os.Stat(requestFile); err == nil {
+				select {
+				case found <- struct{}{}:
+				default:
+				}
+			}
+		})
+	}()
+	defer func() {
+		_ = w.Close()
+		<-done
+	}()
+
+	// The work directory is created after the watcher started, so it is picked up from the parent
+	if err := os.MkdirAll(instDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for !slices.Contains(w.watcher.WatchList(), instDir) {
+		if time.Now().After(deadline) {
+			t.Fatalf("instance directory not watched, watching %v", w.watcher.WatchList())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if slices.Contains(w.watcher.WatchList(), filepath.Dir(base)) {
+		t.Error("the parent should no longer be watched once the work directory exists")
+	}
+
+	if err := os.WriteFile(requestFile, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-found:
+	case <-time.After(5 * time.Second):
+		t.Fatal("writing a request did not trigger a scan")
+	}
+}
@@ -31,6 +31,11 @@ import (
 )
 
 func main() {
+	// --check exits immediately, to verify the image can be pulled and started
+	if len(os.Args) > 1 && os.Args[1] == "--check" {
+		return
+	}
+
 	// Create a channel to receive signals
 	sigChan := make(chan os.Signal, 1)
 
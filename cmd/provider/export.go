@@ -0,0 +1,116 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// exportRootfs writes a tar archive of the full rootfs at root to w, as used by
+// kubectl sc export-rootfs. The provider image has no tar, so this is built in.
+// The /proc, /dev and /sys mounts below the rootfs are kept as empty directories:
+// the walk does not cross into other filesystems.
+func exportRootfs(w io.Writer, root string) error {
+	rootInfo, err := os.Lstat(root)
+	if err != nil {
+		return err
+	}
+	if !rootInfo.IsDir() {
+		return fmt.Errorf("%s is not a directory", root)
+	}
+	rootDev := rootInfo.Sys().(*syscall.Stat_t).Dev
+
+	tw := tar.NewWriter(w)
+	// hardLinks maps the inode of each file with several links to its first name
+	hardLinks := map[uint64]string{}
+
+	err = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name, err := filepath.Rel(root, p)
+		if err != nil || name == "." {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		// Sockets cannot be archived and are recreated by whatever listens on them
+		if info.Mode()&fs.ModeSocket != 0 {
+			return nil
+		}
+
+		var link string
+		if info.Mode()&fs.ModeSymlink != 0 {
+			if link, err = os.Readlink(p); err != nil {
+				return err
+			}
+		}
+		hdr, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		hdr.Name = filepath.ToSlash(name)
+		// User and group names would be looked up in the provider's (empty) /etc
+		hdr.Uname, hdr.Gname = "", ""
+
+		stat := info.Sys().(*syscall.Stat_t)
+		if info.Mode().IsRegular() && stat.Nlink > 1 {
+			if first, ok := hardLinks[stat.Ino]; ok {
+				hdr.Typeflag, hdr.Linkname, hdr.Size = tar.TypeLink, first, 0
+			} else {
+				hardLinks[stat.Ino] = hdr.Name
+			}
+		}
+		if info.IsDir() {
+			hdr.Name += "/"
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+
+		switch {
+		case info.IsDir() && stat.Dev != rootDev:
+			return fs.SkipDir
+		case hdr.Typeflag == tar.TypeReg:
+			return copyFile(tw, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+// copyFile copies the contents of the file at p to w
+func copyFile(w io.Writer, p string) error {
+	f, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+	_, err = io.Copy(w, f)
+	return err
+}
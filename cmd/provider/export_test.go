@@ -0,0 +1,104 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExportRootfs(t *testing.T) {
+	root := t.TempDir()
+	mustWrite := func(name, content string, mode os.FileMode) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), mode); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(root, "etc", "app"), 0755); err != nil {
+		t.Fatalf("Failed to create dirs: %v", err)
+	}
+	mustWrite("etc/app/config", "key=value", 0640)
+	mustWrite("etc/hostname", "devbox", 0644)
+	if err := os.Symlink("app/config", filepath.Join(root, "etc", "config")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+	if err := os.Link(filepath.Join(root, "etc", "hostname"), filepath.Join(root, "etc", "hostname.bak")); err != nil {
+		t.Fatalf("Failed to create hard link: %v", err)
+	}
+	// Short relative path: socket paths are limited to about 100 bytes
+	wd, _ := os.Getwd()
+	if err := os.Chdir(root); err != nil {
+		t.Fatalf("Failed to chdir: %v", err)
+	}
+	listener, err := net.Listen("unix", "app.sock")
+	_ = os.Chdir(wd)
+	if err != nil {
+		t.Fatalf("Failed to create socket: %v", err)
+	}
+	defer func() { _ = listener.Close() }()
+
+	var buf bytes.Buffer
+	if err := exportRootfs(&buf, root); err != nil {
+		t.Fatalf("exportRootfs() error = %v", err)
+	}
+
+	entries := map[string]*tar.Header{}
+	contents := map[string]string{}
+	tr := tar.NewReader(&buf)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("reading archive: %v", err)
+		}
+		entries[hdr.Name] = hdr
+		data, _ := io.ReadAll(tr)
+		contents[hdr.Name] = string(data)
+	}
+
+	for _, name := range []string{"etc/", "etc/app/", "etc/app/config", "etc/hostname", "etc/config", "etc/hostname.bak"} {
+		if entries[name] == nil {
+			t.Errorf("archive is missing %s, has %v", name, entries)
+		}
+	}
+	if entries["app.sock"] != nil {
+		t.Error("archive contains a socket")
+	}
+	if hdr := entries["etc/app/config"]; hdr != nil {
+		if contents["etc/app/config"] != "key=value" || hdr.Mode&0777 != 0640 {
+			t.Errorf("etc/app/config = %q mode %o, want %q mode 640", contents["etc/app/config"], hdr.Mode&0777, "key=value")
+		}
+	}
+	if hdr := entries["etc/config"]; hdr != nil && (hdr.Typeflag != tar.TypeSymlink || hdr.Linkname != "app/config") {
+		t.Errorf("etc/config = type %c link %q, want symlink to app/config", hdr.Typeflag, hdr.Linkname)
+	}
+	if hdr := entries["etc/hostname.bak"]; hdr != nil && (hdr.Typeflag != tar.TypeLink || hdr.Linkname != "etc/hostname") {
+		t.Errorf("etc/hostname.bak = type %c link %q, want hard link to etc/hostname", hdr.Typeflag, hdr.Linkname)
+	}
+
+	if err := exportRootfs(&buf, filepath.Join(root, "etc", "hostname")); err == nil {
+		t.Error("exportRootfs() of a file expected error")
+	}
+}
@@ -20,8 +20,10 @@ limitations under the License.
 package main
 
 import (
+	"bufio"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"os/signal"
 	"path/filepath"
@@ -40,6 +42,15 @@ const (
 	RootfsDir = "rootfs"
 	// ReadyMarker is a file we create to signal the pod is ready
 	ReadyMarker = "ready"
+	// DeleteFile is written on termination to ask the DaemonSet to unmount and
+	// remove the work directory
+	DeleteFile = "delete"
+	// DefaultCleanupTimeout is used when SC_CLEANUP_TIMEOUT is not set
+	DefaultCleanupTimeout = 10 * time.Second
+	// CleanupPollInterval is how often the provider checks whether the DaemonSet cleaned up
+	CleanupPollInterval = 200 * time.Millisecond
+	// LogFormatEnv selects the log format: "json" for JSON lines, plain text otherwise
+	LogFormatEnv = "SC_LOG_FORMAT"
 )
 
 // MountRequest is the request sent to the DaemonSet
@@ -47,6 +58,11 @@ type MountRequest struct {
 	PodUID    string `json:"pod_uid"`
 	Namespace string `json:"namespace"`
 	Name      string `json:"name"`
+	// OverlayDir is the node directory for the overlay upper and work directories
+	OverlayDir string `json:"overlay_dir,omitempty"`
+	// Persistent asks for the overlay upper and work directories on the persistence
+	// volume mounted in the rootfs container
+	Persistent bool `json:"persistent,omitempty"`
 }
 
 // MountResponse is the response from the DaemonSet
@@ -55,8 +71,39 @@ type MountResponse struct {
 	Message string `json:"message,omitempty"`
 }
 
+// logOutput is where log lines are written
+var logOutput io.Writer = os.Stdout
+
+// terminationLogPath is the container's termination message file. kubelet copies it
+// into the container status, where the controller reads it.
+var terminationLogPath = "/dev/termination-log"
+
 func log(format string, args ...interface{}) {
-	fmt.Printf("[provider] "+format+"\n", args...)
+	logAt("info", format, args...)
+}
+
+// logAt writes a log line at the given level: "info", "warning" or "error". With
+// SC_LOG_FORMAT=json it is a JSON object with the time, level and message.
+func logAt(level, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	if os.Getenv(LogFormatEnv) == "json" {
+		data, _ := json.Marshal(map[string]string{
+			"time":      time.Now().UTC().Format(time.RFC3339Nano),
+			"level":     level,
+			"component": "provider",
+			"msg":       msg,
+		})
+		_, _ = fmt.Fprintln(logOutput, string(data))
+		return
+	}
+	prefix := "[provider] "
+	switch level {
+	case "error":
+		prefix += "ERROR: "
+	case "warning":
+		prefix += "WARNING: "
+	}
+	_, _ = fmt.Fprintln(logOutput, prefix+msg)
 }
 
 func main() {
@@ -74,6 +121,18 @@ func main() {
 				os.Exit(1)
 			}
 			os.Exit(0)
+		case "--export-full-rootfs":
+			// The archive goes to stdout, so errors must not
+			out := bufio.NewWriter(os.Stdout)
+			err := exportRootfs(out, os.Args[2])
+			if err == nil {
+				err = out.Flush()
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "[provider] ERROR: failed to export rootfs: %v\n", err)
+				os.Exit(1)
+			}
+			os.Exit(0)
 		}
 	}
 
@@ -83,9 +142,11 @@ func main() {
 	podUID := os.Getenv("POD_UID")
 	podNamespace := os.Getenv("POD_NAMESPACE")
 	podName := os.Getenv("POD_NAME")
+	overlayDir := os.Getenv("SC_OVERLAY_DIR")
+	persistent := os.Getenv("SC_PERSISTENCE") == "true"
 
 	if podUID == "" {
-		log("ERROR: POD_UID environment variable not set")
+		logAt("error", "POD_UID environment variable not set")
 		os.Exit(1)
 	}
 
@@ -112,9 +173,11 @@ func main() {
 		// Write mount request
 		log("Writing mount request...")
 		request := MountRequest{
-			PodUID:    podUID,
-			Namespace: podNamespace,
-			Name:      podName,
+			PodUID:     podUID,
+			Namespace:  podNamespace,
+			Name:       podName,
+			OverlayDir: overlayDir,
+			Persistent: persistent,
 		}
 		requestData, err := json.Marshal(request)
 		if err != nil {
@@ -172,7 +235,7 @@ func main() {
 	}
 
 	if lastError != nil {
-		log("ERROR: Failed to set up mount after 3 attempts: %v", lastError)
+		logAt("error", "Failed to set up mount after 3 attempts: %v", lastError)
 		os.Exit(1)
 	}
 
@@ -191,7 +254,7 @@ func main() {
 
 	// Create ready marker file for Kubernetes readiness probe
 	if err := os.WriteFile(markerPath, []byte("ready"), 0644); err != nil {
-		log("WARNING: Failed to write ready marker: %v", err)
+		logAt("warning", "Failed to write ready marker: %v", err)
 	}
 
 	log("Provider ready, waiting for termination signal...")
@@ -199,4 +262,50 @@ func main() {
 	// Wait for termination signal
 	sig := <-sigChan
 	log("Received signal %v, shutting down...", sig)
+
+	// The rootfs does not outlive this pod: ask the DaemonSet to clean up
+	// within the termination grace period
+	timeout := DefaultCleanupTimeout
+	if v := os.Getenv("SC_CLEANUP_TIMEOUT"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			timeout = d
+		}
+	}
+	os.Exit(cleanupRootfs(PropagatedPath, podUID, timeout))
+}
+
+// cleanupRootfs requests the cleanup of the rootfs and returns the provider's exit code.
+// The rootfs may still be mounted on the node after a failed cleanup, so the provider
+// then exits non-zero with the reason in its termination message for the controller.
+func cleanupRootfs(dir, podUID string, timeout time.Duration) int {
+	if err := requestCleanup(dir, podUID, timeout); err != nil {
+		logAt("error", "%v", err)
+		if err := os.WriteFile(terminationLogPath, []byte(err.Error()), 0644); err != nil {
+			logAt("warning", "Failed to write termination message: %v", err)
+		}
+		return 1
+	}
+	log("Rootfs cleaned up")
+	return 0
+}
+
+// requestCleanup writes the delete signal into dir and waits up to timeout for the
+// DaemonSet to unmount the rootfs and remove the signal. The DaemonSet only cleans up
+// once the pod's rootfs container is gone, so a container restarted in place times out.
+func requestCleanup(dir, podUID string, timeout time.Duration) error {
+	deletePath := filepath.Join(dir, DeleteFile)
+	if err := os.WriteFile(deletePath, []byte(podUID), 0644); err != nil {
+		return fmt.Errorf("failed to write delete signal: %w", err)
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		if _, err := os.Stat(deletePath); os.IsNotExist(err) {
+			return nil
+		}
+		if !time.Now().Before(deadline) {
+			return fmt.Errorf("rootfs not cleaned up within %s", timeout)
+		}
+		time.Sleep(CleanupPollInterval)
+	}
 }
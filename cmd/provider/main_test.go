@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRequestCleanup(t *testing.T) {
+	t.Run("waits for the DaemonSet to remove the signal", func(t *testing.T) {
+		dir := t.TempDir()
+		deletePath := filepath.Join(dir, DeleteFile)
+
+		// Act as mount-helper: remove the signal once it shows up
+		go func() {
+			for {
+				data, err := os.ReadFile(deletePath)
+				if err == nil && string(data) == "uid-1" {
+					_ = os.Remove(deletePath)
+					return
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+		}()
+
+		if err := requestCleanup(dir, "uid-1", 5*time.Second); err != nil {
+			t.Fatalf("requestCleanup() error = %v", err)
+		}
+	})
+
+	t.Run("times out when nobody cleans up", func(t *testing.T) {
+		dir := t.TempDir()
+		start := time.Now()
+		if err := requestCleanup(dir, "uid-1", 300*time.Millisecond); err == nil {
+			t.Fatal("expected a timeout error")
+		}
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Errorf("requestCleanup() took %s, want about the timeout", elapsed)
+		}
+		if _, err := os.Stat(filepath.Join(dir, DeleteFile)); err != nil {
+			t.Errorf("delete signal missing: %v", err)
+		}
+	})
+
+	t.Run("fails when the signal cannot be written", func(t *testing.T) {
+		if err := requestCleanup(filepath.Join(t.TempDir(), "missing"), "uid-1", time.Second); err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+}
+
+func TestCleanupRootfs(t *testing.T) {
+	original := logOutput
+	defer func() { logOutput = original }()
+	logOutput = io.Discard
+	originalPath := terminationLogPath
+	defer func() { terminationLogPath = originalPath }()
+	terminationLogPath = filepath.Join(t.TempDir(), "termination-log")
+
+	t.Run("exits zero once cleaned up", func(t *testing.T) {
+		dir := t.TempDir()
+		go func() {
+			deletePath := filepath.Join(dir, DeleteFile)
+			for {
+				if _, err := os.Stat(deletePath); err == nil {
+					_ = os.Remove(deletePath)
+					return
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+		}()
+		if code := cleanupRootfs(dir, "uid-1", 5*time.Second); code != 0 {
+			t.Errorf("cleanupRootfs() = %d, want 0", code)
+		}
+		if _, err := os.Stat(terminationLogPath); !os.IsNotExist(err) {
+			t.Errorf("termination message written on success: %v", err)
+		}
+	})
+
+	t.Run("exits non-zero with a termination message on timeout", func(t *testing.T) {
+		if code := cleanupRootfs(t.TempDir(), "uid-1", 300*time.Millisecond); code != 1 {
+			t.Errorf("cleanupRootfs() = %d, want 1", code)
+		}
+		message, err := os.ReadFile(terminationLogPath)
+		if err != nil {
+			t.Fatalf("termination message missing: %v", err)
+		}
+		if !strings.Contains(string(message), "not cleaned up within 300ms") {
+			t.Errorf("termination message = %q", message)
+		}
+	})
+}
+
+func TestLogAt(t *testing.T) {
+	var buf bytes.Buffer
+	original := logOutput
+	defer func() { logOutput = original }()
+	logOutput = &buf
+
+	t.Run("plain text by default", func(t *testing.T) {
+		buf.Reset()
+		t.Setenv(LogFormatEnv, "")
+		log("Rootfs ready at %s", "/rootfs")
+		logAt("error", "mount failed")
+		want := "[provider] Rootfs ready at /rootfs\n[provider] ERROR: mount failed\n"
+		if buf.String() != want {
+			t.Errorf("output = %q, want %q", buf.String(), want)
+		}
+	})
+
+	t.Run("JSON lines with SC_LOG_FORMAT=json", func(t *testing.T) {
+		buf.Reset()
+		t.Setenv(LogFormatEnv, "json")
+		logAt("warning", "retrying in %ds", 5)
+		var entry map[string]string
+		if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+			t.Fatalf("Log line is not valid JSON: %q: %v", buf.String(), err)
+		}
+		if entry["level"] != "warning" || entry["msg"] != "retrying in 5s" || entry["component"] != "provider" {
+			t.Errorf("Log line = %v", entry)
+		}
+		if _, err := time.Parse(time.RFC3339Nano, entry["time"]); err != nil {
+			t.Errorf("Log line time %q: %v", entry["time"], err)
+		}
+	})
+}
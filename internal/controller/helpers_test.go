@@ -17,9 +17,21 @@ limitations under the License.
 package controller
 
 import (
+	"context"
+	"maps"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	scv1alpha1 "github.com/xtlsoft/stoppablecontainer/api/v1alpha1"
+	"github.com/xtlsoft/stoppablecontainer/internal/provider"
 )
 
 func TestIsPodReady(t *testing.T) {
@@ -313,3 +325,900 @@ func TestConditionTypes(t *testing.T) {
 		t.Errorf("ConditionTypeConsumerReady = %s, want ConsumerReady", ConditionTypeConsumerReady)
 	}
 }
+
+func TestIsPodSucceeded(t *testing.T) {
+	if isPodSucceeded(&corev1.Pod{Status: corev1.PodStatus{Phase: corev1.PodRunning}}) {
+		t.Error("isPodSucceeded() = true for running pod, want false")
+	}
+	if !isPodSucceeded(&corev1.Pod{Status: corev1.PodStatus{Phase: corev1.PodSucceeded}}) {
+		t.Error("isPodSucceeded() = false for succeeded pod, want true")
+	}
+}
+
+func TestGetConsumerExitCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []corev1.ContainerStatus
+		expected *int32
+	}{
+		{
+			name:     "no container statuses",
+			expected: nil,
+		},
+		{
+			name: "consumer still running",
+			statuses: []corev1.ContainerStatus{
+				{
+					Name:  "consumer",
+					State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+				},
+			},
+			expected: nil,
+		},
+		{
+			name: "consumer terminated",
+			statuses: []corev1.ContainerStatus{
+				{
+					Name:  "sidecar",
+					State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 9}},
+				},
+				{
+					Name:  "consumer",
+					State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 42}},
+				},
+			},
+			expected: int32Ptr(42),
+		},
+		{
+			name: "consumer restarted by kubelet",
+			statuses: []corev1.ContainerStatus{
+				{
+					Name:         "consumer",
+					RestartCount: 2,
+					State:        corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}},
+					LastTerminationState: corev1.ContainerState{
+						Terminated: &corev1.ContainerStateTerminated{ExitCode: 3, Reason: "Error"},
+					},
+				},
+			},
+			expected: int32Ptr(3),
+		},
+		{
+			name: "current termination wins over the last one",
+			statuses: []corev1.ContainerStatus{
+				{
+					Name:  "consumer",
+					State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 0}},
+					LastTerminationState: corev1.ContainerState{
+						Terminated: &corev1.ContainerStateTerminated{ExitCode: 1},
+					},
+				},
+			},
+			expected: int32Ptr(0),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := &corev1.Pod{Status: corev1.PodStatus{ContainerStatuses: tt.statuses}}
+			result := getConsumerExitCode(pod)
+			if tt.expected == nil {
+				if result != nil {
+					t.Errorf("getConsumerExitCode() = %d, want nil", *result)
+				}
+				return
+			}
+			if result == nil || *result != *tt.expected {
+				t.Errorf("getConsumerExitCode() = %v, want %d", result, *tt.expected)
+			}
+		})
+	}
+}
+
+func TestGetImagePullFailure(t *testing.T) {
+	tests := []struct {
+		name      string
+		statuses  []corev1.ContainerStatus
+		transient bool
+		failed    bool
+	}{
+		{
+			name:     "no init statuses",
+			statuses: nil,
+			failed:   false,
+		},
+		{
+			name: "still pulling",
+			statuses: []corev1.ContainerStatus{
+				{
+					Name:  provider.ImagePullInitName,
+					State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "PodInitializing"}},
+				},
+			},
+			failed: false,
+		},
+		{
+			name: "pull error",
+			statuses: []corev1.ContainerStatus{
+				{
+					Name:  provider.ImagePullInitName,
+					State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ErrImagePull"}},
+				},
+			},
+			transient: true,
+			failed:    true,
+		},
+		{
+			name: "invalid image name",
+			statuses: []corev1.ContainerStatus{
+				{
+					Name:  provider.ImagePullInitName,
+					State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "InvalidImageName"}},
+				},
+			},
+			failed: true,
+		},
+		{
+			name: "pull error on other init container",
+			statuses: []corev1.ContainerStatus{
+				{
+					Name:  provider.PauseInitName,
+					State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ErrImagePull"}},
+				},
+			},
+			failed: false,
+		},
+		{
+			name: "check exited non-zero",
+			statuses: []corev1.ContainerStatus{
+				{
+					Name:  provider.ImagePullInitName,
+					State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 1}},
+				},
+			},
+			failed: true,
+		},
+		{
+			name: "check completed",
+			statuses: []corev1.ContainerStatus{
+				{
+					Name:  provider.ImagePullInitName,
+					State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 0}},
+				},
+			},
+			failed: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := &corev1.Pod{Status: corev1.PodStatus{InitContainerStatuses: tt.statuses}}
+			reason, transient, failed := getImagePullFailure(pod)
+			if failed != tt.failed {
+				t.Errorf("getImagePullFailure() failed = %v, want %v", failed, tt.failed)
+			}
+			if transient != tt.transient {
+				t.Errorf("getImagePullFailure() transient = %v, want %v", transient, tt.transient)
+			}
+			if failed && reason == "" {
+				t.Error("getImagePullFailure() returned an empty reason")
+			}
+		})
+	}
+}
+
+func TestSyncTemplateMetadata(t *testing.T) {
+	sc := &scv1alpha1.StoppableContainer{}
+	sc.Spec.Template.Metadata.Labels = map[string]string{"team": "a"}
+	sc.Spec.Template.Metadata.Annotations = map[string]string{"note": "x"}
+	sc.Spec.Template.Spec.Containers = []corev1.Container{{Name: "main", Image: "alpine:3.20"}}
+
+	sci := &scv1alpha1.StoppableContainerInstance{}
+	sci.Spec.Template.Spec.Containers = []corev1.Container{{Name: "main", Image: "alpine:3.19"}}
+
+	if !syncTemplateMetadata(sc, sci) {
+		t.Fatal("syncTemplateMetadata() = false, want true on drift")
+	}
+	if sci.Spec.Template.Metadata.Labels["team"] != "a" || sci.Spec.Template.Metadata.Annotations["note"] != "x" {
+		t.Errorf("metadata not copied: %v", sci.Spec.Template.Metadata)
+	}
+	// Only metadata is synced; the pod spec is left alone
+	if sci.Spec.Template.Spec.Containers[0].Image != "alpine:3.19" {
+		t.Errorf("image changed to %s", sci.Spec.Template.Spec.Containers[0].Image)
+	}
+	if syncTemplateMetadata(sc, sci) {
+		t.Error("syncTemplateMetadata() = true, want false when in sync")
+	}
+}
+
+func TestSyncTemplateSpec(t *testing.T) {
+	sc := &scv1alpha1.StoppableContainer{}
+	sc.Spec.Template.Metadata.Labels = map[string]string{"team": "a"}
+	sc.Spec.Template.Spec.Containers = []corev1.Container{{Name: "main", Image: "alpine:3.20", Command: []string{"sleep", "infinity"}}}
+
+	sci := &scv1alpha1.StoppableContainerInstance{}
+	sci.Spec.Template.Spec.Containers = []corev1.Container{{Name: "main", Image: "alpine:3.20"}}
+
+	if !syncTemplateSpec(sc, sci) {
+		t.Fatal("syncTemplateSpec() = false, want true on drift")
+	}
+	if got := sci.Spec.Template.Spec.Containers[0].Command; len(got) != 2 || got[0] != "sleep" {
+		t.Errorf("command not copied: %v", got)
+	}
+	// Only the pod spec is synced; metadata is left to syncTemplateMetadata
+	if sci.Spec.Template.Metadata.Labels != nil {
+		t.Errorf("labels copied: %v", sci.Spec.Template.Metadata.Labels)
+	}
+	// The copy must not alias the SC's slices
+	sc.Spec.Template.Spec.Containers[0].Command[0] = "true"
+	if sci.Spec.Template.Spec.Containers[0].Command[0] != "sleep" {
+		t.Error("syncTemplateSpec() shares the command slice with the StoppableContainer")
+	}
+	sc.Spec.Template.Spec.Containers[0].Command[0] = "sleep"
+	if syncTemplateSpec(sc, sci) {
+		t.Error("syncTemplateSpec() = true, want false when in sync")
+	}
+}
+
+func TestSyncReplicas(t *testing.T) {
+	replicas := int32(3)
+	sc := &scv1alpha1.StoppableContainer{}
+	sc.Spec.Replicas = &replicas
+	sci := &scv1alpha1.StoppableContainerInstance{}
+
+	if !syncReplicas(sc, sci) {
+		t.Fatal("syncReplicas() = false, want true on drift")
+	}
+	if sci.Spec.Replicas == nil || *sci.Spec.Replicas != 3 {
+		t.Fatalf("replicas not copied: %v", sci.Spec.Replicas)
+	}
+	if sci.Spec.Replicas == sc.Spec.Replicas {
+		t.Error("syncReplicas() shares the pointer with the StoppableContainer")
+	}
+	if syncReplicas(sc, sci) {
+		t.Error("syncReplicas() = true, want false when in sync")
+	}
+
+	sc.Spec.Replicas = nil
+	if !syncReplicas(sc, sci) || sci.Spec.Replicas != nil {
+		t.Errorf("syncReplicas() did not clear replicas: %v", sci.Spec.Replicas)
+	}
+}
+
+func TestSyncLabels(t *testing.T) {
+	sc := &scv1alpha1.StoppableContainer{}
+	sc.Labels = map[string]string{"team": "payments"}
+	sci := &scv1alpha1.StoppableContainerInstance{}
+
+	if !syncLabels(sc, sci) {
+		t.Fatal("syncLabels() = false, want true on drift")
+	}
+	if sci.Labels["team"] != "payments" {
+		t.Fatalf("label not copied: %v", sci.Labels)
+	}
+	if syncLabels(sc, sci) {
+		t.Error("syncLabels() = true, want false when in sync")
+	}
+
+	sc.Labels = map[string]string{"team": "web"}
+	if !syncLabels(sc, sci) || sci.Labels["team"] != "web" {
+		t.Errorf("syncLabels() did not update the label: %v", sci.Labels)
+	}
+
+	// Labels removed from the SC are kept
+	sc.Labels = nil
+	if syncLabels(sc, sci) || sci.Labels["team"] != "web" {
+		t.Errorf("syncLabels() changed labels removed from the SC: %v", sci.Labels)
+	}
+}
+
+func TestSyncSettings(t *testing.T) {
+	grace := int64(30)
+	sc := &scv1alpha1.StoppableContainer{}
+	sc.Spec.Provider.NodeSelector = map[string]string{"disk": "ssd"}
+	sc.Spec.ExecWrapperImage = "registry.example.com/sc-exec:v2"
+	sc.Spec.Persistence = &scv1alpha1.PersistenceSpec{ClaimName: "data"}
+	sc.Spec.StopGracePeriodSeconds = &grace
+	sci := &scv1alpha1.StoppableContainerInstance{}
+
+	if !syncSettings(sc, sci) {
+		t.Fatal("syncSettings() = false, want true on drift")
+	}
+	if sci.Spec.Provider.NodeSelector["disk"] != "ssd" || sci.Spec.ExecWrapperImage != sc.Spec.ExecWrapperImage ||
+		sci.Spec.Persistence == nil || sci.Spec.Persistence.ClaimName != "data" ||
+		sci.Spec.StopGracePeriodSeconds == nil || *sci.Spec.StopGracePeriodSeconds != 30 {
+		t.Fatalf("settings not copied: %+v", sci.Spec)
+	}
+	// The copy must not alias the SC's maps and pointers
+	sc.Spec.Provider.NodeSelector["disk"] = "hdd"
+	if sci.Spec.Provider.NodeSelector["disk"] != "ssd" {
+		t.Error("syncSettings() shares the node selector with the StoppableContainer")
+	}
+	sc.Spec.Provider.NodeSelector["disk"] = "ssd"
+	if syncSettings(sc, sci) {
+		t.Error("syncSettings() = true, want false when in sync")
+	}
+
+	sc.Spec.Persistence = nil
+	if !syncSettings(sc, sci) || sci.Spec.Persistence != nil {
+		t.Errorf("syncSettings() did not clear persistence: %v", sci.Spec.Persistence)
+	}
+}
+
+func TestProviderOutdated(t *testing.T) {
+	sci := &scv1alpha1.StoppableContainerInstance{}
+	sci.Name = "test"
+	sci.Spec.Template.Spec.Containers = []corev1.Container{{Name: "main", Image: "alpine:3.20"}}
+	pod := provider.NewProviderPodBuilder(sci).Build()
+	if providerOutdated(sci, pod) {
+		t.Fatal("providerOutdated() = true for an up-to-date provider")
+	}
+
+	sci.Spec.Provider.PriorityClassName = "high"
+	if !providerOutdated(sci, pod) {
+		t.Error("providerOutdated() = false after a provider settings change")
+	}
+
+	// Providers created before the settings were hashed are only checked for the image
+	delete(pod.Annotations, provider.AnnotationProviderHash)
+	if providerOutdated(sci, pod) {
+		t.Error("providerOutdated() = true for a provider without a settings hash")
+	}
+	sci.Spec.Template.Spec.Containers[0].Image = "alpine:3.21"
+	if !providerOutdated(sci, pod) {
+		t.Error("providerOutdated() = false after an image change")
+	}
+}
+
+func TestApplyServiceAnnotations(t *testing.T) {
+	svc := &corev1.Service{}
+	svc.Annotations = map[string]string{"example.com/owner": "team-a"}
+
+	applyServiceAnnotations(svc, map[string]string{"b": "2", "a": "1"})
+	if svc.Annotations["a"] != "1" || svc.Annotations["b"] != "2" {
+		t.Fatalf("annotations not copied: %v", svc.Annotations)
+	}
+	if got := svc.Annotations[AnnotationServiceAnnotations]; got != "a,b" {
+		t.Errorf("%s = %q, want %q", AnnotationServiceAnnotations, got, "a,b")
+	}
+
+	applyServiceAnnotations(svc, map[string]string{"a": "1"})
+	if _, ok := svc.Annotations["b"]; ok {
+		t.Error("dropped annotation b kept on the Service")
+	}
+
+	applyServiceAnnotations(svc, nil)
+	want := map[string]string{"example.com/owner": "team-a"}
+	if !maps.Equal(svc.Annotations, want) {
+		t.Errorf("annotations = %v, want %v", svc.Annotations, want)
+	}
+}
+
+func TestPodCPUUsage(t *testing.T) {
+	podMetrics := func(name string, cpu ...string) unstructured.Unstructured {
+		var containers []interface{}
+		for _, c := range cpu {
+			containers = append(containers, map[string]interface{}{"usage": map[string]interface{}{"cpu": c}})
+		}
+		return unstructured.Unstructured{Object: map[string]interface{}{
+			"metadata":   map[string]interface{}{"name": name},
+			"containers": containers,
+		}}
+	}
+
+	usage, err := podCPUUsage([]unstructured.Unstructured{
+		podMetrics("a", "1500000n", "2m"),
+		podMetrics("b"),
+	})
+	if err != nil {
+		t.Fatalf("podCPUUsage() error = %v", err)
+	}
+	if cpu, want := usage["a"], resource.MustParse("3500000n"); cpu.Cmp(want) != 0 {
+		t.Errorf("usage[a] = %s, want %s", cpu.String(), want.String())
+	}
+	if cpu, ok := usage["b"]; !ok || !cpu.IsZero() {
+		t.Errorf("usage[b] = %s, %v, want zero", cpu.String(), ok)
+	}
+
+	if _, err := podCPUUsage([]unstructured.Unstructured{podMetrics("c", "lots")}); err == nil {
+		t.Error("podCPUUsage() error = nil, want error for an invalid quantity")
+	}
+}
+
+func TestConsumersIdle(t *testing.T) {
+	threshold := resource.MustParse("10m")
+	tests := []struct {
+		name     string
+		usage    map[string]resource.Quantity
+		replicas int
+		want     bool
+	}{
+		{"no metrics", nil, 1, false},
+		{"below threshold", map[string]resource.Quantity{"a": resource.MustParse("9m")}, 1, true},
+		{"at threshold", map[string]resource.Quantity{"a": resource.MustParse("10m")}, 1, false},
+		{"one replica busy", map[string]resource.Quantity{"a": resource.MustParse("1m"), "b": resource.MustParse("1")}, 2, false},
+		{"replica without metrics", map[string]resource.Quantity{"a": resource.MustParse("1m")}, 2, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := consumersIdle(tt.usage, tt.replicas, threshold); got != tt.want {
+				t.Errorf("consumersIdle() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIdleStopAt(t *testing.T) {
+	timeout := int64(600)
+	sc := &scv1alpha1.StoppableContainer{}
+	sc.Spec.IdleTimeoutSeconds = &timeout
+	started := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	idleSince := started.Add(time.Minute)
+
+	if got, want := idleStopAt(sc, idleSince, started), idleSince.Add(10*time.Minute); !got.Equal(want) {
+		t.Errorf("idleStopAt() = %v, want %v", got, want)
+	}
+
+	// The initial delay postpones a stop that would come too soon after the start
+	delay := int64(3600)
+	sc.Spec.IdleInitialDelaySeconds = &delay
+	if got, want := idleStopAt(sc, idleSince, started), started.Add(time.Hour); !got.Equal(want) {
+		t.Errorf("idleStopAt() with initial delay = %v, want %v", got, want)
+	}
+	if got, want := idleStopAt(sc, started.Add(2*time.Hour), started), started.Add(2*time.Hour+10*time.Minute); !got.Equal(want) {
+		t.Errorf("idleStopAt() after initial delay = %v, want %v", got, want)
+	}
+}
+
+func TestWithReconcileTimeout(t *testing.T) {
+	t.Run("deadline exceeded requeues", func(t *testing.T) {
+		result, err := withReconcileTimeout(context.Background(), 10*time.Millisecond, 3*time.Second,
+			func(ctx context.Context) (ctrl.Result, error) {
+				<-ctx.Done()
+				return ctrl.Result{}, ctx.Err()
+			})
+		if err != nil {
+			t.Fatalf("withReconcileTimeout() error = %v, want nil", err)
+		}
+		if result.RequeueAfter != 3*time.Second {
+			t.Errorf("withReconcileTimeout() RequeueAfter = %s, want 3s after the deadline", result.RequeueAfter)
+		}
+	})
+
+	t.Run("completes within deadline", func(t *testing.T) {
+		want := ctrl.Result{RequeueAfter: time.Second}
+		result, err := withReconcileTimeout(context.Background(), time.Minute, 3*time.Second,
+			func(ctx context.Context) (ctrl.Result, error) {
+				if _, ok := ctx.Deadline(); !ok {
+					t.Error("reconcile context has no deadline")
+				}
+				return want, nil
+			})
+		if err != nil || result != want {
+			t.Errorf("withReconcileTimeout() = %v, %v, want %v, nil", result, err, want)
+		}
+	})
+}
+
+func TestMountHelperWaitExceeded(t *testing.T) {
+	now := time.Now()
+	providerStatus := func(ready bool, startedAgo time.Duration) corev1.ContainerStatus {
+		return corev1.ContainerStatus{
+			Name:  provider.ProviderContainerName,
+			Ready: ready,
+			State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{
+				StartedAt: metav1.NewTime(now.Add(-startedAgo)),
+			}},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		statuses []corev1.ContainerStatus
+		exceeded bool
+	}{
+		{"no statuses", nil, false},
+		{"waiting within timeout", []corev1.ContainerStatus{providerStatus(false, 30*time.Second)}, false},
+		{"waiting past timeout", []corev1.ContainerStatus{providerStatus(false, 5*time.Minute)}, true},
+		{"ready", []corev1.ContainerStatus{providerStatus(true, 5*time.Minute)}, false},
+		{
+			"not started",
+			[]corev1.ContainerStatus{{Name: provider.ProviderContainerName}},
+			false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := &corev1.Pod{Status: corev1.PodStatus{ContainerStatuses: tt.statuses}}
+			if exceeded := mountHelperWaitExceeded(pod, time.Minute, now); exceeded != tt.exceeded {
+				t.Errorf("mountHelperWaitExceeded() = %v, want %v", exceeded, tt.exceeded)
+			}
+		})
+	}
+}
+
+func TestHostNamespaceWarning(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     corev1.PodSpec
+		contains string
+	}{
+		{"none", corev1.PodSpec{}, ""},
+		{"hostPID", corev1.PodSpec{HostPID: true}, "hostPID enabled"},
+		{"hostIPC", corev1.PodSpec{HostIPC: true}, "hostIPC enabled"},
+		{"both", corev1.PodSpec{HostPID: true, HostIPC: true}, "hostPID and hostIPC"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			warning := hostNamespaceWarning(&tt.spec)
+			if tt.contains == "" {
+				if warning != "" {
+					t.Errorf("hostNamespaceWarning() = %q, want empty", warning)
+				}
+				return
+			}
+			if !strings.Contains(warning, tt.contains) {
+				t.Errorf("hostNamespaceWarning() = %q, want it to contain %q", warning, tt.contains)
+			}
+		})
+	}
+}
+
+func TestNonRootWarning(t *testing.T) {
+	uid := int64(1000)
+	root := int64(0)
+	nonRoot := true
+	tests := []struct {
+		name     string
+		spec     corev1.PodSpec
+		contains []string
+	}{
+		{"none", corev1.PodSpec{}, nil},
+		{"fsGroup only", corev1.PodSpec{SecurityContext: &corev1.PodSecurityContext{FSGroup: &uid}}, nil},
+		{"pod runAsUser root", corev1.PodSpec{SecurityContext: &corev1.PodSecurityContext{RunAsUser: &root}}, nil},
+		{"pod runAsUser", corev1.PodSpec{SecurityContext: &corev1.PodSecurityContext{RunAsUser: &uid}},
+			[]string{"cannot chroot"}},
+		{"pod runAsNonRoot", corev1.PodSpec{SecurityContext: &corev1.PodSecurityContext{RunAsNonRoot: &nonRoot}},
+			[]string{"cannot chroot"}},
+		{"container runAsUser", corev1.PodSpec{Containers: []corev1.Container{
+			{Name: "main", SecurityContext: &corev1.SecurityContext{RunAsUser: &uid}},
+		}}, []string{"main container are ignored"}},
+		{"both", corev1.PodSpec{
+			SecurityContext: &corev1.PodSecurityContext{RunAsNonRoot: &nonRoot},
+			Containers: []corev1.Container{
+				{Name: "main", SecurityContext: &corev1.SecurityContext{RunAsNonRoot: &nonRoot}},
+			},
+		}, []string{"cannot chroot", "main container are ignored"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			warning := nonRootWarning(&tt.spec)
+			if len(tt.contains) == 0 {
+				if warning != "" {
+					t.Errorf("nonRootWarning() = %q, want empty", warning)
+				}
+				return
+			}
+			for _, want := range tt.contains {
+				if !strings.Contains(warning, want) {
+					t.Errorf("nonRootWarning() = %q, want it to contain %q", warning, want)
+				}
+			}
+		})
+	}
+}
+
+func TestFindHostPortConflict(t *testing.T) {
+	consumer := func(name, node string, ports ...corev1.ContainerPort) corev1.Pod {
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec: corev1.PodSpec{
+				NodeName:   node,
+				Containers: []corev1.Container{{Name: "main", Ports: ports}},
+			},
+		}
+	}
+	http := corev1.ContainerPort{ContainerPort: 80, HostPort: 8080}
+
+	tests := []struct {
+		name     string
+		pod      corev1.Pod
+		others   []corev1.Pod
+		conflict bool
+	}{
+		{"same port on same node", consumer("a", "node-1", http),
+			[]corev1.Pod{consumer("b", "node-1", http)}, true},
+		{"same port on other node", consumer("a", "node-1", http),
+			[]corev1.Pod{consumer("b", "node-2", http)}, false},
+		{"different port", consumer("a", "node-1", http),
+			[]corev1.Pod{consumer("b", "node-1", corev1.ContainerPort{ContainerPort: 80, HostPort: 8081})}, false},
+		{"different protocol", consumer("a", "node-1", http),
+			[]corev1.Pod{consumer("b", "node-1",
+				corev1.ContainerPort{ContainerPort: 80, HostPort: 8080, Protocol: corev1.ProtocolUDP})}, false},
+		{"different host IPs", consumer("a", "node-1", corev1.ContainerPort{HostPort: 8080, HostIP: "10.0.0.1"}),
+			[]corev1.Pod{consumer("b", "node-1", corev1.ContainerPort{HostPort: 8080, HostIP: "10.0.0.2"})}, false},
+		{"wildcard host IP", consumer("a", "node-1", corev1.ContainerPort{HostPort: 8080, HostIP: "10.0.0.1"}),
+			[]corev1.Pod{consumer("b", "node-1", corev1.ContainerPort{HostPort: 8080, HostIP: "0.0.0.0"})}, true},
+		{"no hostPort", consumer("a", "node-1", corev1.ContainerPort{ContainerPort: 80}),
+			[]corev1.Pod{consumer("b", "node-1", corev1.ContainerPort{ContainerPort: 80})}, false},
+		{"itself", consumer("a", "node-1", http),
+			[]corev1.Pod{consumer("a", "node-1", http)}, false},
+		{"terminated pod", consumer("a", "node-1", http), func() []corev1.Pod {
+			other := consumer("b", "node-1", http)
+			other.Status.Phase = corev1.PodSucceeded
+			return []corev1.Pod{other}
+		}(), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message, conflict := findHostPortConflict(&tt.pod, tt.others)
+			if conflict != tt.conflict {
+				t.Fatalf("findHostPortConflict() = %q, %v; want conflict %v", message, conflict, tt.conflict)
+			}
+			if conflict && !strings.Contains(message, "default/b") {
+				t.Errorf("findHostPortConflict() = %q, want it to name the other pod", message)
+			}
+		})
+	}
+}
+
+func TestGetConsumerCrashLoop(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  corev1.ContainerStatus
+		looping bool
+	}{
+		{"starting", corev1.ContainerStatus{Name: "main"}, false},
+		{"back-off", corev1.ContainerStatus{Name: "main", RestartCount: 1,
+			State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}}}, true},
+		{"restarting between back-offs", corev1.ContainerStatus{Name: "main", RestartCount: CrashLoopRestartThreshold,
+			State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}}, true},
+		{"few restarts", corev1.ContainerStatus{Name: "main", RestartCount: CrashLoopRestartThreshold - 1,
+			State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}}, false},
+		{"recovered", corev1.ContainerStatus{Name: "main", RestartCount: 10, Ready: true,
+			State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}}, false},
+		{"image pull back-off", corev1.ContainerStatus{Name: "main",
+			State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff"}}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := &corev1.Pod{Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{tt.status}}}
+			message, looping := getConsumerCrashLoop(pod)
+			if looping != tt.looping {
+				t.Errorf("getConsumerCrashLoop() = %q, %v; want %v", message, looping, tt.looping)
+			}
+		})
+	}
+}
+
+func TestNormalizeImageName(t *testing.T) {
+	tests := []struct {
+		image    string
+		expected string
+	}{
+		{"ubuntu", "ubuntu"},
+		{"ubuntu:22.04", "ubuntu"},
+		{"docker.io/library/nginx:1.27", "docker.io/library/nginx"},
+		{"registry.example.com:5000/team/app:v1", "registry.example.com:5000/team/app"},
+		{"registry.example.com:5000/team/app", "registry.example.com:5000/team/app"},
+		{"alpine@sha256:0123abcd", "alpine"},
+		{"alpine:3.20@sha256:0123abcd", "alpine"},
+		{"", "unknown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			if got := normalizeImageName(tt.image); got != tt.expected {
+				t.Errorf("normalizeImageName(%q) = %q, want %q", tt.image, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestObserveColdStart(t *testing.T) {
+	coldStartSeconds.Reset()
+	defer coldStartSeconds.Reset()
+
+	created := metav1.NewTime(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	newSCI := func(image string, startedAfter time.Duration) *scv1alpha1.StoppableContainerInstance {
+		sci := &scv1alpha1.StoppableContainerInstance{}
+		sci.CreationTimestamp = created
+		sci.Spec.Template.Spec.Containers = []corev1.Container{{Name: "main", Image: image}}
+		sci.Status.Conditions = []metav1.Condition{{
+			Type:               ConditionTypeStarted,
+			Status:             metav1.ConditionTrue,
+			LastTransitionTime: metav1.NewTime(created.Add(startedAfter)),
+		}}
+		return sci
+	}
+
+	observeColdStart(newSCI("ubuntu:22.04", 4*time.Second))
+	observeColdStart(newSCI("ubuntu:24.04", 15*time.Second))
+	observeColdStart(newSCI("alpine:3.20", 500*time.Millisecond))
+	// Without a Started condition there is nothing to observe
+	notStarted := newSCI("alpine:3.20", time.Second)
+	notStarted.Status.Conditions = nil
+	observeColdStart(notStarted)
+
+	expected := `
+# HELP stoppablecontainer_cold_start_seconds Time from StoppableContainerInstance creation to Running, by image
+# TYPE stoppablecontainer_cold_start_seconds histogram
+stoppablecontainer_cold_start_seconds_bucket{image="alpine",le="1"} 1
+stoppablecontainer_cold_start_seconds_bucket{image="alpine",le="2"} 1
+stoppablecontainer_cold_start_seconds_bucket{image="alpine",le="5"} 1
+stoppablecontainer_cold_start_seconds_bucket{image="alpine",le="10"} 1
+stoppablecontainer_cold_start_seconds_bucket{image="alpine",le="20"} 1
+stoppablecontainer_cold_start_seconds_bucket{image="alpine",le="30"} 1
+stoppablecontainer_cold_start_seconds_bucket{image="alpine",le="60"} 1
+stoppablecontainer_cold_start_seconds_bucket{image="alpine",le="120"} 1
+stoppablecontainer_cold_start_seconds_bucket{image="alpine",le="300"} 1
+stoppablecontainer_cold_start_seconds_bucket{image="alpine",le="600"} 1
+stoppablecontainer_cold_start_seconds_bucket{image="alpine",le="+Inf"} 1
+stoppablecontainer_cold_start_seconds_sum{image="alpine"} 0.5
+stoppablecontainer_cold_start_seconds_count{image="alpine"} 1
+stoppablecontainer_cold_start_seconds_bucket{image="ubuntu",le="1"} 0
+stoppablecontainer_cold_start_seconds_bucket{image="ubuntu",le="2"} 0
+stoppablecontainer_cold_start_seconds_bucket{image="ubuntu",le="5"} 1
+stoppablecontainer_cold_start_seconds_bucket{image="ubuntu",le="10"} 1
+stoppablecontainer_cold_start_seconds_bucket{image="ubuntu",le="20"} 2
+stoppablecontainer_cold_start_seconds_bucket{image="ubuntu",le="30"} 2
+stoppablecontainer_cold_start_seconds_bucket{image="ubuntu",le="60"} 2
+stoppablecontainer_cold_start_seconds_bucket{image="ubuntu",le="120"} 2
+stoppablecontainer_cold_start_seconds_bucket{image="ubuntu",le="300"} 2
+stoppablecontainer_cold_start_seconds_bucket{image="ubuntu",le="600"} 2
+stoppablecontainer_cold_start_seconds_bucket{image="ubuntu",le="+Inf"} 2
+stoppablecontainer_cold_start_seconds_sum{image="ubuntu"} 19
+stoppablecontainer_cold_start_seconds_count{image="ubuntu"} 2
+`
+	if err := testutil.CollectAndCompare(coldStartSeconds, strings.NewReader(expected)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestObserveStartLatencies(t *testing.T) {
+	providerReadySeconds.Reset()
+	consumerReadySeconds.Reset()
+	defer providerReadySeconds.Reset()
+	defer consumerReadySeconds.Reset()
+
+	created := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	restarted := created.Add(time.Hour)
+	sci := &scv1alpha1.StoppableContainerInstance{}
+	sci.CreationTimestamp = metav1.NewTime(created)
+	sci.Annotations = map[string]string{AnnotationLastTransition: restarted.Format(time.RFC3339)}
+	sci.Spec.Template.Spec.Containers = []corev1.Container{{Name: "main", Image: "postgres:16"}}
+	sci.Status.Conditions = []metav1.Condition{
+		{
+			Type:               ConditionTypeProviderStarted,
+			Status:             metav1.ConditionTrue,
+			LastTransitionTime: metav1.NewTime(created.Add(25 * time.Second)),
+		},
+		{
+			Type:               ConditionTypeStarted,
+			Status:             metav1.ConditionTrue,
+			LastTransitionTime: metav1.NewTime(restarted.Add(3 * time.Second)),
+		},
+	}
+
+	observeProviderReady(sci)
+	observeConsumerReady(sci)
+
+	// The consumer is measured from the last start, the provider from creation
+	expected := `
+# HELP stoppablecontainer_consumer_ready_seconds Time from starting a StoppableContainerInstance to Running, by image
+# TYPE stoppablecontainer_consumer_ready_seconds histogram
+stoppablecontainer_consumer_ready_seconds_bucket{image="postgres",le="1"} 0
+stoppablecontainer_consumer_ready_seconds_bucket{image="postgres",le="2"} 0
+stoppablecontainer_consumer_ready_seconds_bucket{image="postgres",le="5"} 1
+stoppablecontainer_consumer_ready_seconds_bucket{image="postgres",le="10"} 1
+stoppablecontainer_consumer_ready_seconds_bucket{image="postgres",le="20"} 1
+stoppablecontainer_consumer_ready_seconds_bucket{image="postgres",le="30"} 1
+stoppablecontainer_consumer_ready_seconds_bucket{image="postgres",le="60"} 1
+stoppablecontainer_consumer_ready_seconds_bucket{image="postgres",le="120"} 1
+stoppablecontainer_consumer_ready_seconds_bucket{image="postgres",le="300"} 1
+stoppablecontainer_consumer_ready_seconds_bucket{image="postgres",le="600"} 1
+stoppablecontainer_consumer_ready_seconds_bucket{image="postgres",le="+Inf"} 1
+stoppablecontainer_consumer_ready_seconds_sum{image="postgres"} 3
+stoppablecontainer_consumer_ready_seconds_count{image="postgres"} 1
+`
+	if err := testutil.CollectAndCompare(consumerReadySeconds, strings.NewReader(expected)); err != nil {
+		t.Error(err)
+	}
+	expected = `
+# HELP stoppablecontainer_provider_ready_seconds Time from StoppableContainerInstance creation to a ready provider pod, by image
+# TYPE stoppablecontainer_provider_ready_seconds histogram
+stoppablecontainer_provider_ready_seconds_bucket{image="postgres",le="1"} 0
+stoppablecontainer_provider_ready_seconds_bucket{image="postgres",le="2"} 0
+stoppablecontainer_provider_ready_seconds_bucket{image="postgres",le="5"} 0
+stoppablecontainer_provider_ready_seconds_bucket{image="postgres",le="10"} 0
+stoppablecontainer_provider_ready_seconds_bucket{image="postgres",le="20"} 0
+stoppablecontainer_provider_ready_seconds_bucket{image="postgres",le="30"} 1
+stoppablecontainer_provider_ready_seconds_bucket{image="postgres",le="60"} 1
+stoppablecontainer_provider_ready_seconds_bucket{image="postgres",le="120"} 1
+stoppablecontainer_provider_ready_seconds_bucket{image="postgres",le="300"} 1
+stoppablecontainer_provider_ready_seconds_bucket{image="postgres",le="600"} 1
+stoppablecontainer_provider_ready_seconds_bucket{image="postgres",le="+Inf"} 1
+stoppablecontainer_provider_ready_seconds_sum{image="postgres"} 25
+stoppablecontainer_provider_ready_seconds_count{image="postgres"} 1
+`
+	if err := testutil.CollectAndCompare(providerReadySeconds, strings.NewReader(expected)); err != nil {
+		t.Error(err)
+	}
+
+	// A start that did not wait for the provider is not a cold start
+	if providerStartedSince(sci, restarted) {
+		t.Error("providerStartedSince(restart) = true, want false for a provider that was already running")
+	}
+	if !providerStartedSince(sci, created) {
+		t.Error("providerStartedSince(creation) = false, want true")
+	}
+}
+
+func TestIsProviderReadyInstancePhase(t *testing.T) {
+	for phase, expected := range map[scv1alpha1.InstancePhase]bool{
+		"":                                       false,
+		scv1alpha1.InstancePhasePending:          false,
+		scv1alpha1.InstancePhaseProviderStarting: false,
+		scv1alpha1.InstancePhaseFailed:           false,
+		scv1alpha1.InstancePhaseProviderReady:    true,
+		scv1alpha1.InstancePhaseConsumerStarting: true,
+		scv1alpha1.InstancePhaseRunning:          true,
+		scv1alpha1.InstancePhaseStopped:          true,
+	} {
+		if got := isProviderReadyInstancePhase(phase); got != expected {
+			t.Errorf("isProviderReadyInstancePhase(%q) = %v, want %v", phase, got, expected)
+		}
+	}
+}
+
+func TestConsumerRestartBackoff(t *testing.T) {
+	tests := []struct {
+		attempt  int32
+		expected time.Duration
+	}{
+		{1, 10 * time.Second},
+		{2, 20 * time.Second},
+		{3, 40 * time.Second},
+		{6, 5 * time.Minute},
+		{100, ConsumerRestartMaxDelay},
+	}
+
+	for _, tt := range tests {
+		if got := consumerRestartBackoff(tt.attempt); got != tt.expected {
+			t.Errorf("consumerRestartBackoff(%d) = %s, want %s", tt.attempt, got, tt.expected)
+		}
+	}
+}
+
+func TestConsumerRestartWait(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	newSCI := func(restarts int32, ago time.Duration) *scv1alpha1.StoppableContainerInstance {
+		sci := &scv1alpha1.StoppableContainerInstance{}
+		sci.Status.RestartCount = restarts
+		last := metav1.NewTime(now.Add(-ago))
+		sci.Status.LastConsumerRestartTime = &last
+		return sci
+	}
+
+	if wait := consumerRestartWait(&scv1alpha1.StoppableContainerInstance{}, now); wait != 0 {
+		t.Errorf("never restarted: wait = %s, want 0", wait)
+	}
+	if wait := consumerRestartWait(newSCI(1, 4*time.Second), now); wait != 6*time.Second {
+		t.Errorf("first restart 4s ago: wait = %s, want 6s", wait)
+	}
+	if wait := consumerRestartWait(newSCI(2, 4*time.Second), now); wait != 16*time.Second {
+		t.Errorf("second restart 4s ago: wait = %s, want 16s", wait)
+	}
+	if wait := consumerRestartWait(newSCI(1, time.Minute), now); wait != 0 {
+		t.Errorf("backoff elapsed: wait = %s, want 0", wait)
+	}
+}
+
+func int32Ptr(i int32) *int32 {
+	return &i
+}
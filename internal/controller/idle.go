@@ -0,0 +1,206 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	scv1alpha1 "github.com/xtlsoft/stoppablecontainer/api/v1alpha1"
+	"github.com/xtlsoft/stoppablecontainer/internal/provider"
+)
+
+const (
+	// ConditionTypeIdleStopped is set on a StoppableContainer that was stopped for being idle
+	ConditionTypeIdleStopped = "IdleStopped"
+
+	// IdleCheckInterval is how often the CPU usage of a container with an idle timeout is checked
+	IdleCheckInterval = time.Minute
+)
+
+// DefaultIdleCPUThreshold is the CPU usage below which a consumer pod counts as idle
+var DefaultIdleCPUThreshold = resource.MustParse("10m")
+
+var podMetricsListGVK = schema.GroupVersionKind{Group: "metrics.k8s.io", Version: "v1beta1", Kind: "PodMetricsList"}
+
+// PodMetricsReader reads the CPU usage of consumer pods
+type PodMetricsReader interface {
+	// ConsumerCPUUsage returns the CPU usage of each consumer pod of an instance, by pod name.
+	// Pods without metrics yet are left out.
+	ConsumerCPUUsage(ctx context.Context, sci *scv1alpha1.StoppableContainerInstance) (map[string]resource.Quantity, error)
+}
+
+// NewMetricsAPIReader returns a PodMetricsReader for the metrics.k8s.io API, as served
+// by metrics-server. The reader should not be cached, pod metrics cannot be watched.
+func NewMetricsAPIReader(reader client.Reader) PodMetricsReader {
+	return &metricsAPIReader{reader: reader}
+}
+
+type metricsAPIReader struct {
+	reader client.Reader
+}
+
+func (m *metricsAPIReader) ConsumerCPUUsage(ctx context.Context, sci *scv1alpha1.StoppableContainerInstance) (map[string]resource.Quantity, error) {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(podMetricsListGVK)
+	if err := m.reader.List(ctx, list, client.InNamespace(sci.Namespace), client.MatchingLabels{
+		provider.LabelInstance: sci.Name,
+		provider.LabelRole:     "consumer",
+	}); err != nil {
+		return nil, err
+	}
+	return podCPUUsage(list.Items)
+}
+
+// podCPUUsage sums the CPU usage of the containers of each PodMetrics object
+func podCPUUsage(items []unstructured.Unstructured) (map[string]resource.Quantity, error) {
+	usage := make(map[string]resource.Quantity, len(items))
+	for _, item := range items {
+		containers, _, _ := unstructured.NestedSlice(item.Object, "containers")
+		var total resource.Quantity
+		for _, c := range containers {
+			container, ok := c.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			cpu, _, _ := unstructured.NestedString(container, "usage", "cpu")
+			if cpu == "" {
+				continue
+			}
+			q, err := resource.ParseQuantity(cpu)
+			if err != nil {
+				return nil, fmt.Errorf("invalid CPU usage %q of pod %s: %w", cpu, item.GetName(), err)
+			}
+			total.Add(q)
+		}
+		usage[item.GetName()] = total
+	}
+	return usage, nil
+}
+
+// reconcileIdleStop stops a running container whose consumer pods have been idle for its
+// IdleTimeoutSeconds. It tracks the start of the idle period in status.idleSince, which the
+// caller persists, and returns when to check again. stopped is true if it set running to false.
+func (r *StoppableContainerReconciler) reconcileIdleStop(ctx context.Context, sc *scv1alpha1.StoppableContainer, sci *scv1alpha1.StoppableContainerInstance) (requeueAfter time.Duration, stopped bool, err error) {
+	log := logf.FromContext(ctx)
+
+	if sc.Spec.IdleTimeoutSeconds == nil || r.Metrics == nil || sci.Status.Phase != scv1alpha1.InstancePhaseRunning {
+		sc.Status.IdleSince = nil
+		return 0, false, nil
+	}
+
+	usage, err := r.Metrics.ConsumerCPUUsage(ctx, sci)
+	if err != nil {
+		// A missing metrics API must not stop the container, so keep the idle period as is
+		log.Info("Cannot read consumer CPU usage for idle stop", "error", err.Error())
+		return IdleCheckInterval, false, nil
+	}
+
+	now := time.Now()
+	if !consumersIdle(usage, int(sci.Status.Replicas), idleCPUThreshold(sc)) {
+		sc.Status.IdleSince = nil
+		return IdleCheckInterval, false, nil
+	}
+	if sc.Status.IdleSince == nil {
+		idleSince := metav1.NewTime(now)
+		sc.Status.IdleSince = &idleSince
+	}
+
+	stopAt := idleStopAt(sc, sc.Status.IdleSince.Time, instanceStartTime(sci))
+	if now.Before(stopAt) {
+		return min(stopAt.Sub(now), IdleCheckInterval), false, nil
+	}
+
+	idleFor := now.Sub(sc.Status.IdleSince.Time).Round(time.Second)
+	threshold := idleCPUThreshold(sc)
+	message := fmt.Sprintf("Stopped automatically after being idle for %s (CPU below %s)",
+		idleFor, threshold.String())
+
+	sc.Spec.Running = false
+	if err := r.Update(ctx, sc); err != nil {
+		return 0, false, err
+	}
+	log.Info("Stopping idle container", "idleFor", idleFor)
+	r.recordEvent(sc, corev1.EventTypeNormal, "IdleStopped", message)
+
+	// The update returned the stored status, so set the condition on that
+	sc.Status.IdleSince = nil
+	meta.SetStatusCondition(&sc.Status.Conditions, metav1.Condition{
+		Type:               ConditionTypeIdleStopped,
+		Status:             metav1.ConditionTrue,
+		Reason:             "IdleTimeout",
+		Message:            message,
+		ObservedGeneration: sc.Generation,
+	})
+	if err := r.Status().Update(ctx, sc); err != nil {
+		return 0, false, err
+	}
+	return 0, true, nil
+}
+
+// consumersIdle reports whether metrics are known for all replicas and all of them
+// use less CPU than the threshold
+func consumersIdle(usage map[string]resource.Quantity, replicas int, threshold resource.Quantity) bool {
+	if len(usage) == 0 || len(usage) < replicas {
+		return false
+	}
+	for _, cpu := range usage {
+		if cpu.Cmp(threshold) >= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// idleCPUThreshold returns the CPU usage below which a consumer pod of sc counts as idle
+func idleCPUThreshold(sc *scv1alpha1.StoppableContainer) resource.Quantity {
+	if sc.Spec.IdleCPUThreshold != nil {
+		return *sc.Spec.IdleCPUThreshold
+	}
+	return DefaultIdleCPUThreshold
+}
+
+// idleStopAt returns when a container idle since idleSince is stopped. It is never
+// stopped within IdleInitialDelaySeconds of its start.
+func idleStopAt(sc *scv1alpha1.StoppableContainer, idleSince, startedAt time.Time) time.Time {
+	stopAt := idleSince.Add(time.Duration(*sc.Spec.IdleTimeoutSeconds) * time.Second)
+	if sc.Spec.IdleInitialDelaySeconds != nil {
+		if earliest := startedAt.Add(time.Duration(*sc.Spec.IdleInitialDelaySeconds) * time.Second); earliest.After(stopAt) {
+			return earliest
+		}
+	}
+	return stopAt
+}
+
+// instanceStartTime returns when an instance was last started, from its last transition
+// annotation, or its creation time if that is missing
+func instanceStartTime(sci *scv1alpha1.StoppableContainerInstance) time.Time {
+	if t, err := time.Parse(time.RFC3339, sci.Annotations[AnnotationLastTransition]); err == nil {
+		return t
+	}
+	return sci.CreationTimestamp.Time
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	scv1alpha1 "github.com/xtlsoft/stoppablecontainer/api/v1alpha1"
+)
+
+// startBuckets are the histogram buckets for start latencies, in seconds
+var startBuckets = []float64{1, 2, 5, 10, 20, 30, 60, 120, 300, 600}
+
+// coldStartSeconds observes the time from creating a StoppableContainerInstance
+// until it is Running, which covers the image pull, rootfs mount and consumer start
+var coldStartSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "stoppablecontainer_cold_start_seconds",
+	Help:    "Time from StoppableContainerInstance creation to Running, by image",
+	Buckets: startBuckets,
+}, []string{"image"})
+
+// providerReadySeconds observes the time from creating a StoppableContainerInstance
+// until its provider pod is ready, which covers the image pull and rootfs mount
+var providerReadySeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "stoppablecontainer_provider_ready_seconds",
+	Help:    "Time from StoppableContainerInstance creation to a ready provider pod, by image",
+	Buckets: startBuckets,
+}, []string{"image"})
+
+// consumerReadySeconds observes the time from each start of a StoppableContainerInstance
+// until it is Running. A start of a stopped instance only has to start the consumer.
+var consumerReadySeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "stoppablecontainer_consumer_ready_seconds",
+	Help:    "Time from starting a StoppableContainerInstance to Running, by image",
+	Buckets: startBuckets,
+}, []string{"image"})
+
+// phaseTransitions counts the phase changes of StoppableContainerInstances
+var phaseTransitions = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "stoppablecontainer_phase_transitions_total",
+	Help: "Number of StoppableContainerInstance phase changes, by the new phase",
+}, []string{"phase"})
+
+func init() {
+	metrics.Registry.MustRegister(coldStartSeconds, providerReadySeconds, consumerReadySeconds, phaseTransitions)
+}
+
+// normalizeImageName strips the tag and digest from an image reference so that
+// the image label does not grow with every tag
+func normalizeImageName(image string) string {
+	if i := strings.Index(image, "@"); i >= 0 {
+		image = image[:i]
+	}
+	// A colon after the last slash starts the tag, one before it is a registry port
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		image = image[:i]
+	}
+	if image == "" {
+		return "unknown"
+	}
+	return image
+}
+
+// observeColdStart records the cold start latency of an instance that just became
+// Running, from its creation timestamp to the transition time of its Started condition
+func observeColdStart(sci *scv1alpha1.StoppableContainerInstance) {
+	started := meta.FindStatusCondition(sci.Status.Conditions, ConditionTypeStarted)
+	if started == nil || sci.CreationTimestamp.IsZero() {
+		return
+	}
+	observeLatency(coldStartSeconds, sci, sci.CreationTimestamp.Time, started.LastTransitionTime.Time)
+}
+
+// observeProviderReady records how long the provider pod of an instance took to first
+// become ready, from its creation timestamp to the transition time of its ProviderStarted condition
+func observeProviderReady(sci *scv1alpha1.StoppableContainerInstance) {
+	providerStarted := meta.FindStatusCondition(sci.Status.Conditions, ConditionTypeProviderStarted)
+	if providerStarted == nil || sci.CreationTimestamp.IsZero() {
+		return
+	}
+	observeLatency(providerReadySeconds, sci, sci.CreationTimestamp.Time, providerStarted.LastTransitionTime.Time)
+}
+
+// observeConsumerReady records how long an instance that just became Running took since
+// it was last started, to the transition time of its Started condition
+func observeConsumerReady(sci *scv1alpha1.StoppableContainerInstance) {
+	started := meta.FindStatusCondition(sci.Status.Conditions, ConditionTypeStarted)
+	if started == nil {
+		return
+	}
+	observeLatency(consumerReadySeconds, sci, instanceStartTime(sci), started.LastTransitionTime.Time)
+}
+
+// observeLatency records the time from start to end in a histogram labeled with the
+// instance's image; negative latencies from clock skew are dropped
+func observeLatency(histogram *prometheus.HistogramVec, sci *scv1alpha1.StoppableContainerInstance, start, end time.Time) {
+	if start.IsZero() {
+		return
+	}
+	latency := end.Sub(start)
+	if latency < 0 {
+		return
+	}
+
+	image := ""
+	if containers := sci.Spec.Template.Spec.Containers; len(containers) > 0 {
+		image = containers[0].Image
+	}
+	histogram.WithLabelValues(normalizeImageName(image)).Observe(latency.Seconds())
+}
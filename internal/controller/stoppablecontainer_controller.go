@@ -19,13 +19,20 @@ package controller
 import (
 	"context"
 	"fmt"
+	"maps"
+	"slices"
+	"strings"
 	"time"
 
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
@@ -34,6 +41,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	scv1alpha1 "github.com/xtlsoft/stoppablecontainer/api/v1alpha1"
+	"github.com/xtlsoft/stoppablecontainer/internal/provider"
 )
 
 const (
@@ -48,12 +56,47 @@ const (
 
 	// ConditionTypeConsumerReady indicates the consumer is ready
 	ConditionTypeConsumerReady = "ConsumerReady"
+
+	// AnnotationLastTransition records when the instance was last started or stopped
+	AnnotationLastTransition = "stoppablecontainer.xtlsoft.top/last-transition"
+
+	// AnnotationServiceAnnotations records on the managed Service the comma-separated keys of
+	// the annotations last copied from spec.serviceAnnotations, so dropped keys are removed
+	AnnotationServiceAnnotations = "stoppablecontainer.xtlsoft.top/last-applied-service-annotations"
+
+	// DefaultMinTransitionInterval is the default minimum interval between start/stop actions
+	DefaultMinTransitionInterval = 5 * time.Second
+
+	// DefaultReconcileTimeout is the default deadline for a single reconcile
+	DefaultReconcileTimeout = 30 * time.Second
+
+	// DefaultRequeueInterval is the default delay before re-checking an object that is
+	// still being deleted or is in an intermediate phase
+	DefaultRequeueInterval = time.Second
 )
 
 // StoppableContainerReconciler reconciles a StoppableContainer object
 type StoppableContainerReconciler struct {
 	client.Client
-	Scheme *runtime.Scheme
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+
+	// MinTransitionInterval is the minimum interval between start/stop actions.
+	// Defaults to DefaultMinTransitionInterval when zero.
+	MinTransitionInterval time.Duration
+
+	// ReconcileTimeout is the deadline for a single reconcile.
+	// Defaults to DefaultReconcileTimeout when zero.
+	ReconcileTimeout time.Duration
+
+	// RequeueInterval is how long to wait before re-checking a deleted instance;
+	// intermediate phases are polled at twice this interval.
+	// Defaults to DefaultRequeueInterval when zero.
+	RequeueInterval time.Duration
+
+	// Metrics reads the CPU usage of consumer pods for spec.idleTimeoutSeconds.
+	// Idle stop is disabled when nil.
+	Metrics PodMetricsReader
 }
 
 // +kubebuilder:rbac:groups=stoppablecontainer.xtlsoft.top,resources=stoppablecontainers,verbs=get;list;watch;create;update;patch;delete
@@ -62,10 +105,18 @@ type StoppableContainerReconciler struct {
 // +kubebuilder:rbac:groups=stoppablecontainer.xtlsoft.top,resources=stoppablecontainerinstances,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=stoppablecontainer.xtlsoft.top,resources=stoppablecontainerinstances/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups=metrics.k8s.io,resources=pods,verbs=get;list
 
 // Reconcile reconciles the StoppableContainer resource
 func (r *StoppableContainerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	return withReconcileTimeout(ctx, r.ReconcileTimeout, r.requeueInterval(), func(ctx context.Context) (ctrl.Result, error) {
+		return r.reconcile(ctx, req)
+	})
+}
+
+func (r *StoppableContainerReconciler) reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := logf.FromContext(ctx)
 
 	// Fetch the StoppableContainer
@@ -107,6 +158,29 @@ func (r *StoppableContainerReconciler) Reconcile(ctx context.Context, req ctrl.R
 		}
 	}
 
+	// Propagate template, replica and settings edits. The SCI controller applies
+	// label/annotation edits to the consumer in place, recreates it for pod spec and
+	// settings edits, recreates the provider for provider settings edits and scales replicas.
+	if sciExists {
+		metadataChanged := syncTemplateMetadata(sc, sci)
+		specChanged := syncTemplateSpec(sc, sci)
+		replicasChanged := syncReplicas(sc, sci)
+		labelsChanged := syncLabels(sc, sci)
+		settingsChanged := syncSettings(sc, sci)
+		if metadataChanged || specChanged || replicasChanged || labelsChanged || settingsChanged {
+			if err := r.Update(ctx, sci); err != nil {
+				return ctrl.Result{}, err
+			}
+			log.Info("Updated instance template", "metadata", metadataChanged, "spec", specChanged,
+				"replicas", replicasChanged, "labels", labelsChanged, "settings", settingsChanged)
+		}
+	}
+
+	// Create, update or delete the Service exposing the consumer
+	if err := r.reconcileService(ctx, sc); err != nil {
+		return ctrl.Result{}, err
+	}
+
 	// Reconcile based on desired state
 	if sc.Spec.Running {
 		// Container should be running
@@ -117,21 +191,47 @@ func (r *StoppableContainerReconciler) Reconcile(ctx context.Context, req ctrl.R
 
 		// Update SCI if needed
 		if !sci.Spec.Running {
+			if wait := r.transitionDebounce(sci); wait > 0 {
+				r.recordEvent(sc, corev1.EventTypeNormal, "Debounced",
+					fmt.Sprintf("Start delayed by %s to avoid start/stop flapping", wait.Round(time.Second)))
+				return ctrl.Result{RequeueAfter: wait}, nil
+			}
 			sci.Spec.Running = true
+			markTransition(sci)
 			if err := r.Update(ctx, sci); err != nil {
 				return ctrl.Result{}, err
 			}
 			log.Info("Started container instance")
 		}
+		meta.RemoveStatusCondition(&sc.Status.Conditions, ConditionTypeIdleStopped)
+
+		// Stop the container once it has been idle for too long
+		idleRequeue, stopped, err := r.reconcileIdleStop(ctx, sc, sci)
+		if err != nil || stopped {
+			return ctrl.Result{Requeue: stopped}, err
+		}
 
 		// Update status from SCI
-		return r.updateStatusFromInstance(ctx, sc, sci)
+		result, err := r.updateStatusFromInstance(ctx, sc, sci)
+		if err == nil && idleRequeue > 0 && (result.RequeueAfter == 0 || idleRequeue < result.RequeueAfter) {
+			result.RequeueAfter = idleRequeue
+		}
+		return result, err
 	} else {
 		// Container should be stopped
+		sc.Status.IdleSince = nil
 		if sciExists {
 			if sci.Spec.Running {
+				// A stop observed before the consumer exists is applied at once, so a
+				// create-then-stop never starts the consumer
+				if wait := r.transitionDebounce(sci); wait > 0 && sci.Status.ConsumerPodName != "" {
+					r.recordEvent(sc, corev1.EventTypeNormal, "Debounced",
+						fmt.Sprintf("Stop delayed by %s to avoid start/stop flapping", wait.Round(time.Second)))
+					return ctrl.Result{RequeueAfter: wait}, nil
+				}
 				// Stop the consumer but keep the provider
 				sci.Spec.Running = false
+				markTransition(sci)
 				if err := r.Update(ctx, sci); err != nil {
 					return ctrl.Result{}, err
 				}
@@ -166,7 +266,7 @@ func (r *StoppableContainerReconciler) handleDeletion(ctx context.Context, sc *s
 		}
 		log.Info("Deleted StoppableContainerInstance")
 		// Wait for SCI to be fully deleted
-		return ctrl.Result{RequeueAfter: time.Second}, nil
+		return ctrl.Result{RequeueAfter: r.requeueInterval()}, nil
 	} else if !errors.IsNotFound(err) {
 		return ctrl.Result{}, err
 	}
@@ -203,10 +303,17 @@ func (r *StoppableContainerReconciler) createInstance(ctx context.Context, sc *s
 			StoppableContainerName: sc.Name,
 			Running:                true,
 			Template:               sc.Spec.Template,
+			Replicas:               sc.Spec.Replicas,
 			Provider:               sc.Spec.Provider,
 			HostPathPrefix:         sc.Spec.HostPathPrefix,
+			SkipNetworkConfigCopy:  sc.Spec.SkipNetworkConfigCopy,
+			ExecWrapperImage:       sc.Spec.ExecWrapperImage,
+			Persistence:            sc.Spec.Persistence,
+			StopGracePeriodSeconds: sc.Spec.StopGracePeriodSeconds,
 		},
 	}
+	syncLabels(sc, sci)
+	markTransition(sci)
 
 	if err := r.Create(ctx, sci); err != nil {
 		if errors.IsAlreadyExists(err) {
@@ -217,6 +324,13 @@ func (r *StoppableContainerReconciler) createInstance(ctx context.Context, sc *s
 
 	log.Info("Created StoppableContainerInstance")
 
+	if warning := hostNamespaceWarning(&sc.Spec.Template.Spec); warning != "" {
+		r.recordEvent(sc, corev1.EventTypeWarning, "HostNamespaces", warning)
+	}
+	if warning := nonRootWarning(&sc.Spec.Template.Spec); warning != "" {
+		r.recordEvent(sc, corev1.EventTypeWarning, "NonRootChroot", warning)
+	}
+
 	// Update status
 	sc.Status.InstanceName = sci.Name
 	sc.Status.Phase = scv1alpha1.PhasePending
@@ -234,7 +348,7 @@ func (r *StoppableContainerReconciler) createInstance(ctx context.Context, sc *s
 		return ctrl.Result{}, err
 	}
 
-	return ctrl.Result{RequeueAfter: time.Second}, nil
+	return ctrl.Result{RequeueAfter: r.requeueInterval()}, nil
 }
 
 func (r *StoppableContainerReconciler) updateStatusFromInstance(ctx context.Context, sc *scv1alpha1.StoppableContainer, sci *scv1alpha1.StoppableContainerInstance) (ctrl.Result, error) {
@@ -249,6 +363,11 @@ func (r *StoppableContainerReconciler) updateStatusFromInstance(ctx context.Cont
 		conditionStatus = metav1.ConditionFalse
 		reason = "Pending"
 		message = "Instance is starting up"
+		if cond := meta.FindStatusCondition(sci.Status.Conditions, ConditionTypeMountHelperAvailable); cond != nil &&
+			cond.Status == metav1.ConditionFalse {
+			reason = cond.Reason
+			message = cond.Message
+		}
 	case scv1alpha1.InstancePhaseProviderReady, scv1alpha1.InstancePhaseConsumerStarting:
 		phase = scv1alpha1.PhaseProviderReady
 		conditionStatus = metav1.ConditionFalse
@@ -259,11 +378,26 @@ func (r *StoppableContainerReconciler) updateStatusFromInstance(ctx context.Cont
 		conditionStatus = metav1.ConditionTrue
 		reason = "Running"
 		message = "Container is running"
-	case scv1alpha1.InstancePhaseStopping, scv1alpha1.InstancePhaseStopped:
+	case scv1alpha1.InstancePhaseConsumerCrashLooping:
+		phase = scv1alpha1.PhaseCrashLooping
+		conditionStatus = metav1.ConditionFalse
+		reason = "CrashLooping"
+		message = sci.Status.Message
+	case scv1alpha1.InstancePhaseStopping:
+		phase = scv1alpha1.PhaseStopping
+		conditionStatus = metav1.ConditionFalse
+		reason = "Stopping"
+		message = "Waiting for the consumer to shut down"
+	case scv1alpha1.InstancePhaseStopped:
 		phase = scv1alpha1.PhaseStopped
 		conditionStatus = metav1.ConditionFalse
 		reason = "Stopped"
 		message = "Container is stopped, filesystem preserved"
+	case scv1alpha1.InstancePhaseCompleted:
+		phase = scv1alpha1.PhaseCompleted
+		conditionStatus = metav1.ConditionFalse
+		reason = "Completed"
+		message = "Container has run to completion"
 	case scv1alpha1.InstancePhaseFailed:
 		phase = scv1alpha1.PhaseFailed
 		conditionStatus = metav1.ConditionFalse
@@ -276,13 +410,28 @@ func (r *StoppableContainerReconciler) updateStatusFromInstance(ctx context.Cont
 		message = "Unknown state"
 	}
 
+	// Record start/stop times only on the transition, not on every reconcile
+	if phase != sc.Status.Phase {
+		now := metav1.Now()
+		switch phase {
+		case scv1alpha1.PhaseRunning:
+			sc.Status.LastStartedTime = &now
+		case scv1alpha1.PhaseStopped:
+			sc.Status.LastStoppedTime = &now
+		}
+	}
+
 	// Update SC status
 	sc.Status.Phase = phase
 	sc.Status.InstanceName = sci.Name
 	sc.Status.ProviderPodName = sci.Status.ProviderPodName
 	sc.Status.ConsumerPodName = sci.Status.ConsumerPodName
+	sc.Status.Replicas = sci.Status.Replicas
+	sc.Status.ReadyReplicas = sci.Status.ReadyReplicas
 	sc.Status.HostPath = sci.Status.HostPath
 	sc.Status.NodeName = sci.Status.NodeName
+	sc.Status.ExitCode = sci.Status.ExitCode
+	sc.Status.RestartCount = sci.Status.RestartCount
 	sc.Status.ObservedGeneration = sc.Generation
 
 	meta.SetStatusCondition(&sc.Status.Conditions, metav1.Condition{
@@ -298,13 +447,311 @@ func (r *StoppableContainerReconciler) updateStatusFromInstance(ctx context.Cont
 	}
 
 	// Requeue to watch for changes
-	if phase != scv1alpha1.PhaseRunning && phase != scv1alpha1.PhaseStopped && phase != scv1alpha1.PhaseFailed {
-		return ctrl.Result{RequeueAfter: 2 * time.Second}, nil
+	if phase != scv1alpha1.PhaseRunning && phase != scv1alpha1.PhaseStopped &&
+		phase != scv1alpha1.PhaseCompleted && phase != scv1alpha1.PhaseFailed {
+		return ctrl.Result{RequeueAfter: 2 * r.requeueInterval()}, nil
 	}
 
 	return ctrl.Result{}, nil
 }
 
+// reconcileService makes the Service exposing the consumer match the spec.
+// The Service is deleted when CreateService is unset, when no ports are declared,
+// or while stopped if DeleteServiceOnStop is set.
+func (r *StoppableContainerReconciler) reconcileService(ctx context.Context, sc *scv1alpha1.StoppableContainer) error {
+	log := logf.FromContext(ctx)
+
+	ports := buildServicePorts(sc.Spec.Template.Spec.Containers)
+	wantService := sc.Spec.CreateService && len(ports) > 0 &&
+		(sc.Spec.Running || !sc.Spec.DeleteServiceOnStop)
+
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      sc.Name,
+			Namespace: sc.Namespace,
+		},
+	}
+
+	if !wantService {
+		existing := &corev1.Service{}
+		if err := r.Get(ctx, client.ObjectKeyFromObject(svc), existing); err != nil {
+			return client.IgnoreNotFound(err)
+		}
+		// Never delete a Service we don't manage
+		if !metav1.IsControlledBy(existing, sc) {
+			return nil
+		}
+		if err := r.Delete(ctx, existing); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		log.Info("Deleted consumer Service")
+		return nil
+	}
+
+	op, err := controllerutil.CreateOrUpdate(ctx, r.Client, svc, func() error {
+		if svc.Labels == nil {
+			svc.Labels = make(map[string]string)
+		}
+		svc.Labels[provider.LabelManagedBy] = provider.ManagedByValue
+		svc.Labels[provider.LabelInstance] = sc.Name
+		applyServiceAnnotations(svc, sc.Spec.ServiceAnnotations)
+		svc.Spec.Type = serviceType(sc)
+		svc.Spec.Selector = map[string]string{
+			provider.LabelInstance: sc.Name,
+			provider.LabelRole:     "consumer",
+		}
+		if svc.Spec.Type != corev1.ServiceTypeClusterIP {
+			keepNodePorts(ports, svc.Spec.Ports)
+		}
+		svc.Spec.Ports = ports
+		return controllerutil.SetControllerReference(sc, svc, r.Scheme)
+	})
+	if err != nil {
+		return err
+	}
+	if op != controllerutil.OperationResultNone {
+		log.Info("Reconciled consumer Service", "operation", op)
+	}
+	return nil
+}
+
+// applyServiceAnnotations copies the annotations to svc and removes the ones copied by an
+// earlier reconcile that are no longer wanted. Annotations set by others are kept.
+func applyServiceAnnotations(svc *corev1.Service, annotations map[string]string) {
+	for key := range strings.SplitSeq(svc.Annotations[AnnotationServiceAnnotations], ",") {
+		if _, ok := annotations[key]; !ok {
+			delete(svc.Annotations, key)
+		}
+	}
+	delete(svc.Annotations, AnnotationServiceAnnotations)
+	if len(annotations) == 0 {
+		return
+	}
+	if svc.Annotations == nil {
+		svc.Annotations = make(map[string]string)
+	}
+	maps.Copy(svc.Annotations, annotations)
+	svc.Annotations[AnnotationServiceAnnotations] = strings.Join(slices.Sorted(maps.Keys(annotations)), ",")
+}
+
+// serviceType returns the type of the managed Service, ClusterIP unless set
+func serviceType(sc *scv1alpha1.StoppableContainer) corev1.ServiceType {
+	if sc.Spec.ServiceType != "" {
+		return sc.Spec.ServiceType
+	}
+	return corev1.ServiceTypeClusterIP
+}
+
+// keepNodePorts copies the node ports the API server allocated in existing to the
+// ports with the same name, so updating the Service does not reallocate them
+func keepNodePorts(ports, existing []corev1.ServicePort) {
+	for i := range ports {
+		for _, old := range existing {
+			if old.Name == ports[i].Name {
+				ports[i].NodePort = old.NodePort
+			}
+		}
+	}
+}
+
+// buildServicePorts maps the ports declared in the containers to Service ports
+func buildServicePorts(containers []corev1.Container) []corev1.ServicePort {
+	var ports []corev1.ServicePort
+	for _, container := range containers {
+		for _, port := range container.Ports {
+			name := port.Name
+			if name == "" {
+				name = fmt.Sprintf("%s-%d", strings.ToLower(string(protocolOrDefault(port.Protocol))), port.ContainerPort)
+			}
+			ports = append(ports, corev1.ServicePort{
+				Name:       name,
+				Protocol:   protocolOrDefault(port.Protocol),
+				Port:       port.ContainerPort,
+				TargetPort: intstr.FromInt32(port.ContainerPort),
+			})
+		}
+	}
+	return ports
+}
+
+// protocolOrDefault returns the protocol, defaulting to TCP like the API server does
+func protocolOrDefault(protocol corev1.Protocol) corev1.Protocol {
+	if protocol == "" {
+		return corev1.ProtocolTCP
+	}
+	return protocol
+}
+
+// hostNamespaceWarning returns a warning if the template shares the node's PID or IPC
+// namespace with the consumer, or "" if it does not
+func hostNamespaceWarning(spec *corev1.PodSpec) string {
+	var namespaces []string
+	if spec.HostPID {
+		namespaces = append(namespaces, "hostPID")
+	}
+	if spec.HostIPC {
+		namespaces = append(namespaces, "hostIPC")
+	}
+	if len(namespaces) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%s enabled: the workload can see and signal host processes or use host IPC objects",
+		strings.Join(namespaces, " and "))
+}
+
+// nonRootWarning returns a warning if the template asks to run the consumer as a
+// non-root user, or "" if it does not. The pod-level setting also applies to sc-exec,
+// which needs root to chroot into the rootfs, while the main container's runAsUser and
+// runAsNonRoot are not applied at all.
+func nonRootWarning(spec *corev1.PodSpec) string {
+	var warnings []string
+	if sc := spec.SecurityContext; sc != nil && (isTrue(sc.RunAsNonRoot) || (sc.RunAsUser != nil && *sc.RunAsUser != 0)) {
+		warnings = append(warnings, "the pod securityContext runs the consumer as non-root, "+
+			"so sc-exec cannot chroot into the rootfs and the consumer will fail to start")
+	}
+	if len(spec.Containers) > 0 {
+		if sc := spec.Containers[0].SecurityContext; sc != nil && (isTrue(sc.RunAsNonRoot) || (sc.RunAsUser != nil && *sc.RunAsUser != 0)) {
+			warnings = append(warnings, "runAsUser and runAsNonRoot of the main container are ignored, "+
+				"so the workload runs as root inside the rootfs")
+		}
+	}
+	return strings.Join(warnings, "; ")
+}
+
+func isTrue(b *bool) bool {
+	return b != nil && *b
+}
+
+// syncTemplateMetadata copies the SC template's labels and annotations to the SCI.
+// Only metadata is synced: it can be applied to a running consumer without recreating it.
+// Returns true if the SCI was changed.
+func syncTemplateMetadata(sc *scv1alpha1.StoppableContainer, sci *scv1alpha1.StoppableContainerInstance) bool {
+	desired := sc.Spec.Template.Metadata
+	current := &sci.Spec.Template.Metadata
+	if maps.Equal(desired.Labels, current.Labels) && maps.Equal(desired.Annotations, current.Annotations) {
+		return false
+	}
+	current.Labels = maps.Clone(desired.Labels)
+	current.Annotations = maps.Clone(desired.Annotations)
+	return true
+}
+
+// syncTemplateSpec copies the SC template's pod spec to the SCI. The SCI controller then
+// recreates the consumer pod, and the provider pod too when the image changed.
+// Returns true if the SCI was changed.
+func syncTemplateSpec(sc *scv1alpha1.StoppableContainer, sci *scv1alpha1.StoppableContainerInstance) bool {
+	if equality.Semantic.DeepEqual(sc.Spec.Template.Spec, sci.Spec.Template.Spec) {
+		return false
+	}
+	sci.Spec.Template.Spec = *sc.Spec.Template.Spec.DeepCopy()
+	return true
+}
+
+// syncReplicas copies the SC's replica count to the SCI.
+// Returns true if the SCI was changed.
+func syncReplicas(sc *scv1alpha1.StoppableContainer, sci *scv1alpha1.StoppableContainerInstance) bool {
+	if equality.Semantic.DeepEqual(sc.Spec.Replicas, sci.Spec.Replicas) {
+		return false
+	}
+	sci.Spec.Replicas = nil
+	if sc.Spec.Replicas != nil {
+		replicas := *sc.Spec.Replicas
+		sci.Spec.Replicas = &replicas
+	}
+	return true
+}
+
+// syncSettings copies the SC's provider, node and stop settings to the SCI. The SCI
+// controller recreates the provider pod when its settings changed, and the consumer pods
+// when the exec-wrapper image or network config copy changed. Returns true if the SCI
+// was changed.
+func syncSettings(sc *scv1alpha1.StoppableContainer, sci *scv1alpha1.StoppableContainerInstance) bool {
+	desired := scv1alpha1.StoppableContainerInstanceSpec{
+		Provider:               sc.Spec.Provider,
+		HostPathPrefix:         sc.Spec.HostPathPrefix,
+		SkipNetworkConfigCopy:  sc.Spec.SkipNetworkConfigCopy,
+		ExecWrapperImage:       sc.Spec.ExecWrapperImage,
+		Persistence:            sc.Spec.Persistence,
+		StopGracePeriodSeconds: sc.Spec.StopGracePeriodSeconds,
+	}
+	current := scv1alpha1.StoppableContainerInstanceSpec{
+		Provider:               sci.Spec.Provider,
+		HostPathPrefix:         sci.Spec.HostPathPrefix,
+		SkipNetworkConfigCopy:  sci.Spec.SkipNetworkConfigCopy,
+		ExecWrapperImage:       sci.Spec.ExecWrapperImage,
+		Persistence:            sci.Spec.Persistence,
+		StopGracePeriodSeconds: sci.Spec.StopGracePeriodSeconds,
+	}
+	if equality.Semantic.DeepEqual(desired, current) {
+		return false
+	}
+	desired = *desired.DeepCopy()
+	sci.Spec.Provider = desired.Provider
+	sci.Spec.HostPathPrefix = desired.HostPathPrefix
+	sci.Spec.SkipNetworkConfigCopy = desired.SkipNetworkConfigCopy
+	sci.Spec.ExecWrapperImage = desired.ExecWrapperImage
+	sci.Spec.Persistence = desired.Persistence
+	sci.Spec.StopGracePeriodSeconds = desired.StopGracePeriodSeconds
+	return true
+}
+
+// syncLabels copies the SC's labels to the SCI, from where the pod builders put them on
+// the provider and consumer pods. Labels are added or updated; labels removed from the
+// SC are left on the SCI. Returns true if the SCI was changed.
+func syncLabels(sc *scv1alpha1.StoppableContainer, sci *scv1alpha1.StoppableContainerInstance) bool {
+	if mapContainsAll(sci.Labels, sc.Labels) {
+		return false
+	}
+	if sci.Labels == nil {
+		sci.Labels = make(map[string]string)
+	}
+	maps.Copy(sci.Labels, sc.Labels)
+	return true
+}
+
+// requeueInterval returns the configured requeue interval or the default
+func (r *StoppableContainerReconciler) requeueInterval() time.Duration {
+	if r.RequeueInterval > 0 {
+		return r.RequeueInterval
+	}
+	return DefaultRequeueInterval
+}
+
+// transitionDebounce returns how long to wait before the instance may be started or
+// stopped again, or zero if the minimum transition interval has already elapsed.
+func (r *StoppableContainerReconciler) transitionDebounce(sci *scv1alpha1.StoppableContainerInstance) time.Duration {
+	interval := r.MinTransitionInterval
+	if interval == 0 {
+		interval = DefaultMinTransitionInterval
+	}
+
+	last, err := time.Parse(time.RFC3339, sci.Annotations[AnnotationLastTransition])
+	if err != nil {
+		// No (or unparsable) record of the last transition
+		return 0
+	}
+
+	if wait := interval - time.Since(last); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+// markTransition records the current time as the instance's last start/stop transition
+func markTransition(sci *scv1alpha1.StoppableContainerInstance) {
+	if sci.Annotations == nil {
+		sci.Annotations = make(map[string]string)
+	}
+	sci.Annotations[AnnotationLastTransition] = time.Now().UTC().Format(time.RFC3339)
+}
+
+// recordEvent emits an event for the StoppableContainer if a recorder is configured
+func (r *StoppableContainerReconciler) recordEvent(sc *scv1alpha1.StoppableContainer, eventType, reason, message string) {
+	if r.Recorder != nil {
+		r.Recorder.Event(sc, eventType, reason, message)
+	}
+}
+
 func (r *StoppableContainerReconciler) updateStatusStopped(ctx context.Context, sc *scv1alpha1.StoppableContainer) (ctrl.Result, error) {
 	sc.Status.Phase = scv1alpha1.PhaseStopped
 	sc.Status.ObservedGeneration = sc.Generation
@@ -329,6 +776,7 @@ func (r *StoppableContainerReconciler) SetupWithManager(mgr ctrl.Manager) error
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&scv1alpha1.StoppableContainer{}).
 		Owns(&scv1alpha1.StoppableContainerInstance{}).
+		Owns(&corev1.Service{}).
 		Watches(
 			&scv1alpha1.StoppableContainerInstance{},
 			handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, obj client.Object) []reconcile.Request {
@@ -351,6 +799,25 @@ func (r *StoppableContainerReconciler) SetupWithManager(mgr ctrl.Manager) error
 		Complete(r)
 }
 
+// withReconcileTimeout runs fn with a context that expires after timeout (DefaultReconcileTimeout
+// when zero). If the deadline is hit, the request is requeued after requeueAfter instead of
+// returning the error.
+func withReconcileTimeout(ctx context.Context, timeout, requeueAfter time.Duration,
+	fn func(context.Context) (ctrl.Result, error)) (ctrl.Result, error) {
+	if timeout <= 0 {
+		timeout = DefaultReconcileTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	result, err := fn(ctx)
+	if ctx.Err() == context.DeadlineExceeded {
+		logf.FromContext(ctx).Info("Reconcile exceeded deadline, requeueing", "timeout", timeout)
+		return ctrl.Result{RequeueAfter: requeueAfter}, nil
+	}
+	return result, err
+}
+
 func boolPtr(b bool) *bool {
 	return &b
 }
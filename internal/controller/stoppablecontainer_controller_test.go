@@ -18,16 +18,22 @@ package controller
 
 import (
 	"context"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	scv1alpha1 "github.com/xtlsoft/stoppablecontainer/api/v1alpha1"
+	"github.com/xtlsoft/stoppablecontainer/internal/provider"
 )
 
 var _ = Describe("StoppableContainer Controller", func() {
@@ -84,5 +90,463 @@ var _ = Describe("StoppableContainer Controller", func() {
 			// Cleanup
 			Expect(k8sClient.Delete(ctx, sc)).To(Succeed())
 		})
+
+		It("should debounce rapid start/stop transitions", func() {
+			ctx := context.Background()
+			resourceName := "test-sc-debounce"
+
+			typeNamespacedName := types.NamespacedName{
+				Name:      resourceName,
+				Namespace: "default",
+			}
+
+			By("Creating a stopped StoppableContainer with a running instance")
+			sc := &scv1alpha1.StoppableContainer{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       resourceName,
+					Namespace:  "default",
+					Finalizers: []string{FinalizerName},
+				},
+				Spec: scv1alpha1.StoppableContainerSpec{
+					Running: false,
+					Template: scv1alpha1.PodTemplateSpec{
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{Name: "main", Image: "ubuntu:22.04", Command: []string{"sleep", "infinity"}},
+							},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, sc)).To(Succeed())
+
+			sci := &scv1alpha1.StoppableContainerInstance{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+					Annotations: map[string]string{
+						AnnotationLastTransition: time.Now().UTC().Format(time.RFC3339),
+					},
+				},
+				Spec: scv1alpha1.StoppableContainerInstanceSpec{
+					StoppableContainerName: resourceName,
+					Running:                true,
+					Template:               sc.Spec.Template,
+				},
+			}
+			Expect(k8sClient.Create(ctx, sci)).To(Succeed())
+			sci.Status.Phase = scv1alpha1.InstancePhaseRunning
+			sci.Status.ConsumerPodName = resourceName
+			Expect(k8sClient.Status().Update(ctx, sci)).To(Succeed())
+
+			recorder := record.NewFakeRecorder(10)
+			controllerReconciler := &StoppableContainerReconciler{
+				Client:                k8sClient,
+				Scheme:                k8sClient.Scheme(),
+				Recorder:              recorder,
+				MinTransitionInterval: time.Minute,
+			}
+
+			By("Reconciling within the minimum transition interval")
+			result, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(BeNumerically(">", 0))
+			Expect(result.RequeueAfter).To(BeNumerically("<=", time.Minute))
+			Expect(recorder.Events).To(Receive(ContainSubstring("Debounced")))
+
+			updated := &scv1alpha1.StoppableContainerInstance{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, updated)).To(Succeed())
+			Expect(updated.Spec.Running).To(BeTrue())
+
+			By("Reconciling after the minimum transition interval has elapsed")
+			updated.Annotations[AnnotationLastTransition] = time.Now().Add(-2 * time.Minute).UTC().Format(time.RFC3339)
+			Expect(k8sClient.Update(ctx, updated)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, updated)).To(Succeed())
+			Expect(updated.Spec.Running).To(BeFalse())
+			Expect(updated.Annotations[AnnotationLastTransition]).NotTo(BeEmpty())
+
+			// Cleanup
+			Expect(k8sClient.Delete(ctx, updated)).To(Succeed())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, sc)).To(Succeed())
+			sc.Finalizers = nil
+			Expect(k8sClient.Update(ctx, sc)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, sc)).To(Succeed())
+		})
+
+		It("should record start/stop times on phase transitions and the restart count", func() {
+			ctx := context.Background()
+			resourceName := "test-sc-timestamps"
+
+			typeNamespacedName := types.NamespacedName{
+				Name:      resourceName,
+				Namespace: "default",
+			}
+
+			sc := &scv1alpha1.StoppableContainer{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+				Spec: scv1alpha1.StoppableContainerSpec{
+					Running: true,
+					Template: scv1alpha1.PodTemplateSpec{
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{Name: "main", Image: "ubuntu:22.04"},
+							},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, sc)).To(Succeed())
+			sci := &scv1alpha1.StoppableContainerInstance{
+				ObjectMeta: metav1.ObjectMeta{Name: resourceName, Namespace: "default"},
+				Status:     scv1alpha1.StoppableContainerInstanceStatus{Phase: scv1alpha1.InstancePhaseRunning},
+			}
+			controllerReconciler := &StoppableContainerReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+
+			By("Entering the Running phase")
+			_, err := controllerReconciler.updateStatusFromInstance(ctx, sc, sci)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sc.Status.LastStartedTime).NotTo(BeNil())
+			Expect(sc.Status.LastStoppedTime).To(BeNil())
+
+			By("Staying Running")
+			started := metav1.NewTime(time.Now().Add(-time.Hour).Truncate(time.Second))
+			sc.Status.LastStartedTime = &started
+			_, err = controllerReconciler.updateStatusFromInstance(ctx, sc, sci)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sc.Status.LastStartedTime.Equal(&started)).To(BeTrue())
+
+			By("Copying the instance's consumer pod recreations")
+			sci.Status.RestartCount = 2
+			_, err = controllerReconciler.updateStatusFromInstance(ctx, sc, sci)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sc.Status.RestartCount).To(Equal(int32(2)))
+
+			By("Draining the consumer")
+			sci.Status.Phase = scv1alpha1.InstancePhaseStopping
+			_, err = controllerReconciler.updateStatusFromInstance(ctx, sc, sci)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sc.Status.Phase).To(Equal(scv1alpha1.PhaseStopping))
+			Expect(sc.Status.LastStoppedTime).To(BeNil())
+
+			By("Entering the Stopped phase")
+			sci.Status.Phase = scv1alpha1.InstancePhaseStopped
+			_, err = controllerReconciler.updateStatusFromInstance(ctx, sc, sci)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sc.Status.LastStoppedTime).NotTo(BeNil())
+			Expect(sc.Status.LastStartedTime.Equal(&started)).To(BeTrue())
+
+			updated := &scv1alpha1.StoppableContainer{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, updated)).To(Succeed())
+			Expect(updated.Status.LastStartedTime).NotTo(BeNil())
+			Expect(updated.Status.LastStoppedTime).NotTo(BeNil())
+
+			// Cleanup
+			Expect(k8sClient.Delete(ctx, updated)).To(Succeed())
+		})
+
+		It("should create a Service exposing the consumer ports", func() {
+			ctx := context.Background()
+			resourceName := "test-sc-service"
+
+			typeNamespacedName := types.NamespacedName{
+				Name:      resourceName,
+				Namespace: "default",
+			}
+
+			By("Creating a StoppableContainer with createService")
+			sc := &scv1alpha1.StoppableContainer{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       resourceName,
+					Namespace:  "default",
+					Finalizers: []string{FinalizerName},
+				},
+				Spec: scv1alpha1.StoppableContainerSpec{
+					Running:       false,
+					CreateService: true,
+					Template: scv1alpha1.PodTemplateSpec{
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name:  "main",
+									Image: "nginx:stable",
+									Ports: []corev1.ContainerPort{
+										{Name: "http", ContainerPort: 80},
+										{ContainerPort: 53, Protocol: corev1.ProtocolUDP},
+									},
+								},
+							},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, sc)).To(Succeed())
+
+			controllerReconciler := &StoppableContainerReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Verifying the Service selects the consumer and maps the ports")
+			svc := &corev1.Service{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, svc)).To(Succeed())
+			Expect(svc.Spec.Selector).To(HaveKeyWithValue(provider.LabelInstance, resourceName))
+			Expect(svc.Spec.Selector).To(HaveKeyWithValue(provider.LabelRole, "consumer"))
+			Expect(svc.Spec.Ports).To(HaveLen(2))
+			Expect(svc.Spec.Ports[0].Name).To(Equal("http"))
+			Expect(svc.Spec.Ports[0].Port).To(Equal(int32(80)))
+			Expect(svc.Spec.Ports[0].TargetPort.IntValue()).To(Equal(80))
+			Expect(svc.Spec.Ports[1].Name).To(Equal("udp-53"))
+			Expect(svc.Spec.Ports[1].Protocol).To(Equal(corev1.ProtocolUDP))
+			Expect(svc.Spec.Type).To(Equal(corev1.ServiceTypeClusterIP))
+			Expect(metav1.IsControlledBy(svc, sc)).To(BeTrue())
+
+			By("Changing the Service type and annotations")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, sc)).To(Succeed())
+			sc.Spec.ServiceType = corev1.ServiceTypeLoadBalancer
+			sc.Spec.ServiceAnnotations = map[string]string{"service.beta.kubernetes.io/aws-load-balancer-internal": "true"}
+			Expect(k8sClient.Update(ctx, sc)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, svc)).To(Succeed())
+			Expect(svc.Spec.Type).To(Equal(corev1.ServiceTypeLoadBalancer))
+			Expect(svc.Annotations).To(HaveKeyWithValue("service.beta.kubernetes.io/aws-load-balancer-internal", "true"))
+
+			By("Removing an annotation dropped from serviceAnnotations but keeping others")
+			svc.Annotations["example.com/owner"] = "team-a"
+			Expect(k8sClient.Update(ctx, svc)).To(Succeed())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, sc)).To(Succeed())
+			sc.Spec.ServiceAnnotations = map[string]string{"service.beta.kubernetes.io/aws-load-balancer-scheme": "internal"}
+			Expect(k8sClient.Update(ctx, sc)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, svc)).To(Succeed())
+			Expect(svc.Annotations).NotTo(HaveKey("service.beta.kubernetes.io/aws-load-balancer-internal"))
+			Expect(svc.Annotations).To(HaveKeyWithValue("service.beta.kubernetes.io/aws-load-balancer-scheme", "internal"))
+			Expect(svc.Annotations).To(HaveKeyWithValue("example.com/owner", "team-a"))
+
+			By("Keeping the allocated node ports on update")
+			svc.Spec.Ports[0].NodePort = 30080
+			Expect(k8sClient.Update(ctx, svc)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, svc)).To(Succeed())
+			Expect(svc.Spec.Ports[0].NodePort).To(Equal(int32(30080)))
+
+			By("Deleting the Service while stopped when deleteServiceOnStop is set")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, sc)).To(Succeed())
+			sc.Spec.DeleteServiceOnStop = true
+			Expect(k8sClient.Update(ctx, sc)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(errors.IsNotFound(k8sClient.Get(ctx, typeNamespacedName, &corev1.Service{}))).To(BeTrue())
+
+			// Cleanup
+			Expect(k8sClient.Get(ctx, typeNamespacedName, sc)).To(Succeed())
+			sc.Finalizers = nil
+			Expect(k8sClient.Update(ctx, sc)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, sc)).To(Succeed())
+		})
+
+		It("should not start the consumer when stopped right after creation", func() {
+			ctx := context.Background()
+			resourceName := "test-sc-create-stop"
+			typeNamespacedName := types.NamespacedName{Name: resourceName, Namespace: "default"}
+
+			By("Creating a running StoppableContainer")
+			sc := &scv1alpha1.StoppableContainer{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       resourceName,
+					Namespace:  "default",
+					Finalizers: []string{FinalizerName},
+				},
+				Spec: scv1alpha1.StoppableContainerSpec{
+					Running: true,
+					Template: scv1alpha1.PodTemplateSpec{
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{Name: "main", Image: "ubuntu:22.04", Command: []string{"sleep", "infinity"}},
+							},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, sc)).To(Succeed())
+
+			recorder := record.NewFakeRecorder(10)
+			scReconciler := &StoppableContainerReconciler{
+				Client:                k8sClient,
+				Scheme:                k8sClient.Scheme(),
+				Recorder:              recorder,
+				MinTransitionInterval: time.Minute,
+			}
+			_, err := scReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Stopping it before the consumer is created")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, sc)).To(Succeed())
+			sc.Spec.Running = false
+			Expect(k8sClient.Update(ctx, sc)).To(Succeed())
+			_, err = scReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(recorder.Events).NotTo(Receive())
+
+			sci := &scv1alpha1.StoppableContainerInstance{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, sci)).To(Succeed())
+			Expect(sci.Spec.Running).To(BeFalse())
+
+			By("Reconciling the instance once its provider is ready")
+			providerPod := provider.NewProviderPodBuilder(sci).Build()
+			providerPod.Spec.NodeName = "node-1"
+			Expect(k8sClient.Create(ctx, providerPod)).To(Succeed())
+			providerPod.Status = corev1.PodStatus{
+				Phase: corev1.PodRunning,
+				Conditions: []corev1.PodCondition{
+					{Type: corev1.PodReady, Status: corev1.ConditionTrue},
+				},
+			}
+			Expect(k8sClient.Status().Update(ctx, providerPod)).To(Succeed())
+
+			sciReconciler := &StoppableContainerInstanceReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+			for i := 0; i < 2; i++ {
+				_, err = sciReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			Expect(errors.IsNotFound(k8sClient.Get(ctx, typeNamespacedName, &corev1.Pod{}))).To(BeTrue())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, sci)).To(Succeed())
+			Expect(sci.Status.Phase).To(Equal(scv1alpha1.InstancePhaseStopped))
+
+			// Cleanup
+			Expect(k8sClient.Delete(ctx, providerPod)).To(Succeed())
+			sci.Finalizers = nil
+			Expect(k8sClient.Update(ctx, sci)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, sci)).To(Succeed())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, sc)).To(Succeed())
+			sc.Finalizers = nil
+			Expect(k8sClient.Update(ctx, sc)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, sc)).To(Succeed())
+		})
+
+		It("should stop a container that stays idle past its idle timeout", func() {
+			ctx := context.Background()
+			resourceName := "test-sc-idle-stop"
+			typeNamespacedName := types.NamespacedName{Name: resourceName, Namespace: "default"}
+
+			By("Creating a running StoppableContainer with an idle timeout")
+			idleTimeout := int64(300)
+			sc := &scv1alpha1.StoppableContainer{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       resourceName,
+					Namespace:  "default",
+					Finalizers: []string{FinalizerName},
+				},
+				Spec: scv1alpha1.StoppableContainerSpec{
+					Running:            true,
+					IdleTimeoutSeconds: &idleTimeout,
+					Template: scv1alpha1.PodTemplateSpec{
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{Name: "main", Image: "ubuntu:22.04", Command: []string{"sleep", "infinity"}},
+							},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, sc)).To(Succeed())
+
+			metrics := staticPodMetrics{resourceName: resource.MustParse("2m")}
+			recorder := record.NewFakeRecorder(10)
+			scReconciler := &StoppableContainerReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: recorder,
+				Metrics:  metrics,
+			}
+			_, err := scReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Marking the instance as running since an hour")
+			sci := &scv1alpha1.StoppableContainerInstance{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, sci)).To(Succeed())
+			sci.Annotations[AnnotationLastTransition] = time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+			Expect(k8sClient.Update(ctx, sci)).To(Succeed())
+			sci.Status.Phase = scv1alpha1.InstancePhaseRunning
+			sci.Status.Replicas = 1
+			Expect(k8sClient.Status().Update(ctx, sci)).To(Succeed())
+
+			By("Recording the start of the idle period")
+			result, err := scReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(IdleCheckInterval))
+			Expect(k8sClient.Get(ctx, typeNamespacedName, sc)).To(Succeed())
+			Expect(sc.Spec.Running).To(BeTrue())
+			Expect(sc.Status.IdleSince).NotTo(BeNil())
+
+			By("Stopping it once the idle timeout has passed")
+			idleSince := metav1.NewTime(time.Now().Add(-10 * time.Minute))
+			sc.Status.IdleSince = &idleSince
+			Expect(k8sClient.Status().Update(ctx, sc)).To(Succeed())
+			_, err = scReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, sc)).To(Succeed())
+			Expect(sc.Spec.Running).To(BeFalse())
+			Expect(sc.Status.IdleSince).To(BeNil())
+			Expect(meta.IsStatusConditionTrue(sc.Status.Conditions, ConditionTypeIdleStopped)).To(BeTrue())
+			Expect(recorder.Events).To(Receive(ContainSubstring("IdleStopped")))
+
+			By("Clearing the condition when it is started again")
+			sc.Spec.Running = true
+			Expect(k8sClient.Update(ctx, sc)).To(Succeed())
+			_, err = scReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, sc)).To(Succeed())
+			Expect(meta.FindStatusCondition(sc.Status.Conditions, ConditionTypeIdleStopped)).To(BeNil())
+
+			// Cleanup
+			Expect(k8sClient.Get(ctx, typeNamespacedName, sci)).To(Succeed())
+			sci.Finalizers = nil
+			Expect(k8sClient.Update(ctx, sci)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, sci)).To(Succeed())
+			sc.Finalizers = nil
+			Expect(k8sClient.Update(ctx, sc)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, sc)).To(Succeed())
+		})
 	})
 })
+
+// staticPodMetrics is a PodMetricsReader that reports a fixed CPU usage
+type staticPodMetrics map[string]resource.Quantity
+
+func (m staticPodMetrics) ConsumerCPUUsage(context.Context, *scv1alpha1.StoppableContainerInstance) (map[string]resource.Quantity, error) {
+	return m, nil
+}
@@ -19,20 +19,28 @@ package controller
 import (
 	"context"
 	"fmt"
+	"maps"
 	"path/filepath"
+	"slices"
+	"strings"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	scv1alpha1 "github.com/xtlsoft/stoppablecontainer/api/v1alpha1"
@@ -42,12 +50,89 @@ import (
 const (
 	// SCIFinalizerName is the finalizer for StoppableContainerInstance
 	SCIFinalizerName = "stoppablecontainerinstance.xtlsoft.top/finalizer"
+
+	// ConditionTypeMountHelperAvailable indicates whether mount-helper has mounted the rootfs
+	ConditionTypeMountHelperAvailable = "MountHelperAvailable"
+
+	// ConditionTypeStarted is set when the instance becomes Running after it was created or
+	// started again, and removed while it is stopped. Its transition time marks the end of the start.
+	ConditionTypeStarted = "Started"
+
+	// ConditionTypeProviderStarted is set once the provider pod of the instance first becomes
+	// ready. Its transition time marks when the rootfs was first mounted.
+	ConditionTypeProviderStarted = "ProviderStarted"
+
+	// ReasonMountHelperUnavailable is set when the rootfs was not mounted in time
+	ReasonMountHelperUnavailable = "MountHelperUnavailable"
+
+	// ReasonRootfsLost is the event reason when the provider's node, and with it the rootfs, is gone
+	ReasonRootfsLost = "RootfsLost"
+
+	// ReasonRootfsDiscarded is the event reason when the provider pod is replaced and the
+	// changes in its rootfs, kept only by the old pod, are gone
+	ReasonRootfsDiscarded = "RootfsDiscarded"
+
+	// ReasonRootfsCleanupFailed is the event reason when the provider pod of a deleted
+	// instance exits without the rootfs being unmounted from its node
+	ReasonRootfsCleanupFailed = "RootfsCleanupFailed"
+
+	// ReasonOrphaned is the event reason when an instance is deleted because its StoppableContainer is gone
+	ReasonOrphaned = "Orphaned"
+
+	// DefaultMountHelperTimeout is how long the provider may wait for the rootfs mount
+	DefaultMountHelperTimeout = 2 * time.Minute
+
+	// podNodeNameField indexes pods by node. It matches the field selector the API server
+	// supports for pods, so lists work against the cache and the API server alike.
+	podNodeNameField = "spec.nodeName"
+
+	// DefaultImagePullTimeout is how long kubelet may keep retrying a failing image pull
+	// before the instance fails
+	DefaultImagePullTimeout = 5 * time.Minute
+
+	// CrashLoopRestartThreshold is the restart count from which a consumer container
+	// that is not ready counts as crash looping, even between back-off periods
+	CrashLoopRestartThreshold = 3
+
+	// DefaultMaxConsumerRestarts is how often a crash looping consumer pod is
+	// recreated before the instance is marked Failed
+	DefaultMaxConsumerRestarts = 3
+
+	// ConsumerRestartBaseDelay is the delay before the first recreation of a crash
+	// looping consumer pod; it doubles with every further recreation
+	ConsumerRestartBaseDelay = 10 * time.Second
+
+	// ConsumerRestartMaxDelay caps the delay between consumer pod recreations
+	ConsumerRestartMaxDelay = 5 * time.Minute
 )
 
 // StoppableContainerInstanceReconciler reconciles a StoppableContainerInstance object
 type StoppableContainerInstanceReconciler struct {
 	client.Client
-	Scheme *runtime.Scheme
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+
+	// ReconcileTimeout is the deadline for a single reconcile.
+	// Defaults to DefaultReconcileTimeout when zero.
+	ReconcileTimeout time.Duration
+
+	// MountHelperTimeout is how long a running provider may wait for mount-helper.
+	// Defaults to DefaultMountHelperTimeout when zero.
+	MountHelperTimeout time.Duration
+
+	// ImagePullTimeout is how long the provider pod may retry a failing image pull.
+	// Defaults to DefaultImagePullTimeout when zero.
+	ImagePullTimeout time.Duration
+
+	// RequeueInterval is how long to wait before re-checking pods that are being
+	// created or deleted; intermediate phases are polled at twice this interval.
+	// Defaults to DefaultRequeueInterval when zero.
+	RequeueInterval time.Duration
+
+	// MaxConsumerRestarts is how often a crash looping consumer pod is recreated
+	// before the instance is marked Failed. Defaults to DefaultMaxConsumerRestarts
+	// when zero; a negative value disables recreation.
+	MaxConsumerRestarts int
 }
 
 // +kubebuilder:rbac:groups=stoppablecontainer.xtlsoft.top,resources=stoppablecontainerinstances,verbs=get;list;watch;create;update;patch;delete
@@ -55,9 +140,16 @@ type StoppableContainerInstanceReconciler struct {
 // +kubebuilder:rbac:groups=stoppablecontainer.xtlsoft.top,resources=stoppablecontainerinstances/finalizers,verbs=update
 // +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch
 
 // Reconcile reconciles the StoppableContainerInstance resource
 func (r *StoppableContainerInstanceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	return withReconcileTimeout(ctx, r.ReconcileTimeout, r.requeueInterval(), func(ctx context.Context) (ctrl.Result, error) {
+		return r.reconcile(ctx, req)
+	})
+}
+
+func (r *StoppableContainerInstanceReconciler) reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := logf.FromContext(ctx)
 
 	// Fetch the SCI
@@ -74,6 +166,20 @@ func (r *StoppableContainerInstanceReconciler) Reconcile(ctx context.Context, re
 		return r.handleDeletion(ctx, sci)
 	}
 
+	// An instance left behind by its StoppableContainer would hold the provider pod forever.
+	// Deleting it removes the pods through the finalizer.
+	if owner, orphaned, err := r.orphanedOwner(ctx, sci); err != nil {
+		return ctrl.Result{}, err
+	} else if orphaned {
+		log.Info("StoppableContainer is gone, deleting the orphaned instance", "stoppableContainer", owner)
+		r.recordEvent(sci, corev1.EventTypeWarning, ReasonOrphaned,
+			fmt.Sprintf("StoppableContainer %s no longer exists, deleting the instance and its pods", owner))
+		if err := r.Delete(ctx, sci); err != nil && !errors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, nil
+	}
+
 	// Add finalizer if not present
 	if !controllerutil.ContainsFinalizer(sci, SCIFinalizerName) {
 		controllerutil.AddFinalizer(sci, SCIFinalizerName)
@@ -98,32 +204,117 @@ func (r *StoppableContainerInstanceReconciler) Reconcile(ctx context.Context, re
 		}
 	}
 
-	// Get consumer pod
-	consumerPod := &corev1.Pod{}
-	consumerPodName := types.NamespacedName{
-		Namespace: sci.Namespace,
-		Name:      sci.Name, // Consumer pod uses same name as SCI for seamless experience
-	}
-	consumerExists := true
-	if err := r.Get(ctx, consumerPodName, consumerPod); err != nil {
-		if errors.IsNotFound(err) {
-			consumerExists = false
-		} else {
-			return ctrl.Result{}, err
-		}
+	// Get consumer pods
+	consumerPods, missingOrdinals, err := r.getConsumerPods(ctx, sci)
+	if err != nil {
+		return ctrl.Result{}, err
 	}
+	sci.Status.Replicas = int32(provider.ConsumerReplicas(sci))
+	sci.Status.ReadyReplicas = countReadyPods(consumerPods)
 
 	// Reconcile provider pod
 	if !providerExists {
+		// The validating webhook may be disabled, so check before anything uses the host path
+		// or runs the infra images
+		if err := provider.ValidateHostPathPrefix(sci.Spec.HostPathPrefix); err != nil {
+			return r.updatePhase(ctx, sci, scv1alpha1.InstancePhaseFailed,
+				fmt.Sprintf("Invalid hostPathPrefix: %v", err))
+		}
+		if err := validateInfraImages(sci); err != nil {
+			return r.updatePhase(ctx, sci, scv1alpha1.InstancePhaseFailed, fmt.Sprintf("Invalid infra image: %v", err))
+		}
+		// A known node means the provider existed before and was deleted out-of-band
+		if sci.Status.NodeName != "" {
+			if sci.Spec.Persistence == nil {
+				r.recordEvent(sci, corev1.EventTypeWarning, ReasonRootfsDiscarded,
+					fmt.Sprintf("Provider pod %s on node %s is gone; the changes in its rootfs are discarded, "+
+						"a new one is created from the image", sci.Status.ProviderPodName, sci.Status.NodeName))
+			}
+			return r.recoverProviderPod(ctx, sci)
+		}
 		return r.createProviderPod(ctx, sci)
 	}
 
+	// The rootfs lives on the provider's node, so it is lost when the node is deleted
+	if lost, err := r.providerNodeLost(ctx, providerPod); err != nil {
+		return ctrl.Result{}, err
+	} else if lost {
+		return r.recoverLostProviderNode(ctx, sci, providerPod)
+	}
+
+	// The rootfs comes from the user image, so an image or provider settings change needs
+	// a new provider. Once it is gone, a new provider and new consumers are created.
+	if providerOutdated(sci, providerPod) {
+		// Consumers run on the provider's overlay, so stop them before it is unmounted
+		remaining, err := r.stopConsumerPods(ctx, sci)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if remaining > 0 {
+			log.Info("Waiting for consumer pods to shut down (provider change)", "count", remaining)
+			return r.updatePhase(ctx, sci, scv1alpha1.InstancePhaseStopping,
+				fmt.Sprintf("Waiting for %d consumer pod(s) to shut down to apply a provider change", remaining))
+		}
+		if providerPod.DeletionTimestamp.IsZero() {
+			log.Info("Deleting provider pod to apply a provider change")
+			if err := r.Delete(ctx, providerPod); err != nil && !errors.IsNotFound(err) {
+				return ctrl.Result{}, err
+			}
+			if sci.Spec.Persistence == nil {
+				r.recordEvent(sci, corev1.EventTypeWarning, ReasonRootfsDiscarded,
+					fmt.Sprintf("Provider pod %s is replaced to apply a provider change; the changes in its rootfs are discarded",
+						providerPod.Name))
+			}
+			// The new provider is created, not recovered, so the loss is reported once
+			resetPodStatus(sci)
+		}
+		return r.updatePhase(ctx, sci, scv1alpha1.InstancePhaseProviderStarting,
+			"Recreating provider pod to apply a provider change")
+	}
+
 	// Check provider pod status
 	if !isPodReady(providerPod) {
+		if reason, transient, failed := getImagePullFailure(providerPod); failed {
+			// kubelet keeps retrying pulls that failed on the registry or network. It sets
+			// the pod's start time once it accepted the pod, before pulling images.
+			started := providerPod.CreationTimestamp.Time
+			if providerPod.Status.StartTime != nil {
+				started = providerPod.Status.StartTime.Time
+			}
+			if !transient || time.Since(started) > r.imagePullTimeout() {
+				return r.updatePhase(ctx, sci, scv1alpha1.InstancePhaseFailed,
+					fmt.Sprintf("Failed to pull image: %s", reason))
+			}
+			return r.updatePhase(ctx, sci, scv1alpha1.InstancePhaseProviderStarting,
+				fmt.Sprintf("Waiting for the image pull to be retried (%s)", reason))
+		}
+		if mountHelperWaitExceeded(providerPod, r.mountHelperTimeout(), time.Now()) {
+			// The message names the timeout, not the time waited, so it does not change on
+			// every reconcile and cause a status update each time
+			message := fmt.Sprintf("Rootfs not mounted within %s; check that the mount-helper DaemonSet is running on node %s",
+				r.mountHelperTimeout(), providerPod.Spec.NodeName)
+			meta.SetStatusCondition(&sci.Status.Conditions, metav1.Condition{
+				Type:               ConditionTypeMountHelperAvailable,
+				Status:             metav1.ConditionFalse,
+				Reason:             ReasonMountHelperUnavailable,
+				Message:            message,
+				ObservedGeneration: sci.Generation,
+			})
+			return r.updatePhase(ctx, sci, scv1alpha1.InstancePhaseProviderStarting, message)
+		}
 		return r.updatePhase(ctx, sci, scv1alpha1.InstancePhaseProviderStarting,
 			"Waiting for provider pod to be ready")
 	}
 
+	// Provider is ready, so the rootfs is mounted
+	meta.SetStatusCondition(&sci.Status.Conditions, metav1.Condition{
+		Type:               ConditionTypeMountHelperAvailable,
+		Status:             metav1.ConditionTrue,
+		Reason:             "Mounted",
+		Message:            "Rootfs is mounted",
+		ObservedGeneration: sci.Generation,
+	})
+
 	// Provider is ready - update node name and host path
 	sci.Status.NodeName = providerPod.Spec.NodeName
 	sci.Status.HostPath = filepath.Join(provider.GetHostPath(sci), "rootfs")
@@ -132,40 +323,119 @@ func (r *StoppableContainerInstanceReconciler) Reconcile(ctx context.Context, re
 
 	// If we shouldn't be running, make sure consumer is deleted
 	if !sci.Spec.Running {
-		if consumerExists {
-			log.Info("Deleting consumer pod (stopping)")
-			if err := r.Delete(ctx, consumerPod); err != nil && !errors.IsNotFound(err) {
-				return ctrl.Result{}, err
-			}
+		remaining, err := r.stopConsumerPods(ctx, sci)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if remaining > 0 {
+			log.Info("Waiting for consumer pods to shut down (stopping)", "count", remaining)
 			return r.updatePhase(ctx, sci, scv1alpha1.InstancePhaseStopping,
-				"Stopping consumer pod")
+				fmt.Sprintf("Waiting for %d consumer pod(s) to shut down", remaining))
 		}
 		return r.updatePhase(ctx, sci, scv1alpha1.InstancePhaseStopped,
 			"Consumer stopped, provider maintaining filesystem")
 	}
 
-	// We should be running - create consumer if needed
-	if !consumerExists {
+	// Delete consumer pods left over from a different replica count
+	desiredNames := make([]string, provider.ConsumerReplicas(sci))
+	for i := range desiredNames {
+		desiredNames[i] = provider.ConsumerPodName(sci, i)
+	}
+	if deleted, err := r.deleteConsumerPods(ctx, sci, desiredNames...); err != nil {
+		return ctrl.Result{}, err
+	} else if deleted > 0 {
+		log.Info("Deleted surplus consumer pods", "count", deleted)
+	}
+
+	// We should be running - create consumers if needed, one per reconcile
+	if len(missingOrdinals) > 0 {
 		// Make sure provider is fully ready first
 		if sci.Status.NodeName == "" {
 			return r.updatePhase(ctx, sci, scv1alpha1.InstancePhaseProviderReady,
 				"Provider ready, waiting for node assignment")
 		}
-		return r.createConsumerPod(ctx, sci)
+		// Back off before recreating a consumer pod that was crash looping
+		if wait := consumerRestartWait(sci, time.Now()); wait > 0 {
+			result, err := r.updatePhase(ctx, sci, scv1alpha1.InstancePhaseConsumerCrashLooping,
+				fmt.Sprintf("Waiting to recreate the crash looping consumer pod (attempt %d of %d)",
+					sci.Status.RestartCount, r.maxConsumerRestarts()))
+			if err != nil {
+				return result, err
+			}
+			return ctrl.Result{RequeueAfter: wait}, nil
+		}
+		return r.createConsumerPod(ctx, sci, missingOrdinals[0])
 	}
 
-	// Check consumer pod status
-	sci.Status.ConsumerPodName = consumerPod.Name
-	sci.Status.ConsumerPodUID = string(consumerPod.UID)
+	for _, consumerPod := range consumerPods {
+		// Other template changes need a new consumer pod. Pods without a hash predate it and
+		// are kept; syncConsumerMetadata records the current hash on them.
+		if hash, ok := consumerPod.Annotations[provider.AnnotationTemplateHash]; ok && hash != provider.TemplateHash(sci) {
+			if consumerPod.DeletionTimestamp.IsZero() {
+				log.Info("Deleting consumer pod to apply template changes", "pod", consumerPod.Name)
+				if err := r.Delete(ctx, consumerPod); err != nil && !errors.IsNotFound(err) {
+					return ctrl.Result{}, err
+				}
+			}
+			return r.updatePhase(ctx, sci, scv1alpha1.InstancePhaseConsumerStarting,
+				"Recreating consumer pod to apply template changes")
+		}
 
-	if isPodFailed(consumerPod) {
-		return r.updatePhase(ctx, sci, scv1alpha1.InstancePhaseFailed,
-			fmt.Sprintf("Consumer pod failed: %s", getPodFailureReason(consumerPod)))
+		// Apply template label/annotation changes to the running consumer without recreating it
+		if err := r.syncConsumerMetadata(ctx, sci, consumerPod); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	// Check consumer pod status. The first replica is reported as the consumer pod.
+	sci.Status.ConsumerPodName = consumerPods[0].Name
+	sci.Status.ConsumerPodUID = string(consumerPods[0].UID)
+
+	succeeded, deadlineExceeded := 0, 0
+	for _, consumerPod := range consumerPods {
+		// Record the consumer's exit code once it has terminated
+		if exitCode := getConsumerExitCode(consumerPod); exitCode != nil {
+			sci.Status.ExitCode = exitCode
+		}
+		if isPodSucceeded(consumerPod) {
+			succeeded++
+		} else if isPodDeadlineExceeded(consumerPod) {
+			deadlineExceeded++
+		}
+	}
+
+	// A consumer stopped by kubelet at the template's activeDeadlineSeconds has run for
+	// as long as it was meant to, so it completed rather than failed
+	if succeeded+deadlineExceeded == len(consumerPods) {
+		message := "Consumer pod completed successfully"
+		if deadlineExceeded > 0 {
+			message = "Consumer pod was stopped at its activeDeadlineSeconds"
+		}
+		return r.updatePhase(ctx, sci, scv1alpha1.InstancePhaseCompleted, message)
 	}
 
-	if !isPodReady(consumerPod) {
-		return r.updatePhase(ctx, sci, scv1alpha1.InstancePhaseConsumerStarting,
-			"Waiting for consumer pod to be ready")
+	for _, consumerPod := range consumerPods {
+		if isPodDeadlineExceeded(consumerPod) {
+			continue
+		}
+		if isPodFailed(consumerPod) {
+			return r.updatePhase(ctx, sci, scv1alpha1.InstancePhaseFailed,
+				fmt.Sprintf("Consumer pod failed: %s", getPodFailureReason(consumerPod)))
+		}
+
+		// With RestartPolicy Always a crashing consumer never fails, it just keeps restarting
+		if message, looping := getConsumerCrashLoop(consumerPod); looping {
+			return r.restartCrashLoopingConsumer(ctx, sci, consumerPod, message)
+		}
+	}
+
+	if replicas := len(consumerPods); int(sci.Status.ReadyReplicas) < replicas {
+		message := "Waiting for consumer pod to be ready"
+		if replicas > 1 {
+			message = fmt.Sprintf("Waiting for consumer pods to be ready (%d of %d ready)",
+				sci.Status.ReadyReplicas, replicas)
+		}
+		return r.updatePhase(ctx, sci, scv1alpha1.InstancePhaseConsumerStarting, message)
 	}
 
 	// Everything is running
@@ -180,18 +450,12 @@ func (r *StoppableContainerInstanceReconciler) handleDeletion(ctx context.Contex
 		return ctrl.Result{}, nil
 	}
 
-	// Delete consumer pod if exists
-	consumerPod := &corev1.Pod{}
-	consumerPodName := types.NamespacedName{
-		Namespace: sci.Namespace,
-		Name:      sci.Name, // Consumer pod uses same name as SCI
-	}
-	if err := r.Get(ctx, consumerPodName, consumerPod); err == nil {
-		if err := r.Delete(ctx, consumerPod); err != nil && !errors.IsNotFound(err) {
-			return ctrl.Result{}, err
-		}
-		log.Info("Deleted consumer pod")
-		return ctrl.Result{RequeueAfter: time.Second}, nil
+	// Delete consumer pods if they exist
+	if deleted, err := r.deleteConsumerPods(ctx, sci); err != nil {
+		return ctrl.Result{}, err
+	} else if deleted > 0 {
+		log.Info("Deleted consumer pods", "count", deleted)
+		return ctrl.Result{RequeueAfter: r.requeueInterval()}, nil
 	}
 
 	// Delete provider pod if exists
@@ -201,11 +465,20 @@ func (r *StoppableContainerInstanceReconciler) handleDeletion(ctx context.Contex
 		Name:      fmt.Sprintf("%s-provider", sci.Name),
 	}
 	if err := r.Get(ctx, providerPodName, providerPod); err == nil {
+		// The provider exits non-zero when the mount-helper did not unmount the rootfs
+		// in time. The finalizer is not kept for it: the mount-helper's orphan sweep
+		// removes the rootfs once the pod is gone.
+		if message, failed := getProviderCleanupFailure(providerPod); failed {
+			r.recordEvent(sci, corev1.EventTypeWarning, ReasonRootfsCleanupFailed,
+				fmt.Sprintf("Provider pod %s did not clean up the rootfs on node %s: %s; "+
+					"the mount-helper removes it once it notices the pod is gone",
+					providerPod.Name, providerPod.Spec.NodeName, message))
+		}
 		if err := r.Delete(ctx, providerPod); err != nil && !errors.IsNotFound(err) {
 			return ctrl.Result{}, err
 		}
 		log.Info("Deleted provider pod")
-		return ctrl.Result{RequeueAfter: time.Second}, nil
+		return ctrl.Result{RequeueAfter: r.requeueInterval()}, nil
 	}
 
 	// Remove finalizer
@@ -236,16 +509,166 @@ func (r *StoppableContainerInstanceReconciler) createProviderPod(ctx context.Con
 		"Provider pod created")
 }
 
-func (r *StoppableContainerInstanceReconciler) createConsumerPod(ctx context.Context, sci *scv1alpha1.StoppableContainerInstance) (ctrl.Result, error) {
+// recoverProviderPod handles a provider pod that disappeared while the instance was set up.
+// The consumers have lost their rootfs mount, so they are deleted to be recreated once the
+// new provider is ready, and the stale node pin is cleared since the provider may be rescheduled.
+func (r *StoppableContainerInstanceReconciler) recoverProviderPod(ctx context.Context, sci *scv1alpha1.StoppableContainerInstance) (ctrl.Result, error) {
+	log := logf.FromContext(ctx)
+
+	log.Info("Provider pod is gone, recovering", "previousNode", sci.Status.NodeName)
+
+	deleted, err := r.deleteConsumerPods(ctx, sci)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if deleted > 0 {
+		log.Info("Deleted consumer pods that lost their provider", "count", deleted)
+	}
+
+	resetPodStatus(sci)
+	return r.createProviderPod(ctx, sci)
+}
+
+// resetPodStatus forgets the provider and consumer pods and the node they ran on
+func resetPodStatus(sci *scv1alpha1.StoppableContainerInstance) {
+	sci.Status.NodeName = ""
+	sci.Status.HostPath = ""
+	sci.Status.ProviderPodName = ""
+	sci.Status.ProviderPodUID = ""
+	sci.Status.ConsumerPodName = ""
+	sci.Status.ConsumerPodUID = ""
+}
+
+// orphanedOwner reports whether the StoppableContainer owning the instance is gone, or has
+// been replaced by a new one of the same name. Instances without a StoppableContainer owner
+// reference, created directly or orphaned on purpose, are never orphaned.
+func (r *StoppableContainerInstanceReconciler) orphanedOwner(ctx context.Context, sci *scv1alpha1.StoppableContainerInstance) (string, bool, error) {
+	for _, ref := range sci.OwnerReferences {
+		if ref.Kind != "StoppableContainer" || ref.APIVersion != scv1alpha1.GroupVersion.String() {
+			continue
+		}
+		sc := &scv1alpha1.StoppableContainer{}
+		if err := r.Get(ctx, types.NamespacedName{Name: ref.Name, Namespace: sci.Namespace}, sc); err != nil {
+			if errors.IsNotFound(err) {
+				return ref.Name, true, nil
+			}
+			return "", false, err
+		}
+		return ref.Name, sc.UID != ref.UID, nil
+	}
+	return "", false, nil
+}
+
+// validateInfraImages checks the instance's provider and exec-wrapper image overrides
+// against the operator's allowlist
+func validateInfraImages(sci *scv1alpha1.StoppableContainerInstance) error {
+	if err := provider.ValidateInfraImage(sci.Spec.Provider.Image); err != nil {
+		return fmt.Errorf("spec.provider.image: %w", err)
+	}
+	if err := provider.ValidateInfraImage(sci.Spec.ExecWrapperImage); err != nil {
+		return fmt.Errorf("spec.execWrapperImage: %w", err)
+	}
+	return nil
+}
+
+// ownerAllowsPrivileged reports whether the StoppableContainer owning the instance carries
+// AnnotationAllowPrivileged. Only StoppableContainers pass the validating webhook, so the
+// instance's own annotations are not trusted, and instances created directly never run
+// privileged consumers.
+func (r *StoppableContainerInstanceReconciler) ownerAllowsPrivileged(ctx context.Context, sci *scv1alpha1.StoppableContainerInstance) (bool, error) {
+	for _, ref := range sci.OwnerReferences {
+		if ref.Kind != "StoppableContainer" || ref.APIVersion != scv1alpha1.GroupVersion.String() {
+			continue
+		}
+		sc := &scv1alpha1.StoppableContainer{}
+		if err := r.Get(ctx, types.NamespacedName{Name: ref.Name, Namespace: sci.Namespace}, sc); err != nil {
+			return false, client.IgnoreNotFound(err)
+		}
+		return sc.UID == ref.UID && sc.Annotations[scv1alpha1.AnnotationAllowPrivileged] == "true", nil
+	}
+	return false, nil
+}
+
+// providerNodeLost reports whether the node the provider pod was scheduled to no longer exists
+func (r *StoppableContainerInstanceReconciler) providerNodeLost(ctx context.Context, providerPod *corev1.Pod) (bool, error) {
+	if providerPod.Spec.NodeName == "" {
+		return false, nil
+	}
+	if err := r.Get(ctx, types.NamespacedName{Name: providerPod.Spec.NodeName}, &corev1.Node{}); err != nil {
+		if errors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	}
+	return false, nil
+}
+
+// recoverLostProviderNode handles a provider whose node was deleted. No kubelet is left to
+// finish a graceful deletion, so the provider and consumer pods are removed at once, and a
+// new provider is created to be scheduled elsewhere with a fresh rootfs from the image.
+func (r *StoppableContainerInstanceReconciler) recoverLostProviderNode(ctx context.Context, sci *scv1alpha1.StoppableContainerInstance, providerPod *corev1.Pod) (ctrl.Result, error) {
+	log := logf.FromContext(ctx)
+	node := providerPod.Spec.NodeName
+
+	log.Info("Provider node is gone, rescheduling the provider", "node", node)
+	r.recordEvent(sci, corev1.EventTypeWarning, ReasonRootfsLost,
+		fmt.Sprintf("Node %s of the provider pod no longer exists; the rootfs and its changes are lost, "+
+			"a new one is created from the image", node))
+
+	consumers, err := r.listConsumerPods(ctx, sci)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	for i := range consumers {
+		if err := r.Delete(ctx, &consumers[i], client.GracePeriodSeconds(0)); err != nil && !errors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+	}
+	if err := r.Delete(ctx, providerPod, client.GracePeriodSeconds(0)); err != nil && !errors.IsNotFound(err) {
+		return ctrl.Result{}, err
+	}
+
+	return r.recoverProviderPod(ctx, sci)
+}
+
+// recordEvent emits an event for the instance if a recorder is configured
+func (r *StoppableContainerInstanceReconciler) recordEvent(sci *scv1alpha1.StoppableContainerInstance, eventType, reason, message string) {
+	if r.Recorder != nil {
+		r.Recorder.Event(sci, eventType, reason, message)
+	}
+}
+
+func (r *StoppableContainerInstanceReconciler) createConsumerPod(ctx context.Context, sci *scv1alpha1.StoppableContainerInstance, ordinal int) (ctrl.Result, error) {
 	log := logf.FromContext(ctx)
 
 	if sci.Status.NodeName == "" {
-		return ctrl.Result{RequeueAfter: time.Second}, nil
+		return ctrl.Result{RequeueAfter: r.requeueInterval()}, nil
 	}
 
-	builder := provider.NewConsumerPodBuilder(sci, sci.Status.NodeName)
+	if err := validateInfraImages(sci); err != nil {
+		return r.updatePhase(ctx, sci, scv1alpha1.InstancePhaseFailed, fmt.Sprintf("Invalid infra image: %v", err))
+	}
+	allowPrivileged, err := r.ownerAllowsPrivileged(ctx, sci)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	builder := provider.NewConsumerPodBuilder(sci, sci.Status.NodeName).
+		WithOrdinal(ordinal).
+		WithAllowPrivileged(allowPrivileged)
 	pod := builder.Build()
 
+	// The consumer is pinned to the provider's node and bypasses the scheduler,
+	// so a hostPort clash would only surface as a kubelet admission failure
+	conflict, err := r.findConsumerHostPortConflict(ctx, pod)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if conflict != "" {
+		log.Info("Not creating consumer pod because of a hostPort conflict", "conflict", conflict)
+		return r.updatePhase(ctx, sci, scv1alpha1.InstancePhaseProviderReady,
+			fmt.Sprintf("Waiting for hostPort to become free: %s", conflict))
+	}
+
 	if err := r.Create(ctx, pod); err != nil {
 		if errors.IsAlreadyExists(err) {
 			return ctrl.Result{Requeue: true}, nil
@@ -254,11 +677,273 @@ func (r *StoppableContainerInstanceReconciler) createConsumerPod(ctx context.Con
 	}
 
 	log.Info("Created consumer pod", "name", pod.Name, "node", sci.Status.NodeName)
+	sci.Status.ExitCode = nil
 	return r.updatePhase(ctx, sci, scv1alpha1.InstancePhaseConsumerStarting,
 		"Consumer pod created")
 }
 
+// restartCrashLoopingConsumer deletes a crash looping consumer pod so that it is
+// recreated after a backoff, until MaxConsumerRestarts is reached and the instance fails
+func (r *StoppableContainerInstanceReconciler) restartCrashLoopingConsumer(ctx context.Context, sci *scv1alpha1.StoppableContainerInstance, consumerPod *corev1.Pod, message string) (ctrl.Result, error) {
+	log := logf.FromContext(ctx)
+
+	maxRestarts := r.maxConsumerRestarts()
+	if maxRestarts < 0 {
+		return r.updatePhase(ctx, sci, scv1alpha1.InstancePhaseConsumerCrashLooping, message)
+	}
+	if !consumerPod.DeletionTimestamp.IsZero() {
+		return r.updatePhase(ctx, sci, scv1alpha1.InstancePhaseConsumerCrashLooping,
+			fmt.Sprintf("%s; waiting for the consumer pod to terminate", message))
+	}
+	if int(sci.Status.RestartCount) >= maxRestarts {
+		return r.updatePhase(ctx, sci, scv1alpha1.InstancePhaseFailed,
+			fmt.Sprintf("%s; gave up after recreating the consumer pod %d times", message, sci.Status.RestartCount))
+	}
+
+	// Record the attempt before deleting, so a failed status update cannot skip the backoff
+	sci.Status.RestartCount++
+	now := metav1.Now()
+	sci.Status.LastConsumerRestartTime = &now
+	backoff := consumerRestartBackoff(sci.Status.RestartCount)
+	if _, err := r.updatePhase(ctx, sci, scv1alpha1.InstancePhaseConsumerCrashLooping,
+		fmt.Sprintf("%s; recreating the consumer pod in %s (attempt %d of %d)",
+			message, backoff, sci.Status.RestartCount, maxRestarts)); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	log.Info("Deleting crash looping consumer pod", "pod", consumerPod.Name, "attempt", sci.Status.RestartCount)
+	if err := r.Delete(ctx, consumerPod); err != nil && !errors.IsNotFound(err) {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: backoff}, nil
+}
+
+// consumerRestartBackoff returns the delay before the given consumer pod recreation,
+// doubling from ConsumerRestartBaseDelay up to ConsumerRestartMaxDelay
+func consumerRestartBackoff(attempt int32) time.Duration {
+	delay := ConsumerRestartBaseDelay
+	for i := int32(1); i < attempt && delay < ConsumerRestartMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > ConsumerRestartMaxDelay {
+		delay = ConsumerRestartMaxDelay
+	}
+	return delay
+}
+
+// consumerRestartWait returns how long to wait before recreating the consumer pod
+// of an instance whose consumer was deleted for crash looping
+func consumerRestartWait(sci *scv1alpha1.StoppableContainerInstance, now time.Time) time.Duration {
+	if sci.Status.LastConsumerRestartTime == nil {
+		return 0
+	}
+	restartAt := sci.Status.LastConsumerRestartTime.Add(consumerRestartBackoff(sci.Status.RestartCount))
+	if wait := restartAt.Sub(now); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+// getConsumerPods fetches the consumer pods of an instance. It returns the existing pods
+// in ordinal order and the ordinals of the missing ones.
+func (r *StoppableContainerInstanceReconciler) getConsumerPods(ctx context.Context, sci *scv1alpha1.StoppableContainerInstance) ([]*corev1.Pod, []int, error) {
+	var pods []*corev1.Pod
+	var missing []int
+	for i := 0; i < provider.ConsumerReplicas(sci); i++ {
+		pod := &corev1.Pod{}
+		name := types.NamespacedName{Namespace: sci.Namespace, Name: provider.ConsumerPodName(sci, i)}
+		if err := r.Get(ctx, name, pod); err != nil {
+			if !errors.IsNotFound(err) {
+				return nil, nil, err
+			}
+			missing = append(missing, i)
+			continue
+		}
+		pods = append(pods, pod)
+	}
+	return pods, missing, nil
+}
+
+// deleteConsumerPods deletes the consumer pods of an instance, except the ones named in
+// keep, and returns how many it deleted. Pods that are already terminating are counted.
+func (r *StoppableContainerInstanceReconciler) deleteConsumerPods(ctx context.Context, sci *scv1alpha1.StoppableContainerInstance, keep ...string) (int, error) {
+	consumers, err := r.listConsumerPods(ctx, sci)
+	if err != nil {
+		return 0, err
+	}
+	deleted := 0
+	for i := range consumers {
+		pod := &consumers[i]
+		if slices.Contains(keep, pod.Name) {
+			continue
+		}
+		if err := r.Delete(ctx, pod); err != nil && !errors.IsNotFound(err) {
+			return deleted, err
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+// stopConsumerPods deletes the consumer pods of a stopped instance with its stop grace
+// period and returns how many are still shutting down. Pods already terminating are
+// left alone, so the grace period is not restarted on every reconcile.
+func (r *StoppableContainerInstanceReconciler) stopConsumerPods(ctx context.Context, sci *scv1alpha1.StoppableContainerInstance) (int, error) {
+	consumers, err := r.listConsumerPods(ctx, sci)
+	if err != nil {
+		return 0, err
+	}
+	for i := range consumers {
+		pod := &consumers[i]
+		if pod.DeletionTimestamp != nil {
+			continue
+		}
+		if err := r.Delete(ctx, pod, stopDeleteOptions(sci)...); err != nil && !errors.IsNotFound(err) {
+			return 0, err
+		}
+	}
+	return len(consumers), nil
+}
+
+// stopDeleteOptions returns the options consumer pods are deleted with on stop
+func stopDeleteOptions(sci *scv1alpha1.StoppableContainerInstance) []client.DeleteOption {
+	if sci.Spec.StopGracePeriodSeconds == nil {
+		return nil
+	}
+	return []client.DeleteOption{client.GracePeriodSeconds(*sci.Spec.StopGracePeriodSeconds)}
+}
+
+// listConsumerPods returns all consumer pods of an instance, whatever their ordinal
+func (r *StoppableContainerInstanceReconciler) listConsumerPods(ctx context.Context, sci *scv1alpha1.StoppableContainerInstance) ([]corev1.Pod, error) {
+	var consumers corev1.PodList
+	if err := r.List(ctx, &consumers, client.InNamespace(sci.Namespace), client.MatchingLabels{
+		provider.LabelInstance: sci.Name,
+		provider.LabelRole:     "consumer",
+	}); err != nil {
+		return nil, err
+	}
+	return consumers.Items, nil
+}
+
+// countReadyPods returns how many of the pods are ready
+func countReadyPods(pods []*corev1.Pod) int32 {
+	var ready int32
+	for _, pod := range pods {
+		if isPodReady(pod) {
+			ready++
+		}
+	}
+	return ready
+}
+
+// findConsumerHostPortConflict checks the hostPorts of a consumer pod against all other
+// pods on its node, whether or not they belong to a StoppableContainer
+func (r *StoppableContainerInstanceReconciler) findConsumerHostPortConflict(ctx context.Context, pod *corev1.Pod) (string, error) {
+	if !hasHostPorts(pod) {
+		return "", nil
+	}
+	var pods corev1.PodList
+	if err := r.List(ctx, &pods, client.MatchingFields{podNodeNameField: pod.Spec.NodeName}); err != nil {
+		return "", err
+	}
+	conflict, _ := findHostPortConflict(pod, pods.Items)
+	return conflict, nil
+}
+
+// syncConsumerMetadata patches the consumer pod's labels and annotations to match the
+// template. Keys are added or updated; keys removed from the template are left on the
+// pod, since they may have been set by other controllers.
+func (r *StoppableContainerInstanceReconciler) syncConsumerMetadata(ctx context.Context, sci *scv1alpha1.StoppableContainerInstance, consumerPod *corev1.Pod) error {
+	desired := provider.NewConsumerPodBuilder(sci, consumerPod.Spec.NodeName).Build()
+	if mapContainsAll(consumerPod.Labels, desired.Labels) && mapContainsAll(consumerPod.Annotations, desired.Annotations) {
+		return nil
+	}
+
+	patch := client.MergeFrom(consumerPod.DeepCopy())
+	if consumerPod.Labels == nil {
+		consumerPod.Labels = map[string]string{}
+	}
+	maps.Copy(consumerPod.Labels, desired.Labels)
+	if len(desired.Annotations) > 0 {
+		if consumerPod.Annotations == nil {
+			consumerPod.Annotations = map[string]string{}
+		}
+		maps.Copy(consumerPod.Annotations, desired.Annotations)
+	}
+	if err := r.Patch(ctx, consumerPod, patch); err != nil {
+		return err
+	}
+	logf.FromContext(ctx).Info("Updated consumer pod metadata")
+	return nil
+}
+
+// providerOutdated reports whether the provider pod's rootfs container runs a different
+// image than the instance's template asks for, or the pod was built from different
+// provider settings. Pods without AnnotationProviderHash are only checked for the image.
+func providerOutdated(sci *scv1alpha1.StoppableContainerInstance, providerPod *corev1.Pod) bool {
+	desired := provider.NewProviderPodBuilder(sci).Build()
+	if hash, ok := providerPod.Annotations[provider.AnnotationProviderHash]; ok &&
+		hash != desired.Annotations[provider.AnnotationProviderHash] {
+		return true
+	}
+	for _, want := range desired.Spec.Containers {
+		if want.Name != provider.RootfsContainerName {
+			continue
+		}
+		for _, have := range providerPod.Spec.Containers {
+			if have.Name == provider.RootfsContainerName {
+				return have.Image != want.Image
+			}
+		}
+	}
+	return false
+}
+
+// mapContainsAll reports whether every key in want is present in have with the same value
+func mapContainsAll(have, want map[string]string) bool {
+	for k, v := range want {
+		if cur, ok := have[k]; !ok || cur != v {
+			return false
+		}
+	}
+	return true
+}
+
 func (r *StoppableContainerInstanceReconciler) updatePhase(ctx context.Context, sci *scv1alpha1.StoppableContainerInstance, phase scv1alpha1.InstancePhase, message string) (ctrl.Result, error) {
+	// Starts are only observed when the instance gets past a starting phase, not on a later
+	// recovery. An instance past it before the conditions existed just gets them.
+	oldPhase := sci.Status.Phase
+	providerStarted := false
+	if isProviderReadyInstancePhase(phase) && meta.FindStatusCondition(sci.Status.Conditions, ConditionTypeProviderStarted) == nil {
+		providerStarted = isStartingInstancePhase(oldPhase)
+		meta.SetStatusCondition(&sci.Status.Conditions, metav1.Condition{
+			Type:               ConditionTypeProviderStarted,
+			Status:             metav1.ConditionTrue,
+			Reason:             "ProviderReady",
+			Message:            "Provider pod became ready",
+			ObservedGeneration: sci.Generation,
+		})
+	}
+	// A stopped instance is started again when it next becomes Running, with a fresh
+	// budget of consumer pod recreations
+	if !sci.Spec.Running {
+		meta.RemoveStatusCondition(&sci.Status.Conditions, ConditionTypeStarted)
+		sci.Status.RestartCount = 0
+		sci.Status.LastConsumerRestartTime = nil
+	}
+	started, coldStarted := false, false
+	if phase == scv1alpha1.InstancePhaseRunning && meta.FindStatusCondition(sci.Status.Conditions, ConditionTypeStarted) == nil {
+		started = isStartingInstancePhase(oldPhase)
+		meta.SetStatusCondition(&sci.Status.Conditions, metav1.Condition{
+			Type:               ConditionTypeStarted,
+			Status:             metav1.ConditionTrue,
+			Reason:             "Running",
+			Message:            "Instance became Running",
+			ObservedGeneration: sci.Generation,
+		})
+		// Only the start that had to bring up the provider is a cold start
+		coldStarted = started && providerStartedSince(sci, instanceStartTime(sci))
+	}
 	sci.Status.Phase = phase
 	sci.Status.Message = message
 	sci.Status.ObservedGeneration = sci.Generation
@@ -286,18 +971,75 @@ func (r *StoppableContainerInstanceReconciler) updatePhase(ctx context.Context,
 		ObservedGeneration: sci.Generation,
 	})
 
-	if err := r.Status().Update(ctx, sci); err != nil {
-		return ctrl.Result{}, err
+	// Waiting phases are reconciled again and again; skip the write when the stored status
+	// is already up to date
+	stored := &scv1alpha1.StoppableContainerInstance{}
+	if err := r.Get(ctx, client.ObjectKeyFromObject(sci), stored); err != nil ||
+		!equality.Semantic.DeepEqual(stored.Status, sci.Status) {
+		if err := r.Status().Update(ctx, sci); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	if phase != oldPhase {
+		phaseTransitions.WithLabelValues(string(phase)).Inc()
+	}
+	if providerStarted {
+		observeProviderReady(sci)
+	}
+	if started {
+		observeConsumerReady(sci)
+	}
+	if coldStarted {
+		observeColdStart(sci)
 	}
 
 	// Requeue for intermediate states
-	if phase != scv1alpha1.InstancePhaseRunning && phase != scv1alpha1.InstancePhaseStopped && phase != scv1alpha1.InstancePhaseFailed {
-		return ctrl.Result{RequeueAfter: 2 * time.Second}, nil
+	if !isTerminalInstancePhase(phase) {
+		return ctrl.Result{RequeueAfter: 2 * r.requeueInterval()}, nil
 	}
 
 	return ctrl.Result{}, nil
 }
 
+// isTerminalInstancePhase returns true for phases that only change on an external event
+func isTerminalInstancePhase(phase scv1alpha1.InstancePhase) bool {
+	switch phase {
+	case scv1alpha1.InstancePhaseRunning, scv1alpha1.InstancePhaseStopped,
+		scv1alpha1.InstancePhaseCompleted, scv1alpha1.InstancePhaseFailed:
+		return true
+	}
+	return false
+}
+
+// isStartingInstancePhase returns true for the phases an instance passes through
+// before it first becomes Running
+func isStartingInstancePhase(phase scv1alpha1.InstancePhase) bool {
+	switch phase {
+	case "", scv1alpha1.InstancePhasePending, scv1alpha1.InstancePhaseProviderStarting,
+		scv1alpha1.InstancePhaseProviderReady, scv1alpha1.InstancePhaseConsumerStarting:
+		return true
+	}
+	return false
+}
+
+// isProviderReadyInstancePhase returns true for the phases an instance can only reach
+// once its provider pod is ready
+func isProviderReadyInstancePhase(phase scv1alpha1.InstancePhase) bool {
+	switch phase {
+	case "", scv1alpha1.InstancePhasePending, scv1alpha1.InstancePhaseProviderStarting,
+		scv1alpha1.InstancePhaseFailed:
+		return false
+	}
+	return true
+}
+
+// providerStartedSince reports whether the provider pod of the instance first became
+// ready at or after t
+func providerStartedSince(sci *scv1alpha1.StoppableContainerInstance, t time.Time) bool {
+	cond := meta.FindStatusCondition(sci.Status.Conditions, ConditionTypeProviderStarted)
+	return cond != nil && !cond.LastTransitionTime.Time.Before(t.Truncate(time.Second))
+}
+
 func isPodReady(pod *corev1.Pod) bool {
 	if pod.Status.Phase != corev1.PodRunning {
 		return false
@@ -314,6 +1056,212 @@ func isPodFailed(pod *corev1.Pod) bool {
 	return pod.Status.Phase == corev1.PodFailed
 }
 
+// isPodDeadlineExceeded reports whether kubelet stopped the pod at its activeDeadlineSeconds
+func isPodDeadlineExceeded(pod *corev1.Pod) bool {
+	return isPodFailed(pod) && pod.Status.Reason == "DeadlineExceeded"
+}
+
+func isPodSucceeded(pod *corev1.Pod) bool {
+	return pod.Status.Phase == corev1.PodSucceeded
+}
+
+// getConsumerExitCode returns the exit code of the terminated consumer container, or
+// nil if it has never terminated. A container restarted by kubelet is only briefly in
+// the terminated state, so its last termination counts as well.
+func getConsumerExitCode(pod *corev1.Pod) *int32 {
+	for _, cs := range pod.Status.ContainerStatuses {
+		if cs.Name != provider.ConsumerContainerName {
+			continue
+		}
+		terminated := cs.State.Terminated
+		if terminated == nil {
+			terminated = cs.LastTerminationState.Terminated
+		}
+		if terminated != nil {
+			exitCode := terminated.ExitCode
+			return &exitCode
+		}
+	}
+	return nil
+}
+
+// getProviderCleanupFailure returns the termination message of a provider container
+// that exited non-zero, as it does when the rootfs was not cleaned up on shutdown
+func getProviderCleanupFailure(pod *corev1.Pod) (string, bool) {
+	for _, cs := range pod.Status.ContainerStatuses {
+		if cs.Name != provider.ProviderContainerName {
+			continue
+		}
+		if terminated := cs.State.Terminated; terminated != nil && terminated.ExitCode != 0 {
+			message := strings.TrimSpace(terminated.Message)
+			if message == "" {
+				message = fmt.Sprintf("exit code %d", terminated.ExitCode)
+			}
+			return message, true
+		}
+	}
+	return "", false
+}
+
+// maxConsumerRestarts returns the configured consumer restart limit or the default
+func (r *StoppableContainerInstanceReconciler) maxConsumerRestarts() int {
+	if r.MaxConsumerRestarts == 0 {
+		return DefaultMaxConsumerRestarts
+	}
+	return r.MaxConsumerRestarts
+}
+
+// requeueInterval returns the configured requeue interval or the default
+func (r *StoppableContainerInstanceReconciler) requeueInterval() time.Duration {
+	if r.RequeueInterval > 0 {
+		return r.RequeueInterval
+	}
+	return DefaultRequeueInterval
+}
+
+// mountHelperTimeout returns the configured mount-helper timeout or the default
+func (r *StoppableContainerInstanceReconciler) mountHelperTimeout() time.Duration {
+	if r.MountHelperTimeout > 0 {
+		return r.MountHelperTimeout
+	}
+	return DefaultMountHelperTimeout
+}
+
+// imagePullTimeout returns the configured image pull timeout or the default
+func (r *StoppableContainerInstanceReconciler) imagePullTimeout() time.Duration {
+	if r.ImagePullTimeout > 0 {
+		return r.ImagePullTimeout
+	}
+	return DefaultImagePullTimeout
+}
+
+// mountHelperWaitExceeded reports whether the provider container has been running but
+// not ready (its ready file never appeared) for longer than timeout
+func mountHelperWaitExceeded(pod *corev1.Pod, timeout time.Duration, now time.Time) bool {
+	for _, cs := range pod.Status.ContainerStatuses {
+		if cs.Name != provider.ProviderContainerName {
+			continue
+		}
+		if cs.Ready || cs.State.Running == nil {
+			return false
+		}
+		return now.Sub(cs.State.Running.StartedAt.Time) > timeout
+	}
+	return false
+}
+
+// imagePullFailureReasons are the waiting reasons that indicate the image cannot be
+// pulled, mapped to whether kubelet retries the pull
+var imagePullFailureReasons = map[string]bool{
+	"ErrImagePull":      true,
+	"ImagePullBackOff":  true,
+	"InvalidImageName":  false,
+	"ErrImageNeverPull": false,
+}
+
+// getImagePullFailure reports whether the provider's image-pull init container
+// failed to pull or start the user image, why, and whether kubelet retries the pull
+func getImagePullFailure(pod *corev1.Pod) (reason string, transient, failed bool) {
+	for _, cs := range pod.Status.InitContainerStatuses {
+		if cs.Name != provider.ImagePullInitName {
+			continue
+		}
+		if w := cs.State.Waiting; w != nil {
+			if transient, ok := imagePullFailureReasons[w.Reason]; ok {
+				if w.Message != "" {
+					return fmt.Sprintf("%s: %s", w.Reason, w.Message), transient, true
+				}
+				return w.Reason, transient, true
+			}
+		}
+		if t := cs.State.Terminated; t != nil && t.ExitCode != 0 {
+			return fmt.Sprintf("image %s exited with code %d", cs.Image, t.ExitCode), false, true
+		}
+	}
+	return "", false, false
+}
+
+// hasHostPorts reports whether any container of the pod declares a hostPort
+func hasHostPorts(pod *corev1.Pod) bool {
+	for _, c := range pod.Spec.Containers {
+		for _, port := range c.Ports {
+			if port.HostPort != 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// findHostPortConflict returns a description of the first hostPort of pod that is
+// already taken by another pod on the same node. Pods that have terminated or are
+// being deleted are ignored, as is pod itself.
+func findHostPortConflict(pod *corev1.Pod, others []corev1.Pod) (string, bool) {
+	for i := range others {
+		other := &others[i]
+		if other.Spec.NodeName != pod.Spec.NodeName ||
+			(other.Namespace == pod.Namespace && other.Name == pod.Name) ||
+			other.DeletionTimestamp != nil || isPodFailed(other) || isPodSucceeded(other) {
+			continue
+		}
+		for _, c := range pod.Spec.Containers {
+			for _, port := range c.Ports {
+				if port.HostPort == 0 {
+					continue
+				}
+				for _, oc := range other.Spec.Containers {
+					for _, otherPort := range oc.Ports {
+						if hostPortsOverlap(port, otherPort) {
+							return fmt.Sprintf("hostPort %d/%s is already used by pod %s/%s on node %s",
+								port.HostPort, hostPortProtocol(port), other.Namespace, other.Name, pod.Spec.NodeName), true
+						}
+					}
+				}
+			}
+		}
+	}
+	return "", false
+}
+
+// hostPortsOverlap reports whether two container ports bind the same host port.
+// An empty or 0.0.0.0 hostIP binds every address.
+func hostPortsOverlap(a, b corev1.ContainerPort) bool {
+	if a.HostPort == 0 || a.HostPort != b.HostPort || hostPortProtocol(a) != hostPortProtocol(b) {
+		return false
+	}
+	wildcard := func(ip string) bool { return ip == "" || ip == "0.0.0.0" }
+	return a.HostIP == b.HostIP || wildcard(a.HostIP) || wildcard(b.HostIP)
+}
+
+// hostPortProtocol returns the port's protocol, defaulting to TCP
+func hostPortProtocol(port corev1.ContainerPort) corev1.Protocol {
+	if port.Protocol == "" {
+		return corev1.ProtocolTCP
+	}
+	return port.Protocol
+}
+
+// getConsumerCrashLoop reports whether a consumer container is crash looping: it is
+// waiting in CrashLoopBackOff, or it has restarted at least CrashLoopRestartThreshold
+// times and is not ready. A container that restarted but is ready again has recovered.
+func getConsumerCrashLoop(pod *corev1.Pod) (string, bool) {
+	for _, cs := range pod.Status.ContainerStatuses {
+		backingOff := cs.State.Waiting != nil && cs.State.Waiting.Reason == "CrashLoopBackOff"
+		if !backingOff && (cs.Ready || cs.RestartCount < CrashLoopRestartThreshold) {
+			continue
+		}
+		message := fmt.Sprintf("Container %s is crash looping (restarted %d times)", cs.Name, cs.RestartCount)
+		if t := cs.LastTerminationState.Terminated; t != nil {
+			message += fmt.Sprintf(", last exit code %d", t.ExitCode)
+			if t.Reason != "" {
+				message += fmt.Sprintf(" (%s)", t.Reason)
+			}
+		}
+		return message, true
+	}
+	return "", false
+}
+
 func getPodFailureReason(pod *corev1.Pod) string {
 	if pod.Status.Message != "" {
 		return pod.Status.Message
@@ -334,6 +1282,18 @@ func getPodFailureReason(pod *corev1.Pod) string {
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *StoppableContainerInstanceReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// The hostPort check lists the pods on a node
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &corev1.Pod{}, podNodeNameField,
+		func(obj client.Object) []string {
+			pod, ok := obj.(*corev1.Pod)
+			if !ok || pod.Spec.NodeName == "" {
+				return nil
+			}
+			return []string{pod.Spec.NodeName}
+		}); err != nil {
+		return err
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&scv1alpha1.StoppableContainerInstance{}).
 		Owns(&corev1.Pod{}).
@@ -370,6 +1330,44 @@ func (r *StoppableContainerInstanceReconciler) SetupWithManager(mgr ctrl.Manager
 				}
 			}),
 		).
+		// A deleted node takes the rootfs of the providers on it along
+		Watches(
+			&corev1.Node{},
+			handler.EnqueueRequestsFromMapFunc(r.instancesOnNode),
+			builder.WithPredicates(deletesOnly),
+		).
+		// The instance of a deleted StoppableContainer shares its name
+		Watches(
+			&scv1alpha1.StoppableContainer{},
+			&handler.EnqueueRequestForObject{},
+			builder.WithPredicates(deletesOnly),
+		).
 		Named("stoppablecontainerinstance").
 		Complete(r)
 }
+
+// deletesOnly passes delete events only
+var deletesOnly = predicate.Funcs{
+	CreateFunc:  func(event.CreateEvent) bool { return false },
+	UpdateFunc:  func(event.UpdateEvent) bool { return false },
+	DeleteFunc:  func(event.DeleteEvent) bool { return true },
+	GenericFunc: func(event.GenericEvent) bool { return false },
+}
+
+// instancesOnNode maps a node to the instances whose provider runs on it
+func (r *StoppableContainerInstanceReconciler) instancesOnNode(ctx context.Context, obj client.Object) []reconcile.Request {
+	var instances scv1alpha1.StoppableContainerInstanceList
+	if err := r.List(ctx, &instances); err != nil {
+		logf.FromContext(ctx).Error(err, "Failed to list instances for a deleted node", "node", obj.GetName())
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, sci := range instances.Items {
+		if sci.Status.NodeName == obj.GetName() {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: sci.Name, Namespace: sci.Namespace},
+			})
+		}
+	}
+	return requests
+}
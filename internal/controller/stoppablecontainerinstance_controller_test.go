@@ -18,11 +18,17 @@ package controller
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -106,5 +112,1417 @@ var _ = Describe("StoppableContainerInstance Controller", func() {
 			Expect(k8sClient.Delete(ctx, providerPod)).To(Succeed())
 			Expect(k8sClient.Delete(ctx, sci)).To(Succeed())
 		})
+
+		It("should fail an instance whose hostPathPrefix is not allowed", func() {
+			ctx := context.Background()
+			typeNamespacedName := types.NamespacedName{Name: "test-sci-bad-prefix", Namespace: "default"}
+			sci := &scv1alpha1.StoppableContainerInstance{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       typeNamespacedName.Name,
+					Namespace:  typeNamespacedName.Namespace,
+					Finalizers: []string{SCIFinalizerName},
+				},
+				Spec: scv1alpha1.StoppableContainerInstanceSpec{
+					StoppableContainerName: typeNamespacedName.Name,
+					Running:                true,
+					Template: scv1alpha1.PodTemplateSpec{
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{{Name: "main", Image: "ubuntu:22.04"}},
+						},
+					},
+					HostPathPrefix: "/var/lib/stoppablecontainer/../../etc",
+				},
+			}
+			Expect(k8sClient.Create(ctx, sci)).To(Succeed())
+
+			controllerReconciler := &StoppableContainerInstanceReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, sci)).To(Succeed())
+			Expect(sci.Status.Phase).To(Equal(scv1alpha1.InstancePhaseFailed))
+			Expect(sci.Status.Message).To(ContainSubstring("Invalid hostPathPrefix"))
+
+			providerPod := &corev1.Pod{}
+			err = k8sClient.Get(ctx, types.NamespacedName{Namespace: "default", Name: typeNamespacedName.Name + "-provider"}, providerPod)
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+
+			sci.Finalizers = nil
+			Expect(k8sClient.Update(ctx, sci)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, sci)).To(Succeed())
+		})
+
+		It("should fail an instance whose provider image is not allowed", func() {
+			ctx := context.Background()
+			typeNamespacedName := types.NamespacedName{Name: "test-sci-bad-infra-image", Namespace: "default"}
+			sci := &scv1alpha1.StoppableContainerInstance{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       typeNamespacedName.Name,
+					Namespace:  typeNamespacedName.Namespace,
+					Finalizers: []string{SCIFinalizerName},
+				},
+				Spec: scv1alpha1.StoppableContainerInstanceSpec{
+					StoppableContainerName: typeNamespacedName.Name,
+					Running:                true,
+					Template: scv1alpha1.PodTemplateSpec{
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{{Name: "main", Image: "ubuntu:22.04"}},
+						},
+					},
+					Provider: scv1alpha1.ProviderSpec{Image: "registry.example.com/attacker/provider:latest"},
+				},
+			}
+			Expect(k8sClient.Create(ctx, sci)).To(Succeed())
+
+			controllerReconciler := &StoppableContainerInstanceReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, sci)).To(Succeed())
+			Expect(sci.Status.Phase).To(Equal(scv1alpha1.InstancePhaseFailed))
+			Expect(sci.Status.Message).To(ContainSubstring("Invalid infra image"))
+
+			providerPod := &corev1.Pod{}
+			err = k8sClient.Get(ctx, types.NamespacedName{Namespace: "default", Name: typeNamespacedName.Name + "-provider"}, providerPod)
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+
+			sci.Finalizers = nil
+			Expect(k8sClient.Update(ctx, sci)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, sci)).To(Succeed())
+		})
+
+		It("should capture the consumer exit code on success", func() {
+			sci := reconcileTerminatedConsumer("test-sci-exit-success", corev1.PodSucceeded, 0)
+			Expect(sci.Status.Phase).To(Equal(scv1alpha1.InstancePhaseCompleted))
+			Expect(sci.Status.ExitCode).NotTo(BeNil())
+			Expect(*sci.Status.ExitCode).To(Equal(int32(0)))
+		})
+
+		It("should capture the consumer exit code on failure", func() {
+			sci := reconcileTerminatedConsumer("test-sci-exit-failure", corev1.PodFailed, 3)
+			Expect(sci.Status.Phase).To(Equal(scv1alpha1.InstancePhaseFailed))
+			Expect(sci.Status.ExitCode).NotTo(BeNil())
+			Expect(*sci.Status.ExitCode).To(Equal(int32(3)))
+		})
+
+		It("should report a consumer stopped at its active deadline as completed", func() {
+			sci := reconcileConsumerWithStatus("test-sci-deadline", corev1.PodStatus{
+				Phase:   corev1.PodFailed,
+				Reason:  "DeadlineExceeded",
+				Message: "Pod was active on the node longer than the specified deadline",
+				ContainerStatuses: []corev1.ContainerStatus{
+					{
+						Name: provider.ConsumerContainerName,
+						State: corev1.ContainerState{
+							Terminated: &corev1.ContainerStateTerminated{ExitCode: 137},
+						},
+					},
+				},
+			})
+			Expect(sci.Status.Phase).To(Equal(scv1alpha1.InstancePhaseCompleted))
+			Expect(sci.Status.Message).To(ContainSubstring("activeDeadlineSeconds"))
+			Expect(*sci.Status.ExitCode).To(Equal(int32(137)))
+		})
+
+		It("should report a consumer in CrashLoopBackOff as crash looping and recreate it", func() {
+			sci := reconcileConsumerWithStatus("test-sci-crashloop", crashLoopingConsumerStatus())
+			Expect(sci.Status.Phase).To(Equal(scv1alpha1.InstancePhaseConsumerCrashLooping))
+			Expect(sci.Status.Message).To(ContainSubstring("restarted 4 times"))
+			Expect(sci.Status.Message).To(ContainSubstring("last exit code 2"))
+			Expect(sci.Status.Message).To(ContainSubstring("recreating the consumer pod in 10s (attempt 1 of 3)"))
+			Expect(sci.Status.ExitCode).NotTo(BeNil())
+			Expect(*sci.Status.ExitCode).To(Equal(int32(2)))
+			Expect(sci.Status.RestartCount).To(Equal(int32(1)))
+			Expect(sci.Status.LastConsumerRestartTime).NotTo(BeNil())
+		})
+
+		It("should mark the instance Failed once the consumer restart limit is reached", func() {
+			sci := reconcileConsumerWithRestarts("test-sci-crashloop-limit", crashLoopingConsumerStatus(),
+				DefaultMaxConsumerRestarts)
+			Expect(sci.Status.Phase).To(Equal(scv1alpha1.InstancePhaseFailed))
+			Expect(sci.Status.Message).To(ContainSubstring("gave up after recreating the consumer pod 3 times"))
+			Expect(sci.Status.RestartCount).To(Equal(int32(DefaultMaxConsumerRestarts)))
+		})
+
+		It("should wait for the backoff before recreating a crash looping consumer", func() {
+			ctx := context.Background()
+			resourceName := "test-sci-crashloop-backoff"
+			typeNamespacedName := types.NamespacedName{Name: resourceName, Namespace: "default"}
+
+			By("Creating an instance whose consumer was just deleted for crash looping")
+			sci := &scv1alpha1.StoppableContainerInstance{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       resourceName,
+					Namespace:  "default",
+					Finalizers: []string{SCIFinalizerName},
+				},
+				Spec: scv1alpha1.StoppableContainerInstanceSpec{
+					StoppableContainerName: resourceName,
+					Running:                true,
+					Template: scv1alpha1.PodTemplateSpec{
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{Name: "main", Image: "ubuntu:22.04", Command: []string{"false"}},
+							},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, sci)).To(Succeed())
+			lastRestart := metav1.Now()
+			sci.Status.RestartCount = 1
+			sci.Status.LastConsumerRestartTime = &lastRestart
+			Expect(k8sClient.Status().Update(ctx, sci)).To(Succeed())
+
+			providerPod := provider.NewProviderPodBuilder(sci).Build()
+			providerPod.Spec.NodeName = "node-1"
+			Expect(k8sClient.Create(ctx, providerPod)).To(Succeed())
+			providerPod.Status = corev1.PodStatus{
+				Phase: corev1.PodRunning,
+				Conditions: []corev1.PodCondition{
+					{Type: corev1.PodReady, Status: corev1.ConditionTrue},
+				},
+			}
+			Expect(k8sClient.Status().Update(ctx, providerPod)).To(Succeed())
+
+			By("Reconciling within the backoff")
+			controllerReconciler := &StoppableContainerInstanceReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+			result, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(BeNumerically(">", 0))
+			Expect(result.RequeueAfter).To(BeNumerically("<=", ConsumerRestartBaseDelay))
+			Expect(errors.IsNotFound(k8sClient.Get(ctx, typeNamespacedName, &corev1.Pod{}))).To(BeTrue())
+
+			updated := &scv1alpha1.StoppableContainerInstance{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, updated)).To(Succeed())
+			Expect(updated.Status.Phase).To(Equal(scv1alpha1.InstancePhaseConsumerCrashLooping))
+
+			By("Reconciling after the backoff")
+			elapsed := metav1.NewTime(time.Now().Add(-time.Minute))
+			updated.Status.LastConsumerRestartTime = &elapsed
+			Expect(k8sClient.Status().Update(ctx, updated)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			consumerPod := &corev1.Pod{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, consumerPod)).To(Succeed())
+
+			// Cleanup
+			Expect(k8sClient.Delete(ctx, consumerPod)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, providerPod)).To(Succeed())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, updated)).To(Succeed())
+			updated.Finalizers = nil
+			Expect(k8sClient.Update(ctx, updated)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, updated)).To(Succeed())
+		})
+
+		It("should reset the consumer restarts when the instance is stopped", func() {
+			ctx := context.Background()
+			resourceName := "test-sci-crashloop-reset"
+			typeNamespacedName := types.NamespacedName{Name: resourceName, Namespace: "default"}
+
+			By("Creating a stopped instance that used up its consumer restarts")
+			sci := &scv1alpha1.StoppableContainerInstance{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       resourceName,
+					Namespace:  "default",
+					Finalizers: []string{SCIFinalizerName},
+				},
+				Spec: scv1alpha1.StoppableContainerInstanceSpec{
+					StoppableContainerName: resourceName,
+					Running:                false,
+					Template: scv1alpha1.PodTemplateSpec{
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{Name: "main", Image: "ubuntu:22.04", Command: []string{"false"}},
+							},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, sci)).To(Succeed())
+			lastRestart := metav1.Now()
+			sci.Status.Phase = scv1alpha1.InstancePhaseFailed
+			sci.Status.RestartCount = int32(DefaultMaxConsumerRestarts)
+			sci.Status.LastConsumerRestartTime = &lastRestart
+			Expect(k8sClient.Status().Update(ctx, sci)).To(Succeed())
+
+			providerPod := provider.NewProviderPodBuilder(sci).Build()
+			providerPod.Spec.NodeName = "node-1"
+			Expect(k8sClient.Create(ctx, providerPod)).To(Succeed())
+			providerPod.Status = corev1.PodStatus{
+				Phase: corev1.PodRunning,
+				Conditions: []corev1.PodCondition{
+					{Type: corev1.PodReady, Status: corev1.ConditionTrue},
+				},
+			}
+			Expect(k8sClient.Status().Update(ctx, providerPod)).To(Succeed())
+
+			By("Reconciling the stopped instance")
+			controllerReconciler := &StoppableContainerInstanceReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			updated := &scv1alpha1.StoppableContainerInstance{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, updated)).To(Succeed())
+			Expect(updated.Status.Phase).To(Equal(scv1alpha1.InstancePhaseStopped))
+			Expect(updated.Status.RestartCount).To(BeZero())
+			Expect(updated.Status.LastConsumerRestartTime).To(BeNil())
+
+			// Cleanup
+			Expect(k8sClient.Delete(ctx, providerPod)).To(Succeed())
+			updated.Finalizers = nil
+			Expect(k8sClient.Update(ctx, updated)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, updated)).To(Succeed())
+		})
+
+		It("should report a consumer that recovered after restarts as running", func() {
+			sci := reconcileConsumerWithStatus("test-sci-crashloop-recovered", corev1.PodStatus{
+				Phase: corev1.PodRunning,
+				Conditions: []corev1.PodCondition{
+					{Type: corev1.PodReady, Status: corev1.ConditionTrue},
+				},
+				ContainerStatuses: []corev1.ContainerStatus{
+					{
+						Name:         provider.ConsumerContainerName,
+						RestartCount: 4,
+						Ready:        true,
+						State: corev1.ContainerState{
+							Running: &corev1.ContainerStateRunning{},
+						},
+					},
+				},
+			})
+			Expect(sci.Status.Phase).To(Equal(scv1alpha1.InstancePhaseRunning))
+			Expect(meta.IsStatusConditionTrue(sci.Status.Conditions, ConditionTypeStarted)).To(BeTrue())
+		})
+
+		It("should recreate a provider pod deleted out-of-band and restart the consumer", func() {
+			ctx := context.Background()
+			resourceName := "test-sci-provider-gone"
+			typeNamespacedName := types.NamespacedName{Name: resourceName, Namespace: "default"}
+
+			By("Creating a running instance whose provider pod is gone")
+			sci := &scv1alpha1.StoppableContainerInstance{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       resourceName,
+					Namespace:  "default",
+					Finalizers: []string{SCIFinalizerName},
+				},
+				Spec: scv1alpha1.StoppableContainerInstanceSpec{
+					StoppableContainerName: resourceName,
+					Running:                true,
+					Template: scv1alpha1.PodTemplateSpec{
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{Name: "main", Image: "ubuntu:22.04", Command: []string{"sleep", "infinity"}},
+							},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, sci)).To(Succeed())
+			sci.Status.Phase = scv1alpha1.InstancePhaseRunning
+			sci.Status.NodeName = "node-1"
+			sci.Status.ProviderPodName = resourceName + "-provider"
+			Expect(k8sClient.Status().Update(ctx, sci)).To(Succeed())
+
+			consumerPod := provider.NewConsumerPodBuilder(sci, "node-1").Build()
+			Expect(k8sClient.Create(ctx, consumerPod)).To(Succeed())
+
+			By("Reconciling the resource")
+			recorder := record.NewFakeRecorder(10)
+			controllerReconciler := &StoppableContainerInstanceReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: recorder,
+			}
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Verifying the provider was recreated and the consumer deleted")
+			providerPod := &corev1.Pod{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{
+				Namespace: "default",
+				Name:      resourceName + "-provider",
+			}, providerPod)).To(Succeed())
+			Expect(errors.IsNotFound(k8sClient.Get(ctx, typeNamespacedName, &corev1.Pod{}))).To(BeTrue())
+
+			updated := &scv1alpha1.StoppableContainerInstance{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, updated)).To(Succeed())
+			Expect(updated.Status.Phase).To(Equal(scv1alpha1.InstancePhaseProviderStarting))
+			Expect(updated.Status.NodeName).To(BeEmpty())
+
+			var event string
+			Expect(recorder.Events).To(Receive(&event))
+			Expect(event).To(HavePrefix(corev1.EventTypeWarning + " " + ReasonRootfsDiscarded))
+			Expect(event).To(ContainSubstring("node-1"))
+
+			// Cleanup
+			Expect(k8sClient.Delete(ctx, providerPod)).To(Succeed())
+			updated.Finalizers = nil
+			Expect(k8sClient.Update(ctx, updated)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, updated)).To(Succeed())
+		})
+
+		It("should reschedule the provider when its node is deleted", func() {
+			ctx := context.Background()
+			resourceName := "test-sci-node-lost"
+			typeNamespacedName := types.NamespacedName{Name: resourceName, Namespace: "default"}
+
+			By("Creating a running instance whose provider node no longer exists")
+			sci := &scv1alpha1.StoppableContainerInstance{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       resourceName,
+					Namespace:  "default",
+					Finalizers: []string{SCIFinalizerName},
+				},
+				Spec: scv1alpha1.StoppableContainerInstanceSpec{
+					StoppableContainerName: resourceName,
+					Running:                true,
+					Template: scv1alpha1.PodTemplateSpec{
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{Name: "main", Image: "ubuntu:22.04", Command: []string{"sleep", "infinity"}},
+							},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, sci)).To(Succeed())
+
+			staleProvider := provider.NewProviderPodBuilder(sci).Build()
+			staleProvider.Spec.NodeName = "gone-node"
+			Expect(k8sClient.Create(ctx, staleProvider)).To(Succeed())
+			consumerPod := provider.NewConsumerPodBuilder(sci, "gone-node").Build()
+			Expect(k8sClient.Create(ctx, consumerPod)).To(Succeed())
+
+			sci.Status.Phase = scv1alpha1.InstancePhaseRunning
+			sci.Status.NodeName = "gone-node"
+			sci.Status.HostPath = "/var/lib/stoppablecontainer/default/" + resourceName
+			sci.Status.ProviderPodName = staleProvider.Name
+			Expect(k8sClient.Status().Update(ctx, sci)).To(Succeed())
+
+			By("Reconciling the resource")
+			recorder := record.NewFakeRecorder(10)
+			controllerReconciler := &StoppableContainerInstanceReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: recorder,
+			}
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Verifying a new unscheduled provider replaced the stale one")
+			providerPod := &corev1.Pod{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{
+				Namespace: "default",
+				Name:      resourceName + "-provider",
+			}, providerPod)).To(Succeed())
+			Expect(providerPod.Spec.NodeName).To(BeEmpty())
+			Expect(errors.IsNotFound(k8sClient.Get(ctx, typeNamespacedName, &corev1.Pod{}))).To(BeTrue())
+
+			updated := &scv1alpha1.StoppableContainerInstance{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, updated)).To(Succeed())
+			Expect(updated.Status.Phase).To(Equal(scv1alpha1.InstancePhaseProviderStarting))
+			Expect(updated.Status.NodeName).To(BeEmpty())
+			Expect(updated.Status.HostPath).To(BeEmpty())
+
+			var event string
+			Expect(recorder.Events).To(Receive(&event))
+			Expect(event).To(HavePrefix(corev1.EventTypeWarning + " " + ReasonRootfsLost))
+			Expect(event).To(ContainSubstring("gone-node"))
+
+			// Cleanup
+			Expect(k8sClient.Delete(ctx, providerPod)).To(Succeed())
+			updated.Finalizers = nil
+			Expect(k8sClient.Update(ctx, updated)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, updated)).To(Succeed())
+		})
+
+		It("should keep its finalizer until the provider has cleaned up the rootfs", func() {
+			ctx := context.Background()
+			resourceName := "test-sci-delete-cleanup"
+			typeNamespacedName := types.NamespacedName{Name: resourceName, Namespace: "default"}
+			providerName := types.NamespacedName{Name: resourceName + "-provider", Namespace: "default"}
+
+			By("Creating a running instance whose provider is slow to terminate")
+			sci, providerPod := createInstanceWithReadyProvider(resourceName)
+			controllerReconciler := &StoppableContainerInstanceReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, providerName, providerPod)).To(Succeed())
+			// The finalizer keeps the pod terminating, as the provider waiting for the
+			// mount-helper to unmount the rootfs would
+			providerPod.Finalizers = []string{"test.stoppablecontainer.xtlsoft.top/unmount"}
+			Expect(k8sClient.Update(ctx, providerPod)).To(Succeed())
+
+			By("Deleting the instance")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, sci)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, sci)).To(Succeed())
+			for range 3 {
+				_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+				Expect(err).NotTo(HaveOccurred())
+			}
+			Expect(errors.IsNotFound(k8sClient.Get(ctx, typeNamespacedName, &corev1.Pod{}))).To(BeTrue())
+			Expect(k8sClient.Get(ctx, providerName, providerPod)).To(Succeed())
+			Expect(providerPod.DeletionTimestamp).NotTo(BeNil())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, sci)).To(Succeed())
+			Expect(sci.Finalizers).To(ContainElement(SCIFinalizerName))
+
+			By("Reporting a provider that timed out waiting for the unmount")
+			providerPod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+				Name: provider.ProviderContainerName,
+				State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+					ExitCode: 1,
+					Message:  "rootfs not cleaned up within 10s",
+				}},
+			}}
+			Expect(k8sClient.Status().Update(ctx, providerPod)).To(Succeed())
+			recorder := record.NewFakeRecorder(10)
+			controllerReconciler.Recorder = recorder
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			var event string
+			Expect(recorder.Events).To(Receive(&event))
+			Expect(event).To(HavePrefix(corev1.EventTypeWarning + " " + ReasonRootfsCleanupFailed))
+			Expect(event).To(ContainSubstring("rootfs not cleaned up within 10s"))
+
+			By("Removing the finalizer once the provider is gone")
+			Expect(k8sClient.Get(ctx, providerName, providerPod)).To(Succeed())
+			providerPod.Finalizers = nil
+			Expect(k8sClient.Update(ctx, providerPod)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(errors.IsNotFound(k8sClient.Get(ctx, typeNamespacedName, &scv1alpha1.StoppableContainerInstance{}))).To(BeTrue())
+		})
+
+		It("should delete an instance whose StoppableContainer is gone", func() {
+			ctx := context.Background()
+			resourceName := "test-sci-orphaned"
+			typeNamespacedName := types.NamespacedName{Name: resourceName, Namespace: "default"}
+
+			By("Creating an instance owned by a StoppableContainer that no longer exists")
+			sci := &scv1alpha1.StoppableContainerInstance{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       resourceName,
+					Namespace:  "default",
+					Finalizers: []string{SCIFinalizerName},
+					OwnerReferences: []metav1.OwnerReference{{
+						APIVersion: scv1alpha1.GroupVersion.String(),
+						Kind:       "StoppableContainer",
+						Name:       resourceName,
+						UID:        "deleted-sc-uid",
+					}},
+				},
+				Spec: scv1alpha1.StoppableContainerInstanceSpec{
+					StoppableContainerName: resourceName,
+					Running:                true,
+					Template: scv1alpha1.PodTemplateSpec{
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{{Name: "main", Image: "ubuntu:22.04"}},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, sci)).To(Succeed())
+			providerPod := provider.NewProviderPodBuilder(sci).Build()
+			providerPod.Spec.NodeName = "node-1"
+			Expect(k8sClient.Create(ctx, providerPod)).To(Succeed())
+
+			By("Reconciling the resource")
+			recorder := record.NewFakeRecorder(10)
+			controllerReconciler := &StoppableContainerInstanceReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: recorder,
+			}
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			var event string
+			Expect(recorder.Events).To(Receive(&event))
+			Expect(event).To(HavePrefix(corev1.EventTypeWarning + " " + ReasonOrphaned))
+
+			By("Removing the provider pod and the instance through the finalizer")
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(errors.IsNotFound(k8sClient.Get(ctx, types.NamespacedName{
+				Namespace: "default",
+				Name:      resourceName + "-provider",
+			}, &corev1.Pod{}))).To(BeTrue())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(errors.IsNotFound(k8sClient.Get(ctx, typeNamespacedName, &scv1alpha1.StoppableContainerInstance{}))).To(BeTrue())
+		})
+
+		It("should keep an instance without a StoppableContainer owner", func() {
+			ctx := context.Background()
+			sci := &scv1alpha1.StoppableContainerInstance{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-sci-standalone", Namespace: "default"},
+				Spec:       scv1alpha1.StoppableContainerInstanceSpec{StoppableContainerName: "missing"},
+			}
+			controllerReconciler := &StoppableContainerInstanceReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+			owner, orphaned, err := controllerReconciler.orphanedOwner(ctx, sci)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(orphaned).To(BeFalse())
+			Expect(owner).To(BeEmpty())
+
+			By("Treating a StoppableContainer recreated under the same name as a new owner")
+			sc := &scv1alpha1.StoppableContainer{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-sci-recreated-owner", Namespace: "default", UID: "new-uid"},
+				Spec: scv1alpha1.StoppableContainerSpec{
+					Template: scv1alpha1.PodTemplateSpec{
+						Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "main", Image: "ubuntu:22.04"}}},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, sc)).To(Succeed())
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: sc.Name, Namespace: "default"}, sc)).To(Succeed())
+			sci.OwnerReferences = []metav1.OwnerReference{{
+				APIVersion: scv1alpha1.GroupVersion.String(),
+				Kind:       "StoppableContainer",
+				Name:       sc.Name,
+				UID:        sc.UID,
+			}}
+			_, orphaned, err = controllerReconciler.orphanedOwner(ctx, sci)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(orphaned).To(BeFalse())
+
+			sci.OwnerReferences[0].UID = "old-uid"
+			owner, orphaned, err = controllerReconciler.orphanedOwner(ctx, sci)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(orphaned).To(BeTrue())
+			Expect(owner).To(Equal(sc.Name))
+
+			// Cleanup
+			Expect(k8sClient.Delete(ctx, sc)).To(Succeed())
+		})
+
+		It("should only allow privileged consumers through the owning StoppableContainer", func() {
+			ctx := context.Background()
+			controllerReconciler := &StoppableContainerInstanceReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+
+			By("Ignoring the annotation on an instance created directly")
+			sci := &scv1alpha1.StoppableContainerInstance{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "test-sci-privileged",
+					Namespace:   "default",
+					Annotations: map[string]string{scv1alpha1.AnnotationAllowPrivileged: "true"},
+				},
+				Spec: scv1alpha1.StoppableContainerInstanceSpec{StoppableContainerName: "test-sci-privileged"},
+			}
+			allowed, err := controllerReconciler.ownerAllowsPrivileged(ctx, sci)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(allowed).To(BeFalse())
+
+			By("Following the annotation on the owning StoppableContainer")
+			sc := &scv1alpha1.StoppableContainer{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "test-sci-privileged",
+					Namespace:   "default",
+					Annotations: map[string]string{scv1alpha1.AnnotationAllowPrivileged: "true"},
+				},
+				Spec: scv1alpha1.StoppableContainerSpec{
+					Template: scv1alpha1.PodTemplateSpec{
+						Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "main", Image: "ubuntu:22.04"}}},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, sc)).To(Succeed())
+			sci.OwnerReferences = []metav1.OwnerReference{{
+				APIVersion: scv1alpha1.GroupVersion.String(),
+				Kind:       "StoppableContainer",
+				Name:       sc.Name,
+				UID:        sc.UID,
+			}}
+			allowed, err = controllerReconciler.ownerAllowsPrivileged(ctx, sci)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(allowed).To(BeTrue())
+
+			By("Denying it once the StoppableContainer drops the annotation")
+			sc.Annotations = nil
+			Expect(k8sClient.Update(ctx, sc)).To(Succeed())
+			allowed, err = controllerReconciler.ownerAllowsPrivileged(ctx, sci)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(allowed).To(BeFalse())
+
+			// Cleanup
+			Expect(k8sClient.Delete(ctx, sc)).To(Succeed())
+		})
+
+		It("should retry a failing image pre-pull and mark the instance Failed after the timeout", func() {
+			ctx := context.Background()
+			resourceName := "test-sci-image-pull"
+			typeNamespacedName := types.NamespacedName{Name: resourceName, Namespace: "default"}
+
+			By("Creating an instance with image pre-pull enabled")
+			sci := &scv1alpha1.StoppableContainerInstance{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       resourceName,
+					Namespace:  "default",
+					Finalizers: []string{SCIFinalizerName},
+				},
+				Spec: scv1alpha1.StoppableContainerInstanceSpec{
+					StoppableContainerName: resourceName,
+					Running:                true,
+					Template: scv1alpha1.PodTemplateSpec{
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{Name: "main", Image: "example.invalid/missing:1.0"},
+							},
+						},
+					},
+					Provider: scv1alpha1.ProviderSpec{PrePullImage: true},
+				},
+			}
+			Expect(k8sClient.Create(ctx, sci)).To(Succeed())
+
+			By("Creating a provider pod stuck pulling the user image")
+			providerPod := provider.NewProviderPodBuilder(sci).Build()
+			Expect(k8sClient.Create(ctx, providerPod)).To(Succeed())
+			startTime := metav1.Now()
+			providerPod.Status.StartTime = &startTime
+			providerPod.Status.InitContainerStatuses = []corev1.ContainerStatus{
+				{
+					Name:  provider.ImagePullInitName,
+					Image: "example.invalid/missing:1.0",
+					State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{
+						Reason:  "ImagePullBackOff",
+						Message: "Back-off pulling image",
+					}},
+				},
+			}
+			Expect(k8sClient.Status().Update(ctx, providerPod)).To(Succeed())
+
+			By("Reconciling while kubelet retries the pull")
+			controllerReconciler := &StoppableContainerInstanceReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			updated := &scv1alpha1.StoppableContainerInstance{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, updated)).To(Succeed())
+			Expect(updated.Status.Phase).To(Equal(scv1alpha1.InstancePhaseProviderStarting))
+			Expect(updated.Status.Message).To(ContainSubstring("ImagePullBackOff"))
+
+			By("Reconciling once the image pull timeout has passed")
+			controllerReconciler.ImagePullTimeout = time.Nanosecond
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, updated)).To(Succeed())
+			Expect(updated.Status.Phase).To(Equal(scv1alpha1.InstancePhaseFailed))
+			Expect(updated.Status.Message).To(ContainSubstring("ImagePullBackOff"))
+
+			// Cleanup
+			Expect(k8sClient.Delete(ctx, providerPod)).To(Succeed())
+			updated.Finalizers = nil
+			Expect(k8sClient.Update(ctx, updated)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, updated)).To(Succeed())
+		})
+
+		It("should apply template annotation changes to the running consumer in place", func() {
+			ctx := context.Background()
+			resourceName := "test-sci-metadata-sync"
+			typeNamespacedName := types.NamespacedName{Name: resourceName, Namespace: "default"}
+
+			By("Creating a running instance with a ready provider and consumer")
+			sci := &scv1alpha1.StoppableContainerInstance{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       resourceName,
+					Namespace:  "default",
+					Finalizers: []string{SCIFinalizerName},
+				},
+				Spec: scv1alpha1.StoppableContainerInstanceSpec{
+					StoppableContainerName: resourceName,
+					Running:                true,
+					Template: scv1alpha1.PodTemplateSpec{
+						Metadata: metav1.ObjectMeta{
+							Annotations: map[string]string{"prometheus.io/scrape": "false"},
+						},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{Name: "main", Image: "ubuntu:22.04", Command: []string{"sleep", "infinity"}},
+							},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, sci)).To(Succeed())
+
+			providerPod := provider.NewProviderPodBuilder(sci).Build()
+			providerPod.Spec.NodeName = "node-1"
+			Expect(k8sClient.Create(ctx, providerPod)).To(Succeed())
+			providerPod.Status = corev1.PodStatus{
+				Phase: corev1.PodRunning,
+				Conditions: []corev1.PodCondition{
+					{Type: corev1.PodReady, Status: corev1.ConditionTrue},
+				},
+			}
+			Expect(k8sClient.Status().Update(ctx, providerPod)).To(Succeed())
+
+			consumerPod := provider.NewConsumerPodBuilder(sci, "node-1").Build()
+			Expect(k8sClient.Create(ctx, consumerPod)).To(Succeed())
+			originalUID := consumerPod.UID
+
+			By("Changing the template annotations")
+			sci.Spec.Template.Metadata.Annotations = map[string]string{
+				"prometheus.io/scrape": "true",
+				"prometheus.io/port":   "9090",
+			}
+			Expect(k8sClient.Update(ctx, sci)).To(Succeed())
+
+			By("Reconciling the resource")
+			controllerReconciler := &StoppableContainerInstanceReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Verifying the existing consumer pod was patched")
+			updatedConsumer := &corev1.Pod{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, updatedConsumer)).To(Succeed())
+			Expect(updatedConsumer.UID).To(Equal(originalUID))
+			Expect(updatedConsumer.Annotations).To(HaveKeyWithValue("prometheus.io/scrape", "true"))
+			Expect(updatedConsumer.Annotations).To(HaveKeyWithValue("prometheus.io/port", "9090"))
+			Expect(updatedConsumer.Labels).To(HaveKeyWithValue(provider.LabelRole, "consumer"))
+
+			// Cleanup
+			Expect(k8sClient.Delete(ctx, updatedConsumer)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, providerPod)).To(Succeed())
+			updated := &scv1alpha1.StoppableContainerInstance{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, updated)).To(Succeed())
+			updated.Finalizers = nil
+			Expect(k8sClient.Update(ctx, updated)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, updated)).To(Succeed())
+		})
+
+		It("should recreate the consumer but keep the provider when the command changes", func() {
+			ctx := context.Background()
+			resourceName := "test-sci-template-hash"
+			typeNamespacedName := types.NamespacedName{Name: resourceName, Namespace: "default"}
+
+			By("Creating a running instance with a ready provider and consumer")
+			sci, providerPod := createInstanceWithReadyProvider(resourceName)
+			consumerPod := provider.NewConsumerPodBuilder(sci, "node-1").Build()
+			Expect(k8sClient.Create(ctx, consumerPod)).To(Succeed())
+
+			By("Changing the container command")
+			sci.Spec.Template.Spec.Containers[0].Command = []string{"sleep", "3600"}
+			Expect(k8sClient.Update(ctx, sci)).To(Succeed())
+
+			By("Reconciling the resource")
+			controllerReconciler := &StoppableContainerInstanceReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Verifying the consumer was deleted and the provider kept")
+			Expect(errors.IsNotFound(k8sClient.Get(ctx, typeNamespacedName, &corev1.Pod{}))).To(BeTrue())
+			keptProvider := &corev1.Pod{}
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(providerPod), keptProvider)).To(Succeed())
+			Expect(keptProvider.UID).To(Equal(providerPod.UID))
+
+			updated := &scv1alpha1.StoppableContainerInstance{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, updated)).To(Succeed())
+			Expect(updated.Status.Phase).To(Equal(scv1alpha1.InstancePhaseConsumerStarting))
+
+			By("Verifying the next reconcile creates a consumer with the new command")
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			newConsumer := &corev1.Pod{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, newConsumer)).To(Succeed())
+			Expect(newConsumer.Annotations).To(HaveKeyWithValue(provider.AnnotationTemplateHash, provider.TemplateHash(updated)))
+			Expect(newConsumer.Spec.Containers[0].Command).To(ContainElement("3600"))
+
+			// Cleanup
+			Expect(k8sClient.Delete(ctx, newConsumer)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, keptProvider)).To(Succeed())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, updated)).To(Succeed())
+			updated.Finalizers = nil
+			Expect(k8sClient.Update(ctx, updated)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, updated)).To(Succeed())
+		})
+
+		It("should start the instance again after a stop", func() {
+			ctx := context.Background()
+			resourceName := "test-sci-restart"
+			typeNamespacedName := types.NamespacedName{Name: resourceName, Namespace: "default"}
+
+			By("Creating a running instance with a ready provider and consumer")
+			_, providerPod := createInstanceWithReadyProvider(resourceName)
+			controllerReconciler := &StoppableContainerInstanceReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			consumerPod := &corev1.Pod{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, consumerPod)).To(Succeed())
+			consumerPod.Status = corev1.PodStatus{
+				Phase:      corev1.PodRunning,
+				Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+			}
+			Expect(k8sClient.Status().Update(ctx, consumerPod)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			updated := &scv1alpha1.StoppableContainerInstance{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, updated)).To(Succeed())
+			Expect(updated.Status.Phase).To(Equal(scv1alpha1.InstancePhaseRunning))
+			Expect(meta.IsStatusConditionTrue(updated.Status.Conditions, ConditionTypeStarted)).To(BeTrue())
+			Expect(meta.IsStatusConditionTrue(updated.Status.Conditions, ConditionTypeProviderStarted)).To(BeTrue())
+
+			By("Stopping the instance")
+			updated.Spec.Running = false
+			Expect(k8sClient.Update(ctx, updated)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Verifying only the provider still counts as started")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, updated)).To(Succeed())
+			Expect(updated.Status.Phase).To(Equal(scv1alpha1.InstancePhaseStopping))
+			Expect(meta.FindStatusCondition(updated.Status.Conditions, ConditionTypeStarted)).To(BeNil())
+			Expect(meta.IsStatusConditionTrue(updated.Status.Conditions, ConditionTypeProviderStarted)).To(BeTrue())
+
+			// Cleanup
+			Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, consumerPod))).To(Succeed())
+			Expect(k8sClient.Delete(ctx, providerPod)).To(Succeed())
+			updated.Finalizers = nil
+			Expect(k8sClient.Update(ctx, updated)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, updated)).To(Succeed())
+		})
+
+		It("should stay Stopping until the consumer has shut down", func() {
+			ctx := context.Background()
+			resourceName := "test-sci-stop-grace"
+			typeNamespacedName := types.NamespacedName{Name: resourceName, Namespace: "default"}
+
+			By("Creating a running instance with a consumer that is slow to terminate")
+			sci, providerPod := createInstanceWithReadyProvider(resourceName)
+			controllerReconciler := &StoppableContainerInstanceReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			consumerPod := &corev1.Pod{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, consumerPod)).To(Succeed())
+			// The finalizer keeps the pod terminating, as a running preStop hook would
+			consumerPod.Finalizers = []string{"test.stoppablecontainer.xtlsoft.top/drain"}
+			Expect(k8sClient.Update(ctx, consumerPod)).To(Succeed())
+
+			By("Stopping the instance with a stop grace period")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, sci)).To(Succeed())
+			sci.Spec.Running = false
+			gracePeriod := int64(300)
+			sci.Spec.StopGracePeriodSeconds = &gracePeriod
+			Expect(k8sClient.Update(ctx, sci)).To(Succeed())
+			deleteOpts := &client.DeleteOptions{}
+			deleteOpts.ApplyOptions(stopDeleteOptions(sci))
+			Expect(deleteOpts.GracePeriodSeconds).To(Equal(&gracePeriod))
+
+			for range 2 {
+				_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(k8sClient.Get(ctx, typeNamespacedName, sci)).To(Succeed())
+				Expect(sci.Status.Phase).To(Equal(scv1alpha1.InstancePhaseStopping))
+				Expect(sci.Status.Message).To(ContainSubstring("1 consumer pod(s)"))
+			}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, consumerPod)).To(Succeed())
+			Expect(consumerPod.DeletionTimestamp).NotTo(BeNil())
+
+			By("Reporting Stopped once the consumer is gone")
+			consumerPod.Finalizers = nil
+			Expect(k8sClient.Update(ctx, consumerPod)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, sci)).To(Succeed())
+			Expect(sci.Status.Phase).To(Equal(scv1alpha1.InstancePhaseStopped))
+
+			// Cleanup
+			Expect(k8sClient.Delete(ctx, providerPod)).To(Succeed())
+			sci.Finalizers = nil
+			Expect(k8sClient.Update(ctx, sci)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, sci)).To(Succeed())
+		})
+
+		It("should recreate the provider when the image changes", func() {
+			ctx := context.Background()
+			resourceName := "test-sci-image-change"
+			typeNamespacedName := types.NamespacedName{Name: resourceName, Namespace: "default"}
+
+			By("Creating a running instance with a ready provider and consumer")
+			sci, providerPod := createInstanceWithReadyProvider(resourceName)
+			consumerPod := provider.NewConsumerPodBuilder(sci, "node-1").Build()
+			Expect(k8sClient.Create(ctx, consumerPod)).To(Succeed())
+
+			By("Changing the image")
+			sci.Spec.Template.Spec.Containers[0].Image = "ubuntu:24.04"
+			Expect(k8sClient.Update(ctx, sci)).To(Succeed())
+
+			By("Reconciling the resource")
+			recorder := record.NewFakeRecorder(10)
+			controllerReconciler := &StoppableContainerInstanceReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: recorder,
+			}
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Verifying the consumer pod was deleted before the provider pod")
+			Expect(errors.IsNotFound(k8sClient.Get(ctx, client.ObjectKeyFromObject(consumerPod), &corev1.Pod{}))).To(BeTrue())
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(providerPod), &corev1.Pod{})).To(Succeed())
+			updated := &scv1alpha1.StoppableContainerInstance{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, updated)).To(Succeed())
+			Expect(updated.Status.Phase).To(Equal(scv1alpha1.InstancePhaseStopping))
+
+			By("Verifying the next reconcile deletes the provider pod")
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(errors.IsNotFound(k8sClient.Get(ctx, client.ObjectKeyFromObject(providerPod), &corev1.Pod{}))).To(BeTrue())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, updated)).To(Succeed())
+			Expect(updated.Status.Phase).To(Equal(scv1alpha1.InstancePhaseProviderStarting))
+
+			By("Verifying the provider is recreated with the new image and the lost changes are reported")
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			newProvider := &corev1.Pod{}
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(providerPod), newProvider)).To(Succeed())
+			Expect(providerOutdated(updated, newProvider)).To(BeFalse())
+			var event string
+			Expect(recorder.Events).To(Receive(&event))
+			Expect(event).To(HavePrefix(corev1.EventTypeWarning + " " + ReasonRootfsDiscarded))
+
+			// Cleanup
+			Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, consumerPod))).To(Succeed())
+			Expect(k8sClient.Delete(ctx, newProvider)).To(Succeed())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, updated)).To(Succeed())
+			updated.Finalizers = nil
+			Expect(k8sClient.Update(ctx, updated)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, updated)).To(Succeed())
+		})
+
+		It("should create one consumer pod per replica and remove surplus pods", func() {
+			ctx := context.Background()
+			resourceName := "test-sci-replicas"
+			typeNamespacedName := types.NamespacedName{Name: resourceName, Namespace: "default"}
+
+			By("Creating an instance with a ready provider and a single consumer")
+			sci, providerPod := createInstanceWithReadyProvider(resourceName)
+			sci.Status.NodeName = "node-1"
+			Expect(k8sClient.Status().Update(ctx, sci)).To(Succeed())
+			singleConsumer := provider.NewConsumerPodBuilder(sci, "node-1").Build()
+			Expect(k8sClient.Create(ctx, singleConsumer)).To(Succeed())
+
+			By("Scaling to three replicas")
+			replicas := int32(3)
+			sci.Spec.Replicas = &replicas
+			Expect(k8sClient.Update(ctx, sci)).To(Succeed())
+
+			controllerReconciler := &StoppableContainerInstanceReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+			for range 3 {
+				_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			By("Verifying the replicas were created and the single consumer removed")
+			Expect(errors.IsNotFound(k8sClient.Get(ctx, typeNamespacedName, &corev1.Pod{}))).To(BeTrue())
+			consumers := make([]*corev1.Pod, replicas)
+			for i := range consumers {
+				consumers[i] = &corev1.Pod{}
+				Expect(k8sClient.Get(ctx, types.NamespacedName{
+					Namespace: "default",
+					Name:      fmt.Sprintf("%s-consumer-%d", resourceName, i),
+				}, consumers[i])).To(Succeed())
+			}
+
+			By("Marking two replicas ready")
+			for _, consumer := range consumers[:2] {
+				consumer.Status = corev1.PodStatus{
+					Phase:      corev1.PodRunning,
+					Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+				}
+				Expect(k8sClient.Status().Update(ctx, consumer)).To(Succeed())
+			}
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			updated := &scv1alpha1.StoppableContainerInstance{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, updated)).To(Succeed())
+			Expect(updated.Status.Phase).To(Equal(scv1alpha1.InstancePhaseConsumerStarting))
+			Expect(updated.Status.Replicas).To(Equal(int32(3)))
+			Expect(updated.Status.ReadyReplicas).To(Equal(int32(2)))
+			Expect(updated.Status.ConsumerPodName).To(Equal(resourceName + "-consumer-0"))
+
+			By("Scaling down to two replicas")
+			replicas = 2
+			updated.Spec.Replicas = &replicas
+			Expect(k8sClient.Update(ctx, updated)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(errors.IsNotFound(k8sClient.Get(ctx, client.ObjectKeyFromObject(consumers[2]), &corev1.Pod{}))).To(BeTrue())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, updated)).To(Succeed())
+			Expect(updated.Status.Phase).To(Equal(scv1alpha1.InstancePhaseRunning))
+			Expect(updated.Status.ReadyReplicas).To(Equal(int32(2)))
+
+			// Cleanup
+			for _, consumer := range consumers[:2] {
+				Expect(k8sClient.Delete(ctx, consumer)).To(Succeed())
+			}
+			Expect(k8sClient.Delete(ctx, providerPod)).To(Succeed())
+			updated.Finalizers = nil
+			Expect(k8sClient.Update(ctx, updated)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, updated)).To(Succeed())
+		})
+
+		It("should not create a consumer whose hostPort is taken on its node", func() {
+			ctx := context.Background()
+
+			By("Creating two running instances using the same hostPort")
+			newInstance := func(name string) *scv1alpha1.StoppableContainerInstance {
+				return &scv1alpha1.StoppableContainerInstance{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:       name,
+						Namespace:  "default",
+						Finalizers: []string{SCIFinalizerName},
+					},
+					Spec: scv1alpha1.StoppableContainerInstanceSpec{
+						StoppableContainerName: name,
+						Running:                true,
+						Template: scv1alpha1.PodTemplateSpec{
+							Spec: corev1.PodSpec{
+								Containers: []corev1.Container{{
+									Name:  "main",
+									Image: "nginx:latest",
+									Ports: []corev1.ContainerPort{{ContainerPort: 80, HostPort: 8080}},
+								}},
+							},
+						},
+					},
+				}
+			}
+			first := newInstance("test-sci-hostport-a")
+			second := newInstance("test-sci-hostport-b")
+			Expect(k8sClient.Create(ctx, first)).To(Succeed())
+			Expect(k8sClient.Create(ctx, second)).To(Succeed())
+
+			// The first instance's consumer already runs on node-1
+			firstConsumer := provider.NewConsumerPodBuilder(first, "node-1").Build()
+			Expect(k8sClient.Create(ctx, firstConsumer)).To(Succeed())
+
+			// The second instance's provider is ready on the same node
+			providerPod := provider.NewProviderPodBuilder(second).Build()
+			providerPod.Spec.NodeName = "node-1"
+			Expect(k8sClient.Create(ctx, providerPod)).To(Succeed())
+			providerPod.Status = corev1.PodStatus{
+				Phase: corev1.PodRunning,
+				Conditions: []corev1.PodCondition{
+					{Type: corev1.PodReady, Status: corev1.ConditionTrue},
+				},
+			}
+			Expect(k8sClient.Status().Update(ctx, providerPod)).To(Succeed())
+
+			By("Reconciling the second instance")
+			controllerReconciler := &StoppableContainerInstanceReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+			secondName := types.NamespacedName{Name: second.Name, Namespace: "default"}
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: secondName})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Verifying no consumer was created and the conflict is reported")
+			err = k8sClient.Get(ctx, secondName, &corev1.Pod{})
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+			updated := &scv1alpha1.StoppableContainerInstance{}
+			Expect(k8sClient.Get(ctx, secondName, updated)).To(Succeed())
+			Expect(updated.Status.Phase).To(Equal(scv1alpha1.InstancePhaseProviderReady))
+			Expect(updated.Status.Message).To(ContainSubstring("hostPort 8080/TCP"))
+			Expect(updated.Status.Message).To(ContainSubstring(firstConsumer.Name))
+
+			By("Replacing the first consumer with an unrelated pod on the same hostPort")
+			Expect(k8sClient.Delete(ctx, firstConsumer)).To(Succeed())
+			otherPod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-hostport-other", Namespace: "default"},
+				Spec: corev1.PodSpec{
+					NodeName: "node-1",
+					Containers: []corev1.Container{{
+						Name:  "proxy",
+						Image: "envoy:latest",
+						Ports: []corev1.ContainerPort{{ContainerPort: 8080, HostPort: 8080}},
+					}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, otherPod)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: secondName})
+			Expect(err).NotTo(HaveOccurred())
+			err = k8sClient.Get(ctx, secondName, &corev1.Pod{})
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+			Expect(k8sClient.Get(ctx, secondName, updated)).To(Succeed())
+			Expect(updated.Status.Message).To(ContainSubstring(otherPod.Name))
+
+			// Cleanup
+			Expect(k8sClient.Delete(ctx, otherPod)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, providerPod)).To(Succeed())
+			for _, sci := range []*scv1alpha1.StoppableContainerInstance{first, second} {
+				current := &scv1alpha1.StoppableContainerInstance{}
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: sci.Name, Namespace: "default"}, current)).To(Succeed())
+				current.Finalizers = nil
+				Expect(k8sClient.Update(ctx, current)).To(Succeed())
+				Expect(k8sClient.Delete(ctx, current)).To(Succeed())
+			}
+		})
+
+		It("should report MountHelperUnavailable when the rootfs is never mounted", func() {
+			ctx := context.Background()
+			resourceName := "test-sci-mount-helper"
+			typeNamespacedName := types.NamespacedName{Name: resourceName, Namespace: "default"}
+
+			By("Creating an instance with a running but unready provider")
+			sci := &scv1alpha1.StoppableContainerInstance{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       resourceName,
+					Namespace:  "default",
+					Finalizers: []string{SCIFinalizerName},
+				},
+				Spec: scv1alpha1.StoppableContainerInstanceSpec{
+					StoppableContainerName: resourceName,
+					Running:                true,
+					Template: scv1alpha1.PodTemplateSpec{
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{Name: "main", Image: "ubuntu:22.04", Command: []string{"sleep", "infinity"}},
+							},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, sci)).To(Succeed())
+
+			providerPod := provider.NewProviderPodBuilder(sci).Build()
+			providerPod.Spec.NodeName = "node-1"
+			Expect(k8sClient.Create(ctx, providerPod)).To(Succeed())
+			providerPod.Status = corev1.PodStatus{
+				Phase: corev1.PodRunning,
+				ContainerStatuses: []corev1.ContainerStatus{
+					{
+						Name:  provider.ProviderContainerName,
+						Ready: false,
+						State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{
+							StartedAt: metav1.NewTime(time.Now().Add(-10 * time.Minute)),
+						}},
+					},
+				},
+			}
+			Expect(k8sClient.Status().Update(ctx, providerPod)).To(Succeed())
+
+			By("Reconciling the resource")
+			controllerReconciler := &StoppableContainerInstanceReconciler{
+				Client:             k8sClient,
+				Scheme:             k8sClient.Scheme(),
+				MountHelperTimeout: time.Minute,
+			}
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			updated := &scv1alpha1.StoppableContainerInstance{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, updated)).To(Succeed())
+			Expect(updated.Status.Phase).To(Equal(scv1alpha1.InstancePhaseProviderStarting))
+			cond := meta.FindStatusCondition(updated.Status.Conditions, ConditionTypeMountHelperAvailable)
+			Expect(cond).NotTo(BeNil())
+			Expect(cond.Status).To(Equal(metav1.ConditionFalse))
+			Expect(cond.Reason).To(Equal(ReasonMountHelperUnavailable))
+			Expect(cond.Message).To(ContainSubstring("node-1"))
+
+			By("Reconciling again while still waiting")
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			again := &scv1alpha1.StoppableContainerInstance{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, again)).To(Succeed())
+			Expect(again.Status.Message).To(Equal(updated.Status.Message))
+			Expect(again.ResourceVersion).To(Equal(updated.ResourceVersion))
+
+			// Cleanup
+			Expect(k8sClient.Delete(ctx, providerPod)).To(Succeed())
+			updated.Finalizers = nil
+			Expect(k8sClient.Update(ctx, updated)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, updated)).To(Succeed())
+		})
 	})
 })
+
+// reconcileTerminatedConsumer creates an SCI with a ready provider pod and a consumer pod
+// that has terminated in the given phase, reconciles it and returns the updated SCI.
+func reconcileTerminatedConsumer(name string, podPhase corev1.PodPhase, exitCode int32) *scv1alpha1.StoppableContainerInstance {
+	return reconcileConsumerWithStatus(name, corev1.PodStatus{
+		Phase: podPhase,
+		ContainerStatuses: []corev1.ContainerStatus{
+			{
+				Name: provider.ConsumerContainerName,
+				State: corev1.ContainerState{
+					Terminated: &corev1.ContainerStateTerminated{ExitCode: exitCode},
+				},
+			},
+		},
+	})
+}
+
+// reconcileConsumerWithStatus creates an SCI with a ready provider pod and a consumer pod
+// with the given status, reconciles it and returns the updated SCI.
+func reconcileConsumerWithStatus(name string, consumerStatus corev1.PodStatus) *scv1alpha1.StoppableContainerInstance {
+	return reconcileConsumerWithRestarts(name, consumerStatus, 0)
+}
+
+// reconcileConsumerWithRestarts is reconcileConsumerWithStatus for an SCI whose consumer
+// pod the controller has already recreated the given number of times.
+func reconcileConsumerWithRestarts(name string, consumerStatus corev1.PodStatus, restarts int32) *scv1alpha1.StoppableContainerInstance {
+	ctx := context.Background()
+	typeNamespacedName := types.NamespacedName{Name: name, Namespace: "default"}
+
+	By("Creating the StoppableContainerInstance resource")
+	sci := &scv1alpha1.StoppableContainerInstance{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       name,
+			Namespace:  "default",
+			Finalizers: []string{SCIFinalizerName},
+		},
+		Spec: scv1alpha1.StoppableContainerInstanceSpec{
+			StoppableContainerName: name,
+			Running:                true,
+			Template: scv1alpha1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Name: "main", Image: "ubuntu:22.04", Command: []string{"true"}},
+					},
+				},
+			},
+		},
+	}
+	Expect(k8sClient.Create(ctx, sci)).To(Succeed())
+	if restarts > 0 {
+		lastRestart := metav1.NewTime(time.Now().Add(-time.Hour))
+		sci.Status.RestartCount = restarts
+		sci.Status.LastConsumerRestartTime = &lastRestart
+		Expect(k8sClient.Status().Update(ctx, sci)).To(Succeed())
+	}
+
+	By("Creating a ready provider pod")
+	providerPod := provider.NewProviderPodBuilder(sci).Build()
+	providerPod.Spec.NodeName = "node-1"
+	Expect(k8sClient.Create(ctx, providerPod)).To(Succeed())
+	providerPod.Status = corev1.PodStatus{
+		Phase: corev1.PodRunning,
+		Conditions: []corev1.PodCondition{
+			{Type: corev1.PodReady, Status: corev1.ConditionTrue},
+		},
+	}
+	Expect(k8sClient.Status().Update(ctx, providerPod)).To(Succeed())
+
+	By("Creating the consumer pod")
+	consumerPod := provider.NewConsumerPodBuilder(sci, "node-1").Build()
+	Expect(k8sClient.Create(ctx, consumerPod)).To(Succeed())
+	consumerPod.Status = consumerStatus
+	Expect(k8sClient.Status().Update(ctx, consumerPod)).To(Succeed())
+
+	By("Reconciling the resource")
+	controllerReconciler := &StoppableContainerInstanceReconciler{
+		Client: k8sClient,
+		Scheme: k8sClient.Scheme(),
+	}
+	_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+	Expect(err).NotTo(HaveOccurred())
+
+	updated := &scv1alpha1.StoppableContainerInstance{}
+	Expect(k8sClient.Get(ctx, typeNamespacedName, updated)).To(Succeed())
+
+	// Cleanup, the controller may already have deleted a crash looping consumer
+	Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, consumerPod))).To(Succeed())
+	Expect(k8sClient.Delete(ctx, providerPod)).To(Succeed())
+	updated.Finalizers = nil
+	Expect(k8sClient.Update(ctx, updated)).To(Succeed())
+	Expect(k8sClient.Delete(ctx, updated)).To(Succeed())
+
+	return updated
+}
+
+// createInstanceWithReadyProvider creates a running SCI and a ready provider pod for it
+// on node-1, and returns both.
+func createInstanceWithReadyProvider(name string) (*scv1alpha1.StoppableContainerInstance, *corev1.Pod) {
+	ctx := context.Background()
+	sci := &scv1alpha1.StoppableContainerInstance{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       name,
+			Namespace:  "default",
+			Finalizers: []string{SCIFinalizerName},
+		},
+		Spec: scv1alpha1.StoppableContainerInstanceSpec{
+			StoppableContainerName: name,
+			Running:                true,
+			Template: scv1alpha1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Name: "main", Image: "ubuntu:22.04", Command: []string{"sleep", "infinity"}},
+					},
+				},
+			},
+		},
+	}
+	Expect(k8sClient.Create(ctx, sci)).To(Succeed())
+
+	providerPod := provider.NewProviderPodBuilder(sci).Build()
+	providerPod.Spec.NodeName = "node-1"
+	Expect(k8sClient.Create(ctx, providerPod)).To(Succeed())
+	providerPod.Status = corev1.PodStatus{
+		Phase: corev1.PodRunning,
+		Conditions: []corev1.PodCondition{
+			{Type: corev1.PodReady, Status: corev1.ConditionTrue},
+		},
+	}
+	Expect(k8sClient.Status().Update(ctx, providerPod)).To(Succeed())
+	return sci, providerPod
+}
+
+// crashLoopingConsumerStatus returns the status of a consumer pod in CrashLoopBackOff
+func crashLoopingConsumerStatus() corev1.PodStatus {
+	return corev1.PodStatus{
+		Phase: corev1.PodRunning,
+		ContainerStatuses: []corev1.ContainerStatus{
+			{
+				Name:         provider.ConsumerContainerName,
+				RestartCount: 4,
+				State: corev1.ContainerState{
+					Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"},
+				},
+				LastTerminationState: corev1.ContainerState{
+					Terminated: &corev1.ContainerStateTerminated{ExitCode: 2, Reason: "Error"},
+				},
+			},
+		},
+	}
+}
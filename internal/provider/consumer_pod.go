@@ -17,7 +17,11 @@ limitations under the License.
 package provider
 
 import (
+	"fmt"
+	"path"
 	"path/filepath"
+	"slices"
+	"strings"
 
 	scv1alpha1 "github.com/xtlsoft/stoppablecontainer/api/v1alpha1"
 	corev1 "k8s.io/api/core/v1"
@@ -27,8 +31,10 @@ import (
 
 // ConsumerPodBuilder builds consumer pods
 type ConsumerPodBuilder struct {
-	sci      *scv1alpha1.StoppableContainerInstance
-	nodeName string
+	sci             *scv1alpha1.StoppableContainerInstance
+	nodeName        string
+	ordinal         int
+	allowPrivileged bool
 }
 
 // NewConsumerPodBuilder creates a new ConsumerPodBuilder
@@ -39,6 +45,19 @@ func NewConsumerPodBuilder(sci *scv1alpha1.StoppableContainerInstance, nodeName
 	}
 }
 
+// WithOrdinal sets which of the instance's consumer replicas is built
+func (b *ConsumerPodBuilder) WithOrdinal(ordinal int) *ConsumerPodBuilder {
+	b.ordinal = ordinal
+	return b
+}
+
+// WithAllowPrivileged sets whether a privileged securityContext in the template is honored.
+// The controller sets it from the owning StoppableContainer's AnnotationAllowPrivileged.
+func (b *ConsumerPodBuilder) WithAllowPrivileged(allow bool) *ConsumerPodBuilder {
+	b.allowPrivileged = allow
+	return b
+}
+
 // Build creates the consumer pod spec
 func (b *ConsumerPodBuilder) Build() *corev1.Pod {
 	hostPath := filepath.Join(GetHostPath(b.sci), "rootfs")
@@ -61,6 +80,7 @@ func (b *ConsumerPodBuilder) Build() *corev1.Pod {
 
 	// Build the user's command as args for sc-exec --entrypoint
 	userCommand := b.buildUserCommand(mainContainer)
+	imageCommand := commandFromImage(mainContainer)
 
 	// Build volume mounts for the main container
 	mainContainer.VolumeMounts = b.buildVolumeMounts(mainContainer.VolumeMounts)
@@ -68,42 +88,52 @@ func (b *ConsumerPodBuilder) Build() *corev1.Pod {
 	// Build volumes
 	podSpec.Volumes = b.buildVolumes(podSpec.Volumes, hostPath, hostPathType)
 
-	// Add SC_ROOTFS environment variable
-	mainContainer.Env = append(mainContainer.Env, corev1.EnvVar{
-		Name:  "SC_ROOTFS",
-		Value: RootfsMountPath,
-	})
+	mainContainer.Env = b.buildChrootEnv(mainContainer.Env, imageCommand)
 
 	// Build init containers (prepend our init container)
 	podSpec.InitContainers = b.buildInitContainers(podSpec.InitContainers)
 
 	// Override container settings for exec-wrapper
 	mainContainer.Name = ConsumerContainerName
-	mainContainer.Image = ExecWrapperImage
+	mainContainer.Image = execWrapperImage(b.sci)
 	mainContainer.ImagePullPolicy = ExecWrapperPullPolicy
 	mainContainer.Command = b.buildEntrypointCommand(userCommand, mainContainer.WorkingDir)
 	mainContainer.Args = nil // Args are incorporated into Command
 	mainContainer.SecurityContext = b.buildSecurityContext(mainContainer.SecurityContext)
-	mainContainer.ReadinessProbe = &corev1.Probe{
-		ProbeHandler: corev1.ProbeHandler{
-			Exec: &corev1.ExecAction{
-				Command: []string{ExecWrapperBinPath + "/sc-exec", "--ready"},
+	if isReadOnlyRootFilesystem(mainContainer.SecurityContext) && !hasMountPath(mainContainer.VolumeMounts, "/tmp") {
+		// A read-only container filesystem still needs a writable /tmp
+		podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+			Name: TmpVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				EmptyDir: &corev1.EmptyDirVolumeSource{},
 			},
-		},
-		InitialDelaySeconds: 1,
-		PeriodSeconds:       5,
+		})
+		mainContainer.VolumeMounts = append(mainContainer.VolumeMounts, corev1.VolumeMount{
+			Name:      TmpVolumeName,
+			MountPath: "/tmp",
+		})
 	}
+	mainContainer.ReadinessProbe = b.buildReadinessProbe(mainContainer.ReadinessProbe)
+	mainContainer.LivenessProbe = b.buildProbe(mainContainer.LivenessProbe)
+	mainContainer.StartupProbe = b.buildProbe(mainContainer.StartupProbe)
+	mainContainer.Lifecycle = b.buildLifecycle(mainContainer.Lifecycle)
 
-	// Override pod-level settings that must be controlled by the controller
+	// Override pod-level settings that must be controlled by the controller.
+	// Other pod-level fields, such as hostPID and hostIPC, are passed through as-is.
 	podSpec.NodeName = b.nodeName
-	podSpec.RestartPolicy = corev1.RestartPolicyAlways
+	podSpec.RestartPolicy = restartPolicy(podSpec.RestartPolicy)
+	podSpec.ImagePullSecrets = buildImagePullSecrets(podSpec.ImagePullSecrets)
+
+	// Record the template the pod was built from, so template changes can be detected
+	annotations := b.buildAnnotations(template.Metadata.Annotations)
+	annotations[AnnotationTemplateHash] = TemplateHash(b.sci)
 
 	return &corev1.Pod{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:        b.consumerPodName(),
 			Namespace:   b.sci.Namespace,
-			Labels:      b.buildLabels(template.Metadata.Labels),
-			Annotations: b.buildAnnotations(template.Metadata.Annotations),
+			Labels:      buildLabels(b.sci, "consumer", template.Metadata.Labels),
+			Annotations: annotations,
 			OwnerReferences: []metav1.OwnerReference{
 				{
 					APIVersion:         scv1alpha1.GroupVersion.String(),
@@ -120,21 +150,125 @@ func (b *ConsumerPodBuilder) Build() *corev1.Pod {
 }
 
 func (b *ConsumerPodBuilder) consumerPodName() string {
-	// Consumer pod uses the same name as the SCI for a seamless user experience
-	// Users can use "kubectl exec <name>" directly without knowing about the -consumer suffix
-	return b.sci.Name
+	return ConsumerPodName(b.sci, b.ordinal)
+}
+
+// ConsumerReplicas returns the number of consumer pods of an instance, 1 unless set
+func ConsumerReplicas(sci *scv1alpha1.StoppableContainerInstance) int {
+	if sci.Spec.Replicas == nil || *sci.Spec.Replicas < 1 {
+		return 1
+	}
+	return int(*sci.Spec.Replicas)
+}
+
+// ConsumerPodName returns the name of the consumer pod with the given ordinal
+func ConsumerPodName(sci *scv1alpha1.StoppableContainerInstance, ordinal int) string {
+	// A single consumer pod uses the same name as the SCI for a seamless user experience.
+	// Users can use "kubectl exec <name>" directly without knowing about the -consumer suffix.
+	if ConsumerReplicas(sci) == 1 {
+		return sci.Name
+	}
+	return fmt.Sprintf("%s-consumer-%d", sci.Name, ordinal)
 }
 
 func (b *ConsumerPodBuilder) buildUserCommand(container *corev1.Container) []string {
-	if len(container.Command) == 0 && len(container.Args) == 0 {
-		return []string{"/bin/sh"}
+	// The image config is only known on the node. sc-exec resolves the command again
+	// with it, as told by commandFromImage.
+	return ResolveCommand(container.Command, container.Args)
+}
+
+// DefaultShell is the command run when neither the template nor the image declares one
+const DefaultShell = "/bin/sh"
+
+// ResolveCommand returns the command to run in the rootfs as far as the template
+// declares it: command followed by args, or args alone without a command. The image's
+// ENTRYPOINT and CMD are only known on the node, where sc-exec applies them.
+// DefaultShell is the fallback when nothing declares a command.
+func ResolveCommand(command, args []string) []string {
+	resolved := append(slices.Clone(command), args...)
+	if len(resolved) == 0 {
+		// sc-exec reports a clear error if the image has no shell (e.g. distroless)
+		return []string{DefaultShell}
+	}
+	return resolved
+}
+
+// commandFromImage returns the CommandFromImageEnv value for a container, or "" if
+// its command does not depend on the image config
+func commandFromImage(container *corev1.Container) string {
+	switch {
+	case len(container.Command) > 0:
+		return ""
+	case len(container.Args) > 0:
+		return CommandFromImageEntrypoint
+	default:
+		return CommandFromImageEntrypointCmd
+	}
+}
+
+// buildChrootEnv adds the variables sc-exec --entrypoint needs to a container's env.
+// User env and envFrom (including valueFrom entries and envFrom prefixes, which kubelet
+// resolves) are kept and inherited by the chrooted process, merged over the image's
+// own environment.
+func (b *ConsumerPodBuilder) buildChrootEnv(env []corev1.EnvVar, imageCommand string) []corev1.EnvVar {
+	if names := templateEnvNames(env); names != "" {
+		env = append(env, corev1.EnvVar{
+			Name:  TemplateEnvNamesEnv,
+			Value: names,
+		})
+	}
+	env = append(env, corev1.EnvVar{
+		Name:  "SC_ROOTFS",
+		Value: RootfsMountPath,
+	})
+	if b.sci.Spec.SkipNetworkConfigCopy {
+		env = append(env, corev1.EnvVar{
+			Name:  SkipNetworkConfigCopyEnv,
+			Value: "true",
+		})
+	}
+	if imageCommand != "" {
+		env = append(env, corev1.EnvVar{
+			Name:  CommandFromImageEnv,
+			Value: imageCommand,
+		})
+	}
+	return env
+}
+
+// mainContainerEnv returns copies of the template main container's env entries with
+// the given names, for the infra containers, or nil if it has none of them
+func mainContainerEnv(sci *scv1alpha1.StoppableContainerInstance, names ...string) []corev1.EnvVar {
+	containers := sci.Spec.Template.Spec.Containers
+	if len(containers) == 0 {
+		return nil
+	}
+	var env []corev1.EnvVar
+	for _, e := range containers[0].Env {
+		if slices.Contains(names, e.Name) {
+			env = append(env, *e.DeepCopy())
+		}
 	}
+	return env
+}
 
-	var parts []string
-	parts = append(parts, container.Command...)
-	parts = append(parts, container.Args...)
+// restartPolicy returns the consumer pod's restart policy. One-shot consumers may set
+// OnFailure or Never so the pod can complete; otherwise kubelet restarts it.
+func restartPolicy(policy corev1.RestartPolicy) corev1.RestartPolicy {
+	if policy == corev1.RestartPolicyOnFailure || policy == corev1.RestartPolicyNever {
+		return policy
+	}
+	return corev1.RestartPolicyAlways
+}
 
-	return parts
+// templateEnvNames returns the TemplateEnvNamesEnv value for the template's env vars.
+// envFrom keys are only known once kubelet resolves them, so they are not listed.
+func templateEnvNames(env []corev1.EnvVar) string {
+	names := make([]string, 0, len(env))
+	for _, e := range env {
+		names = append(names, e.Name)
+	}
+	return strings.Join(names, ",")
 }
 
 // buildEntrypointCommand creates the command for the consumer container
@@ -151,6 +285,57 @@ func (b *ConsumerPodBuilder) buildEntrypointCommand(userCommand []string, workin
 	return cmd
 }
 
+// buildReadinessProbe passes through the user's readiness probe, or falls back to
+// sc-exec --ready, which reports ready once the rootfs is mounted. A user exec probe
+// runs behind the same check, so the pod is never ready before the rootfs is mounted.
+func (b *ConsumerPodBuilder) buildReadinessProbe(userProbe *corev1.Probe) *corev1.Probe {
+	if userProbe != nil && userProbe.Exec != nil {
+		probe := userProbe.DeepCopy()
+		probe.Exec.Command = append([]string{ExecWrapperBinPath + "/sc-exec", "--ready"}, userProbe.Exec.Command...)
+		return probe
+	}
+	if userProbe != nil {
+		return b.buildProbe(userProbe)
+	}
+	return &corev1.Probe{
+		ProbeHandler: corev1.ProbeHandler{
+			Exec: &corev1.ExecAction{
+				Command: []string{ExecWrapperBinPath + "/sc-exec", "--ready"},
+			},
+		},
+		InitialDelaySeconds: 1,
+		PeriodSeconds:       5,
+	}
+}
+
+// buildProbe adapts a user probe to the consumer container. Exec probes are wrapped
+// with sc-exec so they run inside the chroot; HTTP, TCP and gRPC probes are handled
+// by kubelet against the pod's network and pass through unmodified.
+func (b *ConsumerPodBuilder) buildProbe(userProbe *corev1.Probe) *corev1.Probe {
+	if userProbe == nil || userProbe.Exec == nil {
+		return userProbe
+	}
+	probe := userProbe.DeepCopy()
+	probe.Exec.Command = append([]string{ExecWrapperBinPath + "/sc-exec"}, userProbe.Exec.Command...)
+	return probe
+}
+
+// buildLifecycle adapts the user's postStart and preStop hooks to the consumer container.
+// Exec hooks are wrapped with sc-exec so they run inside the chroot; HTTP and sleep hooks
+// pass through unmodified.
+func (b *ConsumerPodBuilder) buildLifecycle(userLifecycle *corev1.Lifecycle) *corev1.Lifecycle {
+	if userLifecycle == nil {
+		return nil
+	}
+	lifecycle := userLifecycle.DeepCopy()
+	for _, hook := range []*corev1.LifecycleHandler{lifecycle.PostStart, lifecycle.PreStop} {
+		if hook != nil && hook.Exec != nil {
+			hook.Exec.Command = append([]string{ExecWrapperBinPath + "/sc-exec"}, hook.Exec.Command...)
+		}
+	}
+	return lifecycle
+}
+
 func (b *ConsumerPodBuilder) buildVolumeMounts(userMounts []corev1.VolumeMount) []corev1.VolumeMount {
 	mounts := []corev1.VolumeMount{
 		{
@@ -169,6 +354,12 @@ func (b *ConsumerPodBuilder) buildVolumeMounts(userMounts []corev1.VolumeMount)
 			Name:      BinOverlayVolumeName,
 			MountPath: "/bin",
 		},
+		{
+			// The image's ENTRYPOINT and CMD, for templates without a command
+			Name:      ImageConfigVolumeName,
+			MountPath: ImageConfigMountPath,
+			ReadOnly:  true,
+		},
 	}
 
 	for _, m := range userMounts {
@@ -178,15 +369,40 @@ func (b *ConsumerPodBuilder) buildVolumeMounts(userMounts []corev1.VolumeMount)
 
 		// Mount the SAME volume at the rootfs path so init containers
 		// can write files that are visible inside the chroot environment
-		rootfsMount := m.DeepCopy()
-		rootfsMount.Name = "user-" + m.Name // Same volume, different mount path
-		rootfsMount.MountPath = RootfsMountPath + m.MountPath
-		mounts = append(mounts, *rootfsMount)
+		for _, path := range rootfsMountPaths(m.MountPath) {
+			rootfsMount := m.DeepCopy()
+			rootfsMount.Name = "user-" + m.Name // Same volume, different mount path
+			rootfsMount.MountPath = path
+			mounts = append(mounts, *rootfsMount)
+		}
 	}
 
 	return mounts
 }
 
+// rootfsSymlinkedDirs are directories that images commonly ship as absolute symlinks
+var rootfsSymlinkedDirs = []struct{ link, target string }{
+	{"/var/run", "/run"},
+}
+
+// rootfsMountPaths returns where a user volume mounted at mountPath is also mounted so
+// it shows up at mountPath inside the chroot. The container runtime resolves symlinks
+// in a mount target against the consumer container's root, not the rootfs, so for an
+// image with /var/run -> /run a mount at /rootfs/var/run/... would land outside the
+// rootfs. Such paths are mounted at the symlink's target in the rootfs as well.
+// The mount path is cleaned and taken relative to / first, as the runtime does for
+// the original mount; subPath and subPathExpr select within the volume and are kept.
+func rootfsMountPaths(mountPath string) []string {
+	mountPath = path.Join("/", mountPath)
+	paths := []string{path.Join(RootfsMountPath, mountPath)}
+	for _, dir := range rootfsSymlinkedDirs {
+		if rest, ok := strings.CutPrefix(mountPath, dir.link+"/"); ok {
+			paths = append(paths, path.Join(RootfsMountPath, dir.target, rest))
+		}
+	}
+	return paths
+}
+
 func (b *ConsumerPodBuilder) buildVolumes(userVolumes []corev1.Volume, hostPath string, hostPathType corev1.HostPathType) []corev1.Volume {
 	volumes := []corev1.Volume{
 		{
@@ -210,6 +426,17 @@ func (b *ConsumerPodBuilder) buildVolumes(userVolumes []corev1.Volume, hostPath
 				EmptyDir: &corev1.EmptyDirVolumeSource{},
 			},
 		},
+		{
+			// Written by the DaemonSet before the rootfs is ready. FileOrCreate keeps the
+			// consumer starting with an empty file on mount-helpers that do not write it.
+			Name: ImageConfigVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				HostPath: &corev1.HostPathVolumeSource{
+					Path: filepath.Join(GetHostPath(b.sci), ImageConfigFileName),
+					Type: hostPathTypePtr(corev1.HostPathFileOrCreate),
+				},
+			},
+		},
 	}
 
 	for _, v := range userVolumes {
@@ -231,6 +458,11 @@ func (b *ConsumerPodBuilder) buildSecurityContext(userCtx *corev1.SecurityContex
 	//
 	// This is a significant security improvement - we no longer require CAP_SYS_ADMIN
 	// which is a very powerful capability.
+	//
+	// Two filesystems are involved: the consumer container's own root (the exec-wrapper
+	// image) and the chrooted rootfs (the overlay on the propagated hostPath mount).
+	// ReadOnlyRootFilesystem only applies to the former; the overlay the workload runs
+	// in stays writable since it is a volume mount.
 	requiredCaps := []corev1.Capability{
 		"SYS_CHROOT",
 	}
@@ -245,6 +477,14 @@ func (b *ConsumerPodBuilder) buildSecurityContext(userCtx *corev1.SecurityContex
 		if userCtx.RunAsGroup != nil {
 			ctx.RunAsGroup = userCtx.RunAsGroup
 		}
+		if userCtx.ReadOnlyRootFilesystem != nil {
+			ctx.ReadOnlyRootFilesystem = userCtx.ReadOnlyRootFilesystem
+		}
+		// Privileged is only honored when the StoppableContainer opted in, which the
+		// validating webhook also enforces at admission
+		if userCtx.Privileged != nil && *userCtx.Privileged && b.allowPrivileged {
+			ctx.Privileged = boolPtr(true)
+		}
 		// Merge user-requested capabilities with required ones
 		if userCtx.Capabilities != nil {
 			for _, cap := range userCtx.Capabilities.Add {
@@ -267,19 +507,6 @@ func (b *ConsumerPodBuilder) buildSecurityContext(userCtx *corev1.SecurityContex
 	return ctx
 }
 
-func (b *ConsumerPodBuilder) buildLabels(userLabels map[string]string) map[string]string {
-	labels := make(map[string]string)
-	// Copy user labels first
-	for k, v := range userLabels {
-		labels[k] = v
-	}
-	// System labels override user labels to ensure correct operation
-	labels[LabelManagedBy] = "stoppablecontainer"
-	labels[LabelInstance] = b.sci.Name
-	labels[LabelRole] = "consumer"
-	return labels
-}
-
 func (b *ConsumerPodBuilder) buildAnnotations(userAnnotations map[string]string) map[string]string {
 	annotations := make(map[string]string)
 	for k, v := range userAnnotations {
@@ -288,15 +515,34 @@ func (b *ConsumerPodBuilder) buildAnnotations(userAnnotations map[string]string)
 	return annotations
 }
 
+// TemplateHash returns a hash of the pod spec in the instance's template and of the
+// instance settings the consumer pods are built from. A consumer pod whose
+// AnnotationTemplateHash differs was built from an outdated template. Template labels
+// and annotations are not included, they are applied to a running consumer.
+func TemplateHash(sci *scv1alpha1.StoppableContainerInstance) string {
+	// The settings are only hashed when set, so consumers built before they were
+	// included in the hash are not recreated
+	if sci.Spec.ExecWrapperImage == "" && !sci.Spec.SkipNetworkConfigCopy {
+		return hashJSON(sci.Spec.Template.Spec)
+	}
+	return hashJSON(struct {
+		Spec                  corev1.PodSpec
+		ExecWrapperImage      string
+		SkipNetworkConfigCopy bool
+	}{sci.Spec.Template.Spec, sci.Spec.ExecWrapperImage, sci.Spec.SkipNetworkConfigCopy})
+}
+
 func (b *ConsumerPodBuilder) buildInitContainers(userInitContainers []corev1.Container) []corev1.Container {
 	// Use sc-exec --init to set up the bin overlay
 	// This copies sc-exec to /.sc-bin and creates symlinks for common commands
+	// and for the executables it finds in the rootfs
 	initContainers := []corev1.Container{
 		{
 			Name:            ExecWrapperInitName,
-			Image:           ExecWrapperImage,
+			Image:           execWrapperImage(b.sci),
 			ImagePullPolicy: ExecWrapperPullPolicy,
 			Command:         []string{"/sc-exec", "--init", "/sc-bin-overlay"},
+			Env:             mainContainerEnv(b.sci, ExtraCommandsEnv, LogFormatEnv),
 			VolumeMounts: []corev1.VolumeMount{
 				{
 					Name:      ExecWrapperVolumeName,
@@ -306,8 +552,15 @@ func (b *ConsumerPodBuilder) buildInitContainers(userInitContainers []corev1.Con
 					Name:      BinOverlayVolumeName,
 					MountPath: "/sc-bin-overlay",
 				},
+				{
+					// The consumer is only created once the rootfs is mounted
+					Name:             PropagatedVolumeName,
+					MountPath:        RootfsMountPath,
+					ReadOnly:         true,
+					MountPropagation: mountPropagationPtr(corev1.MountPropagationHostToContainer),
+				},
 			},
-			Resources: corev1.ResourceRequirements{
+			Resources: infraResources(b.sci, corev1.ResourceRequirements{
 				Requests: corev1.ResourceList{
 					corev1.ResourceCPU:    resource.MustParse("10m"),
 					corev1.ResourceMemory: resource.MustParse("16Mi"),
@@ -316,13 +569,18 @@ func (b *ConsumerPodBuilder) buildInitContainers(userInitContainers []corev1.Con
 					corev1.ResourceCPU:    resource.MustParse("100m"),
 					corev1.ResourceMemory: resource.MustParse("64Mi"),
 				},
-			},
+			}),
 		},
 	}
 
 	for _, c := range userInitContainers {
 		userInit := c.DeepCopy()
 		userInit.Name = "user-" + c.Name
+		if runInChroot(&c) {
+			b.wrapInitContainer(userInit)
+			initContainers = append(initContainers, *userInit)
+			continue
+		}
 		// Update volumeMount names to use user- prefix to match renamed volumes
 		for i := range userInit.VolumeMounts {
 			userInit.VolumeMounts[i].Name = "user-" + userInit.VolumeMounts[i].Name
@@ -332,3 +590,43 @@ func (b *ConsumerPodBuilder) buildInitContainers(userInitContainers []corev1.Con
 
 	return initContainers
 }
+
+// runInChroot reports whether a template init container asked, with RunInChrootEnv, to
+// run against the rootfs rather than in its own image
+func runInChroot(container *corev1.Container) bool {
+	for _, e := range container.Env {
+		if e.Name == RunInChrootEnv {
+			return e.Value == "true"
+		}
+	}
+	return false
+}
+
+// wrapInitContainer makes a user init container run its command with sc-exec
+// --entrypoint, like the main container, so it sees the mounted rootfs and can use
+// the binaries of the user image. Its image is replaced by the exec-wrapper.
+func (b *ConsumerPodBuilder) wrapInitContainer(container *corev1.Container) {
+	userCommand := b.buildUserCommand(container)
+	container.Env = b.buildChrootEnv(container.Env, commandFromImage(container))
+	container.VolumeMounts = b.buildVolumeMounts(container.VolumeMounts)
+	container.Image = execWrapperImage(b.sci)
+	container.ImagePullPolicy = ExecWrapperPullPolicy
+	container.Command = b.buildEntrypointCommand(userCommand, container.WorkingDir)
+	container.Args = nil
+	container.SecurityContext = b.buildSecurityContext(container.SecurityContext)
+}
+
+// isReadOnlyRootFilesystem reports whether the container's own filesystem is read-only
+func isReadOnlyRootFilesystem(ctx *corev1.SecurityContext) bool {
+	return ctx != nil && ctx.ReadOnlyRootFilesystem != nil && *ctx.ReadOnlyRootFilesystem
+}
+
+// hasMountPath reports whether any of the mounts is at the given path
+func hasMountPath(mounts []corev1.VolumeMount, path string) bool {
+	for _, m := range mounts {
+		if m.MountPath == path {
+			return true
+		}
+	}
+	return false
+}
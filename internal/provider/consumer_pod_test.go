@@ -17,9 +17,15 @@ limitations under the License.
 package provider
 
 import (
+	"reflect"
+	"slices"
 	"testing"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	scv1alpha1 "github.com/xtlsoft/stoppablecontainer/api/v1alpha1"
 )
 
 const (
@@ -122,6 +128,421 @@ func TestConsumerPodBuilder_Build(t *testing.T) {
 	}
 }
 
+func TestConsumerPodBuilder_Replicas(t *testing.T) {
+	sci := createTestSCI(testAppName, "default", "nginx:latest")
+
+	if got := ConsumerReplicas(sci); got != 1 {
+		t.Errorf("ConsumerReplicas() = %d, want 1 when unset", got)
+	}
+	one := int32(1)
+	sci.Spec.Replicas = &one
+	if got := NewConsumerPodBuilder(sci, "node-1").Build().Name; got != testAppName {
+		t.Errorf("Pod name with one replica = %q, want %q", got, testAppName)
+	}
+
+	three := int32(3)
+	sci.Spec.Replicas = &three
+	if got := ConsumerReplicas(sci); got != 3 {
+		t.Errorf("ConsumerReplicas() = %d, want 3", got)
+	}
+	pod := NewConsumerPodBuilder(sci, "node-1").WithOrdinal(2).Build()
+	if pod.Name != testAppName+"-consumer-2" {
+		t.Errorf("Pod name = %q, want %q", pod.Name, testAppName+"-consumer-2")
+	}
+	// All replicas share the labels the Service selects on
+	if pod.Labels[LabelInstance] != testAppName || pod.Labels[LabelRole] != "consumer" {
+		t.Errorf("Pod labels = %v", pod.Labels)
+	}
+}
+
+func TestConsumerPodBuilder_PodSecurityContext(t *testing.T) {
+	sci := createTestSCI("test", "default", "alpine:latest")
+	fsGroup := int64(2000)
+	sci.Spec.Template.Spec.SecurityContext = &corev1.PodSecurityContext{
+		FSGroup:            &fsGroup,
+		SupplementalGroups: []int64{3000},
+	}
+
+	pod := NewConsumerPodBuilder(sci, "node-1").Build()
+
+	// The pod-level security context is passed through as-is
+	if !reflect.DeepEqual(pod.Spec.SecurityContext, sci.Spec.Template.Spec.SecurityContext) {
+		t.Errorf("Pod SecurityContext = %+v, want %+v", pod.Spec.SecurityContext, sci.Spec.Template.Spec.SecurityContext)
+	}
+	if pod.Spec.SecurityContext == sci.Spec.Template.Spec.SecurityContext {
+		t.Error("Pod SecurityContext shares the template's pointer")
+	}
+	// The consumer container keeps the capability it needs to chroot
+	caps := pod.Spec.Containers[0].SecurityContext.Capabilities.Add
+	if len(caps) == 0 || caps[0] != "SYS_CHROOT" {
+		t.Errorf("Consumer capabilities = %v, want SYS_CHROOT", caps)
+	}
+}
+
+func TestConsumerPodBuilder_TerminationGracePeriod(t *testing.T) {
+	sci := createTestSCI(testAppName, "default", "postgres:16")
+	pod := NewConsumerPodBuilder(sci, "node-1").Build()
+	if pod.Spec.TerminationGracePeriodSeconds != nil {
+		t.Errorf("Expected the Kubernetes default grace period, got %d", *pod.Spec.TerminationGracePeriodSeconds)
+	}
+
+	grace := int64(120)
+	sci.Spec.Template.Spec.TerminationGracePeriodSeconds = &grace
+	pod = NewConsumerPodBuilder(sci, "node-1").Build()
+	if pod.Spec.TerminationGracePeriodSeconds == nil || *pod.Spec.TerminationGracePeriodSeconds != grace {
+		t.Errorf("Expected terminationGracePeriodSeconds %d passed through, got %v", grace, pod.Spec.TerminationGracePeriodSeconds)
+	}
+}
+
+func TestConsumerPodBuilder_HostNamespaces(t *testing.T) {
+	sci := createTestSCI(testAppName, "default", "nginx:latest")
+	pod := NewConsumerPodBuilder(sci, "node-1").Build()
+	if pod.Spec.HostPID || pod.Spec.HostIPC {
+		t.Errorf("Expected hostPID/hostIPC off by default, got %v/%v", pod.Spec.HostPID, pod.Spec.HostIPC)
+	}
+
+	sci.Spec.Template.Spec.HostPID = true
+	sci.Spec.Template.Spec.HostIPC = true
+	pod = NewConsumerPodBuilder(sci, "node-1").Build()
+	if !pod.Spec.HostPID || !pod.Spec.HostIPC {
+		t.Errorf("Expected hostPID/hostIPC passed through, got %v/%v", pod.Spec.HostPID, pod.Spec.HostIPC)
+	}
+
+	// The provider pod only holds the rootfs and never shares host namespaces
+	providerPod := NewProviderPodBuilder(sci).Build()
+	if providerPod.Spec.HostPID || providerPod.Spec.HostIPC {
+		t.Error("Expected provider pod to not use host namespaces")
+	}
+}
+
+func TestConsumerPodBuilder_EnvFromPrefix(t *testing.T) {
+	sci := createTestSCI(testAppName, "default", "nginx:latest")
+	sci.Spec.Template.Spec.Containers[0].EnvFrom = []corev1.EnvFromSource{
+		{
+			Prefix: "APP_",
+			ConfigMapRef: &corev1.ConfigMapEnvSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "app-config"},
+			},
+		},
+		{
+			Prefix: "DB_",
+			SecretRef: &corev1.SecretEnvSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "db-creds"},
+			},
+		},
+	}
+
+	pod := NewConsumerPodBuilder(sci, "node-1").Build()
+
+	// kubelet applies the prefix on the consumer container; sc-exec passes the
+	// resulting environment through the chroot unchanged
+	if !reflect.DeepEqual(pod.Spec.Containers[0].EnvFrom, sci.Spec.Template.Spec.Containers[0].EnvFrom) {
+		t.Errorf("EnvFrom = %v, want %v", pod.Spec.Containers[0].EnvFrom, sci.Spec.Template.Spec.Containers[0].EnvFrom)
+	}
+}
+
+func TestConsumerPodBuilder_HostPorts(t *testing.T) {
+	sci := createTestSCI(testAppName, "default", "nginx:latest")
+	sci.Spec.Template.Spec.Containers[0].Ports = []corev1.ContainerPort{
+		{Name: "http", ContainerPort: 80, HostPort: 8080},
+		{Name: "dns", ContainerPort: 53, HostPort: 53, Protocol: corev1.ProtocolUDP},
+	}
+
+	pod := NewConsumerPodBuilder(sci, "node-1").Build()
+
+	// The consumer container is the one listening, so it carries the hostPorts
+	if !reflect.DeepEqual(pod.Spec.Containers[0].Ports, sci.Spec.Template.Spec.Containers[0].Ports) {
+		t.Errorf("Ports = %v, want %v", pod.Spec.Containers[0].Ports, sci.Spec.Template.Spec.Containers[0].Ports)
+	}
+}
+
+func TestConsumerPodBuilder_FieldRefEnv(t *testing.T) {
+	sci := createTestSCI(testAppName, "default", "nginx:latest")
+	sci.Spec.Template.Spec.Containers[0].Env = []corev1.EnvVar{
+		{Name: "FOO", Value: "bar"},
+		{
+			Name: "POD_IP",
+			ValueFrom: &corev1.EnvVarSource{
+				FieldRef: &corev1.ObjectFieldSelector{FieldPath: "status.podIP"},
+			},
+		},
+		{
+			Name: "NODE_NAME",
+			ValueFrom: &corev1.EnvVarSource{
+				FieldRef: &corev1.ObjectFieldSelector{FieldPath: "spec.nodeName"},
+			},
+		},
+	}
+
+	pod := NewConsumerPodBuilder(sci, "node-1").Build()
+	env := pod.Spec.Containers[0].Env
+
+	// User env must be kept on the consumer container so kubelet resolves it;
+	// sc-exec then passes the resolved environment through the chroot
+	fieldPaths := map[string]string{}
+	for _, e := range env {
+		if e.ValueFrom != nil && e.ValueFrom.FieldRef != nil {
+			fieldPaths[e.Name] = e.ValueFrom.FieldRef.FieldPath
+		}
+	}
+	if fieldPaths["POD_IP"] != "status.podIP" {
+		t.Errorf("POD_IP fieldRef = %q, want %q", fieldPaths["POD_IP"], "status.podIP")
+	}
+	if fieldPaths["NODE_NAME"] != "spec.nodeName" {
+		t.Errorf("NODE_NAME fieldRef = %q, want %q", fieldPaths["NODE_NAME"], "spec.nodeName")
+	}
+
+	if len(env) != 5 || env[0].Name != "FOO" || env[4].Name != "SC_ROOTFS" {
+		t.Errorf("Unexpected consumer env %v", env)
+	}
+
+	// sc-exec lets the template's env override the image's environment
+	if env[3].Name != TemplateEnvNamesEnv || env[3].Value != "FOO,POD_IP,NODE_NAME" {
+		t.Errorf("%s = %v, want FOO,POD_IP,NODE_NAME", TemplateEnvNamesEnv, env[3])
+	}
+}
+
+func TestConsumerPodBuilder_Probes(t *testing.T) {
+	sci := createTestSCI(testAppName, "default", "my-grpc-server:latest")
+	grpcProbe := &corev1.Probe{
+		ProbeHandler: corev1.ProbeHandler{
+			GRPC: &corev1.GRPCAction{Port: 9090},
+		},
+		PeriodSeconds: 10,
+	}
+	sci.Spec.Template.Spec.Containers[0].ReadinessProbe = grpcProbe
+	sci.Spec.Template.Spec.Containers[0].LivenessProbe = &corev1.Probe{
+		ProbeHandler: corev1.ProbeHandler{
+			Exec: &corev1.ExecAction{Command: []string{"cat", "/tmp/healthy"}},
+		},
+	}
+
+	container := NewConsumerPodBuilder(sci, "node-1").Build().Spec.Containers[0]
+
+	// gRPC probes are handled by kubelet and must pass through unchanged
+	if !reflect.DeepEqual(container.ReadinessProbe, grpcProbe) {
+		t.Errorf("ReadinessProbe = %+v, want %+v", container.ReadinessProbe, grpcProbe)
+	}
+
+	// Exec probes are wrapped with sc-exec to run inside the chroot
+	if container.LivenessProbe == nil || container.LivenessProbe.Exec == nil {
+		t.Fatal("LivenessProbe exec action is missing")
+	}
+	expected := []string{ExecWrapperBinPath + "/sc-exec", "cat", "/tmp/healthy"}
+	if !reflect.DeepEqual(container.LivenessProbe.Exec.Command, expected) {
+		t.Errorf("LivenessProbe command = %v, want %v", container.LivenessProbe.Exec.Command, expected)
+	}
+	if sci.Spec.Template.Spec.Containers[0].LivenessProbe.Exec.Command[0] != "cat" {
+		t.Error("Building the pod should not modify the SCI's probes")
+	}
+
+	if container.StartupProbe != nil {
+		t.Errorf("StartupProbe = %+v, want nil", container.StartupProbe)
+	}
+}
+
+func TestConsumerPodBuilder_DefaultReadinessProbe(t *testing.T) {
+	sci := createTestSCI(testAppName, "default", "nginx:latest")
+	container := NewConsumerPodBuilder(sci, "node-1").Build().Spec.Containers[0]
+
+	if container.ReadinessProbe == nil || container.ReadinessProbe.Exec == nil {
+		t.Fatal("Default readiness probe is missing")
+	}
+	expected := []string{ExecWrapperBinPath + "/sc-exec", "--ready"}
+	if !reflect.DeepEqual(container.ReadinessProbe.Exec.Command, expected) {
+		t.Errorf("ReadinessProbe command = %v, want %v", container.ReadinessProbe.Exec.Command, expected)
+	}
+}
+
+func TestConsumerPodBuilder_ExecProbes(t *testing.T) {
+	sci := createTestSCI(testAppName, "default", "postgres:16")
+	spec := &sci.Spec.Template.Spec.Containers[0]
+	spec.ReadinessProbe = &corev1.Probe{
+		ProbeHandler: corev1.ProbeHandler{
+			Exec: &corev1.ExecAction{Command: []string{"pg_isready", "-U", "postgres"}},
+		},
+		PeriodSeconds: 5,
+	}
+	spec.StartupProbe = &corev1.Probe{
+		ProbeHandler: corev1.ProbeHandler{
+			Exec: &corev1.ExecAction{Command: []string{"test", "-f", "/var/run/started"}},
+		},
+		FailureThreshold: 30,
+	}
+	spec.LivenessProbe = &corev1.Probe{
+		ProbeHandler: corev1.ProbeHandler{
+			TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromInt32(5432)},
+		},
+	}
+
+	container := NewConsumerPodBuilder(sci, "node-1").Build().Spec.Containers[0]
+
+	// Exec readiness probes keep the rootfs-ready check in front of the user's command
+	expected := []string{ExecWrapperBinPath + "/sc-exec", "--ready", "pg_isready", "-U", "postgres"}
+	if !reflect.DeepEqual(container.ReadinessProbe.Exec.Command, expected) {
+		t.Errorf("ReadinessProbe command = %v, want %v", container.ReadinessProbe.Exec.Command, expected)
+	}
+	if container.ReadinessProbe.PeriodSeconds != 5 {
+		t.Errorf("ReadinessProbe PeriodSeconds = %d, want 5", container.ReadinessProbe.PeriodSeconds)
+	}
+
+	expected = []string{ExecWrapperBinPath + "/sc-exec", "test", "-f", "/var/run/started"}
+	if !reflect.DeepEqual(container.StartupProbe.Exec.Command, expected) {
+		t.Errorf("StartupProbe command = %v, want %v", container.StartupProbe.Exec.Command, expected)
+	}
+	if container.StartupProbe.FailureThreshold != 30 {
+		t.Errorf("StartupProbe FailureThreshold = %d, want 30", container.StartupProbe.FailureThreshold)
+	}
+
+	if !reflect.DeepEqual(container.LivenessProbe, spec.LivenessProbe) {
+		t.Errorf("LivenessProbe = %+v, want %+v", container.LivenessProbe, spec.LivenessProbe)
+	}
+	if spec.ReadinessProbe.Exec.Command[0] != "pg_isready" {
+		t.Error("Building the pod should not modify the SCI's probes")
+	}
+}
+
+func TestConsumerPodBuilder_Lifecycle(t *testing.T) {
+	sci := createTestSCI(testAppName, "default", "my-app:latest")
+	spec := &sci.Spec.Template.Spec.Containers[0]
+	spec.Lifecycle = &corev1.Lifecycle{
+		PostStart: &corev1.LifecycleHandler{
+			HTTPGet: &corev1.HTTPGetAction{Path: "/warmup", Port: intstr.FromInt32(8080)},
+		},
+		PreStop: &corev1.LifecycleHandler{
+			Exec: &corev1.ExecAction{Command: []string{"/bin/sh", "-c", "kill -TERM $(cat /run/app.pid) && sleep 5"}},
+		},
+	}
+
+	container := NewConsumerPodBuilder(sci, "node-1").Build().Spec.Containers[0]
+
+	if container.Lifecycle == nil || container.Lifecycle.PreStop == nil || container.Lifecycle.PreStop.Exec == nil {
+		t.Fatal("PreStop exec hook is missing")
+	}
+	expected := []string{ExecWrapperBinPath + "/sc-exec", "/bin/sh", "-c", "kill -TERM $(cat /run/app.pid) && sleep 5"}
+	if !reflect.DeepEqual(container.Lifecycle.PreStop.Exec.Command, expected) {
+		t.Errorf("PreStop command = %v, want %v", container.Lifecycle.PreStop.Exec.Command, expected)
+	}
+
+	// HTTP hooks are handled by kubelet and must pass through unchanged
+	if !reflect.DeepEqual(container.Lifecycle.PostStart, spec.Lifecycle.PostStart) {
+		t.Errorf("PostStart = %+v, want %+v", container.Lifecycle.PostStart, spec.Lifecycle.PostStart)
+	}
+	if spec.Lifecycle.PreStop.Exec.Command[0] != "/bin/sh" {
+		t.Error("Building the pod should not modify the SCI's lifecycle hooks")
+	}
+}
+
+func TestConsumerPodBuilder_NoLifecycle(t *testing.T) {
+	sci := createTestSCI(testAppName, "default", "nginx:latest")
+	container := NewConsumerPodBuilder(sci, "node-1").Build().Spec.Containers[0]
+
+	if container.Lifecycle != nil {
+		t.Errorf("Lifecycle = %+v, want nil", container.Lifecycle)
+	}
+}
+
+func TestConsumerPodBuilder_ActiveDeadlineSeconds(t *testing.T) {
+	sci := createTestSCI(testAppName, "default", "nginx:latest")
+	deadline := int64(3600)
+	sci.Spec.Template.Spec.ActiveDeadlineSeconds = &deadline
+
+	pod := NewConsumerPodBuilder(sci, "node-1").Build()
+	if pod.Spec.ActiveDeadlineSeconds == nil || *pod.Spec.ActiveDeadlineSeconds != deadline {
+		t.Errorf("Expected activeDeadlineSeconds %d on the consumer pod, got %v", deadline, pod.Spec.ActiveDeadlineSeconds)
+	}
+
+	// The deadline caps the consumer only; the provider holding the rootfs keeps running
+	if providerPod := NewProviderPodBuilder(sci).Build(); providerPod.Spec.ActiveDeadlineSeconds != nil {
+		t.Errorf("Expected no activeDeadlineSeconds on the provider pod, got %d", *providerPod.Spec.ActiveDeadlineSeconds)
+	}
+}
+
+func TestConsumerPodBuilder_RestartPolicy(t *testing.T) {
+	tests := []struct {
+		policy corev1.RestartPolicy
+		want   corev1.RestartPolicy
+	}{
+		{"", corev1.RestartPolicyAlways},
+		{corev1.RestartPolicyAlways, corev1.RestartPolicyAlways},
+		{corev1.RestartPolicyOnFailure, corev1.RestartPolicyOnFailure},
+		{corev1.RestartPolicyNever, corev1.RestartPolicyNever},
+	}
+
+	for _, tt := range tests {
+		sci := createTestSCI("test", "default", "alpine:latest")
+		sci.Spec.Template.Spec.RestartPolicy = tt.policy
+		if got := NewConsumerPodBuilder(sci, "node-1").Build().Spec.RestartPolicy; got != tt.want {
+			t.Errorf("restartPolicy %q: consumer pod restartPolicy = %q, want %q", tt.policy, got, tt.want)
+		}
+	}
+}
+
+func TestConsumerPodBuilder_HostAliases(t *testing.T) {
+	sci := createTestSCI(testAppName, "default", "nginx:latest")
+	sci.Spec.Template.Spec.HostAliases = []corev1.HostAlias{
+		{IP: "10.0.0.10", Hostnames: []string{"db.internal", "db"}},
+	}
+
+	// The kubelet writes the aliases to the pod's /etc/hosts, which the consumer
+	// copies into the rootfs
+	pod := NewConsumerPodBuilder(sci, "node-1").Build()
+	if len(pod.Spec.HostAliases) != 1 || pod.Spec.HostAliases[0].IP != "10.0.0.10" ||
+		len(pod.Spec.HostAliases[0].Hostnames) != 2 {
+		t.Errorf("Expected hostAliases passed through, got %v", pod.Spec.HostAliases)
+	}
+
+	// The provider pod only holds the rootfs and needs no aliases
+	if providerPod := NewProviderPodBuilder(sci).Build(); len(providerPod.Spec.HostAliases) != 0 {
+		t.Errorf("Expected no hostAliases on the provider pod, got %v", providerPod.Spec.HostAliases)
+	}
+}
+
+func TestConsumerPodBuilder_ExecWrapperImage(t *testing.T) {
+	sci := createTestSCI(testAppName, "default", "nginx:latest")
+
+	pod := NewConsumerPodBuilder(sci, "node-1").Build()
+	if pod.Spec.Containers[0].Image != ExecWrapperImage || pod.Spec.InitContainers[0].Image != ExecWrapperImage {
+		t.Errorf("Expected exec-wrapper containers to default to %s, got %s/%s",
+			ExecWrapperImage, pod.Spec.Containers[0].Image, pod.Spec.InitContainers[0].Image)
+	}
+
+	sci.Spec.ExecWrapperImage = "registry.internal/stoppablecontainer-exec:v1"
+	pod = NewConsumerPodBuilder(sci, "node-1").Build()
+	if pod.Spec.Containers[0].Image != sci.Spec.ExecWrapperImage ||
+		pod.Spec.InitContainers[0].Image != sci.Spec.ExecWrapperImage {
+		t.Errorf("Expected exec-wrapper containers to use %s, got %s/%s",
+			sci.Spec.ExecWrapperImage, pod.Spec.Containers[0].Image, pod.Spec.InitContainers[0].Image)
+	}
+
+	// The provider pod has its own override
+	if providerPod := NewProviderPodBuilder(sci).Build(); providerPod.Spec.Containers[0].Image != ExecWrapperImage {
+		t.Errorf("Provider image = %s, want %s", providerPod.Spec.Containers[0].Image, ExecWrapperImage)
+	}
+}
+
+func TestConsumerPodBuilder_SkipNetworkConfigCopy(t *testing.T) {
+	sci := createTestSCI(testAppName, "default", "nginx:latest")
+
+	hasSkipEnv := func(pod *corev1.Pod) bool {
+		for _, e := range pod.Spec.Containers[0].Env {
+			if e.Name == SkipNetworkConfigCopyEnv && e.Value == "true" {
+				return true
+			}
+		}
+		return false
+	}
+
+	if hasSkipEnv(NewConsumerPodBuilder(sci, "node-1").Build()) {
+		t.Errorf("%s should not be set by default", SkipNetworkConfigCopyEnv)
+	}
+
+	sci.Spec.SkipNetworkConfigCopy = true
+	if !hasSkipEnv(NewConsumerPodBuilder(sci, "node-1").Build()) {
+		t.Errorf("%s should be set when SkipNetworkConfigCopy is enabled", SkipNetworkConfigCopyEnv)
+	}
+}
+
 func TestConsumerPodBuilder_BuildSecurityContext(t *testing.T) {
 	sci := createTestSCI("test", "default", "alpine:latest")
 	builder := NewConsumerPodBuilder(sci, "node-1")
@@ -236,6 +657,175 @@ func TestConsumerPodBuilder_BuildSecurityContext(t *testing.T) {
 	}
 }
 
+func TestConsumerPodBuilder_ReadOnlyRootFilesystem(t *testing.T) {
+	sci := createTestSCI("test", "default", "alpine:latest")
+	sci.Spec.Template.Spec.Containers[0].SecurityContext = &corev1.SecurityContext{
+		ReadOnlyRootFilesystem: boolPtr(true),
+	}
+
+	pod := NewConsumerPodBuilder(sci, "node-1").Build()
+	container := pod.Spec.Containers[0]
+
+	if !isReadOnlyRootFilesystem(container.SecurityContext) {
+		t.Error("Consumer container should have a read-only root filesystem")
+	}
+
+	// /tmp is backed by a writable emptyDir
+	foundTmpMount := false
+	for _, m := range container.VolumeMounts {
+		if m.Name == TmpVolumeName && m.MountPath == "/tmp" {
+			foundTmpMount = true
+		}
+	}
+	if !foundTmpMount {
+		t.Error("Writable /tmp mount not found")
+	}
+	foundTmpVolume := false
+	for _, v := range pod.Spec.Volumes {
+		if v.Name == TmpVolumeName && v.EmptyDir != nil {
+			foundTmpVolume = true
+		}
+	}
+	if !foundTmpVolume {
+		t.Error("Writable /tmp emptyDir volume not found")
+	}
+
+	// The chrooted rootfs stays a writable mount
+	for _, m := range container.VolumeMounts {
+		if m.Name == PropagatedVolumeName && m.ReadOnly {
+			t.Error("Rootfs mount should stay writable")
+		}
+	}
+
+	// Without read-only root, no extra /tmp volume is added
+	sci.Spec.Template.Spec.Containers[0].SecurityContext = nil
+	pod = NewConsumerPodBuilder(sci, "node-1").Build()
+	for _, v := range pod.Spec.Volumes {
+		if v.Name == TmpVolumeName {
+			t.Error("Unexpected /tmp volume without read-only root filesystem")
+		}
+	}
+}
+
+func TestConsumerPodBuilder_Privileged(t *testing.T) {
+	sci := createTestSCI("test", "default", "alpine:latest")
+	sci.Spec.Template.Spec.Containers[0].SecurityContext = &corev1.SecurityContext{
+		Privileged: boolPtr(true),
+	}
+
+	// Without the permission, privileged is dropped, whatever the SCI's own annotations say
+	sci.Annotations = map[string]string{scv1alpha1.AnnotationAllowPrivileged: "true"}
+	pod := NewConsumerPodBuilder(sci, "node-1").Build()
+	if ctx := pod.Spec.Containers[0].SecurityContext; ctx.Privileged != nil {
+		t.Errorf("Expected privileged to be dropped, got %v", *ctx.Privileged)
+	}
+
+	pod = NewConsumerPodBuilder(sci, "node-1").WithAllowPrivileged(true).Build()
+	if ctx := pod.Spec.Containers[0].SecurityContext; ctx.Privileged == nil || !*ctx.Privileged {
+		t.Error("Expected privileged to be honored when allowed")
+	}
+}
+
+func TestConsumerPodBuilder_PriorityClassName(t *testing.T) {
+	sci := createTestSCI(testAppName, "default", "ubuntu:22.04")
+	sci.Spec.Provider.PriorityClassName = "rootfs-critical"
+
+	// The provider's priority class does not apply to the consumer
+	if pod := NewConsumerPodBuilder(sci, "node-1").Build(); pod.Spec.PriorityClassName != "" {
+		t.Errorf("PriorityClassName = %q, want none by default", pod.Spec.PriorityClassName)
+	}
+
+	sci.Spec.Template.Spec.PriorityClassName = "batch-low"
+	if pod := NewConsumerPodBuilder(sci, "node-1").Build(); pod.Spec.PriorityClassName != "batch-low" {
+		t.Errorf("PriorityClassName = %q, want %q", pod.Spec.PriorityClassName, "batch-low")
+	}
+}
+
+func TestResolveCommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		command  []string
+		args     []string
+		expected []string
+	}{
+		{
+			name:     "command",
+			command:  []string{"nginx"},
+			expected: []string{"nginx"},
+		},
+		{
+			name:     "command and args",
+			command:  []string{"nginx"},
+			args:     []string{"-g", "daemon off;"},
+			expected: []string{"nginx", "-g", "daemon off;"},
+		},
+		{
+			name:     "args only",
+			args:     []string{"python", "app.py"},
+			expected: []string{"python", "app.py"},
+		},
+		{
+			name:     "nothing declared",
+			expected: []string{DefaultShell},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := ResolveCommand(tt.command, tt.args); !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("ResolveCommand() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestConsumerPodBuilder_CommandFromImage(t *testing.T) {
+	commandFromImageEnv := func(pod *corev1.Pod) string {
+		for _, env := range pod.Spec.Containers[0].Env {
+			if env.Name == CommandFromImageEnv {
+				return env.Value
+			}
+		}
+		return ""
+	}
+
+	sci := createTestSCI("test", "default", "nginx:latest")
+	container := &sci.Spec.Template.Spec.Containers[0]
+	container.Command = nil
+	container.Args = nil
+	pod := NewConsumerPodBuilder(sci, "node-1").Build()
+	if v := commandFromImageEnv(pod); v != CommandFromImageEntrypointCmd {
+		t.Errorf("%s = %q, want %q", CommandFromImageEnv, v, CommandFromImageEntrypointCmd)
+	}
+
+	container.Args = []string{"nginx", "-T"}
+	pod = NewConsumerPodBuilder(sci, "node-1").Build()
+	if v := commandFromImageEnv(pod); v != CommandFromImageEntrypoint {
+		t.Errorf("%s = %q, want %q", CommandFromImageEnv, v, CommandFromImageEntrypoint)
+	}
+
+	container.Command = []string{"nginx"}
+	pod = NewConsumerPodBuilder(sci, "node-1").Build()
+	if v := commandFromImageEnv(pod); v != "" {
+		t.Errorf("%s = %q, want unset with a command", CommandFromImageEnv, v)
+	}
+
+	// The image config is read from the instance's host path directory
+	var imageVolume *corev1.Volume
+	for i, v := range pod.Spec.Volumes {
+		if v.Name == ImageConfigVolumeName {
+			imageVolume = &pod.Spec.Volumes[i]
+		}
+	}
+	expectedPath := "/var/lib/stoppablecontainer/default/test/" + ImageConfigFileName
+	if imageVolume == nil || imageVolume.HostPath == nil || imageVolume.HostPath.Path != expectedPath {
+		t.Fatalf("image config volume = %v, want hostPath %s", imageVolume, expectedPath)
+	}
+	if *imageVolume.HostPath.Type != corev1.HostPathFileOrCreate {
+		t.Errorf("image config hostPath type = %s, want %s", *imageVolume.HostPath.Type, corev1.HostPathFileOrCreate)
+	}
+}
+
 func TestConsumerPodBuilder_BuildUserCommand(t *testing.T) {
 	sci := createTestSCI("test", "default", "alpine:latest")
 	builder := NewConsumerPodBuilder(sci, "node-1")
@@ -272,6 +862,14 @@ func TestConsumerPodBuilder_BuildUserCommand(t *testing.T) {
 			},
 			expected: []string{"/bin/sh", "-c", "echo 'hello world'"},
 		},
+		{
+			name: "args with shell metacharacters are kept verbatim",
+			container: corev1.Container{
+				Command: []string{"/app/run"},
+				Args:    []string{`say "hi"`, "it's", "$HOME", "`id`", "a; rm -rf /", "line1\nline2"},
+			},
+			expected: []string{"/app/run", `say "hi"`, "it's", "$HOME", "`id`", "a; rm -rf /", "line1\nline2"},
+		},
 	}
 
 	for _, tt := range tests {
@@ -318,6 +916,14 @@ func TestConsumerPodBuilder_BuildEntrypointCommand(t *testing.T) {
 			workingDir: "",
 			expected:   []string{"/sc-exec", "--entrypoint", "/", "/bin/bash"},
 		},
+		{
+			// The command is passed as exec args, never embedded in a shell string,
+			// so quotes, newlines and $ need no escaping
+			name:       "odd command contents are passed as separate args",
+			userCmd:    []string{"/bin/echo", `"double"`, "'single'", "$PATH", "multi\nline"},
+			workingDir: "/",
+			expected:   []string{"/sc-exec", "--entrypoint", "/", "/bin/echo", `"double"`, "'single'", "$PATH", "multi\nline"},
+		},
 	}
 
 	for _, tt := range tests {
@@ -336,6 +942,22 @@ func TestConsumerPodBuilder_BuildEntrypointCommand(t *testing.T) {
 	}
 }
 
+func TestConsumerPodBuilder_CommandNotShellWrapped(t *testing.T) {
+	sci := createTestSCI("test", "default", "alpine:latest")
+	sci.Spec.Template.Spec.Containers[0].Command = []string{"/bin/sh", "-c", "echo \"$1\"\nexit 0"}
+	sci.Spec.Template.Spec.Containers[0].Args = []string{"arg with 'quotes'"}
+
+	container := NewConsumerPodBuilder(sci, "node-1").Build().Spec.Containers[0]
+
+	expected := []string{"/sc-exec", "--entrypoint", "/", "/bin/sh", "-c", "echo \"$1\"\nexit 0", "arg with 'quotes'"}
+	if !reflect.DeepEqual(container.Command, expected) {
+		t.Errorf("Command = %q, want %q", container.Command, expected)
+	}
+	if container.Args != nil {
+		t.Errorf("Args = %q, want nil", container.Args)
+	}
+}
+
 func TestConsumerPodBuilder_BuildVolumeMounts(t *testing.T) {
 	sci := createTestSCI("test", "default", "alpine:latest")
 	builder := NewConsumerPodBuilder(sci, "node-1")
@@ -355,11 +977,11 @@ func TestConsumerPodBuilder_BuildVolumeMounts(t *testing.T) {
 	mounts := builder.buildVolumeMounts(userMounts)
 
 	// Should have base mounts + 2 user mounts * 2 (original + rootfs)
-	// Base: PropagatedVolume, ExecWrapperVolume, BinOverlayVolume = 3
+	// Base: PropagatedVolume, ExecWrapperVolume, BinOverlayVolume, ImageConfigVolume = 4
 	// User: 2 * 2 = 4
-	// Total = 7
-	if len(mounts) != 7 {
-		t.Errorf("Expected 7 mounts, got %d", len(mounts))
+	// Total = 8
+	if len(mounts) != 8 {
+		t.Errorf("Expected 8 mounts, got %d", len(mounts))
 	}
 
 	// Check base mounts exist
@@ -433,13 +1055,13 @@ func TestConsumerPodBuilder_BuildVolumes(t *testing.T) {
 	volumes := builder.buildVolumes(userVolumes, "/var/lib/test", hostPathType)
 
 	// Should have base volumes + 2 user volumes
-	// Base: PropagatedVolume, ExecWrapperVolume, BinOverlayVolume = 3
+	// Base: PropagatedVolume, ExecWrapperVolume, BinOverlayVolume, ImageConfigVolume = 4
 	// User: 2
-	// Total = 5
+	// Total = 6
 	// Note: We no longer create separate -rootfs volumes; the same volume
 	// is mounted at both original path and /rootfs/<path>
-	if len(volumes) != 5 {
-		t.Errorf("Expected 5 volumes, got %d", len(volumes))
+	if len(volumes) != 6 {
+		t.Errorf("Expected 6 volumes, got %d", len(volumes))
 	}
 
 	// Check base volumes exist
@@ -488,6 +1110,197 @@ func TestConsumerPodBuilder_BuildVolumes(t *testing.T) {
 	}
 }
 
+func TestConsumerPodBuilder_EmptyDirSharedWithRootfs(t *testing.T) {
+	sci := createTestSCI("test", "default", "alpine:latest")
+	sizeLimit := resource.MustParse("1Gi")
+	sci.Spec.Template.Spec.Volumes = []corev1.Volume{
+		{
+			Name: "data",
+			VolumeSource: corev1.VolumeSource{
+				EmptyDir: &corev1.EmptyDirVolumeSource{
+					Medium:    corev1.StorageMediumMemory,
+					SizeLimit: &sizeLimit,
+				},
+			},
+		},
+	}
+	sci.Spec.Template.Spec.Containers[0].VolumeMounts = []corev1.VolumeMount{
+		{Name: "data", MountPath: "/data"},
+	}
+
+	pod := NewConsumerPodBuilder(sci, "node-1").Build()
+
+	// A single emptyDir (and a single sizeLimit reservation) must back both mounts
+	var dataVolumes []corev1.Volume
+	for _, v := range pod.Spec.Volumes {
+		if v.EmptyDir != nil && v.EmptyDir.SizeLimit != nil {
+			dataVolumes = append(dataVolumes, v)
+		}
+	}
+	if len(dataVolumes) != 1 {
+		t.Fatalf("Expected 1 sized emptyDir volume, got %d", len(dataVolumes))
+	}
+	vol := dataVolumes[0]
+	if vol.EmptyDir.Medium != corev1.StorageMediumMemory {
+		t.Errorf("EmptyDir medium = %q, want %q", vol.EmptyDir.Medium, corev1.StorageMediumMemory)
+	}
+	if vol.EmptyDir.SizeLimit.Cmp(sizeLimit) != 0 {
+		t.Errorf("EmptyDir sizeLimit = %s, want %s", vol.EmptyDir.SizeLimit, &sizeLimit)
+	}
+
+	var mountPaths []string
+	for _, m := range pod.Spec.Containers[0].VolumeMounts {
+		if m.Name == vol.Name {
+			mountPaths = append(mountPaths, m.MountPath)
+		}
+	}
+	expected := []string{"/data", RootfsMountPath + "/data"}
+	if !reflect.DeepEqual(mountPaths, expected) {
+		t.Errorf("Mount paths for %s = %v, want %v", vol.Name, mountPaths, expected)
+	}
+}
+
+func TestConsumerPodBuilder_VolumeSources(t *testing.T) {
+	expirationSeconds := int64(3600)
+	tests := []struct {
+		name      string
+		source    corev1.VolumeSource
+		mountPath string
+		expected  []string
+	}{
+		{
+			name: "secret",
+			source: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{SecretName: "app-secret"},
+			},
+			mountPath: "/etc/secret",
+			expected:  []string{"/etc/secret", RootfsMountPath + "/etc/secret"},
+		},
+		{
+			name: "configMap",
+			source: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "app-config"},
+				},
+			},
+			mountPath: "/etc/config",
+			expected:  []string{"/etc/config", RootfsMountPath + "/etc/config"},
+		},
+		{
+			name: "downwardAPI",
+			source: corev1.VolumeSource{
+				DownwardAPI: &corev1.DownwardAPIVolumeSource{
+					Items: []corev1.DownwardAPIVolumeFile{{
+						Path:     "labels",
+						FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.labels"},
+					}},
+				},
+			},
+			mountPath: "/etc/podinfo",
+			expected:  []string{"/etc/podinfo", RootfsMountPath + "/etc/podinfo"},
+		},
+		{
+			// Images usually ship /var/run as a symlink to /run, which the runtime
+			// would resolve outside the rootfs, so the token is mounted below /run too
+			name: "projected service account token",
+			source: corev1.VolumeSource{
+				Projected: &corev1.ProjectedVolumeSource{
+					Sources: []corev1.VolumeProjection{
+						{ServiceAccountToken: &corev1.ServiceAccountTokenProjection{
+							Path:              "token",
+							ExpirationSeconds: &expirationSeconds,
+						}},
+						{DownwardAPI: &corev1.DownwardAPIProjection{
+							Items: []corev1.DownwardAPIVolumeFile{{
+								Path:     "namespace",
+								FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.namespace"},
+							}},
+						}},
+					},
+				},
+			},
+			mountPath: "/var/run/secrets/kubernetes.io/serviceaccount",
+			expected: []string{
+				"/var/run/secrets/kubernetes.io/serviceaccount",
+				RootfsMountPath + "/var/run/secrets/kubernetes.io/serviceaccount",
+				RootfsMountPath + "/run/secrets/kubernetes.io/serviceaccount",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sci := createTestSCI("test", "default", "alpine:latest")
+			sci.Spec.Template.Spec.Volumes = []corev1.Volume{{Name: "vol", VolumeSource: tt.source}}
+			sci.Spec.Template.Spec.Containers[0].VolumeMounts = []corev1.VolumeMount{
+				{Name: "vol", MountPath: tt.mountPath, ReadOnly: true},
+			}
+
+			pod := NewConsumerPodBuilder(sci, "node-1").Build()
+
+			// The volume source, including field references, is passed through unchanged
+			var found *corev1.Volume
+			for i := range pod.Spec.Volumes {
+				if pod.Spec.Volumes[i].Name == "user-vol" {
+					found = &pod.Spec.Volumes[i]
+				}
+			}
+			if found == nil {
+				t.Fatal("Volume user-vol not found")
+			}
+			if !reflect.DeepEqual(found.VolumeSource, tt.source) {
+				t.Errorf("VolumeSource = %+v, want %+v", found.VolumeSource, tt.source)
+			}
+
+			var mountPaths []string
+			for _, m := range pod.Spec.Containers[0].VolumeMounts {
+				if m.Name == "user-vol" {
+					mountPaths = append(mountPaths, m.MountPath)
+					if !m.ReadOnly {
+						t.Errorf("Mount at %s should stay read-only", m.MountPath)
+					}
+				}
+			}
+			if !reflect.DeepEqual(mountPaths, tt.expected) {
+				t.Errorf("Mount paths = %v, want %v", mountPaths, tt.expected)
+			}
+		})
+	}
+}
+
+func TestConsumerPodBuilder_SubPathMounts(t *testing.T) {
+	sci := createTestSCI("test", "default", "alpine:latest")
+	builder := NewConsumerPodBuilder(sci, "node-1")
+
+	mounts := builder.buildVolumeMounts([]corev1.VolumeMount{
+		{Name: "data", MountPath: "/var/data", SubPath: "app"},
+		{Name: "logs", MountPath: "/var/log/app/", SubPathExpr: "$(POD_NAME)"},
+		{Name: "cache", MountPath: "cache"},
+	})
+
+	type mountKey struct{ name, path, subPath, subPathExpr string }
+	var got []mountKey
+	for _, m := range mounts {
+		if m.Name == testUserDataVol || m.Name == "user-logs" || m.Name == "user-cache" {
+			got = append(got, mountKey{m.Name, m.MountPath, m.SubPath, m.SubPathExpr})
+		}
+	}
+
+	// The subPath selects within the volume, so the rootfs mount keeps it and only
+	// the mount path is moved below the rootfs
+	expected := []mountKey{
+		{testUserDataVol, "/var/data", "app", ""},
+		{testUserDataVol, RootfsMountPath + "/var/data", "app", ""},
+		{"user-logs", "/var/log/app/", "", "$(POD_NAME)"},
+		{"user-logs", RootfsMountPath + "/var/log/app", "", "$(POD_NAME)"},
+		{"user-cache", "cache", "", ""},
+		{"user-cache", RootfsMountPath + "/cache", "", ""},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Mounts = %v, want %v", got, expected)
+	}
+}
+
 func TestConsumerPodBuilder_BuildAnnotations(t *testing.T) {
 	sci := createTestSCI("test", "default", "alpine:latest")
 	builder := NewConsumerPodBuilder(sci, "node-1")
@@ -532,6 +1345,67 @@ func TestConsumerPodBuilder_BuildAnnotations(t *testing.T) {
 	}
 }
 
+func TestTemplateHash(t *testing.T) {
+	sci := createTestSCI("test", "default", "alpine:latest")
+	hash := TemplateHash(sci)
+	if len(hash) != 8 {
+		t.Errorf("TemplateHash() = %q, want 8 hex digits", hash)
+	}
+
+	pod := NewConsumerPodBuilder(sci, "node-1").Build()
+	if pod.Annotations[AnnotationTemplateHash] != hash {
+		t.Errorf("Pod annotation %s = %q, want %q", AnnotationTemplateHash, pod.Annotations[AnnotationTemplateHash], hash)
+	}
+
+	// Template metadata is applied in place and does not change the hash
+	sci.Spec.Template.Metadata.Labels = map[string]string{"team": "a"}
+	if got := TemplateHash(sci); got != hash {
+		t.Errorf("TemplateHash() changed with template labels: %q, want %q", got, hash)
+	}
+
+	sci.Spec.Template.Spec.Containers[0].Command = []string{"sleep", "infinity"}
+	if got := TemplateHash(sci); got == hash {
+		t.Error("TemplateHash() did not change with the container command")
+	}
+
+	// The consumer is built with the exec-wrapper image and the network config copy
+	hash = TemplateHash(sci)
+	sci.Spec.ExecWrapperImage = "registry.example.com/sc-exec:v2"
+	if got := TemplateHash(sci); got == hash {
+		t.Error("TemplateHash() did not change with the exec-wrapper image")
+	}
+	hash = TemplateHash(sci)
+	sci.Spec.SkipNetworkConfigCopy = true
+	if got := TemplateHash(sci); got == hash {
+		t.Error("TemplateHash() did not change with skipNetworkConfigCopy")
+	}
+}
+
+func TestProviderHash(t *testing.T) {
+	sci := createTestSCI("test", "default", "alpine:latest")
+	hash := ProviderHash(sci)
+	pod := NewProviderPodBuilder(sci).Build()
+	if pod.Annotations[AnnotationProviderHash] != hash {
+		t.Errorf("Pod annotation %s = %q, want %q", AnnotationProviderHash, pod.Annotations[AnnotationProviderHash], hash)
+	}
+
+	// The image is compared on the rootfs container instead
+	sci.Spec.Template.Spec.Containers[0].Image = "alpine:3.20"
+	if got := ProviderHash(sci); got != hash {
+		t.Errorf("ProviderHash() changed with the image: %q, want %q", got, hash)
+	}
+
+	sci.Spec.Provider.PriorityClassName = "high"
+	if got := ProviderHash(sci); got == hash {
+		t.Error("ProviderHash() did not change with the provider settings")
+	}
+	hash = ProviderHash(sci)
+	sci.Spec.Persistence = &scv1alpha1.PersistenceSpec{ClaimName: "data"}
+	if got := ProviderHash(sci); got == hash {
+		t.Error("ProviderHash() did not change with persistence")
+	}
+}
+
 func TestConsumerPodBuilder_BuildInitContainers(t *testing.T) {
 	sci := createTestSCI("test", "default", "alpine:latest")
 	builder := NewConsumerPodBuilder(sci, "node-1")
@@ -564,6 +1438,43 @@ func TestConsumerPodBuilder_BuildInitContainers(t *testing.T) {
 	}
 }
 
+func TestConsumerPodBuilder_BuildInitContainers_BinOverlay(t *testing.T) {
+	sci := createTestSCI("test", "default", "golang:1.24")
+	sci.Spec.Template.Spec.Containers[0].Env = []corev1.EnvVar{
+		{Name: "FOO", Value: "bar"},
+		{Name: ExtraCommandsEnv, Value: "go,gofmt"},
+		{Name: LogFormatEnv, Value: "json"},
+	}
+
+	initContainer := NewConsumerPodBuilder(sci, "node-1").Build().Spec.InitContainers[0]
+
+	// sc-exec --init gets the extra commands and log format, but no other template env
+	wantEnv := []corev1.EnvVar{
+		{Name: ExtraCommandsEnv, Value: "go,gofmt"},
+		{Name: LogFormatEnv, Value: "json"},
+	}
+	if !reflect.DeepEqual(initContainer.Env, wantEnv) {
+		t.Errorf("Init container env = %v, want %v", initContainer.Env, wantEnv)
+	}
+
+	// The rootfs is mounted read-only so executables can be discovered
+	var rootfs *corev1.VolumeMount
+	for i, m := range initContainer.VolumeMounts {
+		if m.Name == PropagatedVolumeName {
+			rootfs = &initContainer.VolumeMounts[i]
+		}
+	}
+	if rootfs == nil || rootfs.MountPath != RootfsMountPath || !rootfs.ReadOnly ||
+		rootfs.MountPropagation == nil || *rootfs.MountPropagation != corev1.MountPropagationHostToContainer {
+		t.Errorf("Init container rootfs mount = %v, want read-only %s with HostToContainer propagation", rootfs, RootfsMountPath)
+	}
+
+	sci.Spec.Template.Spec.Containers[0].Env = nil
+	if env := NewConsumerPodBuilder(sci, "node-1").Build().Spec.InitContainers[0].Env; env != nil {
+		t.Errorf("Init container env = %v, want none", env)
+	}
+}
+
 func TestConsumerPodBuilder_BuildInitContainers_VolumeNameMapping(t *testing.T) {
 	sci := createTestSCI("test", "default", "alpine:latest")
 	builder := NewConsumerPodBuilder(sci, "node-1")
@@ -608,6 +1519,78 @@ func TestConsumerPodBuilder_BuildInitContainers_VolumeNameMapping(t *testing.T)
 	}
 }
 
+func TestConsumerPodBuilder_BuildInitContainers_RunInChroot(t *testing.T) {
+	sci := createTestSCI("test", "default", "myapp:latest")
+	sci.Spec.ExecWrapperImage = "registry.example.com/sc-exec:v1"
+	builder := NewConsumerPodBuilder(sci, "node-1")
+
+	userInitContainers := []corev1.Container{
+		{
+			Name:       "migrate",
+			Image:      "ignored:latest",
+			Command:    []string{"/app/migrate"},
+			Args:       []string{"up"},
+			WorkingDir: "/app",
+			Env:        []corev1.EnvVar{{Name: RunInChrootEnv, Value: "true"}},
+			VolumeMounts: []corev1.VolumeMount{
+				{Name: "config", MountPath: "/etc/app"},
+			},
+		},
+		{
+			Name:    "plain",
+			Image:   "busybox:stable",
+			Command: []string{"echo", "hi"},
+			Env:     []corev1.EnvVar{{Name: RunInChrootEnv, Value: "false"}},
+		},
+	}
+
+	initContainers := builder.buildInitContainers(userInitContainers)
+	if len(initContainers) != 3 {
+		t.Fatalf("Expected 3 init containers, got %d", len(initContainers))
+	}
+
+	migrate := initContainers[1]
+	if migrate.Name != "user-migrate" {
+		t.Errorf("Name = %q, want user-migrate", migrate.Name)
+	}
+	if migrate.Image != sci.Spec.ExecWrapperImage {
+		t.Errorf("Image = %q, want %q", migrate.Image, sci.Spec.ExecWrapperImage)
+	}
+	wantCommand := []string{"/sc-exec", "--entrypoint", "/app", "/app/migrate", "up"}
+	if !reflect.DeepEqual(migrate.Command, wantCommand) || migrate.Args != nil {
+		t.Errorf("Command = %v, args = %v, want %v and no args", migrate.Command, migrate.Args, wantCommand)
+	}
+	if migrate.SecurityContext == nil || !slices.Contains(migrate.SecurityContext.Capabilities.Add, "SYS_CHROOT") {
+		t.Errorf("SecurityContext = %v, want SYS_CHROOT", migrate.SecurityContext)
+	}
+	env := map[string]string{}
+	for _, e := range migrate.Env {
+		env[e.Name] = e.Value
+	}
+	if env["SC_ROOTFS"] != RootfsMountPath || env[TemplateEnvNamesEnv] != RunInChrootEnv {
+		t.Errorf("Env = %v, want SC_ROOTFS and %s", migrate.Env, TemplateEnvNamesEnv)
+	}
+	mounts := map[string]string{}
+	for _, m := range migrate.VolumeMounts {
+		mounts[m.MountPath] = m.Name
+	}
+	for path, name := range map[string]string{
+		RootfsMountPath:              PropagatedVolumeName,
+		"/etc/app":                   "user-config",
+		RootfsMountPath + "/etc/app": "user-config",
+	} {
+		if mounts[path] != name {
+			t.Errorf("Mount at %s = %q, want %q", path, mounts[path], name)
+		}
+	}
+
+	// Without RunInChrootEnv set to "true" the init container runs in its own image
+	plain := initContainers[2]
+	if plain.Image != "busybox:stable" || !reflect.DeepEqual(plain.Command, []string{"echo", "hi"}) {
+		t.Errorf("Plain init container = %s %v, want busybox:stable [echo hi]", plain.Image, plain.Command)
+	}
+}
+
 func int64Ptr(i int64) *int64 {
 	return &i
 }
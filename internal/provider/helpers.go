@@ -17,8 +17,14 @@ limitations under the License.
 package provider
 
 import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
 	"path/filepath"
+	"slices"
+	"strings"
 
+	"github.com/distribution/reference"
 	scv1alpha1 "github.com/xtlsoft/stoppablecontainer/api/v1alpha1"
 	corev1 "k8s.io/api/core/v1"
 )
@@ -26,6 +32,63 @@ import (
 // DefaultHostPathPrefix is the default prefix for host paths used to share rootfs
 const DefaultHostPathPrefix = "/var/lib/stoppablecontainer"
 
+// AllowedHostPathPrefixes are the directories a hostPathPrefix must be in, or equal to
+// (set via --allowed-host-path-prefixes)
+var AllowedHostPathPrefixes = []string{DefaultHostPathPrefix}
+
+// ValidateHostPathPrefix checks that a hostPathPrefix is an absolute path without ".."
+// components inside one of AllowedHostPathPrefixes. An empty prefix selects the default.
+func ValidateHostPathPrefix(prefix string) error {
+	if prefix == "" {
+		return nil
+	}
+	if !filepath.IsAbs(prefix) {
+		return fmt.Errorf("%q is not an absolute path", prefix)
+	}
+	if slices.Contains(strings.Split(prefix, "/"), "..") {
+		return fmt.Errorf("%q must not contain \"..\"", prefix)
+	}
+	cleaned := filepath.Clean(prefix)
+	for _, allowed := range AllowedHostPathPrefixes {
+		allowed = filepath.Clean(allowed)
+		if cleaned == allowed || strings.HasPrefix(cleaned, strings.TrimSuffix(allowed, "/")+"/") {
+			return nil
+		}
+	}
+	return fmt.Errorf("%q is not under an allowed prefix (%s)", prefix, strings.Join(AllowedHostPathPrefixes, ", "))
+}
+
+// AllowedInfraImages are the images spec.provider.image and spec.execWrapperImage may name
+// besides the operator's own infra images (set via --allowed-infra-images)
+var AllowedInfraImages []string
+
+// ValidateInfraImage checks that an infra image override is one of the operator's infra
+// images or matches AllowedInfraImages. An entry without a tag or digest matches any tag
+// or digest of its repository. An empty image selects the operator's image.
+func ValidateInfraImage(image string) error {
+	if image == "" || image == ExecWrapperImage || image == ProviderImage {
+		return nil
+	}
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return fmt.Errorf("%q is not a valid image reference: %w", image, err)
+	}
+	for _, allowed := range AllowedInfraImages {
+		allowedNamed, err := reference.ParseNormalizedNamed(allowed)
+		if err != nil {
+			continue
+		}
+		if reference.IsNameOnly(allowedNamed) {
+			if allowedNamed.Name() == named.Name() {
+				return nil
+			}
+		} else if allowedNamed.String() == named.String() {
+			return nil
+		}
+	}
+	return fmt.Errorf("%q is not an allowed infra image, see the operator's --allowed-infra-images flag", image)
+}
+
 // ManagedByValue is the value used for the managed-by label
 const ManagedByValue = "stoppablecontainer"
 
@@ -48,3 +111,122 @@ func boolPtr(b bool) *bool {
 func mountPropagationPtr(mp corev1.MountPropagationMode) *corev1.MountPropagationMode {
 	return &mp
 }
+
+// hostPathTypePtr returns a pointer to a HostPathType value.
+func hostPathTypePtr(t corev1.HostPathType) *corev1.HostPathType {
+	return &t
+}
+
+// ParsePullPolicy parses an image pull policy: Always, IfNotPresent or Never.
+func ParsePullPolicy(s string) (corev1.PullPolicy, error) {
+	switch policy := corev1.PullPolicy(s); policy {
+	case corev1.PullAlways, corev1.PullIfNotPresent, corev1.PullNever:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("invalid image pull policy %q: must be Always, IfNotPresent or Never", s)
+	}
+}
+
+// ParseHostPathPrefixes parses a comma-separated list of absolute directories for
+// AllowedHostPathPrefixes.
+func ParseHostPathPrefixes(s string) ([]string, error) {
+	var prefixes []string
+	for _, prefix := range strings.Split(s, ",") {
+		if prefix = strings.TrimSpace(prefix); prefix == "" {
+			continue
+		}
+		if !filepath.IsAbs(prefix) {
+			return nil, fmt.Errorf("%q is not an absolute path", prefix)
+		}
+		prefixes = append(prefixes, filepath.Clean(prefix))
+	}
+	if len(prefixes) == 0 {
+		return nil, fmt.Errorf("at least one prefix is required")
+	}
+	return prefixes, nil
+}
+
+// ParseInfraImages parses a comma-separated list of image references for
+// AllowedInfraImages.
+func ParseInfraImages(s string) ([]string, error) {
+	var images []string
+	for _, image := range strings.Split(s, ",") {
+		if image = strings.TrimSpace(image); image == "" {
+			continue
+		}
+		if _, err := reference.ParseNormalizedNamed(image); err != nil {
+			return nil, fmt.Errorf("%q is not a valid image reference: %w", image, err)
+		}
+		images = append(images, image)
+	}
+	return images, nil
+}
+
+// ParseImagePullSecrets parses a comma-separated list of secret names.
+func ParseImagePullSecrets(s string) []corev1.LocalObjectReference {
+	var secrets []corev1.LocalObjectReference
+	for _, name := range strings.Split(s, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			secrets = append(secrets, corev1.LocalObjectReference{Name: name})
+		}
+	}
+	return secrets
+}
+
+// buildImagePullSecrets appends the infra image pull secrets to the user's,
+// skipping any the user already references.
+func buildImagePullSecrets(userSecrets []corev1.LocalObjectReference) []corev1.LocalObjectReference {
+	if len(InfraImagePullSecrets) == 0 {
+		return userSecrets
+	}
+	secrets := append([]corev1.LocalObjectReference{}, userSecrets...)
+	seen := make(map[string]bool, len(secrets))
+	for _, s := range secrets {
+		seen[s.Name] = true
+	}
+	for _, s := range InfraImagePullSecrets {
+		if !seen[s.Name] {
+			secrets = append(secrets, s)
+			seen[s.Name] = true
+		}
+	}
+	return secrets
+}
+
+// providerImage returns the image of the provider pod's infra containers
+func providerImage(sci *scv1alpha1.StoppableContainerInstance) string {
+	if sci.Spec.Provider.Image != "" {
+		return sci.Spec.Provider.Image
+	}
+	if ProviderImage != "" {
+		return ProviderImage
+	}
+	return ExecWrapperImage
+}
+
+// execWrapperImage returns the image of the consumer pod's exec-wrapper containers
+func execWrapperImage(sci *scv1alpha1.StoppableContainerInstance) string {
+	if sci.Spec.ExecWrapperImage != "" {
+		return sci.Spec.ExecWrapperImage
+	}
+	return ExecWrapperImage
+}
+
+// infraResources returns the resources for an infra container: the user's
+// InfraResources if set (possibly empty, to defer to LimitRange defaults),
+// otherwise the given defaults.
+func infraResources(sci *scv1alpha1.StoppableContainerInstance, defaults corev1.ResourceRequirements) corev1.ResourceRequirements {
+	if sci.Spec.Provider.InfraResources != nil {
+		return *sci.Spec.Provider.InfraResources.DeepCopy()
+	}
+	return defaults
+}
+
+// hashJSON returns a short hash of the JSON encoding of v
+func hashJSON(v any) string {
+	// Marshalling the API types cannot fail
+	data, _ := json.Marshal(v)
+	hasher := fnv.New32a()
+	_, _ = hasher.Write(data)
+	return fmt.Sprintf("%08x", hasher.Sum32())
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package provider
 
 import (
+	"strings"
 	"testing"
 
 	scv1alpha1 "github.com/xtlsoft/stoppablecontainer/api/v1alpha1"
@@ -79,6 +80,109 @@ func TestGetHostPath(t *testing.T) {
 	}
 }
 
+func TestValidateHostPathPrefix(t *testing.T) {
+	orig := AllowedHostPathPrefixes
+	defer func() { AllowedHostPathPrefixes = orig }()
+	AllowedHostPathPrefixes = []string{DefaultHostPathPrefix, "/data/sc/"}
+
+	tests := []struct {
+		prefix  string
+		wantErr bool
+	}{
+		{"", false},
+		{DefaultHostPathPrefix, false},
+		{DefaultHostPathPrefix + "/", false},
+		{DefaultHostPathPrefix + "/team-a", false},
+		{"/data/sc", false},
+		{"/data/sc/nested", false},
+		{"/etc", true},
+		{"/", true},
+		{"var/lib/stoppablecontainer", true},
+		{"/var/lib/stoppablecontainer-evil", true},
+		{DefaultHostPathPrefix + "/../../etc", true},
+		{DefaultHostPathPrefix + "/team/..", true},
+	}
+	for _, tt := range tests {
+		if err := ValidateHostPathPrefix(tt.prefix); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateHostPathPrefix(%q) error = %v, wantErr %v", tt.prefix, err, tt.wantErr)
+		}
+	}
+}
+
+func TestParseHostPathPrefixes(t *testing.T) {
+	prefixes, err := ParseHostPathPrefixes(" /var/lib/stoppablecontainer, /data/sc/ ,")
+	if err != nil {
+		t.Fatalf("ParseHostPathPrefixes() error = %v", err)
+	}
+	if len(prefixes) != 2 || prefixes[0] != "/var/lib/stoppablecontainer" || prefixes[1] != "/data/sc" {
+		t.Errorf("ParseHostPathPrefixes() = %v", prefixes)
+	}
+	for _, s := range []string{"", " , ", "relative/dir"} {
+		if _, err := ParseHostPathPrefixes(s); err == nil {
+			t.Errorf("ParseHostPathPrefixes(%q) expected error", s)
+		}
+	}
+}
+
+func TestValidateInfraImage(t *testing.T) {
+	origAllowed, origExec, origProvider := AllowedInfraImages, ExecWrapperImage, ProviderImage
+	defer func() { AllowedInfraImages, ExecWrapperImage, ProviderImage = origAllowed, origExec, origProvider }()
+	ExecWrapperImage = "ghcr.io/xtlsoft/stoppablecontainer-exec:v1"
+	ProviderImage = "ghcr.io/xtlsoft/stoppablecontainer-provider:v1"
+	AllowedInfraImages = []string{"registry.internal/sc-exec", "docker.io/library/sc-provider:v2"}
+
+	tests := []struct {
+		image   string
+		wantErr bool
+	}{
+		{"", false},
+		{ExecWrapperImage, false},
+		{ProviderImage, false},
+		{"registry.internal/sc-exec:v3", false},
+		{"registry.internal/sc-exec@sha256:" + strings.Repeat("a", 64), false},
+		{"sc-provider:v2", false},
+		{"sc-provider:v3", true},
+		{"registry.internal/sc-exec-evil:v1", true},
+		{"ghcr.io/xtlsoft/stoppablecontainer-exec:v2", true},
+		{"registry.example.com/attacker/provider:latest", true},
+		{"not a reference", true},
+	}
+	for _, tt := range tests {
+		if err := ValidateInfraImage(tt.image); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateInfraImage(%q) error = %v, wantErr %v", tt.image, err, tt.wantErr)
+		}
+	}
+}
+
+func TestParseInfraImages(t *testing.T) {
+	images, err := ParseInfraImages(" registry.internal/sc-exec, sc-provider:v2 ,")
+	if err != nil {
+		t.Fatalf("ParseInfraImages() error = %v", err)
+	}
+	if len(images) != 2 || images[0] != "registry.internal/sc-exec" || images[1] != "sc-provider:v2" {
+		t.Errorf("ParseInfraImages() = %v", images)
+	}
+	if images, err := ParseInfraImages(""); err != nil || len(images) != 0 {
+		t.Errorf("ParseInfraImages(\"\") = %v, %v, want none", images, err)
+	}
+	if _, err := ParseInfraImages("registry.internal/sc-exec,not a reference"); err == nil {
+		t.Error("ParseInfraImages() with an invalid reference expected error")
+	}
+}
+
+func TestParsePullPolicy(t *testing.T) {
+	for _, s := range []string{"Always", "IfNotPresent", "Never"} {
+		if policy, err := ParsePullPolicy(s); err != nil || string(policy) != s {
+			t.Errorf("ParsePullPolicy(%q) = %q, %v", s, policy, err)
+		}
+	}
+	for _, s := range []string{"", "always", "Sometimes"} {
+		if _, err := ParsePullPolicy(s); err == nil {
+			t.Errorf("ParsePullPolicy(%q) expected error", s)
+		}
+	}
+}
+
 func TestBoolPtr(t *testing.T) {
 	trueVal := boolPtr(true)
 	if trueVal == nil || *trueVal != true {
@@ -52,7 +52,10 @@ package provider
 
 import (
 	"fmt"
+	"maps"
 	"os"
+	"strings"
+	"time"
 
 	scv1alpha1 "github.com/xtlsoft/stoppablecontainer/api/v1alpha1"
 	corev1 "k8s.io/api/core/v1"
@@ -70,6 +73,10 @@ const (
 	ConsumerContainerName = "consumer"
 	// ExecWrapperInitName is the name of the init container that installs exec-wrapper
 	ExecWrapperInitName = "exec-wrapper-init"
+	// PauseInitName is the name of the init container that injects the pause binary
+	PauseInitName = "pause-init"
+	// ImagePullInitName is the name of the init container that pre-pulls the user image
+	ImagePullInitName = "image-pull"
 )
 
 // Volume names and mount paths
@@ -82,6 +89,13 @@ const (
 	BinOverlayVolumeName = "sc-bin-overlay"
 	// PauseVolumeName is the volume name for the pause binary injection
 	PauseVolumeName = "sc-pause-bin"
+	// TmpVolumeName is the volume name for the writable /tmp of a read-only consumer container
+	TmpVolumeName = "sc-tmp"
+	// PersistenceVolumeName is the volume name for the spec.persistence claim
+	PersistenceVolumeName = "sc-persistence"
+	// PersistenceMountPath is where the persistence volume is mounted in the rootfs container.
+	// The mount-helper finds it there and keeps the overlay's upper and work directories on it.
+	PersistenceMountPath = "/.sc-persistence"
 	// PropagatedMountPath is where the hostPath is mounted in the provider pod
 	PropagatedMountPath = "/propagated"
 	// HostMountPath is where the hostPath is mounted in the rootfs container
@@ -92,6 +106,12 @@ const (
 	ExecWrapperBinPath = "/.sc-bin"
 	// PauseBinPath is where the pause binary is injected into the rootfs container
 	PauseBinPath = "/.sc-pause"
+	// ImageConfigVolumeName is the volume name for the image config file written by the DaemonSet
+	ImageConfigVolumeName = "sc-image-config"
+	// ImageConfigFileName is the image config file in the instance's host path directory
+	ImageConfigFileName = "image.json"
+	// ImageConfigMountPath is where the image config file is mounted in the consumer pod
+	ImageConfigMountPath = "/.sc-image.json"
 )
 
 // Environment variable names for DaemonSet communication
@@ -100,14 +120,63 @@ const (
 	RootfsMarkerEnv = "ROOTFS_MARKER"
 	// PodUIDEnv is the environment variable containing the pod UID
 	PodUIDEnv = "POD_UID"
+	// SkipNetworkConfigCopyEnv tells sc-exec not to copy resolv.conf/hosts into the rootfs
+	SkipNetworkConfigCopyEnv = "SC_SKIP_NETWORK_CONFIG_COPY"
+	// OverlayDirEnv passes spec.provider.overlayDir to the provider process
+	OverlayDirEnv = "SC_OVERLAY_DIR"
+	// PersistenceEnv tells the provider that the rootfs container holds the persistence volume
+	PersistenceEnv = "SC_PERSISTENCE"
+	// CleanupTimeoutEnv tells the provider how long to wait for the rootfs cleanup on termination
+	CleanupTimeoutEnv = "SC_CLEANUP_TIMEOUT"
+	// CommandFromImageEnv tells sc-exec which parts of the command come from the image config
+	CommandFromImageEnv = "SC_COMMAND_FROM_IMAGE"
+	// TemplateEnvNamesEnv lists the names of the template's env vars, comma-separated,
+	// so sc-exec lets them override the image's environment
+	TemplateEnvNamesEnv = "SC_TEMPLATE_ENV"
+	// ExtraCommandsEnv lists more commands for sc-exec --init to symlink into the /bin
+	// overlay, comma-separated. It is read from the template's main container env.
+	ExtraCommandsEnv = "SC_EXEC_EXTRA_COMMANDS"
+	// RunInChrootEnv, set to "true" on a template init container, runs it with
+	// sc-exec --entrypoint against the rootfs instead of in its own image
+	RunInChrootEnv = "SC_RUN_IN_CHROOT"
+	// LogFormatEnv, set to "json" on the template's main container, makes the provider
+	// and sc-exec write JSON log lines instead of plain text
+	LogFormatEnv = "SC_LOG_FORMAT"
+)
+
+// Values of CommandFromImageEnv
+const (
+	// CommandFromImageEntrypointCmd runs the image's ENTRYPOINT and CMD (no command or args set)
+	CommandFromImageEntrypointCmd = "entrypoint-cmd"
+	// CommandFromImageEntrypoint runs the image's ENTRYPOINT with the template's args
+	CommandFromImageEntrypoint = "entrypoint"
+)
+
+const (
+	// DefaultTerminationGracePeriodSeconds is the Kubernetes default grace period
+	DefaultTerminationGracePeriodSeconds int64 = 30
+	// CleanupTimeoutMargin is kept from the grace period so the provider exits before SIGKILL
+	CleanupTimeoutMargin = 2 * time.Second
+	// DefaultLivenessPeriodSeconds is how often the provider liveness probe runs
+	DefaultLivenessPeriodSeconds int32 = 10
+	// DefaultLivenessFailureThreshold is how many failed liveness checks restart the
+	// provider. With the default period a transient mount issue has 5 minutes to recover.
+	DefaultLivenessFailureThreshold int32 = 30
 )
 
 // Default images used by the operator (can be overridden via environment variables)
 var (
 	// ExecWrapperImage is the image containing the exec-wrapper, pause, and provider binaries
+	// (set via --exec-wrapper-image)
 	ExecWrapperImage = getEnvOrDefault("STOPPABLECONTAINER_EXEC_WRAPPER_IMAGE", "ghcr.io/xtlsoft/stoppablecontainer-exec:latest")
+	// ProviderImage is the image of the provider pod's infra containers (set via
+	// --provider-image). ExecWrapperImage is used when it is empty.
+	ProviderImage = getEnvOrDefault("STOPPABLECONTAINER_PROVIDER_IMAGE", "")
 	// ExecWrapperPullPolicy is the image pull policy for the exec-wrapper image
 	ExecWrapperPullPolicy = corev1.PullPolicy(getEnvOrDefault("STOPPABLECONTAINER_EXEC_WRAPPER_PULL_POLICY", string(corev1.PullIfNotPresent)))
+	// InfraImagePullSecrets are added to every generated pod so the infra images
+	// can be pulled from private registries (set via --infra-image-pull-secrets)
+	InfraImagePullSecrets []corev1.LocalObjectReference
 )
 
 func getEnvOrDefault(key, defaultVal string) string {
@@ -125,8 +194,33 @@ const (
 	LabelInstance = "stoppablecontainer.xtlsoft.top/instance"
 	// LabelRole identifies the role of a pod (provider or consumer)
 	LabelRole = "stoppablecontainer.xtlsoft.top/role"
+	// AnnotationTemplateHash records the hash of the pod template a consumer pod was built from
+	AnnotationTemplateHash = "stoppablecontainer.xtlsoft.top/template-hash"
+	// AnnotationProviderHash records the hash of the instance settings a provider pod was built from
+	AnnotationProviderHash = "stoppablecontainer.xtlsoft.top/provider-hash"
 )
 
+// operatorLabelPrefix is the prefix of the labels the operator sets on its pods
+const operatorLabelPrefix = "stoppablecontainer.xtlsoft.top/"
+
+// buildLabels returns the labels of a pod of the instance with the given role. The
+// instance's own labels, mirrored from its StoppableContainer, come first, then
+// templateLabels; the operator's labels always win. Labels with the operator's prefix
+// are not copied from the instance.
+func buildLabels(sci *scv1alpha1.StoppableContainerInstance, role string, templateLabels map[string]string) map[string]string {
+	labels := make(map[string]string)
+	for k, v := range sci.Labels {
+		if !strings.HasPrefix(k, operatorLabelPrefix) {
+			labels[k] = v
+		}
+	}
+	maps.Copy(labels, templateLabels)
+	labels[LabelManagedBy] = ManagedByValue
+	labels[LabelInstance] = sci.Name
+	labels[LabelRole] = role
+	return labels
+}
+
 // ProviderPodBuilder builds provider pods for StoppableContainerInstances.
 // The provider pod consists of:
 //   - A rootfs sidecar container running the user's image
@@ -140,6 +234,17 @@ func NewProviderPodBuilder(sci *scv1alpha1.StoppableContainerInstance) *Provider
 	return &ProviderPodBuilder{sci: sci}
 }
 
+// ProviderHash returns a hash of the instance settings the provider pod is built from,
+// apart from the template's image. A provider pod whose AnnotationProviderHash differs
+// was built from outdated settings.
+func ProviderHash(sci *scv1alpha1.StoppableContainerInstance) string {
+	return hashJSON(struct {
+		Provider       scv1alpha1.ProviderSpec
+		HostPathPrefix string
+		Persistence    *scv1alpha1.PersistenceSpec
+	}{sci.Spec.Provider, sci.Spec.HostPathPrefix, sci.Spec.Persistence})
+}
+
 // Build creates the provider Pod specification.
 // The provider pod uses a DaemonSet-based architecture where:
 // - The rootfs container runs the user's image and marks itself with ROOTFS_MARKER
@@ -153,10 +258,9 @@ func (b *ProviderPodBuilder) Build() *corev1.Pod {
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      fmt.Sprintf("%s-provider", b.sci.Name),
 			Namespace: b.sci.Namespace,
-			Labels: map[string]string{
-				LabelManagedBy: "stoppablecontainer",
-				LabelInstance:  b.sci.Name,
-				LabelRole:      "provider",
+			Labels:    buildLabels(b.sci, "provider", nil),
+			Annotations: map[string]string{
+				AnnotationProviderHash: ProviderHash(b.sci),
 			},
 			OwnerReferences: []metav1.OwnerReference{
 				{
@@ -170,43 +274,22 @@ func (b *ProviderPodBuilder) Build() *corev1.Pod {
 			},
 		},
 		Spec: corev1.PodSpec{
-			ShareProcessNamespace: boolPtr(true),
-			RestartPolicy:         corev1.RestartPolicyAlways,
-			NodeSelector:          b.sci.Spec.Provider.NodeSelector,
-			Tolerations:           b.sci.Spec.Provider.Tolerations,
+			ShareProcessNamespace:         boolPtr(true),
+			RestartPolicy:                 corev1.RestartPolicyAlways,
+			TerminationGracePeriodSeconds: b.sci.Spec.Provider.TerminationGracePeriodSeconds,
+			NodeSelector:                  b.sci.Spec.Provider.NodeSelector,
+			Tolerations:                   b.sci.Spec.Provider.Tolerations,
+			Affinity:                      b.affinity(),
+			TopologySpreadConstraints:     b.sci.Spec.Provider.TopologySpreadConstraints,
+			PriorityClassName:             b.sci.Spec.Provider.PriorityClassName,
 			Containers: []corev1.Container{
 				{
 					Name:            ProviderContainerName,
-					Image:           ExecWrapperImage,
+					Image:           providerImage(b.sci),
 					ImagePullPolicy: ExecWrapperPullPolicy,
 					// Use the sc-provider binary instead of shell script
 					Command: []string{"/sc-provider"},
-					Env: []corev1.EnvVar{
-						{
-							Name: PodUIDEnv,
-							ValueFrom: &corev1.EnvVarSource{
-								FieldRef: &corev1.ObjectFieldSelector{
-									FieldPath: "metadata.uid",
-								},
-							},
-						},
-						{
-							Name: "POD_NAMESPACE",
-							ValueFrom: &corev1.EnvVarSource{
-								FieldRef: &corev1.ObjectFieldSelector{
-									FieldPath: "metadata.namespace",
-								},
-							},
-						},
-						{
-							Name: "POD_NAME",
-							ValueFrom: &corev1.EnvVarSource{
-								FieldRef: &corev1.ObjectFieldSelector{
-									FieldPath: "metadata.name",
-								},
-							},
-						},
-					},
+					Env:     b.providerEnv(),
 					// No privileged required - DaemonSet handles all privileged operations
 					Resources: b.providerResources(),
 					VolumeMounts: []corev1.VolumeMount{
@@ -226,60 +309,185 @@ func (b *ProviderPodBuilder) Build() *corev1.Pod {
 						PeriodSeconds:       1,
 						FailureThreshold:    120,
 					},
-					LivenessProbe: &corev1.Probe{
-						ProbeHandler: corev1.ProbeHandler{
-							Exec: &corev1.ExecAction{
-								Command: []string{"/sc-provider", "--check-dir", "/propagated/rootfs"},
-							},
-						},
-						InitialDelaySeconds: 30,
-						PeriodSeconds:       10,
-					},
+					LivenessProbe: b.livenessProbe(),
 				},
 				// Rootfs container runs the user's image with ROOTFS_MARKER for DaemonSet to find
 				b.buildRootfsContainer(),
 			},
-			InitContainers: []corev1.Container{
-				// Pause init container: copies the pause binary to a shared volume
-				{
-					Name:            "pause-init",
-					Image:           ExecWrapperImage,
-					ImagePullPolicy: ExecWrapperPullPolicy,
-					Command:         []string{"/sc-exec", "--copy", "/sc-pause", PauseBinPath + "/sc-pause"},
-					VolumeMounts: []corev1.VolumeMount{
-						{
-							Name:      PauseVolumeName,
-							MountPath: PauseBinPath,
-						},
-					},
+			InitContainers:   b.buildInitContainers(),
+			Volumes:          b.volumes(hostPath, hostPathType),
+			ImagePullSecrets: buildImagePullSecrets(b.sci.Spec.Template.Spec.ImagePullSecrets),
+		},
+	}
+}
+
+// volumes returns the provider pod's volumes: the propagated host path, the pause binary
+// and, with spec.persistence, the claim holding the overlay's writable layer
+func (b *ProviderPodBuilder) volumes(hostPath string, hostPathType corev1.HostPathType) []corev1.Volume {
+	volumes := []corev1.Volume{
+		{
+			Name: PropagatedVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				HostPath: &corev1.HostPathVolumeSource{
+					Path: hostPath,
+					Type: &hostPathType,
 				},
 			},
-			Volumes: []corev1.Volume{
-				{
-					Name: PropagatedVolumeName,
-					VolumeSource: corev1.VolumeSource{
-						HostPath: &corev1.HostPathVolumeSource{
-							Path: hostPath,
-							Type: &hostPathType,
-						},
-					},
+		},
+		{
+			Name: PauseVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				EmptyDir: &corev1.EmptyDirVolumeSource{},
+			},
+		},
+	}
+	if p := b.sci.Spec.Persistence; p != nil {
+		volumes = append(volumes, corev1.Volume{
+			Name: PersistenceVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: p.ClaimName},
+			},
+		})
+	}
+	return volumes
+}
+
+// affinity returns the provider pod's affinity: the user's, plus with SpreadAcrossNodes
+// a required anti-affinity against the provider pods of the same workload, see
+// spreadSelector
+func (b *ProviderPodBuilder) affinity() *corev1.Affinity {
+	if !b.sci.Spec.Provider.SpreadAcrossNodes {
+		return b.sci.Spec.Provider.Affinity
+	}
+	affinity := &corev1.Affinity{}
+	if b.sci.Spec.Provider.Affinity != nil {
+		affinity = b.sci.Spec.Provider.Affinity.DeepCopy()
+	}
+	if affinity.PodAntiAffinity == nil {
+		affinity.PodAntiAffinity = &corev1.PodAntiAffinity{}
+	}
+	anti := affinity.PodAntiAffinity
+	anti.RequiredDuringSchedulingIgnoredDuringExecution = append(anti.RequiredDuringSchedulingIgnoredDuringExecution,
+		corev1.PodAffinityTerm{
+			LabelSelector: b.spreadSelector(),
+			TopologyKey:   corev1.LabelHostname,
+		})
+	return affinity
+}
+
+// spreadSelector selects the provider pods that SpreadAcrossNodes keeps apart: those
+// carrying all of the labels mirrored from the StoppableContainer. Without such labels
+// every instance's provider pod in the namespace is selected.
+func (b *ProviderPodBuilder) spreadSelector() *metav1.LabelSelector {
+	matchLabels := map[string]string{LabelRole: "provider"}
+	for k, v := range b.sci.Labels {
+		if !strings.HasPrefix(k, operatorLabelPrefix) {
+			matchLabels[k] = v
+		}
+	}
+	if len(matchLabels) > 1 {
+		return &metav1.LabelSelector{MatchLabels: matchLabels}
+	}
+	return &metav1.LabelSelector{
+		MatchLabels: matchLabels,
+		MatchExpressions: []metav1.LabelSelectorRequirement{{
+			Key:      LabelInstance,
+			Operator: metav1.LabelSelectorOpExists,
+		}},
+	}
+}
+
+// providerEnv returns the environment of the provider container
+func (b *ProviderPodBuilder) providerEnv() []corev1.EnvVar {
+	env := []corev1.EnvVar{
+		{
+			Name: PodUIDEnv,
+			ValueFrom: &corev1.EnvVarSource{
+				FieldRef: &corev1.ObjectFieldSelector{
+					FieldPath: "metadata.uid",
 				},
-				{
-					Name: PauseVolumeName,
-					VolumeSource: corev1.VolumeSource{
-						EmptyDir: &corev1.EmptyDirVolumeSource{},
-					},
+			},
+		},
+		{
+			Name: "POD_NAMESPACE",
+			ValueFrom: &corev1.EnvVarSource{
+				FieldRef: &corev1.ObjectFieldSelector{
+					FieldPath: "metadata.namespace",
 				},
 			},
-			ImagePullSecrets: b.sci.Spec.Template.Spec.ImagePullSecrets,
 		},
+		{
+			Name: "POD_NAME",
+			ValueFrom: &corev1.EnvVarSource{
+				FieldRef: &corev1.ObjectFieldSelector{
+					FieldPath: "metadata.name",
+				},
+			},
+		},
+	}
+	if dir := b.sci.Spec.Provider.OverlayDir; dir != "" {
+		env = append(env, corev1.EnvVar{Name: OverlayDirEnv, Value: dir})
+	}
+	if b.sci.Spec.Persistence != nil {
+		env = append(env, corev1.EnvVar{Name: PersistenceEnv, Value: "true"})
+	}
+	env = append(env, mainContainerEnv(b.sci, LogFormatEnv)...)
+	return append(env, corev1.EnvVar{Name: CleanupTimeoutEnv, Value: b.cleanupTimeout().String()})
+}
+
+// cleanupTimeout is how long the provider waits for mount-helper to clean up the rootfs
+// on termination: the grace period minus a margin to exit before SIGKILL
+func (b *ProviderPodBuilder) cleanupTimeout() time.Duration {
+	grace := DefaultTerminationGracePeriodSeconds
+	if b.sci.Spec.Provider.TerminationGracePeriodSeconds != nil {
+		grace = *b.sci.Spec.Provider.TerminationGracePeriodSeconds
+	}
+	timeout := time.Duration(grace)*time.Second - CleanupTimeoutMargin
+	if timeout < 0 {
+		return 0
+	}
+	return timeout
+}
+
+// livenessProbe returns the provider container's liveness probe as configured by
+// spec.provider.livenessProbe, or nil when it is disabled
+func (b *ProviderPodBuilder) livenessProbe() *corev1.Probe {
+	period := DefaultLivenessPeriodSeconds
+	failureThreshold := DefaultLivenessFailureThreshold
+	if cfg := b.sci.Spec.Provider.LivenessProbe; cfg != nil {
+		if cfg.Disabled {
+			return nil
+		}
+		if cfg.PeriodSeconds > 0 {
+			period = cfg.PeriodSeconds
+		}
+		if cfg.FailureThreshold > 0 {
+			failureThreshold = cfg.FailureThreshold
+		}
+	}
+
+	return &corev1.Probe{
+		ProbeHandler: corev1.ProbeHandler{
+			Exec: &corev1.ExecAction{
+				Command: []string{"/sc-provider", "--check-dir", "/propagated/rootfs"},
+			},
+		},
+		InitialDelaySeconds: 30,
+		PeriodSeconds:       period,
+		FailureThreshold:    failureThreshold,
 	}
 }
 
 func (b *ProviderPodBuilder) providerResources() corev1.ResourceRequirements {
-	if b.sci.Spec.Provider.Resources.Requests != nil || b.sci.Spec.Provider.Resources.Limits != nil {
+	if len(b.sci.Spec.Provider.Resources.Requests) > 0 || len(b.sci.Spec.Provider.Resources.Limits) > 0 {
 		return b.sci.Spec.Provider.Resources
 	}
+	return DefaultProviderResources()
+}
+
+// DefaultProviderResources returns the provider container's resources when
+// spec.provider.resources is empty. The provider only holds the rootfs, so they are small.
+func DefaultProviderResources() corev1.ResourceRequirements {
 	return corev1.ResourceRequirements{
 		Requests: corev1.ResourceList{
 			corev1.ResourceCPU:    resource.MustParse("10m"),
@@ -293,7 +501,7 @@ func (b *ProviderPodBuilder) providerResources() corev1.ResourceRequirements {
 }
 
 func (b *ProviderPodBuilder) minimalResources() corev1.ResourceRequirements {
-	return corev1.ResourceRequirements{
+	return infraResources(b.sci, corev1.ResourceRequirements{
 		Requests: corev1.ResourceList{
 			corev1.ResourceCPU:    resource.MustParse("1m"),
 			corev1.ResourceMemory: resource.MustParse("4Mi"),
@@ -302,7 +510,44 @@ func (b *ProviderPodBuilder) minimalResources() corev1.ResourceRequirements {
 			corev1.ResourceCPU:    resource.MustParse("10m"),
 			corev1.ResourceMemory: resource.MustParse("16Mi"),
 		},
+	})
+}
+
+// buildInitContainers builds the provider's init containers. pause-init copies the pause
+// binary to a shared volume; when PrePullImage is set, image-pull then starts the user
+// image with `sc-pause --check` so pull failures are reported before the rootfs container.
+func (b *ProviderPodBuilder) buildInitContainers() []corev1.Container {
+	pauseMount := []corev1.VolumeMount{
+		{
+			Name:      PauseVolumeName,
+			MountPath: PauseBinPath,
+		},
 	}
+
+	initContainers := []corev1.Container{
+		{
+			Name:            PauseInitName,
+			Image:           providerImage(b.sci),
+			ImagePullPolicy: ExecWrapperPullPolicy,
+			Command:         []string{"/sc-exec", "--copy", "/sc-pause", PauseBinPath + "/sc-pause"},
+			Resources:       infraResources(b.sci, corev1.ResourceRequirements{}),
+			VolumeMounts:    pauseMount,
+		},
+	}
+
+	if b.sci.Spec.Provider.PrePullImage && len(b.sci.Spec.Template.Spec.Containers) > 0 {
+		userContainer := b.sci.Spec.Template.Spec.Containers[0]
+		initContainers = append(initContainers, corev1.Container{
+			Name:            ImagePullInitName,
+			Image:           userContainer.Image,
+			ImagePullPolicy: userContainer.ImagePullPolicy,
+			Command:         []string{PauseBinPath + "/sc-pause", "--check"},
+			Resources:       infraResources(b.sci, corev1.ResourceRequirements{}),
+			VolumeMounts:    pauseMount,
+		})
+	}
+
+	return initContainers
 }
 
 // buildRootfsContainer creates the rootfs sidecar container that keeps the user's
@@ -355,6 +600,12 @@ func (b *ProviderPodBuilder) buildRootfsContainer() corev1.Container {
 			},
 		},
 	}
+	if b.sci.Spec.Persistence != nil {
+		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+			Name:      PersistenceVolumeName,
+			MountPath: PersistenceMountPath,
+		})
+	}
 
 	// If user specified ImagePullPolicy, use it
 	if userImagePullPolicy != "" {
@@ -17,6 +17,7 @@ limitations under the License.
 package provider
 
 import (
+	"reflect"
 	"strings"
 	"testing"
 
@@ -24,6 +25,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
 const (
@@ -171,6 +173,54 @@ func TestProviderPodBuilder_Build(t *testing.T) {
 	}
 }
 
+func TestBuildLabels(t *testing.T) {
+	sci := createTestSCI(testProviderAppName, "production", "nginx:latest")
+	sci.Labels = map[string]string{
+		"team":                         "payments",
+		"cost-center":                  "cc-1",
+		LabelRole:                      "spoofed",
+		LabelInstance:                  "other",
+		LabelManagedBy:                 "someone-else",
+		operatorLabelPrefix + "custom": "dropped",
+	}
+	sci.Spec.Template.Metadata.Labels = map[string]string{
+		"team":    "web",
+		LabelRole: "template",
+	}
+
+	provider := NewProviderPodBuilder(sci).Build()
+	consumer := NewConsumerPodBuilder(sci, "node-1").Build()
+
+	tests := []struct {
+		name string
+		got  map[string]string
+		want map[string]string
+	}{
+		{"provider", provider.Labels, map[string]string{
+			"team":         "payments",
+			"cost-center":  "cc-1",
+			LabelManagedBy: ManagedByValue,
+			LabelInstance:  testProviderAppName,
+			LabelRole:      "provider",
+		}},
+		// Template labels win over the instance's, operator labels over both
+		{"consumer", consumer.Labels, map[string]string{
+			"team":         "web",
+			"cost-center":  "cc-1",
+			LabelManagedBy: ManagedByValue,
+			LabelInstance:  testProviderAppName,
+			LabelRole:      "consumer",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !reflect.DeepEqual(tt.got, tt.want) {
+				t.Errorf("labels = %v, want %v", tt.got, tt.want)
+			}
+		})
+	}
+}
+
 func TestProviderPodBuilder_ProviderBinary(t *testing.T) {
 	sci := createTestSCI("test", "default", "alpine:latest")
 	builder := NewProviderPodBuilder(sci)
@@ -257,6 +307,276 @@ func TestProviderPodBuilder_WithNodeSelector(t *testing.T) {
 	}
 }
 
+func TestProviderPodBuilder_WithAffinity(t *testing.T) {
+	sci := createTestSCI("test", "default", "alpine:latest")
+	sci.Spec.Provider.Affinity = &corev1.Affinity{
+		NodeAffinity: &corev1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+				NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+					MatchExpressions: []corev1.NodeSelectorRequirement{{
+						Key:      "node-role.kubernetes.io/control-plane",
+						Operator: corev1.NodeSelectorOpDoesNotExist,
+					}},
+				}},
+			},
+		},
+	}
+
+	pod := NewProviderPodBuilder(sci).Build()
+
+	if !reflect.DeepEqual(pod.Spec.Affinity, sci.Spec.Provider.Affinity) {
+		t.Errorf("Affinity = %v, want %v", pod.Spec.Affinity, sci.Spec.Provider.Affinity)
+	}
+
+	// Without affinity the scheduler is free to pick any node
+	if pod := NewProviderPodBuilder(createTestSCI("test", "default", "alpine:latest")).Build(); pod.Spec.Affinity != nil {
+		t.Errorf("Expected no affinity by default, got %v", pod.Spec.Affinity)
+	}
+}
+
+func TestProviderPodBuilder_SpreadAcrossNodes(t *testing.T) {
+	sci := createTestSCI("test", "default", "alpine:latest")
+	sci.Spec.Provider.Affinity = &corev1.Affinity{
+		PodAntiAffinity: &corev1.PodAntiAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{{
+				LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}},
+				TopologyKey:   corev1.LabelHostname,
+			}},
+		},
+	}
+	userAffinity := sci.Spec.Provider.Affinity.DeepCopy()
+
+	// Disabled: the user's affinity is passed through unchanged
+	if pod := NewProviderPodBuilder(sci).Build(); !reflect.DeepEqual(pod.Spec.Affinity, userAffinity) {
+		t.Errorf("Affinity = %v, want %v", pod.Spec.Affinity, userAffinity)
+	}
+
+	sci.Spec.Provider.SpreadAcrossNodes = true
+	terms := NewProviderPodBuilder(sci).Build().Spec.Affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+	if len(terms) != 2 || !reflect.DeepEqual(terms[0], userAffinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution[0]) {
+		t.Fatalf("Anti-affinity terms = %v, want the user's term and the spread term", terms)
+	}
+	spread := terms[1]
+	if spread.TopologyKey != corev1.LabelHostname {
+		t.Errorf("TopologyKey = %q, want %q", spread.TopologyKey, corev1.LabelHostname)
+	}
+	selector, err := metav1.LabelSelectorAsSelector(spread.LabelSelector)
+	if err != nil {
+		t.Fatalf("LabelSelectorAsSelector() error = %v", err)
+	}
+	other := NewProviderPodBuilder(createTestSCI("other", "default", "alpine:latest")).Build()
+	if !selector.Matches(labels.Set(other.Labels)) {
+		t.Errorf("Selector %s does not match another instance's provider labels %v", selector, other.Labels)
+	}
+	consumer := NewConsumerPodBuilder(createTestSCI("other", "default", "alpine:latest"), "node-1").Build()
+	if selector.Matches(labels.Set(consumer.Labels)) {
+		t.Errorf("Selector %s matches consumer labels %v", selector, consumer.Labels)
+	}
+	if !reflect.DeepEqual(sci.Spec.Provider.Affinity, userAffinity) {
+		t.Error("Building the pod should not modify the SCI's affinity")
+	}
+
+	// Without a user affinity only the spread term is set
+	sci.Spec.Provider.Affinity = nil
+	if pod := NewProviderPodBuilder(sci).Build(); len(pod.Spec.Affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution) != 1 {
+		t.Errorf("Affinity = %v, want only the spread term", pod.Spec.Affinity)
+	}
+}
+
+func TestProviderPodBuilder_SpreadAcrossNodesWorkloadLabels(t *testing.T) {
+	sci := createTestSCI("web-1", "default", "alpine:latest")
+	sci.Labels = map[string]string{"app": "web", LabelManagedBy: ManagedByValue}
+	sci.Spec.Provider.SpreadAcrossNodes = true
+	terms := NewProviderPodBuilder(sci).Build().Spec.Affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+	if len(terms) != 1 {
+		t.Fatalf("Anti-affinity terms = %v, want only the spread term", terms)
+	}
+	want := map[string]string{LabelRole: "provider", "app": "web"}
+	if !reflect.DeepEqual(terms[0].LabelSelector.MatchLabels, want) || len(terms[0].LabelSelector.MatchExpressions) != 0 {
+		t.Errorf("LabelSelector = %v, want matchLabels %v", terms[0].LabelSelector, want)
+	}
+	selector, err := metav1.LabelSelectorAsSelector(terms[0].LabelSelector)
+	if err != nil {
+		t.Fatalf("LabelSelectorAsSelector() error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		labels map[string]string
+		want   bool
+	}{
+		{name: "same workload", labels: map[string]string{"app": "web"}, want: true},
+		{name: "other workload", labels: map[string]string{"app": "db"}, want: false},
+		{name: "unlabelled", labels: nil, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			other := createTestSCI("other", "default", "alpine:latest")
+			other.Labels = tt.labels
+			pod := NewProviderPodBuilder(other).Build()
+			if got := selector.Matches(labels.Set(pod.Labels)); got != tt.want {
+				t.Errorf("Selector %s matches %v = %v, want %v", selector, pod.Labels, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProviderPodBuilder_WithTopologySpreadConstraints(t *testing.T) {
+	sci := createTestSCI("test", "default", "alpine:latest")
+	sci.Spec.Provider.TopologySpreadConstraints = []corev1.TopologySpreadConstraint{
+		{
+			MaxSkew:           1,
+			TopologyKey:       "topology.kubernetes.io/zone",
+			WhenUnsatisfiable: corev1.ScheduleAnyway,
+			LabelSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{LabelRole: "provider"},
+			},
+		},
+	}
+
+	pod := NewProviderPodBuilder(sci).Build()
+
+	if !reflect.DeepEqual(pod.Spec.TopologySpreadConstraints, sci.Spec.Provider.TopologySpreadConstraints) {
+		t.Errorf("TopologySpreadConstraints = %v, want %v",
+			pod.Spec.TopologySpreadConstraints, sci.Spec.Provider.TopologySpreadConstraints)
+	}
+}
+
+func TestProviderPodBuilder_PriorityClassName(t *testing.T) {
+	sci := createTestSCI("test", "default", "alpine:latest")
+	sci.Spec.Template.Spec.PriorityClassName = "batch-low"
+
+	// The template's priority class is the consumer's, not the provider's
+	if pod := NewProviderPodBuilder(sci).Build(); pod.Spec.PriorityClassName != "" {
+		t.Errorf("PriorityClassName = %q, want none by default", pod.Spec.PriorityClassName)
+	}
+
+	sci.Spec.Provider.PriorityClassName = "rootfs-critical"
+	if pod := NewProviderPodBuilder(sci).Build(); pod.Spec.PriorityClassName != "rootfs-critical" {
+		t.Errorf("PriorityClassName = %q, want %q", pod.Spec.PriorityClassName, "rootfs-critical")
+	}
+}
+
+func TestProviderPodBuilder_Image(t *testing.T) {
+	sci := createTestSCI("test", "default", "alpine:latest")
+	infraImages := func(pod *corev1.Pod) map[string]string {
+		images := map[string]string{}
+		for _, c := range append(pod.Spec.InitContainers, pod.Spec.Containers...) {
+			images[c.Name] = c.Image
+		}
+		return images
+	}
+
+	images := infraImages(NewProviderPodBuilder(sci).Build())
+	if images[ProviderContainerName] != ExecWrapperImage || images[PauseInitName] != ExecWrapperImage {
+		t.Errorf("Expected infra containers to default to %s, got %v", ExecWrapperImage, images)
+	}
+
+	// The override applies to the infra containers only; the rootfs container runs the user image
+	sci.Spec.Provider.Image = "registry.internal/stoppablecontainer-exec:v1"
+	images = infraImages(NewProviderPodBuilder(sci).Build())
+	if images[ProviderContainerName] != sci.Spec.Provider.Image || images[PauseInitName] != sci.Spec.Provider.Image {
+		t.Errorf("Expected infra containers to use %s, got %v", sci.Spec.Provider.Image, images)
+	}
+	if images[RootfsContainerName] != "alpine:latest" {
+		t.Errorf("Rootfs container image = %s, want alpine:latest", images[RootfsContainerName])
+	}
+}
+
+func TestConfiguredImages(t *testing.T) {
+	origExec, origProvider := ExecWrapperImage, ProviderImage
+	defer func() { ExecWrapperImage, ProviderImage = origExec, origProvider }()
+
+	sci := createTestSCI("test", "default", "alpine:latest")
+	images := func() (string, string) {
+		providerPod := NewProviderPodBuilder(sci).Build()
+		consumerPod := NewConsumerPodBuilder(sci, "node-1").Build()
+		return providerPod.Spec.Containers[0].Image, consumerPod.Spec.Containers[0].Image
+	}
+
+	// Both builders use the configured exec-wrapper image
+	ExecWrapperImage, ProviderImage = "registry.internal/exec:v1", ""
+	if p, c := images(); p != ExecWrapperImage || c != ExecWrapperImage {
+		t.Errorf("Images = %s/%s, want %s for both", p, c, ExecWrapperImage)
+	}
+
+	// A configured provider image only applies to the provider pod
+	ProviderImage = "registry.internal/provider:v1"
+	if p, c := images(); p != ProviderImage || c != ExecWrapperImage {
+		t.Errorf("Images = %s/%s, want %s/%s", p, c, ProviderImage, ExecWrapperImage)
+	}
+
+	// Per-resource overrides take precedence
+	sci.Spec.Provider.Image = "registry.team/provider:v2"
+	sci.Spec.ExecWrapperImage = "registry.team/exec:v2"
+	if p, c := images(); p != sci.Spec.Provider.Image || c != sci.Spec.ExecWrapperImage {
+		t.Errorf("Images = %s/%s, want %s/%s", p, c, sci.Spec.Provider.Image, sci.Spec.ExecWrapperImage)
+	}
+}
+
+func TestBuildImagePullSecrets_InfraSecrets(t *testing.T) {
+	orig := InfraImagePullSecrets
+	defer func() { InfraImagePullSecrets = orig }()
+	InfraImagePullSecrets = ParseImagePullSecrets("infra-secret, user-secret")
+
+	sci := createTestSCI("test", "default", "alpine:latest")
+	sci.Spec.Template.Spec.ImagePullSecrets = []corev1.LocalObjectReference{{Name: "user-secret"}}
+
+	pods := map[string]*corev1.Pod{
+		"provider": NewProviderPodBuilder(sci).Build(),
+		"consumer": NewConsumerPodBuilder(sci, "node-1").Build(),
+	}
+	for role, pod := range pods {
+		secrets := pod.Spec.ImagePullSecrets
+		if len(secrets) != 2 {
+			t.Fatalf("%s pod: expected 2 image pull secrets, got %v", role, secrets)
+		}
+		if secrets[0].Name != "user-secret" || secrets[1].Name != "infra-secret" {
+			t.Errorf("%s pod: unexpected image pull secrets %v", role, secrets)
+		}
+	}
+
+	if len(sci.Spec.Template.Spec.ImagePullSecrets) != 1 {
+		t.Error("Building pods should not modify the SCI's image pull secrets")
+	}
+}
+
+func TestExecWrapperPullPolicy(t *testing.T) {
+	orig := ExecWrapperPullPolicy
+	defer func() { ExecWrapperPullPolicy = orig }()
+	ExecWrapperPullPolicy = corev1.PullAlways
+
+	sci := createTestSCI("test", "default", "alpine:latest")
+	sci.Spec.Template.Spec.InitContainers = []corev1.Container{{Name: "setup", Image: "busybox:stable"}}
+
+	provider := NewProviderPodBuilder(sci).Build()
+	consumer := NewConsumerPodBuilder(sci, "node-1").Build()
+
+	// Every container running an infra image uses the configured policy; user
+	// containers keep their own
+	infra := map[string]corev1.Container{
+		"provider " + ProviderContainerName: provider.Spec.Containers[0],
+		"consumer " + ConsumerContainerName: consumer.Spec.Containers[0],
+	}
+	for _, c := range provider.Spec.InitContainers {
+		if c.Image == providerImage(sci) {
+			infra["provider "+c.Name] = c
+		}
+	}
+	infra["consumer "+ExecWrapperInitName] = consumer.Spec.InitContainers[0]
+	if _, ok := infra["provider "+PauseInitName]; !ok {
+		t.Fatalf("%s not found in provider init containers", PauseInitName)
+	}
+	for name, c := range infra {
+		if c.ImagePullPolicy != corev1.PullAlways {
+			t.Errorf("%s: ImagePullPolicy = %q, want %q", name, c.ImagePullPolicy, corev1.PullAlways)
+		}
+	}
+	if policy := consumer.Spec.InitContainers[1].ImagePullPolicy; policy != "" {
+		t.Errorf("user init container: ImagePullPolicy = %q, want it unset", policy)
+	}
+}
+
 func TestProviderPodBuilder_ProviderResources(t *testing.T) {
 	t.Run("default resources", func(t *testing.T) {
 		sci := createTestSCI("test", "default", "alpine:latest")
@@ -310,6 +630,84 @@ func TestProviderPodBuilder_ProviderResources(t *testing.T) {
 	})
 }
 
+func TestInfraResources(t *testing.T) {
+	findContainer := func(containers []corev1.Container, name string) *corev1.Container {
+		for i := range containers {
+			if containers[i].Name == name {
+				return &containers[i]
+			}
+		}
+		t.Fatalf("Container %s not found", name)
+		return nil
+	}
+
+	t.Run("default infra resources", func(t *testing.T) {
+		sci := createTestSCI("test", "default", "alpine:latest")
+		providerPod := NewProviderPodBuilder(sci).Build()
+		consumerPod := NewConsumerPodBuilder(sci, "node-1").Build()
+
+		rootfs := findContainer(providerPod.Spec.Containers, RootfsContainerName)
+		if cpu := rootfs.Resources.Requests[corev1.ResourceCPU]; cpu.String() != "1m" {
+			t.Errorf("Expected rootfs CPU request 1m, got %s", cpu.String())
+		}
+		pauseInit := findContainer(providerPod.Spec.InitContainers, PauseInitName)
+		if pauseInit.Resources.Requests != nil || pauseInit.Resources.Limits != nil {
+			t.Errorf("Expected no pause-init resources by default, got %v", pauseInit.Resources)
+		}
+		execInit := findContainer(consumerPod.Spec.InitContainers, ExecWrapperInitName)
+		if cpu := execInit.Resources.Requests[corev1.ResourceCPU]; cpu.String() != "10m" {
+			t.Errorf("Expected exec-wrapper-init CPU request 10m, got %s", cpu.String())
+		}
+	})
+
+	t.Run("custom infra resources", func(t *testing.T) {
+		sci := createTestSCI("test", "default", "alpine:latest")
+		sci.Spec.Provider.InfraResources = &corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("50m"),
+				corev1.ResourceMemory: resource.MustParse("32Mi"),
+			},
+		}
+		providerPod := NewProviderPodBuilder(sci).Build()
+		consumerPod := NewConsumerPodBuilder(sci, "node-1").Build()
+
+		for _, c := range []*corev1.Container{
+			findContainer(providerPod.Spec.Containers, RootfsContainerName),
+			findContainer(providerPod.Spec.InitContainers, PauseInitName),
+			findContainer(consumerPod.Spec.InitContainers, ExecWrapperInitName),
+		} {
+			if cpu := c.Resources.Requests[corev1.ResourceCPU]; cpu.String() != "50m" {
+				t.Errorf("%s: expected CPU request 50m, got %s", c.Name, cpu.String())
+			}
+			if c.Resources.Limits != nil {
+				t.Errorf("%s: expected no limits, got %v", c.Name, c.Resources.Limits)
+			}
+		}
+
+		// The provider container keeps using Provider.Resources
+		provider := findContainer(providerPod.Spec.Containers, ProviderContainerName)
+		if cpu := provider.Resources.Requests[corev1.ResourceCPU]; cpu.String() != "10m" {
+			t.Errorf("Expected provider CPU request 10m, got %s", cpu.String())
+		}
+	})
+
+	t.Run("empty infra resources defer to LimitRange", func(t *testing.T) {
+		sci := createTestSCI("test", "default", "alpine:latest")
+		sci.Spec.Provider.InfraResources = &corev1.ResourceRequirements{}
+		providerPod := NewProviderPodBuilder(sci).Build()
+		consumerPod := NewConsumerPodBuilder(sci, "node-1").Build()
+
+		for _, c := range []*corev1.Container{
+			findContainer(providerPod.Spec.Containers, RootfsContainerName),
+			findContainer(consumerPod.Spec.InitContainers, ExecWrapperInitName),
+		} {
+			if c.Resources.Requests != nil || c.Resources.Limits != nil {
+				t.Errorf("%s: expected no resources, got %v", c.Name, c.Resources)
+			}
+		}
+	})
+}
+
 func TestProviderPodBuilder_BuildRootfsContainer(t *testing.T) {
 	t.Run("default image pull policy", func(t *testing.T) {
 		sci := createTestSCI("test", "default", "alpine:latest")
@@ -384,3 +782,216 @@ func TestProviderPodBuilder_MinimalResources(t *testing.T) {
 		t.Errorf("Expected memory request 4Mi, got %s", memReq.String())
 	}
 }
+
+func TestProviderPodBuilder_PrePullImage(t *testing.T) {
+	t.Run("disabled by default", func(t *testing.T) {
+		sci := createTestSCI("test", "default", "alpine:latest")
+		pod := NewProviderPodBuilder(sci).Build()
+		for _, c := range pod.Spec.InitContainers {
+			if c.Name == ImagePullInitName {
+				t.Errorf("Expected no %s init container by default", ImagePullInitName)
+			}
+		}
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		sci := createTestSCI("test", "default", "alpine:latest")
+		sci.Spec.Template.Spec.Containers[0].ImagePullPolicy = corev1.PullAlways
+		sci.Spec.Provider.PrePullImage = true
+		pod := NewProviderPodBuilder(sci).Build()
+
+		if len(pod.Spec.InitContainers) != 2 {
+			t.Fatalf("Expected 2 init containers, got %d", len(pod.Spec.InitContainers))
+		}
+		// The pause binary must be copied before the pre-pull container runs it
+		if pod.Spec.InitContainers[0].Name != PauseInitName || pod.Spec.InitContainers[1].Name != ImagePullInitName {
+			t.Errorf("Unexpected init container order: %s, %s",
+				pod.Spec.InitContainers[0].Name, pod.Spec.InitContainers[1].Name)
+		}
+
+		pull := pod.Spec.InitContainers[1]
+		if pull.Image != "alpine:latest" {
+			t.Errorf("Expected image alpine:latest, got %s", pull.Image)
+		}
+		if pull.ImagePullPolicy != corev1.PullAlways {
+			t.Errorf("Expected pull policy Always, got %s", pull.ImagePullPolicy)
+		}
+		expectedCmd := []string{PauseBinPath + "/sc-pause", "--check"}
+		if !reflect.DeepEqual(pull.Command, expectedCmd) {
+			t.Errorf("Expected command %v, got %v", expectedCmd, pull.Command)
+		}
+		if len(pull.VolumeMounts) != 1 || pull.VolumeMounts[0].Name != PauseVolumeName {
+			t.Errorf("Expected pause volume mount, got %v", pull.VolumeMounts)
+		}
+	})
+}
+
+func TestProviderPodBuilder_OverlayDir(t *testing.T) {
+	overlayDirEnv := func(pod *corev1.Pod) (string, bool) {
+		for _, env := range pod.Spec.Containers[0].Env {
+			if env.Name == OverlayDirEnv {
+				return env.Value, true
+			}
+		}
+		return "", false
+	}
+
+	sci := createTestSCI("test", "default", "alpine:latest")
+	if _, found := overlayDirEnv(NewProviderPodBuilder(sci).Build()); found {
+		t.Errorf("Expected no %s env by default", OverlayDirEnv)
+	}
+
+	sci.Spec.Provider.OverlayDir = "/mnt/nvme/stoppablecontainer"
+	value, found := overlayDirEnv(NewProviderPodBuilder(sci).Build())
+	if !found || value != "/mnt/nvme/stoppablecontainer" {
+		t.Errorf("Expected %s=/mnt/nvme/stoppablecontainer, got %q (found %v)", OverlayDirEnv, value, found)
+	}
+}
+
+func TestProviderPodBuilder_LogFormat(t *testing.T) {
+	logFormat := func(pod *corev1.Pod) string {
+		for _, c := range pod.Spec.Containers {
+			if c.Name != ProviderContainerName {
+				continue
+			}
+			for _, e := range c.Env {
+				if e.Name == LogFormatEnv {
+					return e.Value
+				}
+			}
+		}
+		return ""
+	}
+
+	sci := createTestSCI("test", "default", "alpine:latest")
+	if got := logFormat(NewProviderPodBuilder(sci).Build()); got != "" {
+		t.Errorf("Expected no %s by default, got %q", LogFormatEnv, got)
+	}
+
+	sci.Spec.Template.Spec.Containers[0].Env = []corev1.EnvVar{{Name: LogFormatEnv, Value: "json"}}
+	if got := logFormat(NewProviderPodBuilder(sci).Build()); got != "json" {
+		t.Errorf("Provider %s = %q, want json", LogFormatEnv, got)
+	}
+}
+
+func TestProviderPodBuilder_Persistence(t *testing.T) {
+	persistence := func(pod *corev1.Pod) (claim string, mountPath string, env bool) {
+		for _, v := range pod.Spec.Volumes {
+			if v.Name == PersistenceVolumeName && v.PersistentVolumeClaim != nil {
+				claim = v.PersistentVolumeClaim.ClaimName
+			}
+		}
+		for _, c := range pod.Spec.Containers {
+			for _, m := range c.VolumeMounts {
+				if m.Name == PersistenceVolumeName {
+					if c.Name != RootfsContainerName {
+						t.Errorf("Persistence volume mounted in %s, want only %s", c.Name, RootfsContainerName)
+					}
+					mountPath = m.MountPath
+				}
+			}
+			for _, e := range c.Env {
+				if e.Name == PersistenceEnv && e.Value == "true" && c.Name == ProviderContainerName {
+					env = true
+				}
+			}
+		}
+		return claim, mountPath, env
+	}
+
+	sci := createTestSCI("test", "default", "alpine:latest")
+	if claim, mountPath, env := persistence(NewProviderPodBuilder(sci).Build()); claim != "" || mountPath != "" || env {
+		t.Errorf("Expected no persistence by default, got claim %q, mount %q, env %v", claim, mountPath, env)
+	}
+
+	sci.Spec.Persistence = &scv1alpha1.PersistenceSpec{ClaimName: "workspace-data"}
+	claim, mountPath, env := persistence(NewProviderPodBuilder(sci).Build())
+	if claim != "workspace-data" {
+		t.Errorf("Persistence claim = %q, want workspace-data", claim)
+	}
+	if mountPath != PersistenceMountPath {
+		t.Errorf("Persistence mount path = %q, want %s", mountPath, PersistenceMountPath)
+	}
+	if !env {
+		t.Errorf("Expected %s=true on the provider container", PersistenceEnv)
+	}
+}
+
+func TestProviderPodBuilder_TerminationGracePeriod(t *testing.T) {
+	cleanupTimeoutEnv := func(pod *corev1.Pod) string {
+		for _, env := range pod.Spec.Containers[0].Env {
+			if env.Name == CleanupTimeoutEnv {
+				return env.Value
+			}
+		}
+		return ""
+	}
+
+	tests := []struct {
+		name            string
+		grace           *int64
+		expectedTimeout string
+	}{
+		{"default", nil, "28s"},
+		{"custom", func() *int64 { v := int64(120); return &v }(), "1m58s"},
+		{"shorter than margin", func() *int64 { v := int64(1); return &v }(), "0s"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sci := createTestSCI("test", "default", "alpine:latest")
+			sci.Spec.Provider.TerminationGracePeriodSeconds = tt.grace
+			pod := NewProviderPodBuilder(sci).Build()
+
+			if !reflect.DeepEqual(pod.Spec.TerminationGracePeriodSeconds, tt.grace) {
+				t.Errorf("Expected terminationGracePeriodSeconds %v, got %v", tt.grace, pod.Spec.TerminationGracePeriodSeconds)
+			}
+			if got := cleanupTimeoutEnv(pod); got != tt.expectedTimeout {
+				t.Errorf("Expected %s=%s, got %q", CleanupTimeoutEnv, tt.expectedTimeout, got)
+			}
+		})
+	}
+}
+
+func TestProviderPodBuilder_LivenessProbe(t *testing.T) {
+	tests := []struct {
+		name             string
+		probe            *scv1alpha1.ProviderLivenessProbe
+		disabled         bool
+		expectedPeriod   int32
+		expectedFailures int32
+	}{
+		{"default", nil, false, DefaultLivenessPeriodSeconds, DefaultLivenessFailureThreshold},
+		{"lengthened", &scv1alpha1.ProviderLivenessProbe{PeriodSeconds: 60, FailureThreshold: 10}, false, 60, 10},
+		{"only period", &scv1alpha1.ProviderLivenessProbe{PeriodSeconds: 30}, false, 30, DefaultLivenessFailureThreshold},
+		{"disabled", &scv1alpha1.ProviderLivenessProbe{Disabled: true, PeriodSeconds: 60}, true, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sci := createTestSCI("test", "default", "alpine:latest")
+			sci.Spec.Provider.LivenessProbe = tt.probe
+			pod := NewProviderPodBuilder(sci).Build()
+
+			probe := pod.Spec.Containers[0].LivenessProbe
+			if tt.disabled {
+				if probe != nil {
+					t.Errorf("Expected no liveness probe, got %+v", probe)
+				}
+				return
+			}
+			if probe == nil {
+				t.Fatal("Expected a liveness probe")
+			}
+			if probe.PeriodSeconds != tt.expectedPeriod {
+				t.Errorf("Expected periodSeconds %d, got %d", tt.expectedPeriod, probe.PeriodSeconds)
+			}
+			if probe.FailureThreshold != tt.expectedFailures {
+				t.Errorf("Expected failureThreshold %d, got %d", tt.expectedFailures, probe.FailureThreshold)
+			}
+			if probe.Exec == nil || !reflect.DeepEqual(probe.Exec.Command, []string{"/sc-provider", "--check-dir", "/propagated/rootfs"}) {
+				t.Errorf("Unexpected probe handler %+v", probe.ProbeHandler)
+			}
+		})
+	}
+}
@@ -0,0 +1,204 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/distribution/reference"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	scv1alpha1 "github.com/xtlsoft/stoppablecontainer/api/v1alpha1"
+	"github.com/xtlsoft/stoppablecontainer/internal/provider"
+)
+
+// log is for logging in this package.
+var stoppablecontainerlog = logf.Log.WithName("stoppablecontainer-resource")
+
+// SetupStoppableContainerWebhookWithManager registers the webhook for StoppableContainer in the manager.
+func SetupStoppableContainerWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&scv1alpha1.StoppableContainer{}).
+		WithValidator(&StoppableContainerCustomValidator{}).
+		WithDefaulter(&StoppableContainerCustomDefaulter{}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-stoppablecontainer-xtlsoft-top-v1alpha1-stoppablecontainer,mutating=true,failurePolicy=fail,sideEffects=None,groups=stoppablecontainer.xtlsoft.top,resources=stoppablecontainers,verbs=create;update,versions=v1alpha1,name=mstoppablecontainer-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// StoppableContainerCustomDefaulter sets default values on StoppableContainers when they are
+// created or updated, so the stored object shows what the controller will use.
+type StoppableContainerCustomDefaulter struct{}
+
+var _ webhook.CustomDefaulter = &StoppableContainerCustomDefaulter{}
+
+// Default implements webhook.CustomDefaulter so a webhook will be registered for the type StoppableContainer.
+func (d *StoppableContainerCustomDefaulter) Default(_ context.Context, obj runtime.Object) error {
+	sc, ok := obj.(*scv1alpha1.StoppableContainer)
+	if !ok {
+		return fmt.Errorf("expected a StoppableContainer object but got %T", obj)
+	}
+	stoppablecontainerlog.V(1).Info("Defaulting for StoppableContainer", "name", sc.GetName())
+
+	defaultStoppableContainer(sc)
+	return nil
+}
+
+// defaultStoppableContainer fills in the host path prefix and the provider resources
+// with the values the pod builders would otherwise apply. Empty requests and limits,
+// such as "requests: {}", are dropped when the object is stored, so they count as unset.
+func defaultStoppableContainer(sc *scv1alpha1.StoppableContainer) {
+	if sc.Spec.HostPathPrefix == "" {
+		sc.Spec.HostPathPrefix = provider.DefaultHostPathPrefix
+	}
+	resources := &sc.Spec.Provider.Resources
+	if len(resources.Requests) == 0 && len(resources.Limits) == 0 {
+		defaults := provider.DefaultProviderResources()
+		resources.Requests = defaults.Requests
+		resources.Limits = defaults.Limits
+	}
+}
+
+// +kubebuilder:webhook:path=/validate-stoppablecontainer-xtlsoft-top-v1alpha1-stoppablecontainer,mutating=false,failurePolicy=fail,sideEffects=None,groups=stoppablecontainer.xtlsoft.top,resources=stoppablecontainers,verbs=create;update,versions=v1alpha1,name=vstoppablecontainer-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// StoppableContainerCustomValidator validates StoppableContainers when they are created or updated.
+// The consumer only gets CAP_SYS_CHROOT, so privileged containers and images that cannot be
+// pulled are rejected up front instead of failing or being dropped later.
+type StoppableContainerCustomValidator struct{}
+
+var _ webhook.CustomValidator = &StoppableContainerCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type StoppableContainer.
+func (v *StoppableContainerCustomValidator) ValidateCreate(_ context.Context, obj runtime.Object) (admission.Warnings, error) {
+	sc, ok := obj.(*scv1alpha1.StoppableContainer)
+	if !ok {
+		return nil, fmt.Errorf("expected a StoppableContainer object but got %T", obj)
+	}
+	stoppablecontainerlog.V(1).Info("Validation for StoppableContainer upon creation", "name", sc.GetName())
+
+	return nil, toInvalid(sc, validateStoppableContainer(sc))
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type StoppableContainer.
+func (v *StoppableContainerCustomValidator) ValidateUpdate(_ context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldSC, ok := oldObj.(*scv1alpha1.StoppableContainer)
+	if !ok {
+		return nil, fmt.Errorf("expected a StoppableContainer object for the oldObj but got %T", oldObj)
+	}
+	sc, ok := newObj.(*scv1alpha1.StoppableContainer)
+	if !ok {
+		return nil, fmt.Errorf("expected a StoppableContainer object for the newObj but got %T", newObj)
+	}
+	stoppablecontainerlog.V(1).Info("Validation for StoppableContainer upon update", "name", sc.GetName())
+
+	// Objects created before the webhook was installed may not pass validation. Only
+	// check their template when it changes, so they can still be started and stopped.
+	// The host path and the infra images reach the node, so they are always checked.
+	var allErrs field.ErrorList
+	if !equality.Semantic.DeepEqual(oldSC.Spec.Template, sc.Spec.Template) ||
+		allowPrivileged(oldSC) != allowPrivileged(sc) {
+		allErrs = validateTemplate(sc)
+	}
+	allErrs = append(allErrs, validateNodeAccess(sc)...)
+
+	return nil, toInvalid(sc, allErrs)
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type StoppableContainer.
+func (v *StoppableContainerCustomValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validateStoppableContainer checks the template with validateTemplate and the fields that
+// reach the node with validateNodeAccess
+func validateStoppableContainer(sc *scv1alpha1.StoppableContainer) field.ErrorList {
+	return append(validateTemplate(sc), validateNodeAccess(sc)...)
+}
+
+// validateTemplate checks the template's containers: every image must be a valid reference,
+// and privileged containers require the AnnotationAllowPrivileged annotation.
+func validateTemplate(sc *scv1alpha1.StoppableContainer) field.ErrorList {
+	var allErrs field.ErrorList
+	specPath := field.NewPath("spec", "template", "spec")
+	allowed := allowPrivileged(sc)
+
+	validate := func(containers []corev1.Container, path *field.Path) {
+		for i := range containers {
+			c := &containers[i]
+			idxPath := path.Index(i)
+			allErrs = append(allErrs, validateImage(c.Image, idxPath.Child("image"))...)
+			if !allowed && c.SecurityContext != nil && c.SecurityContext.Privileged != nil && *c.SecurityContext.Privileged {
+				allErrs = append(allErrs, field.Forbidden(idxPath.Child("securityContext", "privileged"),
+					fmt.Sprintf("privileged containers require the %s: \"true\" annotation",
+						scv1alpha1.AnnotationAllowPrivileged)))
+			}
+		}
+	}
+	validate(sc.Spec.Template.Spec.InitContainers, specPath.Child("initContainers"))
+	validate(sc.Spec.Template.Spec.Containers, specPath.Child("containers"))
+
+	return allErrs
+}
+
+// validateNodeAccess checks the fields that decide what runs on the node with the
+// mount-helper's trust: the hostPathPrefix must be inside one of
+// provider.AllowedHostPathPrefixes, and infra image overrides must be allowed by
+// provider.ValidateInfraImage.
+func validateNodeAccess(sc *scv1alpha1.StoppableContainer) field.ErrorList {
+	var allErrs field.ErrorList
+	if err := provider.ValidateHostPathPrefix(sc.Spec.HostPathPrefix); err != nil {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("spec", "hostPathPrefix"), sc.Spec.HostPathPrefix, err.Error()))
+	}
+	if err := provider.ValidateInfraImage(sc.Spec.Provider.Image); err != nil {
+		allErrs = append(allErrs, field.Forbidden(field.NewPath("spec", "provider", "image"), err.Error()))
+	}
+	if err := provider.ValidateInfraImage(sc.Spec.ExecWrapperImage); err != nil {
+		allErrs = append(allErrs, field.Forbidden(field.NewPath("spec", "execWrapperImage"), err.Error()))
+	}
+	return allErrs
+}
+
+// validateImage checks that image is a valid, non-empty image reference
+func validateImage(image string, path *field.Path) field.ErrorList {
+	if image == "" {
+		return field.ErrorList{field.Required(path, "an image is required")}
+	}
+	if _, err := reference.ParseNormalizedNamed(image); err != nil {
+		return field.ErrorList{field.Invalid(path, image, err.Error())}
+	}
+	return nil
+}
+
+// allowPrivileged reports whether the StoppableContainer opted in to privileged containers
+func allowPrivileged(sc *scv1alpha1.StoppableContainer) bool {
+	return sc.Annotations[scv1alpha1.AnnotationAllowPrivileged] == "true"
+}
+
+func toInvalid(sc *scv1alpha1.StoppableContainer, errs field.ErrorList) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(scv1alpha1.GroupVersion.WithKind("StoppableContainer").GroupKind(), sc.Name, errs)
+}
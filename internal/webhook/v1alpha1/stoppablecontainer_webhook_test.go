@@ -0,0 +1,350 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	scv1alpha1 "github.com/xtlsoft/stoppablecontainer/api/v1alpha1"
+	"github.com/xtlsoft/stoppablecontainer/internal/provider"
+)
+
+func newStoppableContainer(annotations map[string]string, containers ...corev1.Container) *scv1alpha1.StoppableContainer {
+	return &scv1alpha1.StoppableContainer{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default", Annotations: annotations},
+		Spec: scv1alpha1.StoppableContainerSpec{
+			Template: scv1alpha1.PodTemplateSpec{
+				Spec: corev1.PodSpec{Containers: containers},
+			},
+		},
+	}
+}
+
+func privileged(name, image string) corev1.Container {
+	return corev1.Container{
+		Name:            name,
+		Image:           image,
+		SecurityContext: &corev1.SecurityContext{Privileged: boolPtr(true)},
+	}
+}
+
+func TestDefaultStoppableContainer(t *testing.T) {
+	custom := corev1.ResourceRequirements{
+		Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("256Mi")},
+	}
+
+	tests := []struct {
+		name          string
+		spec          scv1alpha1.StoppableContainerSpec
+		wantPrefix    string
+		wantResources corev1.ResourceRequirements
+	}{
+		{
+			name:          "empty",
+			wantPrefix:    provider.DefaultHostPathPrefix,
+			wantResources: provider.DefaultProviderResources(),
+		},
+		{
+			name:          "custom host path prefix",
+			spec:          scv1alpha1.StoppableContainerSpec{HostPathPrefix: "/data/sc"},
+			wantPrefix:    "/data/sc",
+			wantResources: provider.DefaultProviderResources(),
+		},
+		{
+			name: "custom resources are kept as given",
+			spec: scv1alpha1.StoppableContainerSpec{
+				Provider: scv1alpha1.ProviderSpec{Resources: custom},
+			},
+			wantPrefix:    provider.DefaultHostPathPrefix,
+			wantResources: custom,
+		},
+		{
+			name: "empty requests are defaulted",
+			spec: scv1alpha1.StoppableContainerSpec{
+				Provider: scv1alpha1.ProviderSpec{Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{},
+				}},
+			},
+			wantPrefix:    provider.DefaultHostPathPrefix,
+			wantResources: provider.DefaultProviderResources(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc := &scv1alpha1.StoppableContainer{Spec: tt.spec}
+			defaultStoppableContainer(sc)
+			if sc.Spec.HostPathPrefix != tt.wantPrefix {
+				t.Errorf("hostPathPrefix = %q, want %q", sc.Spec.HostPathPrefix, tt.wantPrefix)
+			}
+			if !equality.Semantic.DeepEqual(sc.Spec.Provider.Resources, tt.wantResources) {
+				t.Errorf("provider resources = %v, want %v", sc.Spec.Provider.Resources, tt.wantResources)
+			}
+
+			// Defaulting again changes nothing
+			defaulted := sc.DeepCopy()
+			defaultStoppableContainer(sc)
+			if !equality.Semantic.DeepEqual(sc, defaulted) {
+				t.Errorf("defaulting is not idempotent: %v, then %v", defaulted.Spec, sc.Spec)
+			}
+		})
+	}
+}
+
+func TestStoppableContainerCustomDefaulter(t *testing.T) {
+	// A manifest as it arrives in the admission request, with an empty requests map
+	manifest := `{
+		"apiVersion": "stoppablecontainer.xtlsoft.top/v1alpha1",
+		"kind": "StoppableContainer",
+		"metadata": {"name": "web", "namespace": "default"},
+		"spec": {
+			"running": true,
+			"provider": {"resources": {"requests": {}}},
+			"template": {"spec": {"containers": [{"name": "main", "image": "nginx:1.27"}]}}
+		}
+	}`
+	sc := &scv1alpha1.StoppableContainer{}
+	if err := json.Unmarshal([]byte(manifest), sc); err != nil {
+		t.Fatalf("decoding manifest: %v", err)
+	}
+
+	if err := (&StoppableContainerCustomDefaulter{}).Default(context.Background(), sc); err != nil {
+		t.Fatalf("Default() error = %v", err)
+	}
+
+	// What gets stored is the defaulted object after a round trip through JSON
+	data, err := json.Marshal(sc)
+	if err != nil {
+		t.Fatalf("encoding defaulted object: %v", err)
+	}
+	stored := &scv1alpha1.StoppableContainer{}
+	if err := json.Unmarshal(data, stored); err != nil {
+		t.Fatalf("decoding defaulted object: %v", err)
+	}
+	if stored.Spec.HostPathPrefix != provider.DefaultHostPathPrefix {
+		t.Errorf("hostPathPrefix = %q, want %q", stored.Spec.HostPathPrefix, provider.DefaultHostPathPrefix)
+	}
+	if !equality.Semantic.DeepEqual(stored.Spec.Provider.Resources, provider.DefaultProviderResources()) {
+		t.Errorf("provider resources = %v, want %v", stored.Spec.Provider.Resources, provider.DefaultProviderResources())
+	}
+	if got := stored.Spec.Template.Spec.Containers; len(got) != 1 || got[0].Image != "nginx:1.27" {
+		t.Errorf("template containers changed: %v", got)
+	}
+
+	if err := (&StoppableContainerCustomDefaulter{}).Default(context.Background(), &corev1.Pod{}); err == nil {
+		t.Error("Default() expected an error for a non-StoppableContainer object")
+	}
+}
+
+func TestValidateStoppableContainer(t *testing.T) {
+	allow := map[string]string{scv1alpha1.AnnotationAllowPrivileged: "true"}
+
+	tests := []struct {
+		name     string
+		sc       *scv1alpha1.StoppableContainer
+		wantErrs []string
+	}{
+		{
+			name: "valid",
+			sc:   newStoppableContainer(nil, corev1.Container{Name: "main", Image: "ubuntu:22.04"}),
+		},
+		{
+			name: "registry and digest",
+			sc: newStoppableContainer(nil, corev1.Container{
+				Name:  "main",
+				Image: "registry.example.com:5000/team/app@sha256:" + "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+			}),
+		},
+		{
+			name:     "empty image",
+			sc:       newStoppableContainer(nil, corev1.Container{Name: "main"}),
+			wantErrs: []string{"spec.template.spec.containers[0].image"},
+		},
+		{
+			name: "invalid image",
+			sc: newStoppableContainer(nil,
+				corev1.Container{Name: "main", Image: "ubuntu:22.04"},
+				corev1.Container{Name: "sidecar", Image: "Busybox:latest"},
+			),
+			wantErrs: []string{"spec.template.spec.containers[1].image"},
+		},
+		{
+			name:     "privileged",
+			sc:       newStoppableContainer(nil, privileged("main", "ubuntu:22.04")),
+			wantErrs: []string{"spec.template.spec.containers[0].securityContext.privileged"},
+		},
+		{
+			name: "privileged explicitly off",
+			sc: newStoppableContainer(nil, corev1.Container{
+				Name:            "main",
+				Image:           "ubuntu:22.04",
+				SecurityContext: &corev1.SecurityContext{Privileged: boolPtr(false)},
+			}),
+		},
+		{
+			name: "privileged allowed",
+			sc:   newStoppableContainer(allow, privileged("main", "ubuntu:22.04")),
+		},
+		{
+			name: "privileged annotation not true",
+			sc: newStoppableContainer(map[string]string{scv1alpha1.AnnotationAllowPrivileged: "yes"},
+				privileged("main", "ubuntu:22.04")),
+			wantErrs: []string{"spec.template.spec.containers[0].securityContext.privileged"},
+		},
+		{
+			name: "privileged allowed with invalid image",
+			sc:   newStoppableContainer(allow, privileged("main", "ubuntu:")),
+			wantErrs: []string{
+				"spec.template.spec.containers[0].image",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := validateStoppableContainer(tt.sc)
+			if len(errs) != len(tt.wantErrs) {
+				t.Fatalf("validateStoppableContainer() = %v, want errors for %v", errs, tt.wantErrs)
+			}
+			for i, err := range errs {
+				if err.Field != tt.wantErrs[i] {
+					t.Errorf("error %d field = %q, want %q", i, err.Field, tt.wantErrs[i])
+				}
+			}
+		})
+	}
+}
+
+func TestValidateStoppableContainer_InitContainers(t *testing.T) {
+	sc := newStoppableContainer(nil, corev1.Container{Name: "main", Image: "ubuntu:22.04"})
+	sc.Spec.Template.Spec.InitContainers = []corev1.Container{privileged("setup", "")}
+
+	errs := validateStoppableContainer(sc)
+	expected := []string{
+		"spec.template.spec.initContainers[0].image",
+		"spec.template.spec.initContainers[0].securityContext.privileged",
+	}
+	if len(errs) != len(expected) {
+		t.Fatalf("validateStoppableContainer() = %v, want errors for %v", errs, expected)
+	}
+	for i, err := range errs {
+		if err.Field != expected[i] {
+			t.Errorf("error %d field = %q, want %q", i, err.Field, expected[i])
+		}
+	}
+}
+
+func TestValidateStoppableContainer_HostPathPrefix(t *testing.T) {
+	for prefix, valid := range map[string]bool{
+		"":                                      true,
+		"/var/lib/stoppablecontainer":           true,
+		"/var/lib/stoppablecontainer/team":      true,
+		"/etc":                                  false,
+		"/var/lib/stoppablecontainer/../../etc": false,
+	} {
+		sc := newStoppableContainer(nil, corev1.Container{Name: "main", Image: "ubuntu:22.04"})
+		sc.Spec.HostPathPrefix = prefix
+		errs := validateStoppableContainer(sc)
+		if valid && len(errs) != 0 {
+			t.Errorf("hostPathPrefix %q: unexpected errors %v", prefix, errs)
+		}
+		if !valid && (len(errs) != 1 || errs[0].Field != "spec.hostPathPrefix") {
+			t.Errorf("hostPathPrefix %q: errors = %v, want one for spec.hostPathPrefix", prefix, errs)
+		}
+	}
+}
+
+func TestValidateStoppableContainer_InfraImages(t *testing.T) {
+	orig := provider.AllowedInfraImages
+	defer func() { provider.AllowedInfraImages = orig }()
+	provider.AllowedInfraImages = []string{"registry.internal/sc-exec"}
+
+	sc := newStoppableContainer(nil, corev1.Container{Name: "main", Image: "ubuntu:22.04"})
+	sc.Spec.Provider.Image = "registry.internal/sc-exec:v2"
+	sc.Spec.ExecWrapperImage = provider.ExecWrapperImage
+	if errs := validateStoppableContainer(sc); len(errs) != 0 {
+		t.Errorf("allowed infra images: unexpected errors %v", errs)
+	}
+
+	sc.Spec.Provider.Image = "registry.example.com/attacker/provider:latest"
+	sc.Spec.ExecWrapperImage = "registry.example.com/attacker/exec:latest"
+	errs := validateStoppableContainer(sc)
+	if len(errs) != 2 || errs[0].Field != "spec.provider.image" || errs[1].Field != "spec.execWrapperImage" {
+		t.Errorf("errors = %v, want one for each infra image", errs)
+	}
+
+	// Updates are checked even if the template is unchanged
+	v := &StoppableContainerCustomValidator{}
+	old := newStoppableContainer(nil, corev1.Container{Name: "main", Image: "ubuntu:22.04"})
+	if _, err := v.ValidateUpdate(context.Background(), old, sc); err == nil {
+		t.Error("ValidateUpdate() setting a disallowed provider image = nil, want an error")
+	}
+}
+
+func TestStoppableContainerCustomValidator(t *testing.T) {
+	v := &StoppableContainerCustomValidator{}
+	ctx := context.Background()
+
+	if _, err := v.ValidateCreate(ctx, newStoppableContainer(nil, privileged("main", "ubuntu:22.04"))); err == nil {
+		t.Error("ValidateCreate() of a privileged container = nil, want an error")
+	}
+	if _, err := v.ValidateCreate(ctx, newStoppableContainer(nil, corev1.Container{Name: "main", Image: "ubuntu"})); err != nil {
+		t.Errorf("ValidateCreate() = %v, want nil", err)
+	}
+
+	// An object from before the webhook can still be started and stopped
+	old := newStoppableContainer(nil, privileged("main", "ubuntu:22.04"))
+	started := old.DeepCopy()
+	started.Spec.Running = true
+	if _, err := v.ValidateUpdate(ctx, old, started); err != nil {
+		t.Errorf("ValidateUpdate() that only sets running = %v, want nil", err)
+	}
+
+	// But not have its template changed without the annotation
+	edited := old.DeepCopy()
+	edited.Spec.Template.Spec.Containers[0].Image = "ubuntu:24.04"
+	if _, err := v.ValidateUpdate(ctx, old, edited); err == nil {
+		t.Error("ValidateUpdate() of a privileged template = nil, want an error")
+	}
+
+	// Removing the annotation revalidates the unchanged template
+	allowed := newStoppableContainer(map[string]string{scv1alpha1.AnnotationAllowPrivileged: "true"},
+		privileged("main", "ubuntu:22.04"))
+	revoked := allowed.DeepCopy()
+	revoked.Annotations = nil
+	if _, err := v.ValidateUpdate(ctx, allowed, revoked); err == nil {
+		t.Error("ValidateUpdate() removing the annotation = nil, want an error")
+	}
+
+	// The hostPathPrefix is checked even if the template is unchanged
+	moved := old.DeepCopy()
+	moved.Spec.HostPathPrefix = "/etc"
+	if _, err := v.ValidateUpdate(ctx, old, moved); err == nil {
+		t.Error("ValidateUpdate() that only sets hostPathPrefix to /etc = nil, want an error")
+	}
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}